@@ -0,0 +1,147 @@
+package sqlair
+
+import (
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// aliasedValue is a value that is bound to a record expression by its alias,
+// rather than by its type name.
+type aliasedValue struct {
+	alias string
+	value interface{}
+}
+
+// As binds the value to record expressions with the given alias. This allows
+// multiple values of the same type to be populated from a self-join:
+//
+//  querier.ForOne(sqlair.As("mother", &mother), sqlair.As("father", &father))
+//
+//  SELECT {m.* INTO Person AS mother}, {f.* INTO Person AS father} FROM ...
+//
+// Values that aren't wrapped are bound to aliased records of the same type in
+// the order that the records appear in the statement.
+func As(alias string, value interface{}) interface{} {
+	return aliasedValue{
+		alias: alias,
+		value: value,
+	}
+}
+
+// unwrapAlias returns the underlying value and alias of the value, if it has
+// one.
+func unwrapAlias(value interface{}) (interface{}, string) {
+	if aliased, ok := value.(aliasedValue); ok {
+		return aliased.value, aliased.alias
+	}
+	return value, ""
+}
+
+// aliasEntities returns the entities with every aliased record bound to an
+// entity. Records are bound to the entity with a matching alias if there is
+// one, otherwise to the next unbound entity of the same type.
+func aliasEntities(records []recordBinding, entities []sreflect.ReflectStruct) ([]sreflect.ReflectStruct, error) {
+	result := entities
+	bound := make(map[int]bool)
+	for _, record := range records {
+		// Aliased nested records are resolved from their parent entity.
+		if record.alias == "" || strings.Contains(record.name, ".") || hasEntity(result, record.alias) {
+			continue
+		}
+
+		index := -1
+		for i, entity := range entities {
+			if !bound[i] && entity.Name == record.name {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			return nil, &offsetError{
+				err: &UnknownEntityError{
+					Name:  record.name,
+					Alias: record.alias,
+				},
+				offset: record.start,
+			}
+		}
+		bound[index] = true
+
+		// Copy the entities so that we never modify the slice owned by the
+		// caller.
+		if &result[0] == &entities[0] {
+			result = append([]sreflect.ReflectStruct(nil), entities...)
+		}
+		result[index].Name = record.alias
+	}
+	return result, nil
+}
+
+func hasEntity(entities []sreflect.ReflectStruct, name string) bool {
+	for _, entity := range entities {
+		if entity.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ColumnAlias changes the prefix and separator used to alias the columns of
+// expanded records, for when AliasPrefix or AliasSeparator collide with genuine
+// column names. Empty values fall back to the defaults.
+//
+// Changing the column alias resets the statement cache for this querier.
+func (q *Querier) ColumnAlias(prefix, separator string) {
+	q.columnAlias = columnAlias{
+		prefix:    prefix,
+		separator: separator,
+	}
+	q.stmtCache = newStatementCache()
+}
+
+// columnAlias encodes and decodes the aliases of expanded record columns.
+type columnAlias struct {
+	prefix    string
+	separator string
+}
+
+func (a columnAlias) aliasPrefix() string {
+	if a.prefix == "" {
+		return AliasPrefix
+	}
+	return a.prefix
+}
+
+func (a columnAlias) aliasSeparator() string {
+	if a.separator == "" {
+		return AliasSeparator
+	}
+	return a.separator
+}
+
+// encode returns the alias of the column for the record and field name.
+func (a columnAlias) encode(record, name string) string {
+	return a.aliasPrefix() + record + a.aliasSeparator() + name
+}
+
+// decode returns the record and field name of an aliased column. If the
+// column isn't aliased, then false is returned. A column that starts with the
+// prefix but can't be decoded is a genuine column that collides with the
+// prefix, which is reported as an error.
+func (a columnAlias) decode(column string) (string, string, bool, error) {
+	prefix := a.aliasPrefix()
+	if !strings.HasPrefix(column, prefix) {
+		return "", column, false, nil
+	}
+	parts := strings.SplitN(column[len(prefix):], a.aliasSeparator(), 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false, a.collision(column)
+	}
+	return parts[0], parts[1], true, nil
+}
+
+func (a columnAlias) collision(column string) error {
+	return errors.Errorf("column %q collides with the alias prefix %q, use a different prefix with ColumnAlias", column, a.aliasPrefix())
+}
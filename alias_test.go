@@ -0,0 +1,172 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// aliasSchema is a table of people that refer to their parents.
+const aliasSchema = `
+CREATE TABLE people(
+	id     INTEGER,
+	name   TEXT,
+	mother INTEGER,
+	father INTEGER
+);
+INSERT INTO people(id, name, mother, father) values (1, "jane", NULL, NULL), (2, "john", NULL, NULL), (3, "fred", 1, 2);
+`
+
+type aliasPerson struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestParseRecordsWithAlias(t *testing.T) {
+	stmt := "SELECT {m.* INTO Person AS mother}, {Person as father EXCEPT id} FROM people;"
+	records, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+
+	assert.Equal(t, records[0].name, "Person")
+	assert.Equal(t, records[0].alias, "mother")
+	assert.Equal(t, records[0].prefix, "m")
+	assert.Equal(t, records[0].entityName(), "mother")
+
+	assert.Equal(t, records[1].name, "Person")
+	assert.Equal(t, records[1].alias, "father")
+	assert.Equal(t, records[1].exclude, map[string]struct{}{"id": {}})
+	assert.True(t, records[1].wildcard)
+}
+
+func TestQueryWithSelfJoinAliases(t *testing.T) {
+	db := setupSchemaDB(t, aliasSchema)
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var mother, father aliasPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(As("father", &father), As("mother", &mother))
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {m.* INTO aliasPerson AS mother}, {f.* INTO aliasPerson AS father} FROM people AS p INNER JOIN people AS m ON p.mother=m.id INNER JOIN people AS f ON p.father=f.id WHERE p.name=:name;`, map[string]interface{}{
+			"name": "fred",
+		})
+	})
+
+	assert.Equal(t, mother, aliasPerson{ID: 1, Name: "jane"})
+	assert.Equal(t, father, aliasPerson{ID: 2, Name: "john"})
+
+	expected := "SELECT m.id AS _pfx_m_sfx_id, m.name AS _pfx_m_sfx_name, f.id AS _pfx_f_sfx_id, f.name AS _pfx_f_sfx_name FROM people AS p INNER JOIN people AS m ON p.mother=m.id INNER JOIN people AS f ON p.father=f.id WHERE p.name=:name;"
+	assert.Equal(t, processedStmt, expected)
+}
+
+func TestQueryWithSelfJoinAliasesByPosition(t *testing.T) {
+	db := setupSchemaDB(t, aliasSchema)
+
+	querier := NewQuerier()
+
+	var mothers, fathers []aliasPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&mothers, &fathers)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {m.* INTO aliasPerson AS mother}, {f.* INTO aliasPerson AS father} FROM people AS p INNER JOIN people AS m ON p.mother=m.id INNER JOIN people AS f ON p.father=f.id;`)
+	})
+
+	assert.Equal(t, mothers, []aliasPerson{{ID: 1, Name: "jane"}})
+	assert.Equal(t, fathers, []aliasPerson{{ID: 2, Name: "john"}})
+}
+
+func TestQueryWithUnboundAlias(t *testing.T) {
+	db := setupSchemaDB(t, aliasSchema)
+
+	querier := NewQuerier()
+
+	var mother aliasPerson
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&mother)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {m.* INTO aliasPerson AS mother}, {f.* INTO aliasPerson AS father} FROM people AS m, people AS f;`)
+	assert.Equal(t, errors.Cause(err).Error(), `no entity found with the name "aliasPerson" for alias "father"`)
+}
+
+func TestColumnAliasDecode(t *testing.T) {
+	alias := columnAlias{}
+
+	record, name, ok, err := alias.decode("_pfx_m_sfx_name")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, record, "m")
+	assert.Equal(t, name, "name")
+
+	_, name, ok, err = alias.decode("name")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, name, "name")
+
+	_, _, _, err = alias.decode("_pfx_name")
+	assert.Equal(t, err.Error(), `column "_pfx_name" collides with the alias prefix "_pfx_", use a different prefix with ColumnAlias`)
+
+	alias = columnAlias{prefix: "__", separator: "__"}
+	assert.Equal(t, alias.encode("m", "name"), "__m__name")
+}
+
+func TestQueryWithColumnAlias(t *testing.T) {
+	db := setupSchemaDB(t, aliasSchema)
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.ColumnAlias("x_", "_y_")
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var mother, father aliasPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(As("mother", &mother), As("father", &father))
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {m.* INTO aliasPerson AS mother}, {f.* INTO aliasPerson AS father} FROM people AS p INNER JOIN people AS m ON p.mother=m.id INNER JOIN people AS f ON p.father=f.id;`)
+	})
+
+	assert.Equal(t, mother, aliasPerson{ID: 1, Name: "jane"})
+	assert.Equal(t, father, aliasPerson{ID: 2, Name: "john"})
+
+	expected := "SELECT m.id AS x_m_y_id, m.name AS x_m_y_name, f.id AS x_f_y_id, f.name AS x_f_y_name FROM people AS p INNER JOIN people AS m ON p.mother=m.id INNER JOIN people AS f ON p.father=f.id;"
+	assert.Equal(t, processedStmt, expected)
+}
+
+func TestQueryWithColumnAliasCollision(t *testing.T) {
+	db := setupSchemaDB(t, aliasSchema)
+
+	querier := NewQuerier()
+
+	var person aliasPerson
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {people.* INTO aliasPerson}, name AS _pfx_people FROM people;`)
+	assert.Equal(t, errors.Cause(err).Error(), `column "_pfx_people" collides with the alias prefix "_pfx_", use a different prefix with ColumnAlias`)
+
+	err = getter.Query(tx, `SELECT {people.* INTO aliasPerson}, name AS _pfx_other_sfx_name FROM people;`)
+	assert.Equal(t, errors.Cause(err).Error(), `column "_pfx_other_sfx_name" collides with the alias prefix "_pfx_", use a different prefix with ColumnAlias`)
+}
@@ -0,0 +1,600 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/SimonRichardson/sqlair/parser"
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const sqlairPath = "github.com/SimonRichardson/sqlair"
+
+// Analyzer checks the statement literals passed to sqlair against the struct
+// types passed along with them. The record expressions of a statement must
+// name the types, and the fields of the records must be fields of the types:
+//
+//  getter, err := querier.ForOne(&person)
+//  ...
+//  err = getter.Query(tx, `SELECT {Person} FROM people WHERE name=:nmae;`, person)
+//
+// reports that the named argument "nmae" isn't a field of Person. The types
+// of a query are those passed to the ForOne, ForMany or ForResults call that
+// the query is assigned from, within the same package. Named arguments are
+// only checked when every argument is a struct, as maps and sql.NamedArg
+// values can't be checked statically.
+var Analyzer = &analysis.Analyzer{
+	Name:     "sqlair",
+	Doc:      "check sqlair record expressions and named arguments against the struct types passed with them",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// tagKey is the struct tag key of the columns, see Querier.TagKey.
+var tagKey = sreflect.DefaultTagKey
+
+func init() {
+	Analyzer.Flags.StringVar(&tagKey, "tag", sreflect.DefaultTagKey, "struct tag key of the columns")
+}
+
+// entity is a struct type passed to sqlair, along with the name that records
+// bind to it by.
+type entity struct {
+	name string
+	typ  types.Type
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// The queries are found first, so that a query is known wherever it's
+	// used within the package.
+	queries := make(map[types.Object][]entity)
+	inspect.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.ValueSpec)(nil)}, func(node ast.Node) {
+		var (
+			lhs []ast.Expr
+			rhs []ast.Expr
+		)
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			lhs, rhs = node.Lhs, node.Rhs
+		case *ast.ValueSpec:
+			for _, name := range node.Names {
+				lhs = append(lhs, name)
+			}
+			rhs = node.Values
+		}
+		if len(rhs) != 1 || len(lhs) == 0 {
+			return
+		}
+		call, ok := rhs[0].(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		switch _, method := sqlairMethod(pass, call); method {
+		case "ForOne", "ForMany", "ForResults":
+		default:
+			return
+		}
+		ident, ok := lhs[0].(*ast.Ident)
+		if !ok {
+			return
+		}
+		obj := pass.TypesInfo.ObjectOf(ident)
+		if obj == nil {
+			return
+		}
+		entities, ok := callEntities(pass, call.Args)
+		if !ok || call.Ellipsis.IsValid() {
+			// Unknown types of any assignment prevent the query from being
+			// checked.
+			queries[obj] = nil
+			return
+		}
+		if existing, found := queries[obj]; found && existing == nil {
+			return
+		}
+		queries[obj] = append(queries[obj], entities...)
+	})
+
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node) {
+		call := node.(*ast.CallExpr)
+		recv, method := sqlairMethod(pass, call)
+		switch {
+		case recv == "Querier" && (method == "Prepare" || method == "Compile" || method == "RegisterStatement"):
+			if len(call.Args) < 1 || call.Ellipsis.IsValid() {
+				return
+			}
+			if entities, ok := callEntities(pass, call.Args[1:]); ok {
+				checkRecords(pass, call.Args[0], entities)
+			}
+
+		case recv == "Query" && (method == "Query" || method == "QueryContext"):
+			stmt := 0
+			if method == "QueryContext" {
+				stmt = 1
+			}
+			checkQuery(pass, queries, queryObject(pass, call.Fun.(*ast.SelectorExpr).X), call.Args, stmt+1)
+
+		case recv == "TxQuerier" && method == "Query":
+			if len(call.Args) > 0 {
+				checkQuery(pass, queries, queryObject(pass, call.Args[0]), call.Args, 1)
+			}
+
+		case recv == "Querier" && method == "Exec":
+			checkNames(pass, call.Args, 1)
+		case recv == "Querier" && method == "ExecContext":
+			checkNames(pass, call.Args, 2)
+		case recv == "DB" && method == "Exec":
+			checkNames(pass, call.Args, 1)
+		case recv == "TxQuerier" && method == "Exec":
+			checkNames(pass, call.Args, 0)
+		}
+	})
+	return nil, nil
+}
+
+// checkQuery checks the statement of a query against the types of the query,
+// along with the named arguments of the statement.
+func checkQuery(pass *analysis.Pass, queries map[types.Object][]entity, obj types.Object, args []ast.Expr, stmt int) {
+	if len(args) <= stmt {
+		return
+	}
+	if entities, ok := queries[obj]; ok && entities != nil {
+		checkRecords(pass, args[stmt], entities)
+	}
+	checkNames(pass, args, stmt)
+}
+
+// queryObject returns the variable of the query expression.
+func queryObject(pass *analysis.Pass, expr ast.Expr) types.Object {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	return pass.TypesInfo.ObjectOf(ident)
+}
+
+// sqlairMethod returns the receiver type and name of the sqlair method that
+// the call calls.
+func sqlairMethod(pass *analysis.Pass, call *ast.CallExpr) (string, string) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", ""
+	}
+	selection, ok := pass.TypesInfo.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return "", ""
+	}
+	fn, ok := selection.Obj().(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != sqlairPath {
+		return "", ""
+	}
+	recv := fn.Type().(*types.Signature).Recv().Type()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	named, ok := recv.(*types.Named)
+	if !ok {
+		return "", ""
+	}
+	return named.Obj().Name(), fn.Name()
+}
+
+// isAsCall returns the alias and value of a call to sqlair.As.
+func isAsCall(pass *analysis.Pass, expr ast.Expr) (string, ast.Expr, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 2 {
+		return "", nil, false
+	}
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.Ident:
+		ident = fun
+	default:
+		return "", nil, false
+	}
+	fn, ok := pass.TypesInfo.ObjectOf(ident).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != sqlairPath || fn.Name() != "As" {
+		return "", nil, false
+	}
+	alias, ok := stringConstant(pass, call.Args[0])
+	if !ok {
+		return "", nil, false
+	}
+	return alias, call.Args[1], true
+}
+
+// callEntities returns the struct types of the values, which is false if any
+// of the types can't be known statically.
+func callEntities(pass *analysis.Pass, args []ast.Expr) ([]entity, bool) {
+	var entities []entity
+	for _, arg := range args {
+		name := ""
+		if alias, value, ok := isAsCall(pass, arg); ok {
+			name, arg = alias, value
+		}
+		typ := pass.TypesInfo.TypeOf(arg)
+		if typ == nil {
+			return nil, false
+		}
+		if isQueryOption(typ) {
+			continue
+		}
+		if isMapType(typ) {
+			// Map destinations select any columns, so only the name of their
+			// records is checked.
+			if name == "" {
+				name = mapName(typ)
+			}
+			entities = append(entities, entity{
+				name: name,
+			})
+			continue
+		}
+		named, ok := structType(typ)
+		if !ok {
+			if _, isInterface := typ.Underlying().(*types.Interface); isInterface {
+				return nil, false
+			}
+			continue
+		}
+		if name == "" {
+			name = named.Obj().Name()
+		}
+		entities = append(entities, entity{
+			name: name,
+			typ:  named,
+		})
+	}
+	return entities, true
+}
+
+// isMapType returns true if the type is a map, or a pointer to a map.
+func isMapType(typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	_, ok := typ.Underlying().(*types.Map)
+	return ok
+}
+
+// mapName returns the name that record expressions select a map destination
+// with, which is the name of a named map type, otherwise M.
+func mapName(typ types.Type) string {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return "M"
+}
+
+// isQueryOption returns true if the type is a sqlair.QueryOption.
+func isQueryOption(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == sqlairPath && named.Obj().Name() == "QueryOption"
+}
+
+// structType returns the named struct type of a struct, or of a pointer or
+// slice of the struct.
+func structType(typ types.Type) (*types.Named, bool) {
+	for {
+		switch t := typ.(type) {
+		case *types.Pointer:
+			typ = t.Elem()
+			continue
+		case *types.Slice:
+			typ = t.Elem()
+			continue
+		case *types.Named:
+			if _, ok := t.Underlying().(*types.Struct); ok {
+				return t, true
+			}
+		}
+		return nil, false
+	}
+}
+
+// stringConstant returns the value of a constant string expression.
+func stringConstant(pass *analysis.Pass, expr ast.Expr) (string, bool) {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// position returns the position of the offset within the statement
+// expression, which is exact for string literals without escapes.
+func position(expr ast.Expr, offset int) token.Pos {
+	if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		return lit.Pos() + token.Pos(offset+1)
+	}
+	return expr.Pos()
+}
+
+// checkRecords reports the record expressions of the statement that don't
+// match the entities.
+func checkRecords(pass *analysis.Pass, expr ast.Expr, entities []entity) {
+	stmt, ok := stringConstant(pass, expr)
+	if !ok {
+		return
+	}
+	offset := parser.IndexOfRecord(stmt, 0)
+	if offset < 0 {
+		return
+	}
+	records, err := parser.ParseRecords(stmt, offset)
+	if err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			pass.Reportf(position(expr, perr.Pos.Offset), "invalid record expression: %s", perr.Message)
+		}
+		return
+	}
+
+	for _, record := range records {
+		// Where and order clauses are bound to the arguments, rather than to
+		// the entities.
+		if record.Where || record.Order {
+			continue
+		}
+		pos := position(expr, record.Pos.Offset)
+		parts := strings.Split(record.Entity, ".")
+
+		var found *entity
+		for i := range entities {
+			if entities[i].name == parts[0] {
+				found = &entities[i]
+				break
+			}
+		}
+		if found == nil {
+			names := make([]string, len(entities))
+			for i, entity := range entities {
+				names[i] = entity.name
+			}
+			pass.Reportf(pos, "unknown entity %q in record expression, expected one of %s", parts[0], strings.Join(names, ", "))
+			continue
+		}
+		if record.Table || found.typ == nil {
+			continue
+		}
+
+		typ, ok := nestedType(found.typ, parts[1:])
+		if !ok {
+			pass.Reportf(pos, "unknown field path %q of %s in record expression", record.Entity, found.name)
+			continue
+		}
+		columns := structColumns(typ)
+		for _, column := range record.Columns {
+			if column.IsWildcard() || columns[column.Name] {
+				continue
+			}
+			pass.Reportf(position(expr, column.Pos.Offset), "unknown field %q of %s in record expression", column.Name, record.Entity)
+		}
+		for _, name := range record.Exclude {
+			if !columns[name] {
+				pass.Reportf(pos, "unknown excluded field %q of %s in record expression", name, record.Entity)
+			}
+		}
+	}
+}
+
+// nestedType walks the Go fields of the path through the struct type,
+// returning the struct type at the end of the path.
+func nestedType(typ types.Type, path []string) (types.Type, bool) {
+	for _, name := range path {
+		st, ok := typ.Underlying().(*types.Struct)
+		if !ok {
+			return nil, false
+		}
+		var next types.Type
+		for i := 0; i < st.NumFields(); i++ {
+			if st.Field(i).Name() == name {
+				next = st.Field(i).Type()
+				break
+			}
+		}
+		if next == nil {
+			return nil, false
+		}
+		named, ok := structType(next)
+		if !ok {
+			return nil, false
+		}
+		typ = named
+	}
+	return typ, true
+}
+
+// structColumns returns the columns of the struct type, following the
+// reflect package: tagged fields are named by their tag, other exported
+// fields by the snake case of their name, and embedded structs are
+// flattened into the struct.
+func structColumns(typ types.Type) map[string]bool {
+	columns := make(map[string]bool)
+	visited := make(map[types.Type]bool)
+
+	var walk func(typ types.Type, prefix string)
+	walk = func(typ types.Type, prefix string) {
+		if visited[typ] {
+			return
+		}
+		visited[typ] = true
+
+		st, ok := typ.Underlying().(*types.Struct)
+		if !ok {
+			return
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			tag, ok := reflect.StructTag(st.Tag(i)).Lookup(tagKey)
+			if tag == "-" {
+				continue
+			}
+			options := strings.Split(tag, ",")
+			name := options[0]
+
+			var nestedPrefix string
+			var json, relation bool
+			for _, option := range options[1:] {
+				switch {
+				case strings.HasPrefix(option, "prefix="):
+					nestedPrefix = strings.TrimPrefix(option, "prefix=")
+				case strings.ToLower(option) == "json":
+					json = true
+				case strings.HasPrefix(option, "hasmany="), strings.HasPrefix(option, "belongsto="):
+					relation = true
+				}
+			}
+			if relation {
+				continue
+			}
+
+			if field.Anonymous() && name == "" && !json {
+				if named, ok := structType(field.Type()); ok {
+					walk(named, prefix+nestedPrefix)
+					continue
+				}
+			}
+			if !field.Exported() {
+				continue
+			}
+			if !ok || name == "" {
+				name = sreflect.SnakeCase(field.Name())
+			}
+			columns[prefix+name] = true
+		}
+	}
+	walk(typ, "")
+	return columns
+}
+
+// checkNames reports the named arguments of the statement that aren't
+// fields of the struct arguments, when every argument is a struct.
+func checkNames(pass *analysis.Pass, args []ast.Expr, stmtIndex int) {
+	if len(args) <= stmtIndex {
+		return
+	}
+	expr := args[stmtIndex]
+	stmt, ok := stringConstant(pass, expr)
+	if !ok {
+		return
+	}
+
+	sources := args[stmtIndex+1:]
+	if len(sources) == 0 {
+		return
+	}
+	columns := make(map[string]bool)
+	var names []string
+	for _, source := range sources {
+		if _, ok := source.(*ast.Ellipsis); ok {
+			return
+		}
+		typ := pass.TypesInfo.TypeOf(source)
+		if typ == nil {
+			return
+		}
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		named, ok := typ.(*types.Named)
+		if !ok {
+			return
+		}
+		if _, ok := named.Underlying().(*types.Struct); !ok {
+			return
+		}
+		if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "database/sql" {
+			return
+		}
+		for column := range structColumns(named) {
+			columns[column] = true
+		}
+		names = append(names, named.Obj().Name())
+	}
+	sort.Strings(names)
+
+	for _, arg := range namedArguments(stmt) {
+		if !columns[arg.name] {
+			pass.Reportf(position(expr, arg.offset), "unknown named argument %q, expected a field of %s", arg.name, strings.Join(names, ", "))
+		}
+	}
+}
+
+// namedArgument is a named argument of a statement.
+type namedArgument struct {
+	name   string
+	offset int
+}
+
+// namedArguments returns the named arguments of the statement in the same
+// way as sqlair parses them, skipping record expressions, positional and
+// numbered arguments, along with dotted names that reach into nested
+// values.
+func namedArguments(stmt string) []namedArgument {
+	var args []namedArgument
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipPast(stmt, i+1, string(c))
+			continue
+		case strings.HasPrefix(stmt[i:], "--"):
+			i = skipPast(stmt, i+2, "\n")
+			continue
+		case strings.HasPrefix(stmt[i:], "/*"):
+			i = skipPast(stmt, i+2, "*/")
+			continue
+		case c == '{':
+			i = skipPast(stmt, i+1, "}")
+			continue
+		case strings.HasPrefix(stmt[i:], "::"):
+			i += 2
+			continue
+		case c != ':' && c != '@' && c != '$':
+			i++
+			continue
+		}
+
+		end := i + 1
+		for end < len(stmt) && (isIdent(stmt[end]) || (stmt[end] == '.' && end+1 < len(stmt) && isIdent(stmt[end+1]))) {
+			end++
+		}
+		name := stmt[i+1 : end]
+		if name != "" && !strings.Contains(name, ".") && strings.Trim(name, "0123456789") != "" {
+			args = append(args, namedArgument{
+				name:   name,
+				offset: i,
+			})
+		}
+		i = end
+	}
+	return args
+}
+
+func isIdent(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_'
+}
+
+// skipPast returns the index directly after the terminator, starting at the
+// offset.
+func skipPast(stmt string, offset int, terminator string) int {
+	if index := strings.Index(stmt[offset:], terminator); index >= 0 {
+		return offset + index + len(terminator)
+	}
+	return len(stmt)
+}
@@ -0,0 +1,14 @@
+// Command sqlairvet checks the sqlair statements of packages against the
+// struct types passed with them:
+//
+//  go vet -vettool=$(which sqlairvet) ./...
+package main
+
+import (
+	"github.com/SimonRichardson/sqlair/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
@@ -0,0 +1,14 @@
+module github.com/SimonRichardson/sqlair/analyzer
+
+go 1.22.0
+
+require (
+	github.com/SimonRichardson/sqlair v0.1.0
+	golang.org/x/tools v0.30.0
+)
+
+require (
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+)
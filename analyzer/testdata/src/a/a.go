@@ -0,0 +1,59 @@
+package a
+
+import (
+	"database/sql"
+
+	"github.com/SimonRichardson/sqlair"
+)
+
+type Address struct {
+	City string `db:"city"`
+}
+
+type Person struct {
+	Name      string `db:"name"`
+	Age       int    `db:"age"`
+	CreatedAt int64
+	Address   Address `db:"-"`
+}
+
+func query(tx *sql.Tx, querier *sqlair.Querier) {
+	var person Person
+	getter, _ := querier.ForOne(&person)
+	getter.Query(tx, `SELECT {Person} FROM people WHERE name=:name;`, person)
+	getter.Query(tx, `SELECT {people.name, people.created_at INTO Person} FROM people;`)
+	getter.Query(tx, `SELECT {Persn} FROM people;`)                           // want `unknown entity "Persn" in record expression, expected one of Person`
+	getter.Query(tx, `SELECT {people.nmae INTO Person} FROM people;`)         // want `unknown field "nmae" of Person in record expression`
+	getter.Query(tx, `SELECT {Person} FROM people WHERE name=:nmae;`, person) // want `unknown named argument "nmae", expected a field of Person`
+	getter.Query(tx, `SELECT {Person} FROM people WHERE name=:nmae;`, map[string]interface{}{})
+	getter.Query(tx, `SELECT {location.* INTO Person.Address} FROM location;`)
+	getter.Query(tx, `SELECT {location.town INTO Person.Address} FROM location;`) // want `unknown field "town" of Person.Address in record expression`
+	getter.Query(tx, `SELECT p.* AS &Person.*, &Person.age FROM people AS p;`)
+	getter.Query(tx, `SELECT p.nmae AS &Person.nmae FROM people AS p;`) // want `unknown field "nmae" of Person in record expression`
+}
+
+func queryMany(tx *sql.Tx, querier *sqlair.Querier) {
+	var persons []Person
+	getter, _ := querier.ForMany(sqlair.As("p", &persons))
+	getter.Query(tx, `SELECT {p.* INTO p EXCEPT age} FROM people p;`)
+	getter.Query(tx, `SELECT {Person} FROM people;`) // want `unknown entity "Person" in record expression, expected one of p`
+}
+
+func queryMap(tx *sql.Tx, querier *sqlair.Querier) {
+	row := sqlair.M{}
+	getter, _ := querier.ForOne(&row)
+	getter.Query(tx, `SELECT {people.name, people.anything INTO M} FROM people;`)
+	getter.Query(tx, `SELECT {people.* INTO Person} FROM people;`) // want `unknown entity "Person" in record expression, expected one of M`
+}
+
+func unknown(tx *sql.Tx, querier *sqlair.Querier, values ...interface{}) {
+	getter, _ := querier.ForOne(values...)
+	getter.Query(tx, `SELECT {Anything} FROM people;`)
+}
+
+func exec(tx *sql.Tx, querier *sqlair.Querier, person Person) {
+	querier.Exec(tx, `UPDATE people SET age=:age WHERE name=:name AND created_at > $1;`, person)
+	querier.Exec(tx, `UPDATE people SET age=:agee;`, &person) // want `unknown named argument "agee", expected a field of Person`
+	querier.Exec(tx, `UPDATE people SET age=:agee;`, sql.Named("agee", 1))
+	querier.Compile(`SELECT {Person} FROM people WHERE age::text=:age;`, Person{})
+}
@@ -0,0 +1,33 @@
+// Package sqlair is a stub of the sqlair API for the analyzer tests.
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Querier struct{}
+
+type Query struct{}
+
+type QueryOption func()
+
+type M = map[string]interface{}
+
+func As(alias string, value interface{}) interface{} { return value }
+
+func (q *Querier) ForOne(values ...interface{}) (Query, error)     { return Query{}, nil }
+func (q *Querier) ForMany(values ...interface{}) (Query, error)    { return Query{}, nil }
+func (q *Querier) ForResults(values ...interface{}) (Query, error) { return Query{}, nil }
+
+func (q *Querier) Compile(stmt string, values ...interface{}) (string, error) { return stmt, nil }
+
+func (q *Querier) Exec(tx *sql.Tx, stmt string, args ...interface{}) (sql.Result, error) {
+	return nil, nil
+}
+
+func (q Query) Query(tx *sql.Tx, stmt string, args ...interface{}) error { return nil }
+
+func (q Query) QueryContext(ctx context.Context, tx *sql.Tx, stmt string, args ...interface{}) error {
+	return nil
+}
@@ -0,0 +1,24 @@
+package sqlair
+
+// Args holds the values of named arguments by name. Args is a map, so it can
+// be used anywhere a map of named arguments can, and Set allows the arguments
+// to be built up by chaining, including arguments that are only set
+// sometimes:
+//
+//  args := sqlair.Args{}.Set("name", name).Set("limit", 10)
+//  if age > 0 {
+//  	args = args.Set("age", age)
+//  }
+//  err := getter.Query(tx, stmt, args)
+//
+type Args map[string]interface{}
+
+// Set sets the value of the named argument, returning the arguments so that
+// calls can be chained. Setting an argument of nil Args allocates them.
+func (a Args) Set(name string, value interface{}) Args {
+	if a == nil {
+		a = make(Args)
+	}
+	a[name] = value
+	return a
+}
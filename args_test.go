@@ -0,0 +1,681 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type argsPerson struct {
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func TestConstructInputNamedArgsMergesSources(t *testing.T) {
+	type Filter struct {
+		Name string `db:"name"`
+	}
+	namedArgs, err := constructInputNamedArgs(reflect.DefaultConfig(), fieldMapper{}, argumentCheck{}, []interface{}{
+		Filter{Name: "fred"},
+		map[string]interface{}{"name": "fred", "limit": 10},
+	}, []nameBinding{
+		{':', "name", 0},
+		{':', "limit", 0},
+		{':', "name", 0},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, namedArgs, []sql.NamedArg{
+		{Name: "name", Value: "fred"},
+		{Name: "limit", Value: 10},
+	})
+}
+
+func TestConstructInputNamedArgsFromTypedMaps(t *testing.T) {
+	type Key string
+
+	namedArgs, err := constructInputNamedArgs(reflect.DefaultConfig(), fieldMapper{}, argumentCheck{}, []interface{}{
+		map[string]string{"name": "fred"},
+		map[Key]int{"limit": 10},
+		map[string]interface{}{"page": map[string]int{"offset": 20}},
+	}, []nameBinding{
+		{':', "name", 0},
+		{':', "limit", 0},
+		{':', "page.offset", 0},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, namedArgs, []sql.NamedArg{
+		{Name: "name", Value: "fred"},
+		{Name: "limit", Value: 10},
+		{Name: "page__offset", Value: 20},
+	})
+}
+
+func TestConstructInputNamedArgsConflict(t *testing.T) {
+	type Filter struct {
+		Name string `db:"name"`
+	}
+	_, err := constructInputNamedArgs(reflect.DefaultConfig(), fieldMapper{}, argumentCheck{}, []interface{}{
+		Filter{Name: "fred"},
+		map[string]interface{}{"name": "frank"},
+	}, []nameBinding{
+		{':', "name", 0},
+	})
+	assert.Equal(t, err, &ConflictingArgumentError{Name: "name"})
+}
+
+func TestConstructInputNamedArgsMissingFromSources(t *testing.T) {
+	type Filter struct {
+		Name string `db:"name"`
+	}
+	_, err := constructInputNamedArgs(reflect.DefaultConfig(), fieldMapper{}, argumentCheck{}, []interface{}{
+		Filter{Name: "fred"},
+		map[string]interface{}{"age": 21},
+	}, []nameBinding{
+		{':', "limit", 0},
+	})
+	assert.Equal(t, err.Error(), `field "limit" missing from type sqlair.Filter and map`)
+}
+
+func TestIsNamedSource(t *testing.T) {
+	assert.True(t, isNamedSource(map[string]interface{}{}))
+	assert.True(t, isNamedSource(argsPerson{}))
+	assert.True(t, isNamedSource(&argsPerson{}))
+	assert.False(t, isNamedSource(nil))
+	assert.False(t, isNamedSource(1))
+	assert.False(t, isNamedSource("fred"))
+	// Maps with unsupported keys are reported, rather than bound as a value.
+	assert.True(t, isNamedSource(map[float64]string{}))
+	assert.True(t, isNamedSource(map[int]string{}))
+	assert.False(t, isNamedSource(sql.Named("name", "fred")))
+	assert.False(t, isNamedSource(sql.NullString{}))
+}
+
+func TestQueryWithMergedNamedArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	type Filter struct {
+		Age int `db:"age"`
+	}
+	type Page struct {
+		Limit int `db:"limit"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.age<:age ORDER BY people.name LIMIT :limit;`, Filter{Age: 30}, Page{Limit: 1})
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "fred", Age: 21},
+	})
+}
+
+func TestExecWithConflictingNamedArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=:age WHERE name=:name;`, argsPerson{Name: "fred", Age: 22}, map[string]interface{}{
+		"name": "frank",
+	})
+	assert.True(t, errors.As(err, new(*ConflictingArgumentError)))
+	assert.Equal(t, err.Error(), `constructing named arguments: conflicting values for named argument "name" at 1:39
+	UPDATE people SET age=:age WHERE name=:name;
+	                                      ^`)
+}
+
+func TestParseNamesWithDottedNames(t *testing.T) {
+	names, err := parseNames("SELECT * FROM people WHERE name=:person.name AND city=@address.city. AND x=?1.5;", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, names, []nameBinding{
+		{':', "person.name", 32},
+		{'@', "address.city", 54},
+		{'?', "1", 75},
+	})
+}
+
+func TestRewriteNames(t *testing.T) {
+	stmt := "SELECT * FROM people WHERE name=:person.name AND age=:age;"
+	names, err := parseNames(stmt, 0)
+	assert.Nil(t, err)
+
+	rewritten, err := rewriteNames(stmt, names)
+	assert.Nil(t, err)
+	assert.Equal(t, rewritten, "SELECT * FROM people WHERE name=:person__name AND age=:age;")
+
+	stmt = "SELECT * FROM people WHERE age=:age;"
+	names, err = parseNames(stmt, 0)
+	assert.Nil(t, err)
+
+	rewritten, err = rewriteNames(stmt, names)
+	assert.Nil(t, err)
+	assert.Equal(t, rewritten, stmt)
+}
+
+func TestRewriteNamesCollision(t *testing.T) {
+	stmt := "SELECT * FROM people WHERE name=:person.name AND other=:person__name;"
+	names, err := parseNames(stmt, 0)
+	assert.Nil(t, err)
+
+	_, err = rewriteNames(stmt, names)
+	assert.Equal(t, errors.Cause(err).Error(), `named argument "person__name" collides with "person.name"`)
+}
+
+func TestQueryWithNestedNamedArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	type Address struct {
+		Age int `db:"age"`
+	}
+	type Filter struct {
+		Person  argsPerson `db:"person"`
+		Address *Address   `db:"address"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		if err := getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.name=:person.name OR people.age=:address.age ORDER BY people.name;`, Filter{
+			Person:  argsPerson{Name: "fred"},
+			Address: &Address{Age: 42},
+		}); err != nil {
+			return err
+		}
+
+		return getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.name=:person.name;`, map[string]interface{}{
+			"person": map[string]interface{}{
+				"name": "jane",
+			},
+		})
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "fred", Age: 21},
+		{Name: "jane", Age: 23},
+	})
+	assert.Equal(t, processedStmt, "SELECT people.age, people.name FROM people WHERE people.name=:person__name;")
+}
+
+func TestQueryWithMissingNestedNamedArg(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=1 WHERE name=:person.name;`, map[string]interface{}{
+		"person": map[string]interface{}{},
+	})
+	assert.Equal(t, errors.Cause(err).Error(), `key "person.name" missing from map`)
+}
+
+func TestRewriteNamesNumbersPositional(t *testing.T) {
+	stmt := "SELECT * FROM people WHERE name=:name AND age>? AND age<?;"
+	names, err := parseNames(stmt, 0)
+	assert.Nil(t, err)
+
+	rewritten, err := rewriteNames(stmt, names)
+	assert.Nil(t, err)
+	assert.Equal(t, rewritten, "SELECT * FROM people WHERE name=:name AND age>:p__1 AND age<:p__2;")
+}
+
+func TestRewriteNamesPositionalCollision(t *testing.T) {
+	stmt := "SELECT * FROM people WHERE name=:p__1 AND age>?;"
+	names, err := parseNames(stmt, 0)
+	assert.Nil(t, err)
+
+	_, err = rewriteNames(stmt, names)
+	assert.Equal(t, errors.Cause(err).Error(), `named argument "?" collides with "p__1"`)
+}
+
+func TestQueryWithPositionalAndNamedArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		// The named argument comes first in the statement, so it takes the
+		// first parameter index in SQLite.
+		return getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.name!=:name AND people.age>? AND people.age<? ORDER BY people.name;`, map[string]interface{}{
+			"name": "fred",
+		}, 20, 30)
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "jane", Age: 23},
+	})
+}
+
+func TestExecWithPositionalArgsOnly(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := querier.Exec(tx, `UPDATE people SET age=? WHERE name=?;`, 30, "fred"); err != nil {
+			return err
+		}
+
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.age=?;`, 30)
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "fred", Age: 30},
+	})
+}
+
+func TestExecWithMismatchedPositionalArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=? WHERE name=:name;`, map[string]interface{}{
+		"name": "fred",
+	})
+	assert.Equal(t, errors.Cause(err).Error(), `expected 1 positional arguments, got 0`)
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=1 WHERE name=:name;`, map[string]interface{}{
+		"name": "fred",
+	}, 1)
+	assert.Equal(t, errors.Cause(err).Error(), `expected 0 positional arguments, got 1`)
+}
+
+func TestRewriteNamesNumbered(t *testing.T) {
+	stmt := "SELECT * FROM people WHERE name=?1 OR nickname=?1 OR age=?2;"
+	names, err := parseNames(stmt, 0)
+	assert.Nil(t, err)
+
+	rewritten, err := rewriteNames(stmt, names)
+	assert.Nil(t, err)
+	assert.Equal(t, rewritten, "SELECT * FROM people WHERE name=:n__1 OR nickname=:n__1 OR age=:n__2;")
+}
+
+func TestQueryWithNumberedArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.name=:name OR people.age=?2 OR people.name=?1 OR people.age=?2 ORDER BY people.name;`, [2]interface{}{"fred", 42}, map[string]interface{}{
+			"name": "jane",
+		})
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "fred", Age: 21},
+		{Name: "jane", Age: 23},
+	})
+}
+
+func TestExecWithMissingNumberedArg(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=?2 WHERE name=?1;`, []string{"fred"})
+	assert.Equal(t, errors.Cause(err).Error(), `index 2 missing from type []string`)
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=?2 WHERE name=?1;`, []byte("fred"))
+	assert.Equal(t, errors.Cause(err).Error(), `"slice" not supported`)
+}
+
+func TestSplitNamedArgs(t *testing.T) {
+	name := sql.Named("name", "fred")
+	args, explicit, err := splitNamedArgs([]interface{}{
+		argsPerson{},
+		sql.Named("age", 21),
+		10,
+		&name,
+		sql.Named("age", 21),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, args, []interface{}{argsPerson{}, 10})
+	assert.Equal(t, explicit, []sql.NamedArg{
+		{Name: "age", Value: 21},
+		{Name: "name", Value: "fred"},
+	})
+
+	_, _, err = splitNamedArgs([]interface{}{
+		sql.Named("age", 21),
+		sql.Named("age", 42),
+	})
+	assert.Equal(t, err, &ConflictingArgumentError{Name: "age"})
+}
+
+func TestQueryWithNamedArgPrecedence(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.name=:name OR people.age=:age ORDER BY people.name;`, argsPerson{Name: "fred", Age: 21}, sql.Named("age", 42))
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "fred", Age: 21},
+	})
+}
+
+func TestQueryWithOnlyNamedArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.name=:person.name OR people.age=? ORDER BY people.name;`, 23, sql.Named("person.name", "fred"))
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "fred", Age: 21},
+		{Name: "jane", Age: 23},
+	})
+}
+
+func TestExecWithConflictingExplicitNamedArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=:age WHERE name=:name;`, sql.Named("name", "fred"), sql.Named("age", 22), sql.Named("name", "frank"))
+	assert.True(t, errors.As(err, new(*ConflictingArgumentError)))
+	assert.Equal(t, err.Error(), `constructing named arguments: conflicting values for named argument "name" at 1:39
+	UPDATE people SET age=:age WHERE name=:name;
+	                                      ^`)
+}
+
+func TestArgsSet(t *testing.T) {
+	args := Args{}.Set("name", "fred").Set("age", 21)
+	assert.Equal(t, args, Args{"name": "fred", "age": 21})
+
+	var empty Args
+	assert.Equal(t, empty.Set("name", "fred"), Args{"name": "fred"})
+}
+
+func TestQueryWithArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		args := Args{}.Set("age", 30).Set("limit", 2)
+		if err := getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.age<:age ORDER BY people.name LIMIT :limit;`, args); err != nil {
+			return err
+		}
+
+		// M is a map[string]interface{}, so it provides named arguments
+		// in the same way.
+		return getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.name=:name;`, M{"name": "frank"})
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "fred", Age: 21},
+		{Name: "jane", Age: 23},
+		{Name: "frank", Age: 42},
+	})
+}
+
+func TestQueryWithIdentInArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	stmt, err := querier.Prepare(`SELECT {argsPerson} FROM people ORDER BY :column;`, []argsPerson{})
+	assert.Nil(t, err)
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		args := Args{}.Set("column", Ident("age"))
+		if err := getter.Query(tx, `SELECT {argsPerson} FROM people ORDER BY :column;`, args); err != nil {
+			return err
+		}
+
+		// Prepared statements can't splice identifiers.
+		var prepared []argsPerson
+		err = stmt.Query(tx, &prepared, args)
+		assert.Equal(t, err.Error(), `unexpected identifier "age" for prepared statement`)
+		return nil
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "fred", Age: 21},
+		{Name: "jane", Age: 23},
+		{Name: "frank", Age: 42},
+	})
+}
+
+func TestQueryWithMapOfStrings(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	// Query parameters are commonly held as strings.
+	params := map[string]string{"name": "jane"}
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {argsPerson} FROM people WHERE name=:name;`, params)
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "jane", Age: 23},
+	})
+}
+
+type argsKey string
+
+func TestQueryWithTypedKeyMaps(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {argsPerson} FROM people WHERE name=:name OR age=?1 ORDER BY name;`, map[argsKey]string{
+			"name": "jane",
+		}, map[int]interface{}{
+			1: 42,
+		})
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "jane", Age: 23},
+	})
+}
+
+func TestExecWithUnsupportedKeyMap(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=:age WHERE name="fred";`, map[float64]int{1: 42})
+	assert.True(t, errors.As(err, new(*ArgumentKeyError)))
+	assert.Equal(t, errors.Cause(err).Error(), `unsupported key type float64 of argument map map[float64]int, expected string or integer keys`)
+}
+
+func TestConstructNamedArgumentsBindsNamesOnce(t *testing.T) {
+	config := reflect.DefaultConfig()
+
+	stmt, args, err := constructNamedArguments(config, fieldMapper{}, argumentCheck{}, `SELECT * FROM people WHERE name=:name OR alias=@name OR (age=:age AND name=:name);`, []interface{}{
+		map[string]interface{}{"name": "fred", "age": 21},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, stmt, `SELECT * FROM people WHERE name=:name OR alias=@name OR (age=:age AND name=:name);`)
+	assert.Equal(t, args, []interface{}{
+		sql.Named("name", "fred"),
+		sql.Named("age", 21),
+	})
+}
+
+func TestConstructNamedArgumentsOrdersNames(t *testing.T) {
+	config := reflect.DefaultConfig()
+
+	// Names are bound in the order they first appear in the statement, with
+	// the named arguments of the caller interleaved, followed by any named
+	// arguments that aren't in the statement.
+	_, args, err := constructNamedArguments(config, fieldMapper{}, argumentCheck{}, `SELECT * FROM people WHERE name=:name OR age=:age OR alias=:name;`, []interface{}{
+		sql.Named("extra", 1),
+		sql.Named("name", "fred"),
+		map[string]interface{}{"age": 21},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, args, []interface{}{
+		sql.Named("name", "fred"),
+		sql.Named("age", 21),
+		sql.Named("extra", 1),
+	})
+}
+
+func TestConstructNamedArgumentsBindsRewrittenNamesOnce(t *testing.T) {
+	config := reflect.DefaultConfig()
+
+	// Dotted names.
+	stmt, args, err := constructNamedArguments(config, fieldMapper{}, argumentCheck{}, `SELECT * FROM people LIMIT :page.limit OFFSET :page.offset * :page.limit;`, []interface{}{
+		map[string]interface{}{"page": map[string]interface{}{"limit": 10, "offset": 2}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, stmt, `SELECT * FROM people LIMIT :page__limit OFFSET :page__offset * :page__limit;`)
+	assert.Equal(t, args, []interface{}{
+		sql.Named("page__limit", 10),
+		sql.Named("page__offset", 2),
+	})
+
+	// Numbered arguments, mixed with positional placeholders, which are
+	// bound once for every placeholder.
+	stmt, args, err = constructNamedArguments(config, fieldMapper{}, argumentCheck{}, `SELECT * FROM people WHERE name=?1 OR alias=?1 OR age=? OR age=?;`, []interface{}{
+		[]interface{}{"fred"}, 21, 42,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, stmt, `SELECT * FROM people WHERE name=:n__1 OR alias=:n__1 OR age=:p__1 OR age=:p__2;`)
+	assert.Equal(t, args, []interface{}{
+		sql.Named("p__1", 21),
+		sql.Named("p__2", 42),
+		sql.Named("n__1", "fred"),
+	})
+
+	// Identifiers are spliced into every use of the name.
+	stmt, args, err = constructNamedArguments(config, fieldMapper{dialect: MySQL}, argumentCheck{}, `SELECT :column FROM people WHERE :column=:name ORDER BY :column;`, []interface{}{
+		map[string]interface{}{"column": Ident("name"), "name": "fred"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, stmt, "SELECT `name` FROM people WHERE `name`=:name ORDER BY `name`;")
+	assert.Equal(t, args, []interface{}{
+		sql.Named("name", "fred"),
+	})
+}
+
+func TestConstructNamedArgumentsConflictingBindName(t *testing.T) {
+	config := reflect.DefaultConfig()
+
+	// The dotted name is bound to the same name as the named argument.
+	_, _, err := constructNamedArguments(config, fieldMapper{}, argumentCheck{}, `SELECT * FROM people OFFSET :page.offset;`, []interface{}{
+		sql.Named("page__offset", 1),
+		map[string]interface{}{"page": map[string]interface{}{"offset": 2}},
+	})
+	assert.True(t, errors.As(err, new(*ConflictingArgumentError)))
+
+	_, args, err := constructNamedArguments(config, fieldMapper{}, argumentCheck{}, `SELECT * FROM people OFFSET :page.offset;`, []interface{}{
+		sql.Named("page__offset", 2),
+		map[string]interface{}{"page": map[string]interface{}{"offset": 2}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, args, []interface{}{
+		sql.Named("page__offset", 2),
+	})
+}
+
+func TestQueryWithRepeatedNamedArgs(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {argsPerson} FROM people WHERE name=:name OR (name<>:name AND age>:age) OR age=:age ORDER BY name;`, argsPerson{Name: "fred", Age: 23})
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "fred", Age: 21},
+		{Name: "jane", Age: 23},
+	})
+}
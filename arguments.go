@@ -0,0 +1,157 @@
+package sqlair
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/SimonRichardson/sqlair/parser"
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+)
+
+// ArgumentHook is called with each suspicious argument found by strict
+// arguments, which is either an UnusedArgumentError or an EmptyArgumentError.
+// Returning an error prevents the statement from being executed, with the
+// error being returned to the caller. Returning nil allows the hook to only
+// warn about the argument.
+type ArgumentHook func(error) error
+
+// StrictArguments enables the checking of the arguments of a statement. Any
+// key of a map, field of a type, element of a slice or sql.NamedArg that
+// doesn't match a named argument of the statement is reported, as is a named
+// argument that's bound from a zero valued field tagged with omitempty. These
+// are a frequent cause of queries that silently return nothing.
+//
+// If the hook is nil, the first argument that's reported is returned as an
+// error.
+//
+//  querier.StrictArguments(func(err error) error {
+//  	log.Printf("warning: %v", err)
+//  	return nil
+//  })
+//
+// Only the top level keys and fields of an argument are checked, so a nested
+// map is used as long as one of its keys is.
+func (q *Querier) StrictArguments(hook ArgumentHook) {
+	q.argCheck = argumentCheck{
+		enabled: true,
+		hook:    hook,
+	}
+}
+
+type argumentCheck struct {
+	enabled bool
+	hook    ArgumentHook
+	// partial binds the named arguments that aren't found in the arguments
+	// to their defaults, or NULL, rather than erroring (see Defaults).
+	partial  bool
+	defaults map[string]interface{}
+}
+
+// withDefaults returns the check with the defaults of the query options.
+func (c argumentCheck) withDefaults(options queryOptions) argumentCheck {
+	c.partial = options.partial
+	c.defaults = options.defaults
+	return c
+}
+
+// missing returns the value bound to a named argument that isn't found in the
+// arguments, and false if the named argument must be provided.
+func (c argumentCheck) missing(name string) (interface{}, bool) {
+	if !c.partial {
+		return nil, false
+	}
+	return c.defaults[name], true
+}
+
+// report returns the error for a suspicious argument, unless the checking is
+// disabled or the hook allows it.
+func (c argumentCheck) report(err error) error {
+	if !c.enabled {
+		return nil
+	}
+	if c.hook == nil {
+		return err
+	}
+	return c.hook(err)
+}
+
+// unused reports the keys and fields of the sources, along with the named
+// arguments, that don't match any of the names of the statement.
+func (c argumentCheck) unused(config sreflect.Config, sources []interface{}, explicit []sql.NamedArg, names []nameBinding) error {
+	if !c.enabled {
+		return nil
+	}
+
+	used := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name.name == "" {
+			continue
+		}
+		used[name.name] = struct{}{}
+		if path, err := parser.ParsePath(name.name); err == nil {
+			used[path[0]] = struct{}{}
+		}
+	}
+
+	for _, source := range sources {
+		keys, typ := sourceKeys(config, source)
+		for _, key := range keys {
+			if _, ok := used[key]; ok {
+				continue
+			}
+			if err := c.report(&UnusedArgumentError{Name: key, Type: typ}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, arg := range explicit {
+		if _, ok := used[arg.Name]; ok {
+			continue
+		}
+		if err := c.report(&UnusedArgumentError{Name: arg.Name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceKeys returns the sorted top level keys of a source, along with the
+// type of the source, which is empty for maps.
+func sourceKeys(config sreflect.Config, source interface{}) ([]string, string) {
+	if m, ok := convertMapStringInterface(source); ok {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys, ""
+	}
+
+	typ := fmt.Sprintf("%T", source)
+	rv := reflect.ValueOf(source)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		keys := make([]string, rv.Len())
+		for i := range keys {
+			keys[i] = strconv.Itoa(i + 1)
+		}
+		return keys, typ
+	}
+
+	ref, err := config.Reflect(rv)
+	if err != nil {
+		return nil, typ
+	}
+	refStruct, ok := ref.(sreflect.ReflectStruct)
+	if !ok {
+		return nil, typ
+	}
+	keys := make([]string, 0, len(refStruct.Fields))
+	for key := range refStruct.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, typ
+}
@@ -0,0 +1,134 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecWithStrictArgumentsUnusedKey(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+	querier.StrictArguments(nil)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=:age WHERE name=:name;`, map[string]interface{}{
+		"name": "fred",
+		"age":  22,
+		"nmae": "frank",
+	})
+	assert.True(t, errors.As(err, new(*UnusedArgumentError)))
+	assert.Equal(t, errors.Cause(err).Error(), `argument "nmae" not used in statement`)
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=:age WHERE name=:name;`, argsPerson{Name: "fred"}, sql.Named("limit", 1))
+	assert.Equal(t, errors.Cause(err).Error(), `argument "limit" not used in statement`)
+}
+
+func TestExecWithStrictArgumentsUnusedField(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	type Filter struct {
+		Name string `db:"name"`
+		City string `db:"city"`
+	}
+
+	querier := NewQuerier()
+	querier.StrictArguments(nil)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `DELETE FROM people WHERE name=:name;`, Filter{Name: "fred"})
+	assert.Equal(t, errors.Cause(err).Error(), `argument "city" of type sqlair.Filter not used in statement`)
+
+	_, err = querier.Exec(tx, `DELETE FROM people WHERE name=?1;`, []string{"fred", "frank"})
+	assert.Equal(t, errors.Cause(err).Error(), `argument "2" of type []string not used in statement`)
+
+	// Nested arguments are used if any of their keys are.
+	_, err = querier.Exec(tx, `DELETE FROM people WHERE name=:person.name;`, map[string]interface{}{
+		"person": argsPerson{Name: "fred"},
+	})
+	assert.Nil(t, err)
+}
+
+func TestExecWithStrictArgumentsEmptyField(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	type Filter struct {
+		Name string `db:"name,omitempty"`
+	}
+
+	querier := NewQuerier()
+	querier.StrictArguments(nil)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `DELETE FROM people WHERE name=:name;`, Filter{})
+	assert.True(t, errors.As(err, new(*EmptyArgumentError)))
+	assert.Equal(t, err.Error(), `constructing named arguments: named argument "name" bound from empty omitempty field at 1:31
+	DELETE FROM people WHERE name=:name;
+	                              ^`)
+
+	_, err = querier.Exec(tx, `DELETE FROM people WHERE name=:name;`, Filter{Name: "fred"})
+	assert.Nil(t, err)
+}
+
+func TestQueryWithStrictArgumentsHook(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	type Filter struct {
+		Name  string `db:"name"`
+		Limit int    `db:"limit,omitempty"`
+	}
+
+	var reported []string
+
+	querier := NewQuerier()
+	querier.StrictArguments(func(err error) error {
+		reported = append(reported, err.Error())
+		return nil
+	})
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.name=:name LIMIT :limit;`, Filter{Name: "fred"}, map[string]interface{}{
+			"age": 21,
+		})
+	})
+
+	assert.Equal(t, persons, []argsPerson(nil))
+	assert.Equal(t, reported, []string{
+		`named argument "limit" bound from empty omitempty field`,
+		`argument "age" not used in statement`,
+	})
+}
+
+func TestExecWithoutStrictArguments(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	type Filter struct {
+		Name string `db:"name,omitempty"`
+		City string `db:"city"`
+	}
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `DELETE FROM people WHERE name=:name;`, Filter{})
+	assert.Nil(t, err)
+}
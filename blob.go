@@ -0,0 +1,52 @@
+package sqlair
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+var rawBytesType = reflect.TypeOf(sql.RawBytes(nil))
+
+// isRawBytes returns true if the type is a sql.RawBytes, or a pointer to one.
+func isRawBytes(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == rawBytesType
+}
+
+// rawBytesScanner scans a BLOB or TEXT column into a sql.RawBytes or
+// *sql.RawBytes destination. The database/sql package scans sql.RawBytes
+// without copying, so the bytes are owned by the driver and are overwritten
+// by the next row. The scanner copies the bytes once, so that the field can
+// outlive the row.
+type rawBytesScanner struct {
+	dest reflect.Value
+}
+
+// Scan implements sql.Scanner.
+func (s rawBytesScanner) Scan(src interface{}) error {
+	if src == nil {
+		s.dest.Set(reflect.Zero(s.dest.Type()))
+		return nil
+	}
+
+	var b sql.RawBytes
+	switch src := src.(type) {
+	case []byte:
+		b = append(make(sql.RawBytes, 0, len(src)), src...)
+	case string:
+		b = sql.RawBytes(src)
+	default:
+		return errors.Errorf("unable to scan %T into %s", src, s.dest.Type())
+	}
+
+	if s.dest.Kind() == reflect.Ptr {
+		s.dest.Set(reflect.ValueOf(&b))
+		return nil
+	}
+	s.dest.Set(reflect.ValueOf(b))
+	return nil
+}
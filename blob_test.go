@@ -0,0 +1,116 @@
+package sqlair
+
+import (
+	"bytes"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type blobFile struct {
+	Name    string        `db:"name"`
+	Data    []byte        `db:"data"`
+	Raw     sql.RawBytes  `db:"raw"`
+	Thumb   *[]byte       `db:"thumb"`
+	Preview *sql.RawBytes `db:"preview"`
+}
+
+func TestQueryWithBlobs(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE files(
+	name    TEXT,
+	data    BLOB,
+	raw     BLOB,
+	thumb   BLOB,
+	preview BLOB
+);
+	`)
+	assert.Nil(t, err)
+
+	large := bytes.Repeat([]byte{0, 1, 2, 0xff}, 1<<16)
+	thumb := []byte{9, 8}
+	preview := sql.RawBytes{7}
+
+	querier := NewQuerier()
+	runTx(t, db, func(tx *sql.Tx) error {
+		for _, file := range []blobFile{{
+			Name:    "a",
+			Data:    large,
+			Raw:     sql.RawBytes{1, 2, 3},
+			Thumb:   &thumb,
+			Preview: &preview,
+		}, {
+			Name: "b",
+			Data: []byte{},
+			Raw:  sql.RawBytes{4, 5, 6},
+		}} {
+			if _, err := querier.Exec(tx, `INSERT INTO files (name, data, raw, thumb, preview) VALUES (:name, :data, :raw, :thumb, :preview);`, file); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	var files []blobFile
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&files)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {blobFile} FROM files ORDER BY name;`)
+	})
+
+	// The raw bytes of every row are copied, rather than referring to the
+	// memory of the driver, which is reused for the next row.
+	assert.Equal(t, files, []blobFile{{
+		Name:    "a",
+		Data:    large,
+		Raw:     sql.RawBytes{1, 2, 3},
+		Thumb:   &thumb,
+		Preview: &preview,
+	}, {
+		Name: "b",
+		Data: []byte{},
+		Raw:  sql.RawBytes{4, 5, 6},
+	}})
+
+	values := make(map[string]interface{})
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&values)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT data, thumb, substr(raw, 2) AS tail FROM files WHERE name=:name;`, map[string]interface{}{
+			"name": "a",
+		})
+	})
+	assert.Equal(t, values, map[string]interface{}{
+		"data":  large,
+		"thumb": thumb,
+		"tail":  []byte{2, 3},
+	})
+}
+
+func TestRawBytesScanner(t *testing.T) {
+	var raw sql.RawBytes
+	dest := rawBytesScanner{dest: reflect.ValueOf(&raw).Elem()}
+
+	src := []byte{1, 2}
+	err := dest.Scan(src)
+	assert.Nil(t, err)
+	src[0] = 9
+	assert.Equal(t, raw, sql.RawBytes{1, 2})
+
+	err = dest.Scan("ab")
+	assert.Nil(t, err)
+	assert.Equal(t, raw, sql.RawBytes("ab"))
+
+	err = dest.Scan(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, raw)
+
+	err = dest.Scan(int64(1))
+	assert.Equal(t, err.Error(), "unable to scan int64 into sql.RawBytes")
+}
@@ -0,0 +1,86 @@
+package sqlair
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseBool converts a value scanned from the database into a bool. SQLite
+// doesn't have a boolean type, so booleans are stored as integers, where any
+// non-zero value is true, or as text that strconv.ParseBool understands.
+func parseBool(src interface{}) (bool, bool) {
+	switch src := src.(type) {
+	case bool:
+		return src, true
+	case int64:
+		return src != 0, true
+	case float64:
+		return src != 0, true
+	case []byte:
+		return parseBool(string(src))
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(src))
+		return b, err == nil
+	}
+	return false, false
+}
+
+// isBoolType returns true if the type is a bool, or a pointer to one.
+func isBoolType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
+
+// boolScanner scans INTEGER, REAL, TEXT and boolean values into a bool or
+// *bool destination, see parseBool.
+type boolScanner struct {
+	dest reflect.Value
+}
+
+// Scan implements sql.Scanner.
+func (s boolScanner) Scan(src interface{}) error {
+	if src == nil {
+		s.dest.Set(reflect.Zero(s.dest.Type()))
+		return nil
+	}
+
+	b, ok := parseBool(src)
+	if !ok {
+		return errors.Errorf("unable to scan %T %v into %s", src, src, s.dest.Type())
+	}
+
+	dest := s.dest
+	if dest.Kind() == reflect.Ptr {
+		dest.Set(reflect.New(dest.Type().Elem()))
+		dest = dest.Elem()
+	}
+	dest.SetBool(b)
+	return nil
+}
+
+// nullBool is the scan destination of BOOL and BOOLEAN columns of map
+// destinations, which converts the values in the same way as boolScanner.
+type nullBool struct {
+	Bool  bool
+	Valid bool
+}
+
+// Scan implements sql.Scanner.
+func (n *nullBool) Scan(src interface{}) error {
+	if src == nil {
+		n.Bool, n.Valid = false, false
+		return nil
+	}
+
+	b, ok := parseBool(src)
+	if !ok {
+		return errors.Errorf("unable to scan %T %v into bool", src, src)
+	}
+	n.Bool, n.Valid = b, true
+	return nil
+}
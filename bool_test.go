@@ -0,0 +1,96 @@
+package sqlair
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryWithBools(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE flags(
+	name    TEXT,
+	active  INTEGER,
+	admin   TEXT,
+	deleted BOOLEAN
+);
+INSERT INTO flags(name, active, admin, deleted) values ("fred", 1, "true", 0), ("frank", 2, "f", NULL), ("jane", 0, NULL, 1);
+	`)
+	assert.Nil(t, err)
+
+	type Flags struct {
+		Name    string `db:"name"`
+		Active  bool   `db:"active"`
+		Admin   *bool  `db:"admin"`
+		Deleted bool   `db:"deleted"`
+	}
+
+	querier := NewQuerier()
+
+	var flags []Flags
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&flags)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {Flags} FROM flags ORDER BY name;`)
+	})
+
+	yes, no := true, false
+	assert.Equal(t, flags, []Flags{
+		{Name: "frank", Active: true, Admin: &no},
+		{Name: "fred", Active: true, Admin: &yes},
+		{Name: "jane", Deleted: true},
+	})
+
+	// Expressions don't have a declared type, so a map of bools converts
+	// them from integers.
+	values := make(map[string]bool)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&values)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT active, active > 1 AS many, deleted FROM flags WHERE name=:name;`, map[string]interface{}{
+			"name": "frank",
+		})
+	})
+	assert.Equal(t, values, map[string]bool{
+		"active":  true,
+		"many":    true,
+		"deleted": false,
+	})
+
+	// Bools are bound as they are, leaving the conversion to the driver.
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := querier.Exec(tx, `UPDATE flags SET active=:active WHERE name=:name;`, Flags{Name: "jane", Active: true})
+		return err
+	})
+	var active int
+	err = db.QueryRow(`SELECT active FROM flags WHERE name="jane";`).Scan(&active)
+	assert.Nil(t, err)
+	assert.Equal(t, active, 1)
+}
+
+func TestBoolScanner(t *testing.T) {
+	var b bool
+	dest := boolScanner{dest: reflect.ValueOf(&b).Elem()}
+
+	for src, expected := range map[interface{}]bool{
+		int64(3): true,
+		int64(0): false,
+		1.5:      true,
+		"TRUE":   true,
+		"0":      false,
+		true:     true,
+	} {
+		err := dest.Scan(src)
+		assert.Nil(t, err)
+		assert.Equal(t, b, expected)
+	}
+
+	err := dest.Scan("maybe")
+	assert.Equal(t, err.Error(), "unable to scan string maybe into bool")
+}
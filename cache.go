@@ -0,0 +1,383 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+)
+
+// Cached returns a copy of the query that memoizes its results for the
+// duration of the ttl. The results are keyed by the statement, the types of
+// the destinations and the values of the arguments, so a query with the same
+// arguments populates the destinations from the cache, without reaching the
+// database.
+//
+//  getter, err := querier.ForMany(&countries)
+//  ...
+//  err = getter.Cached(5*time.Minute).Query(tx, `SELECT {Country} FROM countries;`)
+//
+// Cached results are shared by every query of the querier, and can be
+// removed before they expire with Querier.InvalidateCache. Only the
+// destinations of the query are cached, so the cache is best suited to
+// reference data that rarely changes.
+func (q Query) Cached(ttl time.Duration) Query {
+	q.cacheTTL = ttl
+	return q
+}
+
+// InvalidateCache removes the cached results of every statement that
+// references the table. Table names are matched case insensitively, either
+// in full or without their schema, so "countries" matches main.countries but
+// not countries_archive. An empty table removes every cached result.
+//
+//  querier.InvalidateCache("countries")
+//
+func (q *Querier) InvalidateCache(table string) {
+	q.results.invalidate(table)
+}
+
+// cachedQuery executes the query, unless the results for the statement and
+// the arguments are already cached. Arguments that can't be keyed by their
+// values are never cached.
+func (q Query) cachedQuery(ctx context.Context, tx *sql.Tx, stmt string, args []interface{}) error {
+	key, ok := resultKey(stmt, q.entities, args)
+	if !ok {
+		return q.executePlan(q, ctx, tx, stmt, args)
+	}
+	if result, ok := q.results.get(key); ok {
+		result.restore(q.entities)
+		if q.scanned != nil {
+			*q.scanned += result.rows
+		}
+		return nil
+	}
+
+	// The rows are counted, so that the count is restored along with the
+	// results.
+	if q.scanned == nil {
+		q.scanned = new(int64)
+	}
+	scanned := *q.scanned
+
+	lengths := make([]int, len(q.entities))
+	for i, entity := range q.entities {
+		if value, ok := entity.(sreflect.ReflectValue); ok && value.Value.Kind() == reflect.Slice {
+			lengths[i] = value.Value.Len()
+		}
+	}
+	if err := q.executePlan(q, ctx, tx, stmt, args); err != nil {
+		return err
+	}
+	snapshot := snapshotEntities(q.entities, lengths)
+	snapshot.rows = *q.scanned - scanned
+	q.results.set(key, stmt, snapshot, q.cacheTTL)
+	return nil
+}
+
+// resultKey returns the key of the results of a statement, which includes
+// the types of the entities and the values of the arguments. The arguments
+// are keyed by the values that are sent to the database, so pointers are
+// keyed by the values that they point to, and driver.Valuer types by their
+// database values. It returns false if an argument can't be keyed.
+func resultKey(stmt string, entities []sreflect.ReflectInfo, args []interface{}) (string, bool) {
+	var builder strings.Builder
+	builder.WriteString(stmt)
+	for _, entity := range entities {
+		switch entity := entity.(type) {
+		case sreflect.ReflectStruct:
+			fmt.Fprintf(&builder, "\x00%s:%s", entity.Name, entity.Value.Type())
+		case sreflect.ReflectValue:
+			fmt.Fprintf(&builder, "\x00%s", entity.Value.Type())
+		}
+	}
+	for _, arg := range args {
+		builder.WriteString("\x00")
+		if !writeKeyValue(&builder, reflect.ValueOf(arg), 0) {
+			return "", false
+		}
+	}
+	return builder.String(), true
+}
+
+// maxKeyDepth limits the depth of the arguments that are keyed, which stops
+// cyclic values from being walked forever.
+const maxKeyDepth = 32
+
+// writeKeyValue writes the value of an argument to the key. Values that the
+// database accepts are written as their driver values, and any other
+// structs, maps and slices are written by their elements.
+func writeKeyValue(builder *strings.Builder, value reflect.Value, depth int) bool {
+	if depth > maxKeyDepth {
+		return false
+	}
+	if !value.IsValid() {
+		builder.WriteString("nil")
+		return true
+	}
+	if value.CanInterface() {
+		if v, err := driver.DefaultParameterConverter.ConvertValue(value.Interface()); err == nil {
+			fmt.Fprintf(builder, "%T(%#v)", v, v)
+			return true
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			builder.WriteString("nil")
+			return true
+		}
+		return writeKeyValue(builder, value.Elem(), depth+1)
+	case reflect.Struct:
+		fmt.Fprintf(builder, "%s{", value.Type())
+		for i := 0; i < value.NumField(); i++ {
+			fmt.Fprintf(builder, "%s:", value.Type().Field(i).Name)
+			if !writeKeyValue(builder, value.Field(i), depth+1) {
+				return false
+			}
+			builder.WriteString(",")
+		}
+		builder.WriteString("}")
+		return true
+	case reflect.Slice, reflect.Array:
+		fmt.Fprintf(builder, "%s{", value.Type())
+		for i := 0; i < value.Len(); i++ {
+			if !writeKeyValue(builder, value.Index(i), depth+1) {
+				return false
+			}
+			builder.WriteString(",")
+		}
+		builder.WriteString("}")
+		return true
+	case reflect.Map:
+		// The entries are sorted by their keys, as the order of a map isn't
+		// stable.
+		entries := make([]string, 0, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			var entry strings.Builder
+			if !writeKeyValue(&entry, iter.Key(), depth+1) {
+				return false
+			}
+			entry.WriteString(":")
+			if !writeKeyValue(&entry, iter.Value(), depth+1) {
+				return false
+			}
+			entries = append(entries, entry.String())
+		}
+		sort.Strings(entries)
+		fmt.Fprintf(builder, "%s{%s}", value.Type(), strings.Join(entries, ","))
+		return true
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return false
+	}
+	fmt.Fprintf(builder, "%s(%v)", value.Type(), value)
+	return true
+}
+
+// resultSnapshot is a copy of the values of the entities once they've been
+// populated by a query.
+type resultSnapshot struct {
+	values []reflect.Value
+	// rows is the number of rows scanned by the query.
+	rows int64
+}
+
+// snapshotEntities copies the values of the entities. Only the elements that
+// were appended to slices are copied, using the lengths of the slices from
+// before the query. The values are copied deeply, so that the snapshot
+// doesn't share any pointers, slices or maps with the entities.
+func snapshotEntities(entities []sreflect.ReflectInfo, lengths []int) resultSnapshot {
+	values := make([]reflect.Value, len(entities))
+	for i, entity := range entities {
+		value := entityValue(entity)
+		if value.Kind() == reflect.Slice {
+			value = value.Slice(lengths[i], value.Len())
+		}
+		values[i] = deepCopy(value)
+	}
+	return resultSnapshot{
+		values: values,
+	}
+}
+
+// restore populates the entities from the snapshot, in the same way that the
+// query would have. The entities receive copies of the snapshot, so that the
+// cached results aren't changed through them.
+func (s resultSnapshot) restore(entities []sreflect.ReflectInfo) {
+	for i, entity := range entities {
+		value := entityValue(entity)
+		cached := deepCopy(s.values[i])
+
+		switch value.Kind() {
+		case reflect.Slice:
+			value.Set(reflect.AppendSlice(value, cached))
+		case reflect.Map:
+			iter := cached.MapRange()
+			for iter.Next() {
+				value.SetMapIndex(iter.Key(), iter.Value())
+			}
+		default:
+			value.Set(cached)
+		}
+	}
+}
+
+func entityValue(entity sreflect.ReflectInfo) reflect.Value {
+	switch entity := entity.(type) {
+	case sreflect.ReflectStruct:
+		return entity.Value
+	case sreflect.ReflectValue:
+		return entity.Value
+	}
+	return reflect.Value{}
+}
+
+// deepCopy returns a copy of the value that doesn't share any pointers,
+// slices or maps with it. Unexported fields are copied as they are.
+func deepCopy(value reflect.Value) reflect.Value {
+	result := reflect.New(value.Type()).Elem()
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return result
+		}
+		ptr := reflect.New(value.Type().Elem())
+		ptr.Elem().Set(deepCopy(value.Elem()))
+		result.Set(ptr)
+	case reflect.Interface:
+		if value.IsNil() {
+			return result
+		}
+		result.Set(deepCopy(value.Elem()))
+	case reflect.Struct:
+		result.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			if field := result.Field(i); field.CanSet() {
+				field.Set(deepCopy(value.Field(i)))
+			}
+		}
+	case reflect.Slice:
+		if value.IsNil() {
+			return result
+		}
+		result.Set(reflect.MakeSlice(value.Type(), value.Len(), value.Len()))
+		for i := 0; i < value.Len(); i++ {
+			result.Index(i).Set(deepCopy(value.Index(i)))
+		}
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			result.Index(i).Set(deepCopy(value.Index(i)))
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			return result
+		}
+		result.Set(reflect.MakeMapWithSize(value.Type(), value.Len()))
+		iter := value.MapRange()
+		for iter.Next() {
+			result.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+	default:
+		result.Set(value)
+	}
+	return result
+}
+
+type cachedResult struct {
+	tables   []string
+	snapshot resultSnapshot
+	expires  time.Time
+}
+
+// sweepInterval is how often the cache removes the results that have
+// expired without being queried again.
+const sweepInterval = time.Minute
+
+// resultCache holds the results of cached queries until they expire.
+// Expired results are removed when they're next queried, and the rest are
+// swept at most once every sweepInterval as results are added.
+type resultCache struct {
+	mutex   sync.Mutex
+	results map[string]cachedResult
+	sweepAt time.Time
+	now     func() time.Time
+}
+
+func newResultCache() *resultCache {
+	return &resultCache{
+		results: make(map[string]cachedResult),
+		now:     time.Now,
+	}
+}
+
+func (c *resultCache) get(key string) (resultSnapshot, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result, ok := c.results[key]
+	if !ok {
+		return resultSnapshot{}, false
+	}
+	if !c.now().Before(result.expires) {
+		delete(c.results, key)
+		return resultSnapshot{}, false
+	}
+	return result.snapshot, true
+}
+
+func (c *resultCache) set(key, stmt string, snapshot resultSnapshot, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Remove any expired results, so that the cache doesn't grow with
+	// arguments that are never queried again.
+	now := c.now()
+	if !now.Before(c.sweepAt) {
+		for k, result := range c.results {
+			if !now.Before(result.expires) {
+				delete(c.results, k)
+			}
+		}
+		c.sweepAt = now.Add(sweepInterval)
+	}
+
+	c.results[key] = cachedResult{
+		tables:   Classify(stmt).Tables,
+		snapshot: snapshot,
+		expires:  now.Add(ttl),
+	}
+}
+
+func (c *resultCache) invalidate(table string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, result := range c.results {
+		if table == "" || referencesTable(result.tables, table) {
+			delete(c.results, key)
+		}
+	}
+}
+
+// referencesTable returns true if any of the tables is the table, ignoring
+// case and, if the table isn't qualified, the schema of the tables.
+func referencesTable(tables []string, table string) bool {
+	for _, name := range tables {
+		if strings.EqualFold(name, table) {
+			return true
+		}
+		if i := strings.LastIndex(name, "."); i >= 0 && !strings.Contains(table, ".") && strings.EqualFold(name[i+1:], table) {
+			return true
+		}
+	}
+	return false
+}
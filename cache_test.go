@@ -0,0 +1,226 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryCached(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var queried int
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		queried++
+		return nil
+	})
+
+	now := time.Now()
+	querier.results.now = func() time.Time {
+		return now
+	}
+
+	query := func(age int) []argsPerson {
+		var persons []argsPerson
+		runTx(t, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForMany(&persons)
+			assert.Nil(t, err)
+
+			return getter.Cached(time.Minute).Query(tx, `SELECT {people.* INTO argsPerson} FROM people WHERE people.age>:age ORDER BY people.name;`, map[string]interface{}{
+				"age": age,
+			})
+		})
+		return persons
+	}
+
+	expected := []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "jane", Age: 23},
+	}
+	assert.Equal(t, query(22), expected)
+
+	_, err := db.Exec(`DELETE FROM people WHERE name="jane";`)
+	assert.Nil(t, err)
+
+	// The results are cached for the same arguments.
+	assert.Equal(t, query(22), expected)
+	assert.Equal(t, queried, 1)
+
+	assert.Equal(t, query(30), []argsPerson{
+		{Name: "frank", Age: 42},
+	})
+	assert.Equal(t, queried, 2)
+
+	// The results expire after the ttl.
+	now = now.Add(time.Minute)
+	assert.Equal(t, query(22), []argsPerson{
+		{Name: "frank", Age: 42},
+	})
+	assert.Equal(t, queried, 3)
+}
+
+func TestQueryCachedOne(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	query := func() (argsPerson, map[string]interface{}) {
+		var person argsPerson
+		values := make(map[string]interface{})
+		runTx(t, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForOne(&person)
+			assert.Nil(t, err)
+
+			err = getter.Cached(time.Minute).Query(tx, `SELECT {argsPerson} FROM people WHERE name="fred";`)
+			assert.Nil(t, err)
+
+			getter, err = querier.ForOne(&values)
+			assert.Nil(t, err)
+
+			return getter.Cached(time.Minute).Query(tx, `SELECT name FROM people WHERE name="fred";`)
+		})
+		return person, values
+	}
+
+	person, values := query()
+	assert.Equal(t, person, argsPerson{Name: "fred", Age: 21})
+	assert.Equal(t, values, map[string]interface{}{"name": "fred"})
+
+	_, err := db.Exec(`UPDATE people SET name="frederick", age=22 WHERE name="fred";`)
+	assert.Nil(t, err)
+
+	person, values = query()
+	assert.Equal(t, person, argsPerson{Name: "fred", Age: 21})
+	assert.Equal(t, values, map[string]interface{}{"name": "fred"})
+
+	querier.InvalidateCache("people")
+
+	person, values = query()
+	assert.Equal(t, person, argsPerson{})
+	assert.Equal(t, values, map[string]interface{}{"name": nil})
+}
+
+func TestQueryCachedPointerArguments(t *testing.T) {
+	type Filter struct {
+		Name *string `db:"name"`
+	}
+	type cachedPerson struct {
+		Name *string `db:"name"`
+		Age  int     `db:"age"`
+	}
+
+	db := setupSchemaDB(t, peopleSchema)
+
+	var queried int
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		queried++
+		return nil
+	})
+
+	query := func(filter Filter) []cachedPerson {
+		var persons []cachedPerson
+		runTx(t, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForMany(&persons)
+			assert.Nil(t, err)
+
+			return getter.Cached(time.Minute).Query(tx, `SELECT {cachedPerson} FROM people WHERE name=:name;`, filter)
+		})
+		return persons
+	}
+	str := func(s string) *string {
+		return &s
+	}
+
+	// The arguments are keyed by the values that they point to.
+	name := "fred"
+	assert.Equal(t, query(Filter{Name: &name}), []cachedPerson{{Name: str("fred"), Age: 21}})
+	name = "jane"
+	assert.Equal(t, query(Filter{Name: &name}), []cachedPerson{{Name: str("jane"), Age: 23}})
+	assert.Equal(t, queried, 2)
+
+	// Changing the results doesn't change the cached results.
+	persons := query(Filter{Name: str("jane")})
+	assert.Equal(t, queried, 2)
+	*persons[0].Name = "changed"
+	assert.Equal(t, query(Filter{Name: str("jane")}), []cachedPerson{{Name: str("jane"), Age: 23}})
+	assert.Equal(t, queried, 2)
+}
+
+func TestResultKeyDriverValues(t *testing.T) {
+	type Filter struct {
+		Name *string `db:"name"`
+	}
+	fred, jane := "fred", "jane"
+
+	key := func(args ...interface{}) string {
+		key, ok := resultKey("SELECT", nil, args)
+		assert.True(t, ok)
+		return key
+	}
+	assert.Equal(t, key(Filter{Name: &fred}), key(Filter{Name: &fred}))
+	assert.NotEqual(t, key(Filter{Name: &fred}), key(Filter{Name: &jane}))
+	assert.Equal(t, key(&fred), key("fred"))
+	assert.Equal(t, key(sql.NullString{String: "fred", Valid: true}), key("fred"))
+	assert.Equal(t, key(map[string]interface{}{"a": 1, "b": &fred}), key(map[string]interface{}{"b": "fred", "a": 1}))
+
+	_, ok := resultKey("SELECT", nil, []interface{}{func() {}})
+	assert.False(t, ok)
+}
+
+func TestResultCacheInvalidate(t *testing.T) {
+	cache := newResultCache()
+	cache.set("a", "SELECT * FROM people;", resultSnapshot{}, time.Minute)
+	cache.set("b", "SELECT * FROM location;", resultSnapshot{}, time.Minute)
+
+	cache.invalidate("people")
+	_, ok := cache.get("a")
+	assert.False(t, ok)
+	_, ok = cache.get("b")
+	assert.True(t, ok)
+
+	cache.invalidate("")
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+}
+
+func TestResultCacheInvalidateTable(t *testing.T) {
+	cache := newResultCache()
+	cache.set("a", "SELECT * FROM people;", resultSnapshot{}, time.Minute)
+	cache.set("b", "SELECT * FROM people_archive;", resultSnapshot{}, time.Minute)
+	cache.set("c", `SELECT * FROM "PEOPLE";`, resultSnapshot{}, time.Minute)
+	cache.set("d", "SELECT * FROM main.people;", resultSnapshot{}, time.Minute)
+	cache.set("e", "SELECT * FROM location WHERE name = 'people';", resultSnapshot{}, time.Minute)
+
+	cache.invalidate("people")
+	for key, expected := range map[string]bool{"a": false, "b": true, "c": false, "d": false, "e": true} {
+		_, ok := cache.get(key)
+		assert.Equal(t, ok, expected, key)
+	}
+}
+
+func TestResultCacheSweep(t *testing.T) {
+	now := time.Now()
+	cache := newResultCache()
+	cache.now = func() time.Time {
+		return now
+	}
+
+	cache.set("a", "SELECT * FROM people;", resultSnapshot{}, time.Second)
+	now = now.Add(2 * time.Second)
+
+	// The expired result isn't swept until the sweep interval has passed.
+	cache.set("b", "SELECT * FROM people;", resultSnapshot{}, time.Hour)
+	assert.Len(t, cache.results, 2)
+
+	now = now.Add(sweepInterval)
+	cache.set("c", "SELECT * FROM people;", resultSnapshot{}, time.Hour)
+	assert.Len(t, cache.results, 2)
+	_, ok := cache.results["a"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,490 @@
+package sqlair
+
+import (
+	"strings"
+
+	"github.com/SimonRichardson/sqlair/parser"
+)
+
+// StatementKind is the kind of a statement, as given by its leading keyword.
+type StatementKind int
+
+const (
+	// OtherStatement is a statement of any other kind, such as BEGIN or
+	// VACUUM.
+	OtherStatement StatementKind = iota
+	// SelectStatement is a query, which is a SELECT or VALUES statement.
+	SelectStatement
+	// InsertStatement is an INSERT or REPLACE statement.
+	InsertStatement
+	UpdateStatement
+	DeleteStatement
+	// DDLStatement is a statement that changes the schema, which is a
+	// CREATE, ALTER, DROP or TRUNCATE statement.
+	DDLStatement
+	PragmaStatement
+)
+
+func (k StatementKind) String() string {
+	switch k {
+	case SelectStatement:
+		return "SELECT"
+	case InsertStatement:
+		return "INSERT"
+	case UpdateStatement:
+		return "UPDATE"
+	case DeleteStatement:
+		return "DELETE"
+	case DDLStatement:
+		return "DDL"
+	case PragmaStatement:
+		return "PRAGMA"
+	}
+	return "OTHER"
+}
+
+// statementKind returns the kind of statement that the leading keyword
+// starts.
+func statementKind(keyword string) StatementKind {
+	switch keyword {
+	case "SELECT", "VALUES":
+		return SelectStatement
+	case "INSERT", "REPLACE":
+		return InsertStatement
+	case "UPDATE":
+		return UpdateStatement
+	case "DELETE":
+		return DeleteStatement
+	case "CREATE", "ALTER", "DROP", "TRUNCATE":
+		return DDLStatement
+	case "PRAGMA":
+		return PragmaStatement
+	}
+	return OtherStatement
+}
+
+// Classification describes what a statement does, without executing it.
+type Classification struct {
+	// Kind is the kind of the first statement that modifies the database,
+	// or of the first statement if every statement is read-only.
+	Kind StatementKind
+	// Keyword is the leading keyword of that statement, in upper case. The
+	// common table expressions of a WITH clause are skipped, so that the
+	// keyword of WITH ... UPDATE is UPDATE.
+	Keyword string
+	// Tables are the tables that the statement reads or writes, in the
+	// order they're first referenced, without any quotes. Tables are
+	// qualified with their schema if they're written with it (main.people),
+	// and the common table expressions of a WITH clause aren't tables.
+	Tables []string
+	// ReadOnly is true if no statement modifies the database. Locking reads
+	// aren't read-only, as they lock the rows that they read.
+	ReadOnly bool
+	// Locking is true if a SELECT statement locks the rows that it reads
+	// (FOR UPDATE, FOR SHARE or LOCK IN SHARE MODE).
+	Locking bool
+}
+
+// Classify classifies the statement by its kind and the tables that it
+// references:
+//
+//  c := sqlair.Classify(`UPDATE people SET name=:name WHERE id IN (SELECT id FROM families);`)
+//  // c.Kind is UpdateStatement, c.Tables is [people families] and c.ReadOnly is false.
+//
+// The statement is lexed with the parser, and its record expressions are found
+// with the parsed AST, so that comments, string literals, named arguments and
+// records are never mistaken for keywords or tables. The same classification
+// routes statements to replicas (see Router), rejects modifying statements in
+// read-only transactions, and finds the statements that soft deletes,
+// LimitOne and optimistic locks rewrite. Tables that are only named by the
+// record expressions of a statement ({Person.table}) are found once the
+// statement is compiled (see Statement.Classification and Querier.Debug).
+func Classify(stmt string) Classification {
+	keywords, tables, locking := classifyStatement(stmt)
+
+	var (
+		keyword  string
+		readOnly = !locking
+	)
+	for _, word := range keywords {
+		if word != "SELECT" && word != "VALUES" {
+			keyword, readOnly = word, false
+			break
+		}
+	}
+	if keyword == "" && len(keywords) > 0 {
+		keyword = keywords[0]
+	}
+	return Classification{
+		Kind:     statementKind(keyword),
+		Keyword:  keyword,
+		Tables:   tables,
+		ReadOnly: readOnly,
+		Locking:  locking,
+	}
+}
+
+// tableScan is the state of classifyStatement within a statement.
+type tableScan struct {
+	// found is true once the leading keyword of the statement is found.
+	found bool
+	// expect is true if the next name is a table.
+	expect bool
+	// list is true if a comma at the depth of the list is followed by
+	// another table (FROM a, b), and words counts the words that follow the
+	// last table of the list, which are its alias.
+	list      bool
+	listDepth int
+	words     int
+	// with is true within the common table expressions of a WITH clause,
+	// and cte is true if the next name is a common table expression.
+	with      bool
+	withDepth int
+	cte       bool
+	index     bool
+	// calls records whether each open parenthesis is the argument list of a
+	// function call, in which FROM isn't a clause (EXTRACT(YEAR FROM t)).
+	calls []bool
+	// last and prev are the last two words, which are empty if they're
+	// followed by punctuation.
+	last, prev string
+}
+
+// classifyStatement returns the leading keyword of each statement within the
+// statement, in upper case, along with the tables that it references in the
+// order they're first referenced. The common table expressions of a WITH
+// clause are skipped for the keyword (WITH ... UPDATE is UPDATE), and aren't
+// tables. Tables follow the FROM, JOIN, INTO, UPDATE and TABLE keywords,
+// along with the ON of CREATE INDEX. Whether any SELECT statement is a
+// locking read is returned along with them.
+func classifyStatement(stmt string) ([]string, []string, bool) {
+	var (
+		keywords []string
+		tables   []string
+		locking  bool
+		seen     = make(map[string]bool)
+		ctes     = make(map[string]bool)
+		scan     tableScan
+		tokens   = classifyTokens(stmt)
+	)
+	for i := 0; i < len(tokens); {
+		token := tokens[i]
+		switch {
+		case token.Kind == parser.LBRACE || isLiteralToken(token):
+			scan.expect, scan.last, scan.prev = false, "", ""
+			i++
+		case token.Kind == parser.LPAREN:
+			scan.calls = append(scan.calls, scan.last != "" && !isSubqueryKeyword(scan.last))
+			scan.expect, scan.last, scan.prev = false, "", ""
+			i++
+		case token.Kind == parser.RPAREN:
+			if len(scan.calls) > 0 {
+				scan.calls = scan.calls[:len(scan.calls)-1]
+			}
+			if len(scan.calls) < scan.listDepth {
+				scan.list = false
+			}
+			scan.expect, scan.last, scan.prev = false, "", ""
+			i++
+		case token.Kind == parser.COMMA:
+			depth := len(scan.calls)
+			if scan.list && depth == scan.listDepth {
+				scan.expect, scan.words = true, 0
+			}
+			if scan.with && depth == scan.withDepth {
+				scan.cte = true
+			}
+			scan.last, scan.prev = "", ""
+			i++
+		case token.Kind == parser.SEMICOLON:
+			scan = tableScan{}
+			i++
+		case isNameToken(token):
+			var (
+				parts  []string
+				quoted bool
+			)
+			parts, quoted, i = tokenName(tokens, i)
+			name := strings.Join(parts, ".")
+			word := ""
+			if len(parts) == 1 && !quoted {
+				word = strings.ToUpper(name)
+			}
+
+			depth := len(scan.calls)
+			if scan.with && depth == scan.withDepth && isStatementKeyword(word) {
+				scan.with = false
+			}
+			if depth == 0 && !scan.found && word != "" && word != "WITH" && !scan.with {
+				keywords = append(keywords, word)
+				scan.found = true
+			}
+			if scan.found && keywords[len(keywords)-1] == "SELECT" && isLockingClause(scan.prev, scan.last, word) {
+				locking = true
+			}
+			switch {
+			case scan.cte && word == "RECURSIVE":
+			case scan.cte:
+				ctes[strings.ToUpper(name)] = true
+				scan.cte = false
+			case word == "WITH":
+				scan.with, scan.withDepth, scan.cte = true, depth, true
+			case scan.expect && isTableModifier(word):
+			case scan.expect && isClauseKeyword(word):
+				scan.expect = false
+			case scan.expect:
+				scan.expect, scan.words = false, 0
+				if len(parts) == 1 && ctes[strings.ToUpper(name)] {
+					break
+				}
+				if !seen[name] {
+					seen[name] = true
+					tables = append(tables, name)
+				}
+			case word == "INDEX":
+				scan.index = true
+			case word == "FROM" || word == "JOIN":
+				if depth > 0 && scan.calls[depth-1] {
+					break
+				}
+				scan.expect = true
+				scan.list, scan.listDepth, scan.words = word == "FROM", depth, 0
+			case word == "UPDATE" && (scan.last == "KEY" || scan.last == "FOR"):
+				// ON DUPLICATE KEY UPDATE and SELECT ... FOR UPDATE.
+			case word == "INTO" || word == "UPDATE" || word == "TABLE" || word == "TRUNCATE" || (word == "ON" && scan.index):
+				scan.expect, scan.list = true, false
+			case scan.list && depth == scan.listDepth:
+				// A table of a list is followed by at most its alias (AS p),
+				// after which the list has ended (WHERE).
+				if scan.words++; scan.words > 2 || (scan.words == 2 && scan.last != "AS") {
+					scan.list = false
+				}
+			}
+			scan.prev, scan.last = scan.last, word
+		default:
+			i++
+		}
+	}
+	return keywords, tables, locking
+}
+
+// isSingleSelect returns true if the statement is a single SELECT statement,
+// which can follow the common table expressions of a WITH clause.
+func isSingleSelect(stmt string) bool {
+	keywords, _, _ := classifyStatement(stmt)
+	return len(keywords) == 1 && keywords[0] == "SELECT"
+}
+
+// classifyTokens returns the tokens of the statement that classify it. The
+// record expressions of the statement are found by parsing it, and each is
+// returned as a single LBRACE token holding the text of the record.
+// Whitespace, comments, parameters, numbers and the types of casts (::int)
+// are skipped.
+func classifyTokens(stmt string) []parser.Token {
+	var records []*parser.RecordExpr
+	if ast, err := parser.Parse(stmt); err == nil {
+		parser.Inspect(ast, func(node parser.Node) bool {
+			if record, ok := node.(*parser.RecordExpr); ok {
+				records = append(records, record)
+				return false
+			}
+			return true
+		})
+	}
+
+	var (
+		tokens []parser.Token
+		lexer  = parser.NewLexer(stmt, 0)
+		cast   bool
+	)
+	for {
+		token := lexer.Next()
+		offset := token.Pos.Offset
+		if len(records) > 0 && token.Kind != parser.EOF && offset >= records[0].Pos.Offset {
+			record := records[0]
+			records = records[1:]
+			tokens = append(tokens, parser.Token{
+				Kind:  parser.LBRACE,
+				Value: stmt[record.Pos.Offset:record.End.Offset],
+				Pos:   record.Pos,
+			})
+			lexer = parser.NewLexer(stmt, record.End.Offset)
+			continue
+		}
+
+		switch token.Kind {
+		case parser.EOF:
+			return tokens
+		case parser.WS, parser.PARAM:
+			continue
+		case parser.DCOLON:
+			cast = true
+			continue
+		case parser.IDENT:
+			if cast || isDigit(token.Value[0]) {
+				cast = false
+				continue
+			}
+		case parser.MINUS, parser.SLASH:
+			if strings.HasPrefix(stmt[offset:], "--") {
+				lexer = parser.NewLexer(stmt, skipUntil(stmt, offset, "\n"))
+				continue
+			}
+			if strings.HasPrefix(stmt[offset:], "/*") {
+				lexer = parser.NewLexer(stmt, skipUntil(stmt, offset, "*/"))
+				continue
+			}
+		case parser.LBRACE:
+			// The statement couldn't be parsed, so the record is skipped up
+			// to its closing brace.
+			end := skipUntil(stmt, offset, "}")
+			token.Value = stmt[offset:end]
+			lexer = parser.NewLexer(stmt, end)
+		case parser.ILLEGAL:
+			// The lexer ends quoted strings at a closing brace, as they're
+			// only lexed within record expressions, so the string is found
+			// again up to its closing quote.
+			quote := stmt[offset]
+			if quote == '[' {
+				quote = ']'
+			}
+			end := skipQuoted(stmt, offset, quote)
+			if end-offset > 1 && stmt[end-1] == quote {
+				token.Kind = parser.STRING
+			}
+			token.Value = stmt[offset:end]
+			lexer = parser.NewLexer(stmt, end)
+		}
+		cast = false
+		tokens = append(tokens, token)
+	}
+}
+
+// isLiteralToken returns true if the token is a string literal, which is
+// quoted with single quotes.
+func isLiteralToken(token parser.Token) bool {
+	return (token.Kind == parser.STRING || token.Kind == parser.ILLEGAL) && token.Value[0] == '\''
+}
+
+// isNameToken returns true if the token is a word or a quoted identifier.
+func isNameToken(token parser.Token) bool {
+	return token.Kind == parser.IDENT || ((token.Kind == parser.STRING || token.Kind == parser.ILLEGAL) && token.Value[0] != '\'')
+}
+
+// tokenName returns the parts of the dotted name starting at the token,
+// without their quotes, and whether any of them is quoted, along with the
+// index of the token directly after the name.
+func tokenName(tokens []parser.Token, i int) ([]string, bool, int) {
+	var (
+		parts  []string
+		quoted bool
+	)
+	for {
+		token := tokens[i]
+		parts = append(parts, token.Text())
+		quoted = quoted || token.Kind != parser.IDENT
+		i++
+
+		end := token.Pos.Offset + len(token.Value)
+		if i+1 >= len(tokens) || tokens[i].Kind != parser.DOT || tokens[i].Pos.Offset != end ||
+			!isNameToken(tokens[i+1]) || tokens[i+1].Pos.Offset != end+1 {
+			return parts, quoted, i
+		}
+		i++
+	}
+}
+
+// scanName returns the parts of the dotted name starting at the offset,
+// without their quotes, and whether any of them is quoted, along with the
+// offset directly after the name.
+func scanName(stmt string, offset int) ([]string, bool, int) {
+	var (
+		parts  []string
+		quoted bool
+	)
+	i := offset
+	for i < len(stmt) {
+		switch c := stmt[i]; {
+		case c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(stmt[i+1:], closing)
+			if end < 0 {
+				return append(parts, stmt[i+1:]), true, len(stmt)
+			}
+			parts = append(parts, stmt[i+1:i+1+end])
+			quoted = true
+			i += end + 2
+		case isKeywordChar(c):
+			start := i
+			for i < len(stmt) && (isKeywordChar(stmt[i]) || isDigit(stmt[i]) || stmt[i] == '$') {
+				i++
+			}
+			parts = append(parts, stmt[start:i])
+		default:
+			return parts, quoted, i
+		}
+		if i >= len(stmt) || stmt[i] != '.' || i+1 >= len(stmt) {
+			break
+		}
+		if next := stmt[i+1]; next != '"' && next != '`' && next != '[' && !isKeywordChar(next) {
+			break
+		}
+		i++
+	}
+	return parts, quoted, i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isTableModifier returns true if the keyword can be written between the
+// keyword that introduces a table and the table, such as DROP TABLE IF EXISTS
+// or UPDATE OR IGNORE.
+func isTableModifier(word string) bool {
+	switch word {
+	case "ONLY", "IF", "NOT", "EXISTS", "OR", "ROLLBACK", "ABORT", "REPLACE", "FAIL", "IGNORE", "TABLE", "LATERAL":
+		return true
+	}
+	return false
+}
+
+// isClauseKeyword returns true if the keyword can follow the keyword that
+// introduces a table in place of the table, such as the SET of ON CONFLICT DO
+// UPDATE SET.
+func isClauseKeyword(word string) bool {
+	switch word {
+	case "SET", "SELECT", "VALUES", "DEFAULT", "WHERE":
+		return true
+	}
+	return false
+}
+
+// isLockingClause returns true if the words end a locking clause of a SELECT
+// statement: FOR UPDATE, FOR SHARE, FOR NO KEY UPDATE, FOR KEY SHARE or
+// LOCK IN SHARE (MODE).
+func isLockingClause(prev, last, word string) bool {
+	switch {
+	case (word == "UPDATE" || word == "SHARE") && last == "FOR":
+		return true
+	case word == "UPDATE" && last == "KEY" && prev == "NO":
+		return true
+	case word == "SHARE" && ((last == "KEY" && prev == "FOR") || (last == "IN" && prev == "LOCK")):
+		return true
+	}
+	return false
+}
+
+// isSubqueryKeyword returns true if a parenthesis that follows the keyword
+// isn't the argument list of a function call.
+func isSubqueryKeyword(word string) bool {
+	switch word {
+	case "", "AS", "IN", "EXISTS", "ANY", "ALL", "SOME", "NOT", "FROM", "JOIN", "LATERAL", "ON",
+		"WHERE", "AND", "OR", "SELECT", "VALUES", "UNION", "EXCEPT", "INTERSECT", "INTO", "USING":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package sqlair
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected Classification
+	}{{
+		stmt:     `SELECT {Person} FROM people WHERE name=:name;`,
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people"}, ReadOnly: true},
+	}, {
+		stmt:     `SELECT p.name, a.city FROM people AS p, pets JOIN "main"."addresses" a ON a.id = p.address_id WHERE p.age > :age ORDER BY p.name, a.city;`,
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people", "pets", "main.addresses"}, ReadOnly: true},
+	}, {
+		stmt:     `SELECT EXTRACT(YEAR FROM created), COUNT(*) FROM people WHERE id IN (SELECT owner_id FROM pets) AND EXISTS (SELECT 1 FROM people);`,
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people", "pets"}, ReadOnly: true},
+	}, {
+		stmt:     `WITH RECURSIVE adults(name) AS (SELECT name FROM people WHERE age>18), seniors AS (SELECT * FROM adults) UPDATE pets SET owner=NULL WHERE owner IN (SELECT name FROM seniors);`,
+		expected: Classification{Kind: UpdateStatement, Keyword: "UPDATE", Tables: []string{"people", "pets"}},
+	}, {
+		stmt:     `INSERT OR REPLACE INTO people (name, age) VALUES (:name, :age) ON CONFLICT(name) DO UPDATE SET age=excluded.age;`,
+		expected: Classification{Kind: InsertStatement, Keyword: "INSERT", Tables: []string{"people"}},
+	}, {
+		stmt:     `INSERT INTO people (name) VALUES (:name) ON DUPLICATE KEY UPDATE name=VALUES(name);`,
+		expected: Classification{Kind: InsertStatement, Keyword: "INSERT", Tables: []string{"people"}},
+	}, {
+		stmt:     `DELETE FROM ONLY people WHERE name='FROM pets';`,
+		expected: Classification{Kind: DeleteStatement, Keyword: "DELETE", Tables: []string{"people"}},
+	}, {
+		stmt:     `CREATE TABLE IF NOT EXISTS people (id INTEGER PRIMARY KEY, name TEXT); CREATE UNIQUE INDEX people_name ON people(name);`,
+		expected: Classification{Kind: DDLStatement, Keyword: "CREATE", Tables: []string{"people"}},
+	}, {
+		stmt:     `DROP TABLE IF EXISTS "people"; TRUNCATE TABLE pets;`,
+		expected: Classification{Kind: DDLStatement, Keyword: "DROP", Tables: []string{"people", "pets"}},
+	}, {
+		stmt:     `PRAGMA foreign_keys = OFF;`,
+		expected: Classification{Kind: PragmaStatement, Keyword: "PRAGMA"},
+	}, {
+		stmt:     `SELECT * FROM people; DELETE FROM pets;`,
+		expected: Classification{Kind: DeleteStatement, Keyword: "DELETE", Tables: []string{"people", "pets"}},
+	}, {
+		stmt:     `SELECT * FROM people FOR UPDATE;`,
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people"}, Locking: true},
+	}, {
+		stmt:     `SELECT * FROM people p JOIN pets ON pets.owner = p.name FOR NO KEY UPDATE OF p NOWAIT;`,
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people", "pets"}, Locking: true},
+	}, {
+		stmt:     `SELECT * FROM people WHERE id IN (SELECT owner FROM pets FOR SHARE);`,
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people", "pets"}, Locking: true},
+	}, {
+		stmt:     `SELECT * FROM people LOCK IN SHARE MODE;`,
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people"}, Locking: true},
+	}, {
+		stmt:     `SELECT 'FOR UPDATE', share FROM people /* FOR SHARE */;`,
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people"}, ReadOnly: true},
+	}, {
+		stmt:     "-- FROM comments\nVALUES (1), (2);",
+		expected: Classification{Kind: SelectStatement, Keyword: "VALUES", ReadOnly: true},
+	}, {
+		stmt:     "/* DELETE FROM pets; */ SELECT name FROM people WHERE note = 'it''s; UPDATE pets' -- INSERT INTO pets\n;",
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people"}, ReadOnly: true},
+	}, {
+		stmt:     `UPDATE people SET note = '} FROM pets {', age = age::int WHERE id = :id AND name = @name;`,
+		expected: Classification{Kind: UpdateStatement, Keyword: "UPDATE", Tables: []string{"people"}},
+	}, {
+		stmt:     `SELECT {p.* INTO Person AS p}, a.city AS &Address.city FROM people p JOIN [addresses] a ON a.id = p.address_id;`,
+		expected: Classification{Kind: SelectStatement, Keyword: "SELECT", Tables: []string{"people", "addresses"}, ReadOnly: true},
+	}, {
+		stmt:     `INSERT INTO people (name) SELECT {Person.table} FROM {Person.table} /* FROM pets */;`,
+		expected: Classification{Kind: InsertStatement, Keyword: "INSERT", Tables: []string{"people"}},
+	}, {
+		stmt:     `VACUUM;`,
+		expected: Classification{Kind: OtherStatement, Keyword: "VACUUM"},
+	}}
+	for i, test := range tests {
+		t.Logf("test %d: %s", i, test.stmt)
+		assert.Equal(t, Classify(test.stmt), test.expected)
+	}
+}
+
+func TestClassifyStatementKeywords(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected []string
+	}{{
+		stmt:     `SELECT {Person} FROM people;`,
+		expected: []string{"SELECT"},
+	}, {
+		stmt:     "  -- update the people\n select * from people",
+		expected: []string{"SELECT"},
+	}, {
+		stmt:     `/* DELETE */ update people SET name='DELETE';`,
+		expected: []string{"UPDATE"},
+	}, {
+		stmt:     `WITH adults AS (SELECT * FROM people WHERE age>18), seniors(name) AS (DELETE FROM people) SELECT * FROM adults;`,
+		expected: []string{"SELECT"},
+	}, {
+		stmt:     `WITH RECURSIVE t(n) AS (VALUES(1)) INSERT INTO numbers SELECT n FROM t;`,
+		expected: []string{"INSERT"},
+	}, {
+		stmt:     `SELECT 1; DROP TABLE people;`,
+		expected: []string{"SELECT", "DROP"},
+	}, {
+		stmt:     `;`,
+		expected: nil,
+	}}
+	for i, test := range tests {
+		t.Logf("test %d: %s", i, test.stmt)
+		keywords, _, _ := classifyStatement(test.stmt)
+		assert.Equal(t, keywords, test.expected)
+	}
+}
+
+func TestClassifyReadOnly(t *testing.T) {
+	c := Classify(`WITH a AS (SELECT 1) SELECT * FROM a; VALUES (1);`)
+	assert.True(t, c.ReadOnly)
+	assert.Equal(t, c.Keyword, "SELECT")
+
+	c = Classify(`SELECT 1; PRAGMA foreign_keys = OFF;`)
+	assert.False(t, c.ReadOnly)
+	assert.Equal(t, c.Keyword, "PRAGMA")
+}
+
+func TestIsSingleSelect(t *testing.T) {
+	assert.True(t, isSingleSelect(`WITH a AS (SELECT 1) SELECT * FROM a;`))
+	assert.True(t, isSingleSelect("-- DELETE\nSELECT * FROM people WHERE name='; DELETE';"))
+	assert.False(t, isSingleSelect(`SELECT 1; SELECT 2;`))
+	assert.False(t, isSingleSelect(`UPDATE people SET name='SELECT';`))
+}
+
+func TestStatementKindString(t *testing.T) {
+	assert.Equal(t, SelectStatement.String(), "SELECT")
+	assert.Equal(t, DDLStatement.String(), "DDL")
+	assert.Equal(t, OtherStatement.String(), "OTHER")
+}
+
+func TestPreparedStatementClassification(t *testing.T) {
+	querier := NewQuerier()
+
+	stmt, err := querier.Prepare(`SELECT {p.* INTO tablePerson} FROM {tablePerson.table} AS p WHERE p.name=:name;`, tablePerson{})
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.Classification(), Classification{
+		Kind:     SelectStatement,
+		Keyword:  "SELECT",
+		Tables:   []string{"people"},
+		ReadOnly: true,
+	})
+}
+
+func TestQuerierDebugClassification(t *testing.T) {
+	querier := NewQuerier()
+
+	var person tablePerson
+	info, err := querier.Debug(`SELECT {tablePerson} FROM {tablePerson.table} WHERE id=:id;`, tablePerson{ID: 1}, &person)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Classification.Kind, SelectStatement)
+	assert.Equal(t, info.Classification.Tables, []string{"people"})
+}
@@ -0,0 +1,351 @@
+// sqlair-gen generates the methods of sqlair.GeneratedRecord for structs, so
+// that sqlair scans and binds their columns without reflection. It's run with
+// go generate from the package of the structs:
+//
+//  //go:generate sqlair-gen -type Person,Location
+//
+// The methods are written to <type>_sqlair.go, named after the first type,
+// unless another file is given with -output. The columns follow the same rules
+// as the reflection of sqlair, so the tag key must match the tag key of the
+// querier.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma separated list of struct type names; required")
+	output    = flag.String("output", "", "output file name; default <type>_sqlair.go")
+	tagKey    = flag.String("tag", sreflect.DefaultTagKey, "struct tag key of the columns")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("sqlair-gen: ")
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if args := flag.Args(); len(args) > 0 {
+		dir = args[0]
+	}
+
+	types := strings.Split(*typeNames, ",")
+	name := *output
+	if name == "" {
+		name = strings.ToLower(types[0]) + "_sqlair.go"
+	}
+	name = filepath.Join(dir, name)
+
+	src, err := generateDir(dir, filepath.Base(name), *tagKey, types)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(name, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generateDir generates the methods of the types declared in the package of
+// the directory, skipping the output file and any test files.
+func generateDir(dir, output, tagKey string, types []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return info.Name() != output && !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(pkgs) != 1 {
+		return nil, errors.Errorf("expected one package in %q, got %d", dir, len(pkgs))
+	}
+	for _, pkg := range pkgs {
+		var files []*ast.File
+		for _, file := range pkg.Files {
+			files = append(files, file)
+		}
+		return generate(pkg.Name, files, tagKey, types)
+	}
+	return nil, nil
+}
+
+// generator holds the struct types and methods declared in a package.
+type generator struct {
+	tagKey  string
+	structs map[string]*ast.StructType
+	// methods are the names of the methods of every type.
+	methods map[string]map[string]bool
+}
+
+// column is a column of a struct, along with the selector of its field.
+type column struct {
+	name     string
+	selector string
+	// bind is true if the field can be bound as it is, without converting
+	// it.
+	bind bool
+}
+
+// generate returns the formatted source of the methods of the types, which are
+// declared in the files of the package.
+func generate(pkg string, files []*ast.File, tagKey string, types []string) ([]byte, error) {
+	g := generator{
+		tagKey:  tagKey,
+		structs: make(map[string]*ast.StructType),
+		methods: make(map[string]map[string]bool),
+	}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if spec, ok := spec.(*ast.TypeSpec); ok {
+						if st, ok := spec.Type.(*ast.StructType); ok {
+							g.structs[spec.Name.Name] = st
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv == nil || len(decl.Recv.List) == 0 {
+					continue
+				}
+				recv := typeName(decl.Recv.List[0].Type)
+				if g.methods[recv] == nil {
+					g.methods[recv] = make(map[string]bool)
+				}
+				g.methods[recv][decl.Name.Name] = true
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by sqlair-gen. DO NOT EDIT.\n\npackage %s\n", pkg)
+	for _, name := range types {
+		name = strings.TrimSpace(name)
+		columns, err := g.columns(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "type %q", name)
+		}
+		writeMethods(&buf, name, columns)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "formatting generated source")
+	}
+	return src, nil
+}
+
+type embeddedStruct struct {
+	st       *ast.StructType
+	selector string
+	prefix   string
+}
+
+// columns returns the columns of the struct type. The fields are walked breadth
+// first, in the same way as the reflect package of sqlair.
+func (g generator) columns(name string) ([]column, error) {
+	st, ok := g.structs[name]
+	if !ok {
+		return nil, errors.Errorf("struct type not found")
+	}
+
+	var columns []column
+	depths := make(map[string]int)
+	visited := map[string]bool{
+		name: true,
+	}
+	current := []embeddedStruct{{
+		st: st,
+	}}
+	for depth := 0; len(current) > 0; depth++ {
+		var next []embeddedStruct
+		for _, parent := range current {
+			for _, field := range parent.st.Fields.List {
+				var tag sreflect.ReflectTag
+				if field.Tag != nil {
+					raw, err := strconv.Unquote(field.Tag.Value)
+					if err != nil {
+						return nil, errors.WithStack(err)
+					}
+					if rawTag, ok := reflect.StructTag(raw).Lookup(g.tagKey); ok {
+						if rawTag == "-" {
+							continue
+						}
+						if tag, err = sreflect.ParseTag(rawTag); err != nil {
+							return nil, err
+						}
+					}
+				}
+
+				names := field.Names
+				if len(names) == 0 {
+					embedded := typeName(field.Type)
+					if st, ok := g.embeddedStruct(field.Type); ok && tag.Name == "" && !tag.JSON {
+						if visited[embedded] {
+							continue
+						}
+						visited[embedded] = true
+
+						next = append(next, embeddedStruct{
+							st:       st,
+							selector: parent.selector + embedded + ".",
+							prefix:   parent.prefix + tag.Prefix,
+						})
+						continue
+					}
+					if _, ok := field.Type.(*ast.StarExpr); ok {
+						return nil, errors.Errorf("embedded struct pointer %q not supported", embedded)
+					}
+					names = []*ast.Ident{ast.NewIdent(embedded)}
+				}
+
+				for _, ident := range names {
+					if !ident.IsExported() {
+						continue
+					}
+					if tag.Prefix != "" {
+						return nil, errors.Errorf("unexpected prefix on non-embedded field %q", ident.Name)
+					}
+					if tag.HasMany != "" || tag.BelongsTo != "" {
+						continue
+					}
+
+					column := column{
+						name:     tag.Name,
+						selector: parent.selector + ident.Name,
+						bind:     !tag.JSON && !tag.OmitEmpty && len(tag.Enum) == 0 && isBasicType(field.Type),
+					}
+					if column.name == "" {
+						column.name = sreflect.SnakeCase(ident.Name)
+					}
+					column.name = parent.prefix + column.name
+
+					if existing, ok := depths[column.name]; ok {
+						if existing == depth {
+							return nil, errors.Errorf("ambiguous field %q found in embedded structs", column.name)
+						}
+						continue
+					}
+					depths[column.name] = depth
+					columns = append(columns, column)
+				}
+			}
+		}
+		current = next
+	}
+	return columns, nil
+}
+
+// embeddedStruct returns the struct type of an embedded field, if it's a
+// struct declared in the package that's flattened into the parent. Structs
+// that implement sql.Scanner or driver.Valuer are a single column.
+func (g generator) embeddedStruct(expr ast.Expr) (*ast.StructType, bool) {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil, false
+	}
+	st, ok := g.structs[ident.Name]
+	if !ok || g.methods[ident.Name]["Scan"] || g.methods[ident.Name]["Value"] {
+		return nil, false
+	}
+	return st, true
+}
+
+// typeName returns the name of the type of an embedded field or receiver.
+func typeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.StarExpr:
+		return typeName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	}
+	return ""
+}
+
+var basicTypes = map[string]bool{
+	"bool":    true,
+	"string":  true,
+	"int":     true,
+	"int8":    true,
+	"int16":   true,
+	"int32":   true,
+	"int64":   true,
+	"uint":    true,
+	"uint8":   true,
+	"uint16":  true,
+	"uint32":  true,
+	"uint64":  true,
+	"float32": true,
+	"float64": true,
+	"byte":    true,
+	"rune":    true,
+}
+
+// isBasicType returns true if the type is a builtin type, a time.Time, a byte
+// slice, or a pointer to one of them. The values of other types might have to
+// be converted, so they're bound by reflection.
+func isBasicType(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return basicTypes[expr.Name]
+	case *ast.StarExpr:
+		return isBasicType(expr.X)
+	case *ast.SelectorExpr:
+		pkg, ok := expr.X.(*ast.Ident)
+		return ok && pkg.Name == "time" && expr.Sel.Name == "Time"
+	case *ast.ArrayType:
+		elem, ok := expr.Elt.(*ast.Ident)
+		return expr.Len == nil && ok && (elem.Name == "byte" || elem.Name == "uint8")
+	}
+	return false
+}
+
+func writeMethods(buf *bytes.Buffer, name string, columns []column) {
+	fmt.Fprintf(buf, "\n// SqlairColumns implements sqlair.GeneratedRecord.\n")
+	fmt.Fprintf(buf, "func (v %s) SqlairColumns() []string {\n\treturn []string{", name)
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(strconv.Quote(column.name))
+	}
+	buf.WriteString("}\n}\n")
+
+	fmt.Fprintf(buf, "\n// SqlairScan implements sqlair.GeneratedRecord.\n")
+	fmt.Fprintf(buf, "func (v *%s) SqlairScan(column string) interface{} {\n\tswitch column {\n", name)
+	for _, column := range columns {
+		fmt.Fprintf(buf, "\tcase %q:\n\t\treturn &v.%s\n", column.name, column.selector)
+	}
+	buf.WriteString("\t}\n\treturn nil\n}\n")
+
+	fmt.Fprintf(buf, "\n// SqlairBind implements sqlair.GeneratedRecord.\n")
+	fmt.Fprintf(buf, "func (v %s) SqlairBind(column string) (interface{}, bool) {\n\tswitch column {\n", name)
+	for _, column := range columns {
+		if column.bind {
+			fmt.Fprintf(buf, "\tcase %q:\n\t\treturn v.%s, true\n", column.name, column.selector)
+		}
+	}
+	buf.WriteString("\t}\n\treturn nil, false\n}\n")
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := generateDir("testdata", "person_sqlair.go", "db", []string{"Person", "Pet"})
+	assert.Nil(t, err)
+
+	expected, err := os.ReadFile("testdata/person_sqlair.go.golden")
+	assert.Nil(t, err)
+	assert.Equal(t, string(src), string(expected))
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := generateDir("testdata", "person_sqlair.go", "db", []string{"Missing"})
+	assert.Equal(t, err.Error(), `type "Missing": struct type not found`)
+
+	_, err = generateDir("testdata", "person_sqlair.go", "db", []string{"Status"})
+	assert.Equal(t, err.Error(), `type "Status": struct type not found`)
+}
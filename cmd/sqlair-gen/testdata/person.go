@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Base struct {
+	ID      int64     `db:"id,pk"`
+	Created time.Time `db:"created"`
+}
+
+type Address struct {
+	City string
+}
+
+type Person struct {
+	Base
+	Address  `db:",prefix=home_"`
+	Name     string          `db:"name"`
+	Age      *int            `db:"age,omitempty"`
+	Tags     json.RawMessage `db:"tags,json"`
+	Status   Status
+	Pets     []Pet  `db:"pets,hasmany=person_id"`
+	Password string `db:"-"`
+	internal string
+}
+
+type Status string
+
+type Pet struct {
+	ID       int64 `db:"id,pk"`
+	PersonID int64
+}
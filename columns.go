@@ -0,0 +1,33 @@
+package sqlair
+
+import (
+	"sort"
+	"strings"
+)
+
+// Columns returns the comma separated columns of the struct type of the value,
+// in the same order as a wildcard record expression expands them. Each column
+// is prefixed with the table or alias, unless the prefix is empty. It keeps
+// hand written SQL in sync with the tags of the struct:
+//
+//  columns, err := querier.Columns(&Person{}, "p")
+//  // p.age, p.id, p.name
+//
+// The value can be a struct, or a pointer or slice of the struct.
+func (q *Querier) Columns(value interface{}, prefix string) (string, error) {
+	structs, err := q.registerStructs([]interface{}{value})
+	if err != nil {
+		return "", err
+	}
+
+	names := structs[0].DeclaredFieldNames()
+	if q.order == SortedOrder {
+		sort.Strings(names)
+	}
+	if prefix != "" {
+		for i, name := range names {
+			names[i] = prefix + "." + name
+		}
+	}
+	return strings.Join(names, ", "), nil
+}
@@ -0,0 +1,38 @@
+package sqlair
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumns(t *testing.T) {
+	type Person struct {
+		Name string `db:"name"`
+		ID   int64  `db:"id,pk"`
+		Age  int    `db:"age"`
+		Note string `db:"-"`
+	}
+
+	querier := NewQuerier()
+	columns, err := querier.Columns(&Person{}, "people")
+	assert.Nil(t, err)
+	assert.Equal(t, columns, "people.age, people.id, people.name")
+
+	// The columns match the expansion of a wildcard record.
+	stmt, err := querier.Compile(`SELECT {people.* INTO Person} FROM people;`, Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, stmt, "SELECT "+columns+" FROM people;")
+
+	columns, err = querier.Columns([]Person{}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, columns, "age, id, name")
+
+	querier.ColumnOrder(DeclarationOrder)
+	columns, err = querier.Columns(Person{}, "p")
+	assert.Nil(t, err)
+	assert.Equal(t, columns, "p.name, p.id, p.age")
+
+	_, err = querier.Columns(1, "")
+	assert.Equal(t, err.Error(), `expected struct type to register, got "int"`)
+}
@@ -0,0 +1,95 @@
+package sqlair
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Commenter returns a set of key value pairs that are appended to a compiled
+// statement as a SQL comment. This allows database side logs (slow query logs
+// for example) to be correlated with application traces.
+//
+// The comment follows the sqlcommenter format:
+//
+//  SELECT name FROM people /*route='GET%20%2Fpeople',traceparent='00-...'*/;
+//
+type Commenter func(context.Context) map[string]string
+
+// Commenter assigns the commenter to the querier. The commenter is called for
+// every statement that is executed, along with any comment values found in
+// the context (see WithComment).
+func (q *Querier) Commenter(commenter Commenter) {
+	q.commenter = commenter
+}
+
+type commentKey struct{}
+
+// WithComment returns a new context that carries a comment key and value.
+// Every statement executed with the context will have the comment appended,
+// regardless of whether the querier has a commenter.
+func WithComment(ctx context.Context, key, value string) context.Context {
+	existing, _ := ctx.Value(commentKey{}).(map[string]string)
+	comments := make(map[string]string, len(existing)+1)
+	for k, v := range existing {
+		comments[k] = v
+	}
+	comments[key] = value
+	return context.WithValue(ctx, commentKey{}, comments)
+}
+
+// annotateStatement appends the comments from the context and the commenter
+// to the statement. If there are no comments, then the statement is returned
+// untouched.
+func annotateStatement(ctx context.Context, stmt string, commenter Commenter) string {
+	comments, _ := ctx.Value(commentKey{}).(map[string]string)
+	if commenter != nil {
+		// The commenter values take precedence over the context values, as
+		// they're the more specific of the two.
+		if values := commenter(ctx); len(values) > 0 {
+			merged := make(map[string]string, len(comments)+len(values))
+			for k, v := range comments {
+				merged[k] = v
+			}
+			for k, v := range values {
+				merged[k] = v
+			}
+			comments = merged
+		}
+	}
+	if len(comments) == 0 {
+		return stmt
+	}
+
+	comment := formatComment(comments)
+
+	// The comment should be placed before the statement terminator and any
+	// trailing comments, otherwise a trailing line comment would swallow it.
+	end := contentEnd(stmt)
+	tail := strings.TrimRight(stmt[end:], " \t\r\n")
+	if trimmed := strings.TrimLeft(tail, " \t\r\n"); strings.HasPrefix(trimmed, ";") {
+		tail = trimmed
+	}
+	return stmt[:end] + " " + comment + trailingText(tail)
+}
+
+// formatComment serializes the comments using the sqlcommenter format. Keys
+// are sorted so that the statement is deterministic. Both keys and values are
+// URL encoded, which also encodes any quotes or asterisks that could otherwise
+// terminate the value or the comment early.
+//
+// See https://google.github.io/sqlcommenter/spec/
+func formatComment(comments map[string]string) string {
+	keys := make([]string, 0, len(comments))
+	for key := range comments {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = url.PathEscape(key) + "='" + url.PathEscape(comments[key]) + "'"
+	}
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
@@ -0,0 +1,103 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotateStatementWithoutComments(t *testing.T) {
+	stmt := annotateStatement(context.Background(), "SELECT name FROM test;", nil)
+	assert.Equal(t, stmt, "SELECT name FROM test;")
+}
+
+func TestAnnotateStatementWithCommenter(t *testing.T) {
+	stmt := annotateStatement(context.Background(), "SELECT name FROM test; ", func(context.Context) map[string]string {
+		return map[string]string{
+			"traceparent": "00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01",
+			"route":       "GET /people",
+		}
+	})
+	assert.Equal(t, stmt, "SELECT name FROM test /*route='GET%20%2Fpeople',traceparent='00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01'*/;")
+}
+
+func TestAnnotateStatementWithContext(t *testing.T) {
+	ctx := WithComment(context.Background(), "route", "GET /people")
+	ctx = WithComment(ctx, "action", "it's")
+
+	stmt := annotateStatement(ctx, "SELECT name FROM test", func(context.Context) map[string]string {
+		return map[string]string{
+			"route": "GET /persons",
+		}
+	})
+	assert.Equal(t, stmt, "SELECT name FROM test /*action='it%27s',route='GET%20%2Fpersons'*/")
+}
+
+func TestAnnotateStatementWithTrailingComment(t *testing.T) {
+	commenter := func(context.Context) map[string]string {
+		return map[string]string{"route": "x"}
+	}
+
+	stmt := annotateStatement(context.Background(), "SELECT name FROM test -- trailing", commenter)
+	assert.Equal(t, stmt, "SELECT name FROM test /*route='x'*/\n-- trailing")
+
+	stmt = annotateStatement(context.Background(), "SELECT name FROM test; /* trailing */\n", commenter)
+	assert.Equal(t, stmt, "SELECT name FROM test /*route='x'*/; /* trailing */")
+}
+
+func TestQueryContextWithComment(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	var processedStmts []string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmts = append(processedStmts, stmt)
+		return nil
+	})
+	querier.Commenter(func(ctx context.Context) map[string]string {
+		return map[string]string{
+			"application": "sqlair",
+		}
+	})
+
+	ctx := WithComment(context.Background(), "route", "/people")
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := querier.ExecContext(ctx, tx, "UPDATE test SET age=:age WHERE name=:name;", Person{Name: "fred", Age: 22}); err != nil {
+			return err
+		}
+
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.QueryContext(ctx, tx, `SELECT {test.* INTO Person} FROM test WHERE test.name=:name;`, map[string]interface{}{
+			"name": "fred",
+		})
+	})
+
+	assert.Equal(t, person, Person{Name: "fred", Age: 22})
+
+	expected := []string{
+		"UPDATE test SET age=:age WHERE name=:name /*application='sqlair',route='%2Fpeople'*/;",
+		"SELECT test.age, test.name FROM test WHERE test.name=:name /*application='sqlair',route='%2Fpeople'*/;",
+	}
+	assert.Equal(t, processedStmts, expected)
+}
@@ -0,0 +1,181 @@
+package sqlair
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// runConcurrently runs the function from many goroutines at once, each with
+// its own index.
+func runConcurrently(t *testing.T, fn func(int) error) {
+	const goroutines = 16
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- fn(i)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+}
+
+func queryConcurrently(db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func TestQuerierConcurrentQueries(t *testing.T) {
+	db := setupFileDB(t, peopleSchema)
+
+	var (
+		mutex    sync.Mutex
+		observed int
+	)
+	querier := NewQuerier()
+	querier.OnStatement(func(info StatementInfo) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		observed++
+		return nil
+	})
+
+	runConcurrently(t, func(i int) error {
+		return queryConcurrently(db, func(tx *sql.Tx) error {
+			var persons []argsPerson
+			getter, err := querier.ForMany(&persons)
+			if err != nil {
+				return err
+			}
+			if err := getter.Query(tx, `SELECT {argsPerson} FROM people ORDER BY name;`); err != nil {
+				return err
+			}
+			if len(persons) != 3 {
+				return fmt.Errorf("expected 3 people, got %d", len(persons))
+			}
+
+			var person argsPerson
+			getter, err = querier.ForOne(&person)
+			if err != nil {
+				return err
+			}
+			name := persons[i%len(persons)].Name
+			if err := getter.Query(tx, `SELECT {argsPerson} FROM people WHERE name=:name;`, sql.Named("name", name)); err != nil {
+				return err
+			}
+			if person.Name != name {
+				return fmt.Errorf("expected %q, got %q", name, person.Name)
+			}
+			return nil
+		})
+	})
+
+	assert.Equal(t, observed, 32)
+}
+
+func TestStatementConcurrentQueries(t *testing.T) {
+	db := setupFileDB(t, peopleSchema)
+
+	querier := NewQuerier()
+	stmt, err := querier.Prepare(`SELECT {argsPerson} FROM people WHERE age>=? ORDER BY name;`, []argsPerson{})
+	assert.Nil(t, err)
+
+	runConcurrently(t, func(i int) error {
+		return queryConcurrently(db, func(tx *sql.Tx) error {
+			var persons []argsPerson
+			if err := stmt.Query(tx, &persons, 22); err != nil {
+				return err
+			}
+			if len(persons) != 2 || persons[0].Name != "frank" || persons[1].Name != "jane" {
+				return fmt.Errorf("unexpected people %v", persons)
+			}
+			return nil
+		})
+	})
+}
+
+func TestQuerierConcurrentCachedQueries(t *testing.T) {
+	db := setupFileDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	runConcurrently(t, func(i int) error {
+		return queryConcurrently(db, func(tx *sql.Tx) error {
+			var persons []argsPerson
+			getter, err := querier.ForMany(&persons)
+			if err != nil {
+				return err
+			}
+			result, err := getter.Cached(time.Minute).QueryResult(tx, `SELECT {argsPerson} FROM people ORDER BY name;`)
+			if err != nil {
+				return err
+			}
+			if err := result.ExpectRows(3); err != nil {
+				return err
+			}
+			// The destinations of the query are never shared with the
+			// cache, so they can be changed without affecting other
+			// queries.
+			for j := range persons {
+				persons[j].Name = fmt.Sprintf("changed-%d", i)
+			}
+			if i%4 == 0 {
+				querier.InvalidateCache("people")
+			}
+			return nil
+		})
+	})
+}
+
+func TestQueryConcurrentExecution(t *testing.T) {
+	db := setupFileDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	// The query is executed again while it's populating its destinations,
+	// which is what happens when a query is shared between goroutines.
+	var nested error
+	getter = getter.WithHook(func(string) error {
+		runTx(t, db, func(tx *sql.Tx) error {
+			nested = getter.Query(tx, `SELECT {argsPerson} FROM people;`)
+			return nil
+		})
+		return nil
+	})
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		return getter.Query(tx, `SELECT {argsPerson} FROM people;`)
+	})
+	assert.True(t, errors.Is(nested, ErrConcurrentQuery))
+	assert.Len(t, persons, 3)
+
+	// Once the query has finished, it can be executed again.
+	runTx(t, db, func(tx *sql.Tx) error {
+		return getter.Query(tx, `SELECT {argsPerson} FROM people;`)
+	})
+	assert.Len(t, persons, 6)
+}
@@ -0,0 +1,119 @@
+package sqlair
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// ConvertFunc converts a value to or from its database representation.
+type ConvertFunc func(interface{}) (interface{}, error)
+
+// RegisterConverter registers a pair of functions that convert values of the
+// type t to and from the database. This allows types that don't implement
+// sql.Scanner or driver.Valuer (third party types for example) to be used as
+// fields and arguments.
+//
+// The toDB function is called with a value of type t when it's used as a
+// named argument. The fromDB function is called with the raw value scanned
+// from the database (which may be nil), and must return a value that is
+// assignable or convertible to t. Either function can be nil, in which case
+// the value is passed through untouched.
+//
+// Converters take precedence over any sql.Scanner or driver.Valuer
+// implementations of the type. Pointers to t are also converted, with NULL
+// values being mapped to a nil pointer.
+func (q *Querier) RegisterConverter(t reflect.Type, toDB, fromDB ConvertFunc) {
+	// Copy the converters, so that existing queries and copies of the querier
+	// aren't affected.
+	converters := make(map[reflect.Type]converter, len(q.mapper.converters)+1)
+	for k, v := range q.mapper.converters {
+		converters[k] = v
+	}
+	converters[t] = converter{
+		toDB:   toDB,
+		fromDB: fromDB,
+	}
+	q.mapper.converters = converters
+}
+
+type converter struct {
+	toDB, fromDB ConvertFunc
+	// pointer is true if the converter was registered for the element type
+	// of a pointer.
+	pointer bool
+}
+
+// converter returns the converter for a given type. If the type is a pointer,
+// then the converter for the element type is returned.
+func (m fieldMapper) converter(t reflect.Type) (converter, bool) {
+	if len(m.converters) == 0 {
+		return converter{}, false
+	}
+	if c, ok := m.converters[t]; ok {
+		return c, true
+	}
+	if t.Kind() == reflect.Ptr {
+		if c, ok := m.converters[t.Elem()]; ok {
+			c.pointer = true
+			return c, true
+		}
+	}
+	return converter{}, false
+}
+
+// convert converts the value to the database representation using the
+// converter.
+func (c converter) convert(value reflect.Value) (interface{}, error) {
+	if c.pointer {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	if c.toDB == nil {
+		return value.Interface(), nil
+	}
+	v, err := c.toDB(value.Interface())
+	return v, errors.Wrapf(err, "converting %s", value.Type())
+}
+
+// converterScanner scans a value using the converter, before setting the
+// converted value on the destination.
+type converterScanner struct {
+	dest      reflect.Value
+	converter converter
+}
+
+// Scan implements sql.Scanner.
+func (s converterScanner) Scan(src interface{}) error {
+	value := src
+	if s.converter.fromDB != nil {
+		var err error
+		if value, err = s.converter.fromDB(src); err != nil {
+			return errors.Wrapf(err, "converting %T to %s", src, s.dest.Type())
+		}
+	}
+	if value == nil {
+		s.dest.Set(reflect.Zero(s.dest.Type()))
+		return nil
+	}
+
+	dest := s.dest
+	if s.converter.pointer {
+		ptr := reflect.New(dest.Type().Elem())
+		dest.Set(ptr)
+		dest = ptr.Elem()
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(dest.Type()):
+		dest.Set(v)
+	case v.Type().ConvertibleTo(dest.Type()):
+		dest.Set(v.Convert(dest.Type()))
+	default:
+		return errors.Errorf("converted value %T is not assignable to %s", value, dest.Type())
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package sqlair
+
+import (
+	"database/sql"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func registerIPConverter(querier *Querier) {
+	querier.RegisterConverter(reflect.TypeOf(net.IP{}), func(v interface{}) (interface{}, error) {
+		return v.(net.IP).String(), nil
+	}, func(v interface{}) (interface{}, error) {
+		if v == nil {
+			return nil, nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("unexpected type %T", v)
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.Errorf("invalid ip %q", s)
+		}
+		return ip, nil
+	})
+}
+
+func TestQueryWithConverter(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name    TEXT,
+	address TEXT,
+	gateway TEXT
+);
+	`)
+	assert.Nil(t, err)
+
+	type Host struct {
+		Name    string  `db:"name"`
+		Address net.IP  `db:"address"`
+		Gateway *net.IP `db:"gateway"`
+	}
+
+	querier := NewQuerier()
+	registerIPConverter(querier)
+
+	gateway := net.ParseIP("10.0.0.1")
+
+	var (
+		raw   string
+		hosts []Host
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		for _, host := range []Host{
+			{Name: "alpha", Address: net.ParseIP("10.0.0.2"), Gateway: &gateway},
+			{Name: "beta", Address: net.ParseIP("10.0.0.3")},
+		} {
+			if _, err := querier.Exec(tx, "INSERT INTO test(name, address, gateway) VALUES (:name, :address, :gateway);", host); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.QueryRow("SELECT address FROM test WHERE name='alpha';").Scan(&raw); err != nil {
+			return err
+		}
+
+		getter, err := querier.ForMany(&hosts)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Host} FROM test WHERE address!=:address ORDER BY name;`, map[string]interface{}{
+			"address": net.ParseIP("10.0.0.4"),
+		})
+	})
+
+	assert.Equal(t, raw, "10.0.0.2")
+	assert.Equal(t, hosts, []Host{
+		{Name: "alpha", Address: net.ParseIP("10.0.0.2"), Gateway: &gateway},
+		{Name: "beta", Address: net.ParseIP("10.0.0.3")},
+	})
+}
+
+func TestQueryWithConverterError(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	address TEXT
+);
+INSERT INTO test(address) values ("nowhere");
+	`)
+	assert.Nil(t, err)
+
+	type Host struct {
+		Address net.IP `db:"address"`
+	}
+
+	querier := NewQuerier()
+	registerIPConverter(querier)
+
+	var host Host
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&host)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {test.* INTO Host} FROM test;`)
+	assert.Contains(t, err.Error(), `converting string to net.IP: invalid ip "nowhere"`)
+}
+
+func TestRegisterConverterDoesNotAffectCopies(t *testing.T) {
+	querier := NewQuerier()
+	copied := querier.Copy()
+	registerIPConverter(querier)
+
+	_, ok := querier.mapper.converter(reflect.TypeOf(net.IP{}))
+	assert.True(t, ok)
+	_, ok = copied.mapper.converter(reflect.TypeOf(net.IP{}))
+	assert.False(t, ok)
+}
@@ -0,0 +1,75 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// Result is the result of a query executed with QueryResult.
+type Result struct {
+	// Rows is the number of rows scanned into the destinations. Rows that
+	// are folded into a parent, such as the rows of a one-to-many join, are
+	// counted as well.
+	Rows int64
+}
+
+// ExpectRows returns a RowCountError unless the query scanned exactly n
+// rows.
+func (r Result) ExpectRows(n int64) error {
+	if r.Rows != n {
+		return errors.WithStack(&RowCountError{
+			Expected: n,
+			Actual:   r.Rows,
+		})
+	}
+	return nil
+}
+
+// QueryResult executes a query that returns rows in the same way as Query,
+// returning the number of rows that were scanned:
+//
+//  result, err := getter.QueryResult(tx, `SELECT {Person} FROM people WHERE name=:name;`, person)
+//  ...
+//  err = result.ExpectRows(1)
+//
+// The rows of cached results are counted as if the query had been executed.
+func (q Query) QueryResult(tx *sql.Tx, stmt string, args ...interface{}) (Result, error) {
+	return q.QueryResultContext(context.Background(), tx, stmt, args...)
+}
+
+// QueryResultContext executes a query that returns rows, using the context
+// for cancellation and for any statement comments, returning the number of
+// rows that were scanned. See QueryResult.
+func (q Query) QueryResultContext(ctx context.Context, tx *sql.Tx, stmt string, args ...interface{}) (Result, error) {
+	var rows int64
+	q.scanned = &rows
+	err := q.QueryContext(ctx, tx, stmt, args...)
+	return Result{Rows: rows}, err
+}
+
+// ExpectRowsAffected returns a RowCountError unless the statement affected
+// exactly n rows, which asserts that an UPDATE or DELETE found its row:
+//
+//  result, err := querier.Exec(tx, `UPDATE people SET age=:age WHERE id=:id;`, person)
+//  if err != nil {
+//  	return err
+//  }
+//  if err := sqlair.ExpectRowsAffected(result, 1); err != nil {
+//  	return err
+//  }
+//
+func ExpectRowsAffected(result sql.Result, n int64) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if affected != n {
+		return errors.WithStack(&RowCountError{
+			Expected: n,
+			Actual:   affected,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type countPerson struct {
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+// countSchema is a table of people, of which two are older than 25.
+const countSchema = `
+CREATE TABLE people (name TEXT, age INTEGER);
+INSERT INTO people (name, age) VALUES ("fred", 21), ("frank", 42), ("jane", 30);
+`
+
+func TestQueryResult(t *testing.T) {
+	db := setupSchemaDB(t, countSchema)
+
+	querier := NewQuerier()
+
+	var (
+		persons []countPerson
+		result  Result
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		result, err = getter.QueryResult(tx, `SELECT {countPerson} FROM people WHERE age>:age;`, map[string]interface{}{
+			"age": 25,
+		})
+		return err
+	})
+	assert.Equal(t, result, Result{Rows: 2})
+	assert.Nil(t, result.ExpectRows(2))
+
+	err := result.ExpectRows(1)
+	assert.True(t, errors.As(err, new(*RowCountError)))
+	assert.Equal(t, err.Error(), "expected 1 rows, got 2")
+
+	var person countPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		result, err = getter.QueryResult(tx, `SELECT {countPerson} FROM people WHERE name="nobody";`)
+		return err
+	})
+	assert.Equal(t, result, Result{Rows: 0})
+}
+
+func TestQueryResultCached(t *testing.T) {
+	db := setupSchemaDB(t, countSchema)
+
+	querier := NewQuerier()
+
+	for i := 0; i < 2; i++ {
+		var (
+			persons []countPerson
+			result  Result
+		)
+		runTx(t, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForMany(&persons)
+			assert.Nil(t, err)
+
+			result, err = getter.Cached(time.Minute).QueryResult(tx, `SELECT {countPerson} FROM people;`)
+			return err
+		})
+		assert.Equal(t, result, Result{Rows: 3})
+		assert.Len(t, persons, 3)
+	}
+}
+
+func TestExpectRowsAffected(t *testing.T) {
+	db := setupSchemaDB(t, countSchema)
+
+	querier := NewQuerier()
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		result, err := querier.Exec(tx, `UPDATE people SET age=:age WHERE name=:name;`, countPerson{Name: "fred", Age: 22})
+		assert.Nil(t, err)
+		assert.Nil(t, ExpectRowsAffected(result, 1))
+
+		result, err = querier.Exec(tx, `UPDATE people SET age=:age WHERE name=:name;`, countPerson{Name: "nobody", Age: 22})
+		assert.Nil(t, err)
+		err = ExpectRowsAffected(result, 1)
+		assert.True(t, errors.As(err, new(*RowCountError)))
+		assert.Equal(t, err.Error(), "expected 1 rows, got 0")
+		return nil
+	})
+}
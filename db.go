@@ -0,0 +1,155 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// DB wraps a database with its own Querier, so that the database and the
+// querier don't have to be passed around separately. The hooks and caches of
+// the querier are configured through Querier.
+//
+//  db := sqlair.NewDB(sqlDB)
+//  db.Querier().Hook(logStatement)
+//
+//  var person Person
+//  err := db.GetOne(ctx, &person, `SELECT {Person} FROM people WHERE name=:name;`, filter)
+//
+// Every method runs in its own transaction, unless the context is that of a
+// transaction on the same database (see Querier.Tx), in which case it runs
+// within that transaction.
+//
+// If the querier has a router (see Querier.Router), read-only statements and
+// read-only transactions run on the read replicas of the router, and
+// everything else runs on the database.
+type DB struct {
+	db      *sql.DB
+	querier *Querier
+}
+
+// NewDB creates a DB for the database, with a new querier.
+func NewDB(db *sql.DB) *DB {
+	return NewDBWithQuerier(db, NewQuerier())
+}
+
+// NewDBWithQuerier creates a DB for the database, using the querier.
+func NewDBWithQuerier(db *sql.DB, querier *Querier) *DB {
+	return &DB{
+		db:      db,
+		querier: querier,
+	}
+}
+
+// NewDBWithRouter creates a DB for the primary database of the router, with a
+// new querier that routes read-only statements to the replicas.
+func NewDBWithRouter(router *Router) *DB {
+	querier := NewQuerier()
+	querier.Router(router)
+	return NewDBWithQuerier(router.Primary(), querier)
+}
+
+// DB returns the underlying database.
+func (d *DB) DB() *sql.DB {
+	return d.db
+}
+
+// Querier returns the querier of the database.
+func (d *DB) Querier() *Querier {
+	return d.querier
+}
+
+// GetOne executes a statement that returns rows, populating the destination
+// in the same way as ForOne. Several destinations can be passed as a
+// []interface{}, and query options can follow the destination in the same
+// slice.
+//
+//  err := db.GetOne(ctx, []interface{}{&person, &location}, stmt, filter)
+//
+func (d *DB) GetOne(ctx context.Context, dest interface{}, stmt string, args ...interface{}) error {
+	return d.querier.Tx(ctx, d.database(ctx, stmt), func(tx *TxQuerier) error {
+		return tx.GetOne(dest, stmt, args...)
+	})
+}
+
+// GetAll executes a statement that returns rows, appending every row to the
+// destination slice in the same way as ForMany. Several destinations can be
+// passed as a []interface{}.
+func (d *DB) GetAll(ctx context.Context, dest interface{}, stmt string, args ...interface{}) error {
+	return d.querier.Tx(ctx, d.database(ctx, stmt), func(tx *TxQuerier) error {
+		return tx.GetAll(dest, stmt, args...)
+	})
+}
+
+// Exec executes a statement that doesn't return rows.
+func (d *DB) Exec(ctx context.Context, stmt string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := d.querier.Tx(ctx, d.database(ctx, stmt), func(tx *TxQuerier) error {
+		var err error
+		result, err = tx.Exec(stmt, args...)
+		return err
+	})
+	return result, err
+}
+
+// Tx runs the function in a transaction. See Querier.Tx.
+func (d *DB) Tx(ctx context.Context, fn func(*TxQuerier) error) error {
+	return d.querier.Tx(ctx, d.transactionDB(ctx, false), fn)
+}
+
+// TxWithOptions runs the function in a transaction with the options. See
+// Querier.TxWithOptions.
+func (d *DB) TxWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(*TxQuerier) error) error {
+	return d.querier.TxWithOptions(ctx, d.transactionDB(ctx, opts != nil && opts.ReadOnly), opts, fn)
+}
+
+// database returns the database to execute the statement on.
+func (d *DB) database(ctx context.Context, stmt string) *sql.DB {
+	return d.transactionDB(ctx, Classify(stmt).ReadOnly)
+}
+
+// transactionDB returns the database to run a transaction on. A transaction
+// that's nested in a transaction of the database, or of one of its replicas,
+// runs on the same database, so that it sees the changes of the transaction.
+func (d *DB) transactionDB(ctx context.Context, readOnly bool) *sql.DB {
+	router := d.querier.router
+	if parent, ok := ctx.Value(txContextKey{}).(*TxQuerier); ok {
+		if parent.db == d.db || (router != nil && router.has(parent.db)) {
+			return parent.db
+		}
+	}
+	if readOnly && router != nil {
+		return router.Reader()
+	}
+	return d.db
+}
+
+// GetOne executes a statement that returns rows, populating the destination
+// in the same way as ForOne. See DB.GetOne.
+func (t *TxQuerier) GetOne(dest interface{}, stmt string, args ...interface{}) error {
+	query, err := t.querier.ForOne(destinations(dest)...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return t.Query(query, stmt, args...)
+}
+
+// GetAll executes a statement that returns rows, appending every row to the
+// destination slice in the same way as ForMany. See DB.GetAll.
+func (t *TxQuerier) GetAll(dest interface{}, stmt string, args ...interface{}) error {
+	query, err := t.querier.ForMany(destinations(dest)...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return t.Query(query, stmt, args...)
+}
+
+// destinations returns the values of a destination, which is either a single
+// value or a []interface{} of values.
+func destinations(dest interface{}) []interface{} {
+	if values, ok := dest.([]interface{}); ok {
+		return values
+	}
+	return []interface{}{dest}
+}
@@ -0,0 +1,98 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBGetOne(t *testing.T) {
+	db := NewDB(setupSchemaDB(t, peopleSchema))
+
+	var processedStmt string
+	db.Querier().Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var person argsPerson
+	err := db.GetOne(context.Background(), &person, `SELECT {argsPerson} FROM people WHERE name=:name;`, map[string]interface{}{
+		"name": "fred",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, person, argsPerson{Name: "fred", Age: 21})
+	assert.Equal(t, processedStmt, "SELECT age, name FROM people WHERE name=:name;")
+}
+
+func TestDBGetOneWithDestinations(t *testing.T) {
+	db := NewDB(setupSchemaDB(t, peopleSchema))
+
+	var first, second argsPerson
+	err := db.GetOne(context.Background(), []interface{}{As("first", &first), As("second", &second), Strict()}, `SELECT {a.* INTO argsPerson AS first}, {b.* INTO argsPerson AS second} FROM people AS a, people AS b WHERE a.name=:first AND b.name=:second;`, map[string]interface{}{
+		"first":  "fred",
+		"second": "jane",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, first, argsPerson{Name: "fred", Age: 21})
+	assert.Equal(t, second, argsPerson{Name: "jane", Age: 23})
+}
+
+func TestDBGetAllAndExec(t *testing.T) {
+	db := NewDB(setupSchemaDB(t, peopleSchema))
+
+	_, err := db.Exec(context.Background(), `UPDATE people SET age=age+1 WHERE name=:name;`, argsPerson{Name: "jane"})
+	assert.Nil(t, err)
+
+	var persons []argsPerson
+	err = db.GetAll(context.Background(), &persons, `SELECT {people.* INTO argsPerson} FROM people WHERE people.age>? ORDER BY people.name;`, 22)
+	assert.Nil(t, err)
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "jane", Age: 24},
+	})
+}
+
+func TestDBGetOneWithInvalidTag(t *testing.T) {
+	type invalidPerson struct {
+		Name string `db:""`
+	}
+
+	db := NewDB(setupSchemaDB(t, peopleSchema))
+
+	var person invalidPerson
+	err := db.GetOne(context.Background(), &person, `SELECT {invalidPerson} FROM people;`)
+	assert.Equal(t, err.Error(), "reflect: unexpected empty tag")
+
+	var persons []invalidPerson
+	err = db.GetAll(context.Background(), &persons, `SELECT {invalidPerson} FROM people;`)
+	assert.Equal(t, err.Error(), "reflect: unexpected empty tag")
+
+	_, err = db.Querier().Debug(`SELECT {invalidPerson} FROM people;`, nil, &person)
+	assert.Equal(t, err.Error(), "reflect: unexpected empty tag")
+}
+
+func TestDBWithinTx(t *testing.T) {
+	db := NewDB(setupSchemaDB(t, peopleSchema))
+
+	err := db.Tx(context.Background(), func(tx *TxQuerier) error {
+		if _, err := db.Exec(tx.Context(), `DELETE FROM people;`); err != nil {
+			return err
+		}
+
+		// The statements of the context run within the same transaction.
+		var persons []argsPerson
+		err := db.GetAll(tx.Context(), &persons, `SELECT {people.* INTO argsPerson} FROM people;`)
+		assert.Nil(t, err)
+		assert.Equal(t, len(persons), 0)
+
+		return errors.New("boom")
+	})
+	assert.Equal(t, err.Error(), "boom")
+
+	var persons []argsPerson
+	err = db.GetAll(context.Background(), &persons, `SELECT {people.* INTO argsPerson} FROM people;`)
+	assert.Nil(t, err)
+	assert.Equal(t, len(persons), 3)
+}
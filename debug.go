@@ -0,0 +1,263 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// DebugInfo describes how a statement is compiled for a query, without
+// executing it.
+type DebugInfo struct {
+	// Statement is the compiled statement with the named arguments replaced
+	// by their values as SQL literals, so that it can be pasted into a
+	// sqlite3 or psql shell.
+	Statement string
+	// Compiled is the compiled statement as it's sent to the database, with
+	// Args as its arguments.
+	Compiled string
+	Args     []interface{}
+	// Columns are the columns selected by the record expressions of the
+	// statement, along with the fields that they're scanned into.
+	Columns []DebugColumn
+	// Classification classifies the compiled statement (see Classify).
+	Classification Classification
+}
+
+// DebugColumn is a column selected by a record expression.
+type DebugColumn struct {
+	// Column is the name of the column in the rows of the statement.
+	Column string
+	// Expression is the column as it's written in the compiled statement.
+	Expression string
+	// Entity is the type of the destination, and Field is the name of the Go
+	// field that the column is scanned into.
+	Entity string
+	Field  string
+}
+
+// String returns the statement, followed by a table of the columns and their
+// fields.
+func (d DebugInfo) String() string {
+	var b strings.Builder
+	b.WriteString(d.Statement)
+	if len(d.Columns) == 0 {
+		return b.String()
+	}
+	b.WriteString("\n\n")
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "COLUMN\tFIELD\tEXPRESSION")
+	for _, column := range d.Columns {
+		fmt.Fprintf(w, "%s\t%s.%s\t%s\n", column.Column, column.Entity, column.Field, column.Expression)
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Debug compiles the statement in the same way as a query of ForOne would
+// execute it, without executing it. The values are the destinations of the
+// query, as given to ForOne, and the argument provides the named arguments of
+// the statement. It returns the statement with the named arguments replaced by
+// literals, along with the fields that each column is scanned into:
+//
+//  info, err := querier.Debug(`SELECT {Person} FROM people WHERE name=:name;`, Person{Name: "fred"}, &person)
+//  ...
+//  fmt.Println(info)
+//
+// The literals are quoted for the dialect of the querier (see
+// Querier.Dialect), but they're only meant for debugging. Statements should
+// always be executed with their arguments bound.
+func (q *Querier) Debug(stmt string, arg interface{}, values ...interface{}) (DebugInfo, error) {
+	query, err := q.ForOne(values...)
+	if err != nil {
+		return DebugInfo{}, err
+	}
+
+	var explained explainedStatement
+	query.explained = &explained
+	query.cacheTTL = 0
+
+	var args []interface{}
+	if arg != nil {
+		args = append(args, arg)
+	}
+	// A nil transaction is never used, as the explained query returns before
+	// executing the statement.
+	if err := query.QueryContext(context.Background(), nil, stmt, args...); !errors.Is(err, errExplained) {
+		if err == nil {
+			return DebugInfo{}, errors.Errorf("expected statement to debug")
+		}
+		return DebugInfo{}, err
+	}
+
+	literal, err := literalStatement(q.mapper.dialect, explained.stmt, explained.args)
+	if err != nil {
+		return DebugInfo{}, errors.Wrap(err, "debug")
+	}
+	columns, err := query.debugColumns(explained.records)
+	if err != nil {
+		return DebugInfo{}, errors.Wrap(err, "debug")
+	}
+	return DebugInfo{
+		Statement:      literal,
+		Compiled:       explained.stmt,
+		Args:           explained.args,
+		Columns:        columns,
+		Classification: Classify(explained.stmt),
+	}, nil
+}
+
+// debugColumns returns the columns that the records select, in the same way
+// that the records are expanded.
+func (q Query) debugColumns(records []recordBinding) ([]DebugColumn, error) {
+	var structs []sreflect.ReflectStruct
+	for _, entity := range q.entities {
+		if refStruct, ok := entity.(sreflect.ReflectStruct); ok {
+			structs = append(structs, refStruct)
+		}
+	}
+	entities, err := q.bindEntities(records, structs)
+	if err != nil {
+		return nil, err
+	}
+	intersections := fieldIntersections(entities)
+	options := expansion{
+		order:    q.order,
+		aliasAll: q.aliasAll,
+		alias:    q.columnAlias,
+		config:   q.reflect.Config(),
+	}
+
+	var result []DebugColumn
+	for position, record := range records {
+		for _, entity := range entities {
+			if record.entityName() != entity.Name {
+				continue
+			}
+			columns, err := recordColumns(record, entity, intersections[entity.Name], position, options)
+			if err != nil {
+				return nil, err
+			}
+			for _, column := range columns {
+				result = append(result, DebugColumn{
+					Column:     selectedName(column.expression),
+					Expression: column.expression,
+					Entity:     entity.Name,
+					Field:      entity.Fields[column.field].Name,
+				})
+			}
+			break
+		}
+	}
+	return result, nil
+}
+
+// selectedName returns the name of the column that the expression is selected
+// as, which is either its alias or its unqualified column.
+func selectedName(expression string) string {
+	if index := strings.LastIndex(expression, " AS "); index >= 0 {
+		return expression[index+4:]
+	}
+	return expression[strings.LastIndex(expression, ".")+1:]
+}
+
+// literalStatement replaces the named arguments and positional placeholders
+// of the compiled statement with the literals of their arguments.
+func literalStatement(dialect Dialect, stmt string, args []interface{}) (string, error) {
+	names, err := parseNames(stmt, 0)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		named      = make(map[string]interface{})
+		positional []interface{}
+	)
+	for _, arg := range args {
+		if arg, ok := arg.(sql.NamedArg); ok {
+			named[arg.Name] = arg.Value
+			continue
+		}
+		positional = append(positional, arg)
+	}
+
+	var (
+		builder strings.Builder
+		last    int
+	)
+	for _, name := range names {
+		var value interface{}
+		if name.name == "" {
+			if len(positional) == 0 {
+				return "", errors.Errorf("missing argument for positional placeholder at offset %d", name.offset)
+			}
+			value, positional = positional[0], positional[1:]
+		} else {
+			var ok bool
+			if value, ok = named[name.name]; !ok {
+				return "", errors.Errorf("missing argument for named argument %q", name.name)
+			}
+		}
+
+		lit, err := sqlLiteral(dialect, value)
+		if err != nil {
+			return "", errors.Wrapf(err, "argument %q", name.name)
+		}
+		builder.WriteString(stmt[last:name.offset])
+		builder.WriteString(lit)
+		last = name.offset + 1 + len(name.name)
+	}
+	builder.WriteString(stmt[last:])
+	return builder.String(), nil
+}
+
+// sqlLiteral returns the value as a SQL literal for the dialect. The value is
+// converted in the same way as database/sql converts arguments, so that
+// driver.Valuer types are written as their database values.
+func sqlLiteral(dialect Dialect, value interface{}) (string, error) {
+	v, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case []byte:
+		if dialect == Postgres {
+			return `'\x` + hex.EncodeToString(v) + `'`, nil
+		}
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	case string:
+		return quoteString(dialect, v), nil
+	case time.Time:
+		return quoteString(dialect, v.Format("2006-01-02 15:04:05.999999999-07:00")), nil
+	}
+	return "", errors.Errorf("unexpected argument type %T", v)
+}
+
+// quoteString quotes the string as a SQL string literal. Quotes are escaped by
+// doubling them, as are backslashes for MySQL, which treats them as escapes.
+func quoteString(dialect Dialect, s string) string {
+	if dialect == MySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
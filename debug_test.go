@@ -0,0 +1,102 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuerierDebug(t *testing.T) {
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	type Address struct {
+		Name string `db:"name"`
+		City string `db:"city"`
+	}
+
+	querier := NewQuerier()
+
+	var (
+		person  Person
+		address Address
+	)
+	info, err := querier.Debug(`SELECT {p.* INTO Person}, {a.city INTO Address} FROM people AS p JOIN addresses AS a ON a.name=p.name WHERE p.name=:name AND p.age>:age;`, Person{Name: "o'neil", Age: 21}, &person, &address)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Compiled, `SELECT p.age, p.name AS _pfx_p_sfx_name, a.city FROM people AS p JOIN addresses AS a ON a.name=p.name WHERE p.name=:name AND p.age>:age;`)
+	assert.Equal(t, info.Statement, `SELECT p.age, p.name AS _pfx_p_sfx_name, a.city FROM people AS p JOIN addresses AS a ON a.name=p.name WHERE p.name='o''neil' AND p.age>21;`)
+	assert.Equal(t, info.Args, []interface{}{
+		sql.Named("name", "o'neil"),
+		sql.Named("age", 21),
+	})
+	assert.Equal(t, info.Columns, []DebugColumn{
+		{Column: "age", Expression: "p.age", Entity: "Person", Field: "Age"},
+		{Column: "_pfx_p_sfx_name", Expression: "p.name AS _pfx_p_sfx_name", Entity: "Person", Field: "Name"},
+		{Column: "city", Expression: "a.city", Entity: "Address", Field: "City"},
+	})
+	assert.Equal(t, info.String(), `SELECT p.age, p.name AS _pfx_p_sfx_name, a.city FROM people AS p JOIN addresses AS a ON a.name=p.name WHERE p.name='o''neil' AND p.age>21;
+
+COLUMN           FIELD         EXPRESSION
+age              Person.Age    p.age
+_pfx_p_sfx_name  Person.Name   p.name AS _pfx_p_sfx_name
+city             Address.City  a.city`)
+}
+
+func TestQuerierDebugStatementExecutes(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var person argsPerson
+	info, err := querier.Debug(`SELECT {argsPerson} FROM people WHERE name=:name AND age=:age;`, argsPerson{Name: "jane", Age: 23}, &person)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Statement, `SELECT age, name FROM people WHERE name='jane' AND age=23;`)
+
+	// The literal statement can be executed as it is.
+	err = db.QueryRow(info.Statement).Scan(&person.Age, &person.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, person, argsPerson{Name: "jane", Age: 23})
+}
+
+func TestQuerierDebugMissingArgument(t *testing.T) {
+	querier := NewQuerier()
+
+	var person argsPerson
+	_, err := querier.Debug(`SELECT {argsPerson} FROM people WHERE name=:name;`, M{}, &person)
+	assert.True(t, errors.As(err, new(*MissingArgumentError)))
+}
+
+func TestSQLLiteral(t *testing.T) {
+	when := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		dialect  Dialect
+		value    interface{}
+		expected string
+	}{
+		{SQLite, nil, "NULL"},
+		{SQLite, 42, "42"},
+		{SQLite, uint8(7), "7"},
+		{SQLite, 1.5, "1.5"},
+		{SQLite, true, "TRUE"},
+		{SQLite, "it's", `'it''s'`},
+		{SQLite, []byte("ab"), "X'6162'"},
+		{SQLite, sql.NullString{}, "NULL"},
+		{SQLite, sql.NullInt64{Int64: 3, Valid: true}, "3"},
+		{SQLite, when, "'2021-03-04 05:06:07+00:00'"},
+		{Postgres, []byte("ab"), `'\x6162'`},
+		{Postgres, `a\b`, `'a\b'`},
+		{MySQL, `a\'b`, `'a\\''b'`},
+	}
+	for _, test := range tests {
+		literal, err := sqlLiteral(test.dialect, test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, literal, test.expected)
+	}
+
+	_, err := sqlLiteral(SQLite, struct{}{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,102 @@
+package sqlair
+
+import (
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+)
+
+// DeriveOption configures what a querier created by Derive shares with its
+// parent.
+type DeriveOption func(*deriveOptions)
+
+type deriveOptions struct {
+	shareStatements bool
+	shareResults    bool
+	isolateReflect  bool
+	inheritHooks    bool
+	hooks           []Hook
+}
+
+// ShareStatementCache returns an option that shares the compiled statements of
+// the parent querier, rather than compiling every statement again.
+func ShareStatementCache() DeriveOption {
+	return func(o *deriveOptions) {
+		o.shareStatements = true
+	}
+}
+
+// ShareResultCache returns an option that shares the cached results of the
+// parent querier (see Query.Cached), so that invalidating the cache of either
+// querier invalidates both.
+func ShareResultCache() DeriveOption {
+	return func(o *deriveOptions) {
+		o.shareResults = true
+	}
+}
+
+// IsolateReflectCache returns an option that gives the derived querier its own
+// reflect cache, with the same tag key and name mapper as the parent.
+func IsolateReflectCache() DeriveOption {
+	return func(o *deriveOptions) {
+		o.isolateReflect = true
+	}
+}
+
+// InheritHooks returns an option that keeps the hooks of the parent querier,
+// along with its statement hook, slow query hook and query hook.
+func InheritHooks() DeriveOption {
+	return func(o *deriveOptions) {
+		o.inheritHooks = true
+	}
+}
+
+// AppendHook returns an option that adds the hook to the derived querier,
+// after any inherited hooks.
+func AppendHook(hook Hook) DeriveOption {
+	return func(o *deriveOptions) {
+		o.hooks = append(o.hooks, hook)
+	}
+}
+
+// Derive returns a new Querier in the same way as Copy, with the options
+// choosing which caches and hooks are shared with the parent. This allows a
+// querier to be derived for every request cheaply, sharing the caches of the
+// parent while logging the statements of the request:
+//
+//  derived := querier.Derive(
+//  	sqlair.ShareStatementCache(),
+//  	sqlair.ShareResultCache(),
+//  	sqlair.InheritHooks(),
+//  	sqlair.AppendHook(requestLogger),
+//  )
+//
+// Without any options Derive is the same as Copy. Changing the configuration
+// of either querier afterwards doesn't affect the other, other than through
+// the shared caches.
+func (q *Querier) Derive(options ...DeriveOption) *Querier {
+	var opts deriveOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	derived := q.Copy()
+	if opts.shareStatements {
+		derived.stmtCache = q.stmtCache
+		derived.buffers = q.buffers
+	}
+	if opts.shareResults {
+		derived.results = q.results
+	}
+	if opts.isolateReflect {
+		derived.reflect = sreflect.NewReflectCacheWithConfig(q.reflect.Config())
+	}
+	if opts.inheritHooks {
+		derived.hook = q.hook
+		derived.statementHook = q.statementHook
+		derived.slowQuery = q.slowQuery
+		derived.queryHook = q.queryHook
+	}
+	for _, hook := range opts.hooks {
+		derived.AddHook(hook)
+	}
+	return derived
+}
@@ -0,0 +1,75 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuerierDerive(t *testing.T) {
+	querier := NewQuerier()
+
+	derived := querier.Derive()
+	assert.True(t, derived.reflect == querier.reflect)
+	assert.False(t, derived.stmtCache == querier.stmtCache)
+	assert.False(t, derived.results == querier.results)
+
+	derived = querier.Derive(ShareStatementCache(), ShareResultCache(), IsolateReflectCache())
+	assert.False(t, derived.reflect == querier.reflect)
+	assert.Equal(t, derived.reflect.Config().TagKey, querier.reflect.Config().TagKey)
+	assert.True(t, derived.stmtCache == querier.stmtCache)
+	assert.True(t, derived.buffers == querier.buffers)
+	assert.True(t, derived.results == querier.results)
+}
+
+func TestQuerierDeriveHooks(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var called []string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		called = append(called, "parent")
+		return nil
+	})
+
+	inherited := querier.Derive(InheritHooks(), AppendHook(func(stmt string) error {
+		called = append(called, "derived")
+		return nil
+	}))
+	isolated := querier.Derive(AppendHook(func(stmt string) error {
+		called = append(called, "isolated")
+		return nil
+	}))
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		for _, q := range []*Querier{querier, inherited, isolated} {
+			if _, err := q.Exec(tx, `DELETE FROM people WHERE name="nobody";`); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Equal(t, called, []string{"parent", "parent", "derived", "isolated"})
+}
+
+func TestQuerierDeriveSharesStatements(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+	derived := querier.Derive(ShareStatementCache())
+
+	var person argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		if err != nil {
+			return err
+		}
+		return getter.Query(tx, `SELECT {argsPerson} FROM people WHERE name="fred";`)
+	})
+
+	cached, ok := derived.stmtCache.Get(`SELECT {argsPerson} FROM people WHERE name="fred";`)
+	assert.True(t, ok)
+	assert.Equal(t, cached.stmt, `SELECT age, name FROM people WHERE name="fred";`)
+}
@@ -0,0 +1,111 @@
+package sqlair
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// enumArgument returns the stored value of an enum field for use as a named
+// argument. Enum fields are tagged with their values, separated by "|". String
+// fields hold one of the values, and integer fields hold the index of one of
+// the values. Either way, the value is stored as the string, and values that
+// aren't in the enum return an EnumError when they're bound or scanned:
+//
+//  type Status int
+//
+//  const (
+//  	Active Status = iota
+//  	Archived
+//  	Deleted
+//  )
+//
+//  type Person struct {
+//  	Name   string `db:"name"`
+//  	Status Status `db:"status,enum=active|archived|deleted"`
+//  }
+//
+func enumArgument(value reflect.Value, values []string) (interface{}, error) {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		if indexOfEnum(values, value.String()) < 0 {
+			return nil, errors.WithStack(&EnumError{Value: value.String(), Values: values})
+		}
+		return value.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := value.Int(); i < 0 || i >= int64(len(values)) {
+			return nil, errors.WithStack(&EnumError{Value: i, Values: values})
+		}
+		return values[value.Int()], nil
+	default:
+		if i := value.Uint(); i >= uint64(len(values)) {
+			return nil, errors.WithStack(&EnumError{Value: i, Values: values})
+		}
+		return values[value.Uint()], nil
+	}
+}
+
+func indexOfEnum(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// enumScanner scans the stored value of an enum into a string or integer
+// field, or a pointer to one. Integer columns are accepted for integer fields,
+// as long as they're the index of a value.
+type enumScanner struct {
+	dest   reflect.Value
+	values []string
+}
+
+// Scan implements sql.Scanner.
+func (s enumScanner) Scan(src interface{}) error {
+	if src == nil {
+		s.dest.Set(reflect.Zero(s.dest.Type()))
+		return nil
+	}
+
+	index := -1
+	switch src := src.(type) {
+	case string:
+		index = indexOfEnum(s.values, src)
+	case []byte:
+		index = indexOfEnum(s.values, string(src))
+	case int64:
+		if src >= 0 && src < int64(len(s.values)) {
+			index = int(src)
+		}
+	}
+
+	dest := s.dest
+	if dest.Kind() == reflect.Ptr {
+		dest = reflect.New(dest.Type().Elem()).Elem()
+	}
+	if _, isInt := src.(int64); index < 0 || (isInt && dest.Kind() == reflect.String) {
+		return errors.WithStack(&EnumError{Value: src, Values: s.values})
+	}
+
+	switch dest.Kind() {
+	case reflect.String:
+		dest.SetString(s.values[index])
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dest.SetInt(int64(index))
+	default:
+		dest.SetUint(uint64(index))
+	}
+	if s.dest.Kind() == reflect.Ptr {
+		s.dest.Set(dest.Addr())
+	}
+	return nil
+}
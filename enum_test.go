@@ -0,0 +1,123 @@
+package sqlair
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type enumStatus int
+
+const (
+	enumActive enumStatus = iota
+	enumArchived
+	enumDeleted
+)
+
+type enumPerson struct {
+	Name   string     `db:"name"`
+	Status enumStatus `db:"status,enum=active|archived|deleted"`
+	Role   *string    `db:"role,enum=admin|user"`
+}
+
+func TestQueryWithEnums(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`CREATE TABLE people (name TEXT, status TEXT, role TEXT);`)
+	assert.Nil(t, err)
+
+	admin := "admin"
+	querier := NewQuerier()
+	runTx(t, db, func(tx *sql.Tx) error {
+		for _, person := range []enumPerson{{
+			Name:   "fred",
+			Status: enumArchived,
+			Role:   &admin,
+		}, {
+			Name:   "frank",
+			Status: enumDeleted,
+		}} {
+			if _, err := querier.Exec(tx, `INSERT INTO people (name, status, role) VALUES (:name, :status, :role);`, person); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// The enums are stored as their values.
+	var status string
+	err = db.QueryRow(`SELECT status FROM people WHERE name="fred";`).Scan(&status)
+	assert.Nil(t, err)
+	assert.Equal(t, status, "archived")
+
+	var persons []enumPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {enumPerson} FROM people ORDER BY name;`)
+	})
+	assert.Equal(t, persons, []enumPerson{{
+		Name:   "frank",
+		Status: enumDeleted,
+	}, {
+		Name:   "fred",
+		Status: enumArchived,
+		Role:   &admin,
+	}})
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	guest := "guest"
+	_, err = querier.Exec(tx, `INSERT INTO people (name, status, role) VALUES (:name, :status, :role);`, enumPerson{
+		Name:   "jane",
+		Status: enumActive,
+		Role:   &guest,
+	})
+	assert.True(t, errors.As(err, new(*EnumError)))
+	assert.Equal(t, err.Error(), "constructing named arguments: field \"role\": invalid enum value guest, expected one of admin|user\n\tstatement: INSERT INTO people (name, status, role) VALUES (:name, :status, :role);")
+
+	_, err = querier.Exec(tx, `INSERT INTO people (name, status) VALUES (:name, :status);`, enumPerson{
+		Name:   "jane",
+		Status: 5,
+	})
+	assert.Equal(t, err.Error(), "constructing named arguments: field \"status\": invalid enum value 5, expected one of active|archived|deleted\n\tstatement: INSERT INTO people (name, status) VALUES (:name, :status);")
+
+	_, err = tx.Exec(`UPDATE people SET status='unknown' WHERE name="fred";`)
+	assert.Nil(t, err)
+
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+	err = getter.Query(tx, `SELECT {enumPerson} FROM people WHERE name="fred";`)
+	assert.True(t, errors.As(err, new(*EnumError)))
+}
+
+func TestEnumScanner(t *testing.T) {
+	var status enumStatus
+	scanner := enumScanner{
+		dest:   reflect.ValueOf(&status).Elem(),
+		values: []string{"active", "archived", "deleted"},
+	}
+
+	err := scanner.Scan([]byte("deleted"))
+	assert.Nil(t, err)
+	assert.Equal(t, status, enumDeleted)
+
+	// Integer columns hold the index of the value.
+	err = scanner.Scan(int64(1))
+	assert.Nil(t, err)
+	assert.Equal(t, status, enumArchived)
+
+	err = scanner.Scan(int64(3))
+	assert.Equal(t, err.Error(), "invalid enum value 3, expected one of active|archived|deleted")
+
+	var name string
+	scanner.dest = reflect.ValueOf(&name).Elem()
+	err = scanner.Scan(int64(1))
+	assert.Equal(t, err.Error(), "invalid enum value 1, expected one of active|archived|deleted")
+}
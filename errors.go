@@ -0,0 +1,583 @@
+package sqlair
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SimonRichardson/sqlair/parser"
+	"github.com/pkg/errors"
+)
+
+// Position is the location of a token within a statement. Lines and columns
+// start at 1.
+type Position = parser.Position
+
+// positionOf returns the position of the offset within the statement.
+func positionOf(stmt string, offset int) Position {
+	return parser.PositionOf(stmt, offset)
+}
+
+// ErrorCode identifies the kind of an error returned by sqlair, so that
+// programs can handle errors, or report them, without matching their
+// messages. The code of an error is found with CodeOf.
+type ErrorCode string
+
+// The codes of the errors returned by sqlair. Each typed error has its own
+// code, which is named after its type (CodeMissingField for a
+// MissingFieldError).
+const (
+	CodeSyntax              ErrorCode = "syntax"
+	CodeUnknownEntity       ErrorCode = "unknown_entity"
+	CodeMissingField        ErrorCode = "missing_field"
+	CodeMissingDestination  ErrorCode = "missing_destination"
+	CodeMissingColumn       ErrorCode = "missing_column"
+	CodeMissingArgument     ErrorCode = "missing_argument"
+	CodeArgumentKey         ErrorCode = "argument_key"
+	CodeConflictingArgument ErrorCode = "conflicting_argument"
+	CodeUnusedArgument      ErrorCode = "unused_argument"
+	CodeEmptyArgument       ErrorCode = "empty_argument"
+	CodeConversion          ErrorCode = "conversion"
+	CodeEnum                ErrorCode = "enum"
+	CodeIdent               ErrorCode = "ident"
+	CodeOrderBy             ErrorCode = "order_by"
+	CodeRowCount            ErrorCode = "row_count"
+	CodeReadOnly            ErrorCode = "read_only"
+	CodeConcurrentQuery     ErrorCode = "concurrent_query"
+	CodeStaleObject         ErrorCode = "stale_object"
+	CodeSchemaDrift         ErrorCode = "schema_drift"
+)
+
+// CodeOf returns the code of the error, or of the first error with a code
+// that it wraps. Errors that weren't returned by sqlair have no code.
+//
+//  if sqlair.CodeOf(err) == sqlair.CodeMissingArgument {
+//  	...
+//  }
+//
+func CodeOf(err error) ErrorCode {
+	var coded interface {
+		Code() ErrorCode
+	}
+	switch {
+	case errors.As(err, &coded):
+		return coded.Code()
+	case errors.Is(err, ErrConcurrentQuery):
+		return CodeConcurrentQuery
+	}
+	return ""
+}
+
+// SyntaxError is returned when a statement contains a record expression or a
+// named argument that can't be parsed.
+type SyntaxError struct {
+	Message   string
+	Statement string
+	Position  Position
+}
+
+func syntaxError(stmt string, offset int, format string, args ...interface{}) error {
+	return &SyntaxError{
+		Message:   fmt.Sprintf(format, args...),
+		Statement: stmt,
+		Position:  positionOf(stmt, offset),
+	}
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
+// Code returns CodeSyntax.
+func (e *SyntaxError) Code() ErrorCode {
+	return CodeSyntax
+}
+
+// UnknownEntityError is returned when a record expression refers to a type
+// that wasn't passed to the query.
+type UnknownEntityError struct {
+	Name  string
+	Alias string
+}
+
+func (e *UnknownEntityError) Error() string {
+	if e.Alias != "" {
+		return fmt.Sprintf("no entity found with the name %q for alias %q", e.Name, e.Alias)
+	}
+	return fmt.Sprintf("no entity found with the name %q", e.Name)
+}
+
+// Code returns CodeUnknownEntity.
+func (e *UnknownEntityError) Code() ErrorCode {
+	return CodeUnknownEntity
+}
+
+// MissingFieldError is returned when a record expression refers to a field
+// that isn't found in the entity.
+type MissingFieldError struct {
+	Entity string
+	Field  string
+	// Excluded is true if the field was referenced by an exclusion.
+	Excluded bool
+}
+
+func (e *MissingFieldError) Error() string {
+	if e.Excluded {
+		return fmt.Sprintf("excluded field %q not found in entity %q", e.Field, e.Entity)
+	}
+	return fmt.Sprintf("field %q not found in entity %q", e.Field, e.Entity)
+}
+
+// Code returns CodeMissingField.
+func (e *MissingFieldError) Code() ErrorCode {
+	return CodeMissingField
+}
+
+// MissingDestinationError is returned when a result column has no field to
+// be scanned into.
+type MissingDestinationError struct {
+	Column string
+	Types  []string
+}
+
+func (e *MissingDestinationError) Error() string {
+	return fmt.Sprintf("missing destination name %q in types %v", e.Column, e.Types)
+}
+
+// Code returns CodeMissingDestination.
+func (e *MissingDestinationError) Code() ErrorCode {
+	return CodeMissingDestination
+}
+
+// MissingColumnError is returned by strict queries when a field of an entity
+// didn't receive a column.
+type MissingColumnError struct {
+	Entity string
+	Field  string
+}
+
+func (e *MissingColumnError) Error() string {
+	return fmt.Sprintf("missing column for field %q in entity %q", e.Field, e.Entity)
+}
+
+// Code returns CodeMissingColumn.
+func (e *MissingColumnError) Code() ErrorCode {
+	return CodeMissingColumn
+}
+
+// MissingArgumentError is returned when a named argument isn't found in the
+// arguments of a statement.
+type MissingArgumentError struct {
+	Name string
+	// Type is the type of the argument, which is empty for maps.
+	Type string
+}
+
+func (e *MissingArgumentError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("key %q missing from map", e.Name)
+	}
+	if isNumbered(e.Name) {
+		return fmt.Sprintf("index %s missing from type %s", e.Name, e.Type)
+	}
+	return fmt.Sprintf("field %q missing from type %s", e.Name, e.Type)
+}
+
+// Code returns CodeMissingArgument.
+func (e *MissingArgumentError) Code() ErrorCode {
+	return CodeMissingArgument
+}
+
+// ArgumentKeyError is returned when a map of named arguments has keys that
+// can't name an argument. Maps must have string or integer keys, where integer
+// keys provide the numbered arguments (?NNN).
+type ArgumentKeyError struct {
+	// Type is the type of the map.
+	Type string
+	// Key is the type of the keys of the map.
+	Key string
+}
+
+func (e *ArgumentKeyError) Error() string {
+	return fmt.Sprintf("unsupported key type %s of argument map %s, expected string or integer keys", e.Key, e.Type)
+}
+
+// Code returns CodeArgumentKey.
+func (e *ArgumentKeyError) Code() ErrorCode {
+	return CodeArgumentKey
+}
+
+// ConflictingArgumentError is returned when more than one argument provides a
+// named argument, and the values differ.
+type ConflictingArgumentError struct {
+	Name string
+}
+
+func (e *ConflictingArgumentError) Error() string {
+	return fmt.Sprintf("conflicting values for named argument %q", e.Name)
+}
+
+// Code returns CodeConflictingArgument.
+func (e *ConflictingArgumentError) Code() ErrorCode {
+	return CodeConflictingArgument
+}
+
+// UnusedArgumentError is reported by strict arguments when a key or field of
+// an argument doesn't match any named argument of the statement.
+type UnusedArgumentError struct {
+	Name string
+	// Type is the type of the argument, which is empty for maps and
+	// sql.NamedArg values.
+	Type string
+}
+
+func (e *UnusedArgumentError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("argument %q not used in statement", e.Name)
+	}
+	return fmt.Sprintf("argument %q of type %s not used in statement", e.Name, e.Type)
+}
+
+// Code returns CodeUnusedArgument.
+func (e *UnusedArgumentError) Code() ErrorCode {
+	return CodeUnusedArgument
+}
+
+// EmptyArgumentError is reported by strict arguments when a named argument is
+// bound from a zero valued field that's tagged with omitempty.
+type EmptyArgumentError struct {
+	Name string
+}
+
+func (e *EmptyArgumentError) Error() string {
+	return fmt.Sprintf("named argument %q bound from empty omitempty field", e.Name)
+}
+
+// Code returns CodeEmptyArgument.
+func (e *EmptyArgumentError) Code() ErrorCode {
+	return CodeEmptyArgument
+}
+
+// ConversionError is returned when the value of a column can't be converted
+// to the element type of a map destination.
+type ConversionError struct {
+	Column string
+	Value  interface{}
+	Type   string
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("unable to convert column %q value %v of type %T to %s", e.Column, e.Value, e.Value, e.Type)
+}
+
+// Code returns CodeConversion.
+func (e *ConversionError) Code() ErrorCode {
+	return CodeConversion
+}
+
+// EnumError is returned when the value of an enum field isn't one of the
+// values of its enum tag option, either when it's bound as a named argument or
+// when it's scanned.
+type EnumError struct {
+	Value  interface{}
+	Values []string
+}
+
+func (e *EnumError) Error() string {
+	return fmt.Sprintf("invalid enum value %v, expected one of %s", e.Value, strings.Join(e.Values, "|"))
+}
+
+// Code returns CodeEnum.
+func (e *EnumError) Code() ErrorCode {
+	return CodeEnum
+}
+
+// IdentError is returned when an Ident isn't a valid identifier, so it can't
+// be spliced into a statement.
+type IdentError struct {
+	Ident string
+}
+
+func (e *IdentError) Error() string {
+	return fmt.Sprintf("invalid identifier %q, expected letters, digits and underscores", e.Ident)
+}
+
+// Code returns CodeIdent.
+func (e *IdentError) Code() ErrorCode {
+	return CodeIdent
+}
+
+// OrderByError is returned when the column of an OrderBy isn't one of the
+// columns of the destinations of the query.
+type OrderByError struct {
+	Column  string
+	Columns []string
+}
+
+func (e *OrderByError) Error() string {
+	return fmt.Sprintf("unexpected order by column %q, expected one of %s", e.Column, strings.Join(e.Columns, ", "))
+}
+
+// Code returns CodeOrderBy.
+func (e *OrderByError) Code() ErrorCode {
+	return CodeOrderBy
+}
+
+// RowCountError is returned when a query scanned, or a statement affected, a
+// different number of rows than expected.
+type RowCountError struct {
+	Expected int64
+	Actual   int64
+}
+
+func (e *RowCountError) Error() string {
+	return fmt.Sprintf("expected %d rows, got %d", e.Expected, e.Actual)
+}
+
+// Code returns CodeRowCount.
+func (e *RowCountError) Code() ErrorCode {
+	return CodeRowCount
+}
+
+// ReadOnlyError is returned when a statement that modifies the database is
+// executed in a read-only transaction.
+type ReadOnlyError struct {
+	// Keyword is the leading keyword of the statement, such as UPDATE.
+	Keyword string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("%s statement not allowed in read-only transaction", e.Keyword)
+}
+
+// Code returns CodeReadOnly.
+func (e *ReadOnlyError) Code() ErrorCode {
+	return CodeReadOnly
+}
+
+// ErrConcurrentQuery is returned when a query is executed while it's already
+// executing, as the destinations of the query can't be populated by more than
+// one goroutine at a time.
+var ErrConcurrentQuery = errors.New("query is already executing, create a query for each goroutine")
+
+// ErrStaleObject matches a StaleObjectError with errors.Is.
+var ErrStaleObject = errors.New("stale object")
+
+// StaleObjectError is returned when an optimistically locked UPDATE doesn't
+// update any rows, because the version of the row has changed since the
+// struct was read.
+type StaleObjectError struct {
+	Entity  string
+	Version interface{}
+}
+
+func (e *StaleObjectError) Error() string {
+	return fmt.Sprintf("stale %s: version %v has been modified", e.Entity, e.Version)
+}
+
+// Code returns CodeStaleObject.
+func (e *StaleObjectError) Code() ErrorCode {
+	return CodeStaleObject
+}
+
+// Is returns true if the target is ErrStaleObject.
+func (e *StaleObjectError) Is(target error) bool {
+	return target == ErrStaleObject
+}
+
+// SchemaDriftError is returned by Querier.VerifySchema when the columns of a
+// table don't match the fields of a struct.
+type SchemaDriftError struct {
+	Table string
+	// MissingColumns are the fields that don't have a column.
+	MissingColumns []string
+	// UnknownColumns are the columns that don't have a field.
+	UnknownColumns []string
+	// Mismatches are the columns with a type that can't hold the field.
+	Mismatches []TypeMismatch
+}
+
+// TypeMismatch is a column with a type that can't hold the type of its field.
+type TypeMismatch struct {
+	Column     string
+	FieldType  string
+	ColumnType string
+}
+
+func (e *SchemaDriftError) Error() string {
+	var problems []string
+	if len(e.MissingColumns) > 0 {
+		problems = append(problems, fmt.Sprintf("fields without columns: %s", strings.Join(e.MissingColumns, ", ")))
+	}
+	if len(e.UnknownColumns) > 0 {
+		problems = append(problems, fmt.Sprintf("columns without fields: %s", strings.Join(e.UnknownColumns, ", ")))
+	}
+	if len(e.Mismatches) > 0 {
+		mismatches := make([]string, len(e.Mismatches))
+		for i, mismatch := range e.Mismatches {
+			mismatches[i] = fmt.Sprintf("%s (%s as %s)", mismatch.Column, mismatch.FieldType, mismatch.ColumnType)
+		}
+		problems = append(problems, fmt.Sprintf("type mismatches: %s", strings.Join(mismatches, ", ")))
+	}
+	return fmt.Sprintf("table %q drifted from struct: %s", e.Table, strings.Join(problems, "; "))
+}
+
+// Code returns CodeSchemaDrift.
+func (e *SchemaDriftError) Code() ErrorCode {
+	return CodeSchemaDrift
+}
+
+// StatementError is returned when a statement fails to compile or scan. The
+// error message includes the statement, the compiled statement and a caret
+// pointing at the offending record expression or named argument.
+type StatementError struct {
+	Err       error
+	Statement string
+	// Compiled is the statement after the record expressions have been
+	// expanded, which is empty if the statement failed to compile.
+	Compiled string
+	// Position is the location of the offending record expression or named
+	// argument, if it's known.
+	Position *Position
+}
+
+func statementError(err error, stmt, compiled string) error {
+	return rewrittenStatementError(err, stmt, stmt, compiled)
+}
+
+// rewrittenStatementError is a statementError for an error found in the
+// rewritten form of the statement, such as the statement with its where
+// clauses expanded. The position of the error is mapped back to the
+// statement, so that the caret points at the text that was written.
+func rewrittenStatementError(err error, stmt, rewritten, compiled string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*StatementError); ok {
+		return err
+	}
+
+	stmtErr := &StatementError{
+		Err:       err,
+		Statement: stmt,
+		Compiled:  compiled,
+	}
+
+	var (
+		syntaxErr *SyntaxError
+		offsetErr *offsetError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		position := positionOf(stmt, originalOffset(stmt, syntaxErr.Statement, syntaxErr.Position.Offset))
+		stmtErr.Position = &position
+	case errors.As(err, &offsetErr):
+		position := positionOf(stmt, originalOffset(stmt, rewritten, offsetErr.offset))
+		stmtErr.Position = &position
+	}
+	return stmtErr
+}
+
+// originalOffset maps the offset within the rewritten statement back to the
+// statement. Offsets before the rewritten text keep their place, offsets
+// after it are moved by the change in length, and offsets within it are
+// moved to its start.
+func originalOffset(stmt, rewritten string, offset int) int {
+	if stmt == rewritten {
+		return offset
+	}
+	var prefix, suffix int
+	for prefix < len(stmt) && prefix < len(rewritten) && stmt[prefix] == rewritten[prefix] {
+		prefix++
+	}
+	for suffix < len(stmt)-prefix && suffix < len(rewritten)-prefix && stmt[len(stmt)-1-suffix] == rewritten[len(rewritten)-1-suffix] {
+		suffix++
+	}
+	switch {
+	case offset < prefix:
+		return offset
+	case offset >= len(rewritten)-suffix:
+		return offset - len(rewritten) + len(stmt)
+	}
+	return prefix
+}
+
+func (e *StatementError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	if e.Position != nil {
+		fmt.Fprintf(&b, " at %s\n", e.Position)
+		b.WriteString(excerpt(e.Statement, *e.Position))
+	} else {
+		b.WriteString("\n\tstatement: " + e.Statement)
+	}
+	if e.Compiled != "" && e.Compiled != e.Statement {
+		b.WriteString("\n\tcompiled: " + e.Compiled)
+	}
+	return b.String()
+}
+
+// excerpt returns the line of the statement at the position, followed by a
+// caret that points at the column of the position:
+//
+//  	WHERE name=:name
+//  	           ^
+//
+// Columns are counted in bytes, so the caret is lined up with the runes
+// before it, keeping any tabs so that it lines up however they're shown.
+func excerpt(stmt string, pos Position) string {
+	lines := strings.Split(stmt, "\n")
+	index := pos.Line - 1
+	if index >= len(lines) {
+		index = len(lines) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	line := strings.TrimSuffix(lines[index], "\r")
+
+	column := pos.Column - 1
+	if column > len(line) {
+		column = len(line)
+	}
+	if column < 0 {
+		column = 0
+	}
+	var caret strings.Builder
+	for _, r := range line[:column] {
+		if r == '\t' {
+			caret.WriteRune('\t')
+			continue
+		}
+		caret.WriteRune(' ')
+	}
+	return "\t" + line + "\n\t" + caret.String() + "^"
+}
+
+// Unwrap returns the underlying error.
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error.
+func (e *StatementError) Cause() error {
+	return e.Err
+}
+
+// offsetError records the offset of the offending record expression or named
+// argument within the statement.
+type offsetError struct {
+	err    error
+	offset int
+}
+
+func (e *offsetError) Error() string {
+	return e.err.Error()
+}
+
+func (e *offsetError) Unwrap() error {
+	return e.err
+}
+
+func (e *offsetError) Cause() error {
+	return e.err
+}
@@ -0,0 +1,217 @@
+package sqlair
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPositionOf(t *testing.T) {
+	stmt := "SELECT {Person}\nFROM people\nWHERE name=:name;"
+	assert.Equal(t, positionOf(stmt, 0), Position{Offset: 0, Line: 1, Column: 1})
+	assert.Equal(t, positionOf(stmt, 7), Position{Offset: 7, Line: 1, Column: 8})
+	assert.Equal(t, positionOf(stmt, 16), Position{Offset: 16, Line: 2, Column: 1})
+	assert.Equal(t, positionOf(stmt, 39), Position{Offset: 39, Line: 3, Column: 12})
+	assert.Equal(t, positionOf(stmt, 39).String(), "3:12")
+}
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	stmt := "SELECT name,\n  {test Person}\nFROM test;"
+	_, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+
+	var syntaxErr *SyntaxError
+	assert.True(t, errors.As(err, &syntaxErr))
+	assert.Equal(t, syntaxErr.Position, Position{Offset: 15, Line: 2, Column: 3})
+	assert.Equal(t, syntaxErr.Statement, stmt)
+	assert.Equal(t, err.Error(), `unexpected record expression "test Person"`)
+	assert.True(t, errors.As(err, new(*SyntaxError)))
+	assert.False(t, errors.As(err, new(*MissingFieldError)))
+}
+
+func TestQueryErrorTypes(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO people(name, age) values ("fred", 21);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+
+	var person Person
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {people.email INTO Person} FROM people;`)
+	var fieldErr *MissingFieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, fieldErr, &MissingFieldError{Entity: "Person", Field: "email"})
+	assert.Equal(t, CodeOf(err), CodeMissingField)
+
+	err = getter.Query(tx, `SELECT {people.* INTO Location} FROM people;`)
+	var entityErr *UnknownEntityError
+	assert.True(t, errors.As(err, &entityErr))
+	assert.Equal(t, entityErr.Name, "Location")
+
+	err = getter.Query(tx, `SELECT name, age FROM people;`)
+	assert.True(t, errors.As(err, new(*MissingDestinationError)))
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=:age WHERE name=:name;`, Person{Name: "fred"})
+	var argErr *MissingArgumentError
+	assert.True(t, errors.As(err, &argErr))
+	assert.Equal(t, argErr, &MissingArgumentError{Name: "age", Type: "sqlair.Person"})
+	assert.Equal(t, CodeOf(err), CodeMissingArgument)
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=:age;`, map[string]interface{}{})
+	assert.Equal(t, errors.Cause(err).Error(), `key "age" missing from map`)
+	assert.True(t, errors.As(err, new(*MissingArgumentError)))
+
+	type Account struct {
+		Name  string `db:"name"`
+		Email string `db:"email"`
+	}
+	var account Account
+	getter, err = querier.ForOne(&account, Strict())
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT name FROM people;`)
+	assert.True(t, errors.As(err, new(*MissingColumnError)))
+}
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected ErrorCode
+	}{
+		{err: &SyntaxError{}, expected: CodeSyntax},
+		{err: statementError(&UnknownEntityError{Name: "Person"}, "SELECT {Person};", ""), expected: CodeUnknownEntity},
+		{err: errors.Wrap(&ReadOnlyError{Keyword: "UPDATE"}, "exec"), expected: CodeReadOnly},
+		{err: errors.WithStack(&StaleObjectError{Entity: "Person"}), expected: CodeStaleObject},
+		{err: errors.Wrap(ErrConcurrentQuery, "query"), expected: CodeConcurrentQuery},
+		{err: errors.New("boom"), expected: ""},
+		{err: nil, expected: ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, CodeOf(test.err), test.expected, "%v", test.err)
+	}
+}
+
+func TestStatementErrorMessage(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO people(name, age) values ("fred", 21);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+
+	var person Person
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, "SELECT people.age,\n\t{people.email INTO Person}\nFROM people;")
+	assert.Equal(t, err.Error(), `field "email" not found in entity "Person" at 2:2
+	`+"\t{people.email INTO Person}"+`
+	`+"\t^")
+
+	err = getter.Query(tx, `SELECT {people.* INTO Person}, age FROM people;`)
+	assert.Equal(t, err.Error(), `missing destination name "age" in types [Person]
+	statement: SELECT {people.* INTO Person}, age FROM people;
+	compiled: SELECT people.name, age FROM people;`)
+
+	_, err = querier.Exec(tx, `UPDATE people SET age=:age WHERE name=:name;`, map[string]interface{}{
+		"name": "fred",
+	})
+	assert.Equal(t, err.Error(), `constructing named arguments: key "age" missing from map at 1:23
+	UPDATE people SET age=:age WHERE name=:name;
+	                      ^`)
+}
+
+func TestStatementErrorExcerpt(t *testing.T) {
+	err := &offsetError{err: &UnknownEntityError{Name: "Nope"}, offset: 13}
+
+	// Columns are counted in bytes, but the caret lines up with the runes.
+	stmtErr := statementError(err, "SELECT 'é', {Nope}\nFROM people;", "")
+	assert.Equal(t, stmtErr.Error(), `no entity found with the name "Nope" at 1:14
+	SELECT 'é', {Nope}
+	            ^`)
+
+	stmtErr = statementError(err, "SELECT *,\r\n\t\t{Nope}\r\nFROM people;", "")
+	assert.Equal(t, stmtErr.Error(), `no entity found with the name "Nope" at 2:3
+	`+"\t\t{Nope}"+`
+	`+"\t\t^")
+}
+
+func TestExcerptOutOfRange(t *testing.T) {
+	assert.Equal(t, excerpt("SELECT 1;\nSELECT 2;", Position{Offset: 40, Line: 5, Column: 30}), "\tSELECT 2;\n\t         ^")
+	assert.Equal(t, excerpt("", Position{}), "\t\n\t^")
+}
+
+func TestOriginalOffset(t *testing.T) {
+	stmt := "SELECT * FROM people {where Filters}\nAND name=:name;"
+	rewritten := "SELECT * FROM people WHERE age=:_where_age\nAND name=:name;"
+
+	assert.Equal(t, originalOffset(stmt, rewritten, 7), 7)
+	assert.Equal(t, originalOffset(stmt, rewritten, strings.Index(rewritten, ":name")), strings.Index(stmt, ":name"))
+	// Offsets within the rewritten text point at its start.
+	assert.Equal(t, originalOffset(stmt, rewritten, strings.Index(rewritten, ":_where_age")), strings.Index(stmt, "{"))
+	assert.Equal(t, originalOffset(stmt, stmt, 12), 12)
+}
+
+func TestStatementErrorAfterWhereClause(t *testing.T) {
+	db := setupSchemaDB(t, whereSchema)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	var persons []wherePerson
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	age := 21
+	err = getter.Query(tx, "SELECT {wherePerson}\nFROM people {where whereFilters}\n  AND name=:name;", whereFilters{Age: &age}, map[string]interface{}{
+		"city": "london",
+	})
+	assert.Equal(t, err.Error(), `constructing named arguments: key "name" missing from map at 3:12
+	  AND name=:name;
+	           ^`)
+}
+
+func TestIndexOfName(t *testing.T) {
+	names, err := parseNames("SELECT * FROM people WHERE name=:name AND names=:names;", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, indexOfName(names, "name"), 32)
+	assert.Equal(t, indexOfName(names, "names"), 48)
+	assert.Equal(t, indexOfName(names, "age"), -1)
+}
@@ -2,15 +2,28 @@ package sqlair_test
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/SimonRichardson/sqlair"
 )
 
 func ExampleQuerier_Hook() {
 	querier := sqlair.NewQuerier()
-	querier.Hook(func(s string) {
+	querier.Hook(func(s string) error {
 		fmt.Println(s)
+		return nil
+	})
+}
+
+func ExampleQuerier_Hook_veto() {
+	querier := sqlair.NewQuerier()
+	querier.Hook(func(s string) error {
+		if !strings.Contains(strings.ToUpper(s), "LIMIT") {
+			return errors.New("statement requires a LIMIT")
+		}
+		return nil
 	})
 }
 
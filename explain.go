@@ -0,0 +1,232 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Plan is the query plan of a statement, as reported by the database.
+type Plan struct {
+	// Statement is the compiled statement that was explained.
+	Statement string
+	// Nodes are the top level steps of the plan.
+	Nodes []PlanNode
+}
+
+// PlanNode is a step of a query plan.
+type PlanNode struct {
+	// Detail describes the step, which is the detail of the step for SQLite
+	// (SCAN people) and the node type for Postgres (Seq Scan).
+	Detail string
+	// Table is the table that the step reads, if it reads one.
+	Table string
+	// FullScan is true if the step reads every row of the table, rather than
+	// searching an index.
+	FullScan bool
+	// Children are the steps that the step is made of.
+	Children []PlanNode
+}
+
+// FullScans returns the steps of the plan that read every row of a table, so
+// that tests can assert that a statement uses an index:
+//
+//  plan, err := getter.Explain(tx, `SELECT {Person} FROM people WHERE name=:name;`, person)
+//  ...
+//  if scans := plan.FullScans(); len(scans) > 0 {
+//  	t.Errorf("full scan of %s", scans[0].Table)
+//  }
+//
+func (p Plan) FullScans() []PlanNode {
+	var scans []PlanNode
+	var walk func([]PlanNode)
+	walk = func(nodes []PlanNode) {
+		for _, node := range nodes {
+			if node.FullScan {
+				scans = append(scans, node)
+			}
+			walk(node.Children)
+		}
+	}
+	walk(p.Nodes)
+	return scans
+}
+
+// errExplained is returned by a query that captures its compiled statement,
+// rather than executing it.
+var errExplained = errors.New("explained")
+
+// explainedStatement is the compiled statement of an explained query, along
+// with its arguments and the records that it was compiled from.
+type explainedStatement struct {
+	stmt    string
+	args    []interface{}
+	records []recordBinding
+}
+
+// Explain compiles the statement in the same way as Query, but rather than
+// executing it, returns the query plan of the compiled statement. SQLite
+// statements are explained with EXPLAIN QUERY PLAN and Postgres statements
+// with EXPLAIN (FORMAT JSON), depending on the dialect of the querier (see
+// Querier.Dialect). The plan is read in the same way as a query, so the hooks
+// of the querier are called with the EXPLAIN statement and can veto it. The
+// destinations of the query are left alone, even if they're replaced by every
+// query (see Replace), and the rows of the plan aren't counted as rows of the
+// query.
+func (q Query) Explain(tx *sql.Tx, stmt string, args ...interface{}) (Plan, error) {
+	return q.ExplainContext(context.Background(), tx, stmt, args...)
+}
+
+// ExplainContext returns the query plan of the statement, using the context
+// for cancellation. See Explain.
+func (q Query) ExplainContext(ctx context.Context, tx *sql.Tx, stmt string, args ...interface{}) (Plan, error) {
+	var explained explainedStatement
+	q.explained = &explained
+	// The statement is always compiled, rather than populating the
+	// destinations from the cache.
+	q.cacheTTL = 0
+
+	if err := q.QueryContext(ctx, tx, stmt, args...); !errors.Is(err, errExplained) {
+		if err == nil {
+			return Plan{}, errors.Errorf("expected statement to explain")
+		}
+		return Plan{}, err
+	}
+
+	var (
+		prefix  string
+		explain func(*queryRows) ([]PlanNode, error)
+	)
+	switch q.mapper.dialect {
+	case "", SQLite:
+		prefix, explain = "EXPLAIN QUERY PLAN ", explainSQLite
+	case Postgres:
+		prefix, explain = "EXPLAIN (FORMAT JSON) ", explainPostgres
+	default:
+		return Plan{}, errors.Errorf("explain not supported for dialect %q", q.mapper.dialect)
+	}
+
+	// The plan is read through the hooks, rather than from the transaction
+	// directly, so that a vetoed statement is never explained either.
+	// The rows of the plan aren't rows of the query, so they aren't counted.
+	q.explained, q.scanned = nil, nil
+	rows, _, err := q.query(ctx, tx, prefix+explained.stmt, explained.args, explained.records)
+	if err != nil {
+		return Plan{}, errors.Wrap(err, "explain")
+	}
+	nodes, err := explain(rows)
+	if closeErr := rows.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Plan{}, errors.Wrap(err, "explain")
+	}
+	return Plan{
+		Statement: explained.stmt,
+		Nodes:     nodes,
+	}, nil
+}
+
+// explainSQLite builds the plan from the rows of EXPLAIN QUERY PLAN, which
+// reference their parent step by id.
+func explainSQLite(rows *queryRows) ([]PlanNode, error) {
+	type step struct {
+		id, parent int
+		node       PlanNode
+	}
+	var steps []step
+	for rows.Next() {
+		var (
+			s      step
+			unused int
+		)
+		if err := rows.Scan(&s.id, &s.parent, &unused, &s.node.Detail); err != nil {
+			return nil, err
+		}
+		s.node.Table, s.node.FullScan = sqliteStepTable(s.node.Detail)
+		steps = append(steps, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var build func(parent int) []PlanNode
+	build = func(parent int) []PlanNode {
+		var nodes []PlanNode
+		for _, s := range steps {
+			if s.parent == parent {
+				node := s.node
+				node.Children = build(s.id)
+				nodes = append(nodes, node)
+			}
+		}
+		return nodes
+	}
+	return build(0), nil
+}
+
+// sqliteStepTable returns the table of a SCAN or SEARCH step, and whether the
+// step scans the table without an index.
+func sqliteStepTable(detail string) (string, bool) {
+	words := strings.Fields(detail)
+	if len(words) < 2 || (words[0] != "SCAN" && words[0] != "SEARCH") {
+		return "", false
+	}
+	table := words[1]
+	// Older versions of SQLite write SCAN TABLE people.
+	if table == "TABLE" && len(words) > 2 {
+		table = words[2]
+	}
+	return table, words[0] == "SCAN" && !strings.Contains(detail, " INDEX ")
+}
+
+// explainPostgres builds the plan from the JSON of EXPLAIN (FORMAT JSON).
+func explainPostgres(rows *queryRows) ([]PlanNode, error) {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+	var raw []byte
+	if err := rows.Scan(&raw); err != nil {
+		return nil, err
+	}
+	return parsePostgresPlan(raw)
+}
+
+type postgresNode struct {
+	NodeType     string         `json:"Node Type"`
+	RelationName string         `json:"Relation Name"`
+	Plans        []postgresNode `json:"Plans"`
+}
+
+func parsePostgresPlan(raw []byte) ([]PlanNode, error) {
+	var plans []struct {
+		Plan postgresNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil {
+		return nil, errors.Wrap(err, "decoding plan")
+	}
+
+	var convert func(postgresNode) PlanNode
+	convert = func(n postgresNode) PlanNode {
+		node := PlanNode{
+			Detail:   n.NodeType,
+			Table:    n.RelationName,
+			FullScan: n.NodeType == "Seq Scan",
+		}
+		for _, child := range n.Plans {
+			node.Children = append(node.Children, convert(child))
+		}
+		return node
+	}
+	nodes := make([]PlanNode, len(plans))
+	for i, plan := range plans {
+		nodes[i] = convert(plan.Plan)
+	}
+	return nodes, nil
+}
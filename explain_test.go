@@ -0,0 +1,167 @@
+package sqlair
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryExplain(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	_, err := db.Exec(`CREATE INDEX people_name ON people(name);`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	var scan, search Plan
+	runTx(t, db, func(tx *sql.Tx) error {
+		if scan, err = getter.Explain(tx, `SELECT {argsPerson} FROM people WHERE age>:age;`, sql.Named("age", 21)); err != nil {
+			return err
+		}
+		search, err = getter.Explain(tx, `SELECT {argsPerson} FROM people WHERE name=:name;`, sql.Named("name", "fred"))
+		return err
+	})
+
+	assert.Equal(t, scan.Statement, "SELECT age, name FROM people WHERE age>:age;")
+	assert.Equal(t, scan.FullScans(), []PlanNode{{
+		Detail:   "SCAN people",
+		Table:    "people",
+		FullScan: true,
+	}})
+
+	assert.Equal(t, search.Statement, "SELECT age, name FROM people WHERE name=:name;")
+	assert.Len(t, search.Nodes, 1)
+	assert.Equal(t, search.Nodes[0].Table, "people")
+	assert.Len(t, search.FullScans(), 0)
+
+	// The destinations aren't populated.
+	assert.Len(t, persons, 0)
+}
+
+func TestQueryExplainWithReplace(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	persons := []argsPerson{{Name: "keep"}}
+	getter, err := querier.ForMany(&persons, Replace())
+	assert.Nil(t, err)
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := getter.Explain(tx, `SELECT {argsPerson} FROM people;`)
+		return err
+	})
+
+	// The destinations aren't truncated.
+	assert.Equal(t, persons, []argsPerson{{Name: "keep"}})
+}
+
+func TestQueryExplainWithHook(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	errDenied := errors.New("denied")
+
+	var (
+		statements []string
+		observed   []StatementInfo
+	)
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		statements = append(statements, stmt)
+		if strings.Contains(stmt, "WHERE name") {
+			return errDenied
+		}
+		return nil
+	})
+	querier.OnStatement(func(info StatementInfo) error {
+		observed = append(observed, info)
+		return nil
+	})
+
+	var persons []argsPerson
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		plan, err := getter.Explain(tx, `SELECT {argsPerson} FROM people WHERE age>:age;`, sql.Named("age", 21))
+		assert.Nil(t, err)
+		assert.Len(t, plan.Nodes, 1)
+
+		_, err = getter.Explain(tx, `SELECT {argsPerson} FROM people WHERE name=:name;`, sql.Named("name", "fred"))
+		assert.True(t, errors.Is(err, errDenied))
+		return nil
+	})
+
+	assert.Equal(t, statements, []string{
+		"EXPLAIN QUERY PLAN SELECT age, name FROM people WHERE age>:age;",
+		"EXPLAIN QUERY PLAN SELECT age, name FROM people WHERE name=:name;",
+	})
+	assert.Len(t, observed, 1)
+	assert.Equal(t, observed[0].Records[0].Entity, "argsPerson")
+}
+
+func TestQueryExplainUnsupportedDialect(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+	querier.Dialect(MySQL)
+
+	var person argsPerson
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = getter.Explain(tx, `SELECT {argsPerson} FROM people;`)
+	assert.Equal(t, err.Error(), `explain not supported for dialect "mysql"`)
+}
+
+func TestSQLiteStepTable(t *testing.T) {
+	for detail, expected := range map[string]struct {
+		table    string
+		fullScan bool
+	}{
+		"SCAN people":       {table: "people", fullScan: true},
+		"SCAN TABLE people": {table: "people", fullScan: true},
+		"SCAN people USING COVERING INDEX people_name":   {table: "people"},
+		"SEARCH people USING INDEX people_name (name=?)": {table: "people"},
+		"USE TEMP B-TREE FOR ORDER BY":                   {},
+	} {
+		table, fullScan := sqliteStepTable(detail)
+		assert.Equal(t, table, expected.table, detail)
+		assert.Equal(t, fullScan, expected.fullScan, detail)
+	}
+}
+
+func TestParsePostgresPlan(t *testing.T) {
+	nodes, err := parsePostgresPlan([]byte(`[{"Plan": {
+		"Node Type": "Nested Loop",
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "people"},
+			{"Node Type": "Index Scan", "Relation Name": "pets", "Index Name": "pets_owner"}
+		]
+	}}]`))
+	assert.Nil(t, err)
+
+	plan := Plan{Nodes: nodes}
+	assert.Equal(t, plan.Nodes, []PlanNode{{
+		Detail: "Nested Loop",
+		Children: []PlanNode{
+			{Detail: "Seq Scan", Table: "people", FullScan: true},
+			{Detail: "Index Scan", Table: "pets"},
+		},
+	}})
+	assert.Equal(t, plan.FullScans(), []PlanNode{
+		{Detail: "Seq Scan", Table: "people", FullScan: true},
+	})
+}
@@ -0,0 +1,141 @@
+package sqlair
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+)
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// fieldMapper maps the values of fields to named arguments and scan
+// destinations.
+type fieldMapper struct {
+	timeFormat TimeFormat
+	converters map[reflect.Type]converter
+	// dialect decides how identifiers are quoted.
+	dialect Dialect
+	// types are the concrete types that interface fields are scanned into,
+	// keyed by the name of their scan tag option.
+	types map[string]reflect.Type
+}
+
+// argument returns the value of a field for use as a named argument.
+func (m fieldMapper) argument(field sreflect.ReflectField) (interface{}, error) {
+	value := field.Value
+	if field.Tag.JSON {
+		return marshalJSON(value)
+	}
+	if c, ok := m.converter(value.Type()); ok {
+		return c.convert(value)
+	}
+	// Interface fields are bound by their dynamic value.
+	if value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, nil
+		}
+		return m.value(value.Elem().Interface())
+	}
+	if len(field.Tag.Enum) > 0 {
+		return enumArgument(value, field.Tag.Enum)
+	}
+	if isUUIDField(field, value.Type()) {
+		return uuidArgument(value, field.Tag.UUID), nil
+	}
+
+	// If the driver.Valuer is implemented with a pointer receiver, then we
+	// need to pass a pointer, otherwise the database/sql package will treat
+	// the value as a plain value.
+	if field.Valuer && !value.Type().Implements(valuerType) {
+		if value.CanAddr() {
+			return value.Addr().Interface(), nil
+		}
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		return ptr.Interface(), nil
+	}
+	return m.timeFormat.format(value.Interface()), nil
+}
+
+// value returns the value to be used as a named argument for a given value.
+func (m fieldMapper) value(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if c, ok := m.converter(reflect.TypeOf(v)); ok {
+		return c.convert(reflect.ValueOf(v))
+	}
+	if t := reflect.TypeOf(v); sreflect.IsUUID(t) && !sreflect.IsValuer(t) {
+		return uuidArgument(reflect.ValueOf(v), ""), nil
+	}
+	return m.timeFormat.format(v), nil
+}
+
+// plain returns true if the scan destination of a field of the type is the
+// address of the field.
+func (m fieldMapper) plain(field sreflect.ReflectField, t reflect.Type) bool {
+	if field.Tag.JSON {
+		return false
+	}
+	if _, ok := m.converter(t); ok {
+		return false
+	}
+	if len(field.Tag.Enum) > 0 || isRawBytes(t) || isUUIDField(field, t) || m.isInterfaceField(field, t) {
+		return false
+	}
+	return field.Scanner || (!isTimeType(t) && !isBoolType(t))
+}
+
+// destination returns the scan destination for a field. The address of the
+// field is used, which satisfies sql.Scanner if the field implements it. Byte
+// slices are copied once by the database/sql package, so BLOB columns are
+// scanned into []byte fields without any intermediate copies.
+func (m fieldMapper) destination(field sreflect.ReflectField) interface{} {
+	if m.isInterfaceField(field, field.Value.Type()) {
+		return interfaceScanner{
+			dest:     field.Value,
+			field:    field,
+			concrete: m.types[field.Tag.Scan],
+		}
+	}
+	if field.Tag.JSON {
+		return jsonScanner{
+			dest: field.Value,
+		}
+	}
+	if c, ok := m.converter(field.Value.Type()); ok {
+		return converterScanner{
+			dest:      field.Value,
+			converter: c,
+		}
+	}
+	if len(field.Tag.Enum) > 0 {
+		return enumScanner{
+			dest:   field.Value,
+			values: field.Tag.Enum,
+		}
+	}
+	if isUUIDField(field, field.Value.Type()) {
+		return uuidScanner{
+			dest: field.Value,
+		}
+	}
+	if isRawBytes(field.Value.Type()) {
+		return rawBytesScanner{
+			dest: field.Value,
+		}
+	}
+	if !field.Scanner && isBoolType(field.Value.Type()) {
+		return boolScanner{
+			dest: field.Value,
+		}
+	}
+	if !field.Scanner && isTimeType(field.Value.Type()) {
+		return timeScanner{
+			dest:   field.Value,
+			format: m.timeFormat,
+		}
+	}
+	return field.Value.Addr().Interface()
+}
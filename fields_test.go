@@ -0,0 +1,88 @@
+package sqlair
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// status stores the name of the status in the database, but is an integer in
+// Go. The Valuer uses a pointer receiver on purpose.
+type status int
+
+const (
+	statusActive status = iota
+	statusArchived
+)
+
+var statusNames = []string{"active", "archived"}
+
+func (s *status) Scan(src interface{}) error {
+	name, ok := src.(string)
+	if !ok {
+		return errors.Errorf("unexpected status type %T", src)
+	}
+	for i, n := range statusNames {
+		if n == name {
+			*s = status(i)
+			return nil
+		}
+	}
+	return errors.Errorf("unexpected status %q", name)
+}
+
+func (s *status) Value() (driver.Value, error) {
+	return statusNames[*s], nil
+}
+
+func TestQueryWithScannerAndValuer(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name   TEXT,
+	status TEXT,
+	alias  TEXT
+);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name   string         `db:"name"`
+		Status status         `db:"status"`
+		Alias  sql.NullString `db:"alias"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		for _, person := range []Person{
+			{Name: "fred", Status: statusArchived, Alias: sql.NullString{String: "freddy", Valid: true}},
+			{Name: "frank", Status: statusActive},
+		} {
+			if _, err := querier.Exec(tx, "INSERT INTO test(name, status, alias) VALUES (:name, :status, :alias);", person); err != nil {
+				return err
+			}
+		}
+
+		var raw string
+		if err := tx.QueryRow("SELECT status FROM test WHERE name='fred';").Scan(&raw); err != nil {
+			return err
+		}
+		assert.Equal(t, raw, "archived")
+
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person} FROM test;`)
+	})
+
+	assert.Equal(t, persons, []Person{
+		{Name: "fred", Status: statusArchived, Alias: sql.NullString{String: "freddy", Valid: true}},
+		{Name: "frank", Status: statusActive},
+	})
+}
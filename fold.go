@@ -0,0 +1,251 @@
+package sqlair
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// relationField returns the one-to-many relation of the struct with the Go
+// field name, or nil if the field isn't a relation.
+func (q Query) relationField(value reflect.Value, name string) (*sreflect.ReflectField, error) {
+	info, err := q.reflect.Reflect(reflect.New(value.Type()).Interface())
+	if err != nil {
+		return nil, err
+	}
+	relation, ok := info.(sreflect.ReflectStruct).Relations[name]
+	if !ok || relation.Tag.HasMany == "" {
+		return nil, nil
+	}
+	return &relation, nil
+}
+
+// relationElem returns the struct type of the elements of a relation slice.
+func relationElem(t reflect.Type) reflect.Type {
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		return elem.Elem()
+	}
+	return elem
+}
+
+// foldedChild returns the child type of the one-to-many relation at the end
+// of the path through the struct type, or nil if the path doesn't end with a
+// relation.
+func (q Query) foldedChild(t reflect.Type, path []string) (reflect.Type, error) {
+	for i, name := range path {
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return nil, nil
+		}
+		if i == len(path)-1 {
+			relation, err := q.relationField(reflect.New(t).Elem(), name)
+			if err != nil || relation == nil {
+				return nil, err
+			}
+			return relationElem(field.Type), nil
+		}
+		t = field.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkUnfolded returns an error if any record binds to a one-to-many
+// relation, which can only be folded by ForMany.
+func (q Query) checkUnfolded(records []recordBinding, entities []sreflect.ReflectStruct) error {
+	for _, record := range records {
+		parts := strings.Split(record.name, ".")
+		if len(parts) == 1 {
+			continue
+		}
+		for _, entity := range entities {
+			if entity.Name != parts[0] {
+				continue
+			}
+			child, err := q.foldedChild(entity.Value.Type(), parts[1:])
+			if err != nil {
+				return err
+			}
+			if child != nil {
+				return &offsetError{
+					err:    errors.Errorf("one-to-many record %q expected ForMany", record.name),
+					offset: record.start,
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// foldColumn is the destination of a column of a folded child. Every column
+// of the child is NULL when the parent doesn't have any children, such as
+// with a LEFT JOIN, so the columns are scanned in a way that allows NULL for
+// every field.
+type foldColumn struct {
+	entity int
+	// scanner tracks whether the column was NULL, for destinations that are
+	// scanners.
+	scanner *nullTracker
+	// field is set from ptr, a pointer to a pointer to the field type, for
+	// destinations that aren't scanners.
+	field reflect.Value
+	ptr   reflect.Value
+}
+
+// nullTracker scans a column using the scanner, recording whether it was
+// NULL.
+type nullTracker struct {
+	scanner sql.Scanner
+	null    bool
+}
+
+// Scan implements sql.Scanner.
+func (t *nullTracker) Scan(src interface{}) error {
+	t.null = src == nil
+	return t.scanner.Scan(src)
+}
+
+// foldDestination returns the scan destination of a column of a folded
+// child.
+func (s *rowScanner) foldDestination(entity int, field reflect.Value, dest interface{}) interface{} {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		tracker := &nullTracker{
+			scanner: scanner,
+		}
+		s.folds = append(s.folds, foldColumn{
+			entity:  entity,
+			scanner: tracker,
+		})
+		return tracker
+	}
+
+	ptr := reflect.New(reflect.PtrTo(field.Type()))
+	s.folds = append(s.folds, foldColumn{
+		entity: entity,
+		field:  field,
+		ptr:    ptr,
+	})
+	return ptr.Interface()
+}
+
+// foldedChildren sets the fields of the folded children from the scanned
+// columns, returning whether each entity has a child in the row, which is
+// the case if any of its columns aren't NULL.
+func (s *rowScanner) foldedChildren() []bool {
+	present := make([]bool, len(s.plan.entities))
+	for _, column := range s.folds {
+		if column.scanner != nil {
+			present[column.entity] = present[column.entity] || !column.scanner.null
+			continue
+		}
+		if value := column.ptr.Elem(); !value.IsNil() {
+			column.field.Set(value.Elem())
+			present[column.entity] = true
+		}
+	}
+	return present
+}
+
+// folder folds the rows of a one-to-many join, where the parent is repeated
+// for every child, into a parent for every primary key with a slice of
+// children.
+type folder struct {
+	plan *scanPlan
+	// keys are the primary key fields of the elements that are folded,
+	// which is nil for elements that aren't folded.
+	keys [][]sreflect.ReflectField
+	// parents are the indexes of the parents in the slices, by primary key.
+	parents []map[string]int
+	current []reflect.Value
+}
+
+// newFolder returns a folder for the plan, or nil if the plan doesn't have
+// any folded children.
+func newFolder(plan *scanPlan, elements []sreflect.ReflectStruct) (*folder, error) {
+	f := &folder{
+		plan:    plan,
+		keys:    make([][]sreflect.ReflectField, len(elements)),
+		parents: make([]map[string]int, len(elements)),
+		current: make([]reflect.Value, len(elements)),
+	}
+	var folded bool
+	for _, entity := range plan.entities {
+		if entity.child == nil || f.parents[entity.element] != nil {
+			continue
+		}
+		folded = true
+
+		element := elements[entity.element]
+		for _, name := range primaryKeyColumns(element) {
+			f.keys[entity.element] = append(f.keys[entity.element], element.Fields[name])
+		}
+		if len(f.keys[entity.element]) == 0 {
+			return nil, errors.Errorf("one-to-many records of %q expected fields with the pk tag option", element.Name)
+		}
+		f.parents[entity.element] = make(map[string]int)
+	}
+	if !folded {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// add adds the values of a row to the slices, appending a parent only for
+// the first row of its primary key, and appending any children to the slices
+// of the parents.
+func (f *folder) add(slice []reflectSlice, values []reflect.Value, scanner *rowScanner) {
+	for k, refSlice := range slice {
+		sliceVal := refSlice.slice.Value
+		if f.parents[k] == nil {
+			sliceVal.Set(reflect.Append(sliceVal, values[k]))
+			continue
+		}
+
+		key := f.key(k, values[k])
+		index, ok := f.parents[k][key]
+		if !ok {
+			sliceVal.Set(reflect.Append(sliceVal, values[k]))
+			index = sliceVal.Len() - 1
+			f.parents[k][key] = index
+		}
+		f.current[k] = sliceVal.Index(index)
+	}
+
+	present := scanner.foldedChildren()
+	for i, entity := range f.plan.entities {
+		if entity.child == nil || !present[i] {
+			continue
+		}
+		value := f.current[entity.element]
+		for _, name := range entity.path[:len(entity.path)-1] {
+			value = indirect(value.FieldByName(name))
+		}
+		children := value.FieldByName(entity.path[len(entity.path)-1])
+
+		child := scanner.entities[i]
+		if children.Type().Elem().Kind() == reflect.Ptr {
+			child = child.Addr()
+		}
+		children.Set(reflect.Append(children, child))
+	}
+}
+
+// key returns the primary key of the parent.
+func (f *folder) key(element int, value reflect.Value) string {
+	keys := make([]interface{}, len(f.keys[element]))
+	for i, field := range f.keys[element] {
+		keys[i] = sreflect.FieldByIndex(value, field.Index).Interface()
+	}
+	return fmt.Sprintf("%#v", keys)
+}
@@ -0,0 +1,133 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// foldSchema is a table of people with a one-to-many table of pets.
+const foldSchema = `
+CREATE TABLE people(
+	id   INTEGER,
+	name TEXT
+);
+CREATE TABLE pets(
+	id        INTEGER,
+	person_id INTEGER,
+	name      TEXT
+);
+INSERT INTO people(id, name) values (1, "fred"), (2, "frank"), (3, "jane");
+INSERT INTO pets(id, person_id, name) values (1, 1, "rex"), (2, 3, "tom"), (3, 1, "fido");
+`
+
+func TestQueryWithFoldedRecords(t *testing.T) {
+	db := setupSchemaDB(t, foldSchema)
+
+	type Pet struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	type Person struct {
+		ID   int    `db:"id,pk"`
+		Name string `db:"name"`
+		Pets []Pet  `db:"pets,hasmany=person_id"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person}, {pets.* INTO Person.Pets} FROM people LEFT JOIN pets ON pets.person_id=people.id ORDER BY people.id, pets.id;`)
+	})
+
+	assert.Equal(t, persons, []Person{
+		{ID: 1, Name: "fred", Pets: []Pet{{ID: 1, Name: "rex"}, {ID: 3, Name: "fido"}}},
+		{ID: 2, Name: "frank"},
+		{ID: 3, Name: "jane", Pets: []Pet{{ID: 2, Name: "tom"}}},
+	})
+}
+
+func TestQueryWithFoldedPointerRecords(t *testing.T) {
+	db := setupSchemaDB(t, foldSchema)
+
+	type Pet struct {
+		Name *string `db:"name"`
+	}
+	type Person struct {
+		ID   int    `db:"id,pk"`
+		Pets []*Pet `db:"pets,hasmany=person_id"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.id INTO Person}, {pets.name INTO Person.Pets} FROM people LEFT JOIN pets ON pets.person_id=people.id WHERE people.id IN (1, 2) ORDER BY people.id, pets.id;`)
+	})
+
+	rex, fido := "rex", "fido"
+	assert.Equal(t, persons, []Person{
+		{ID: 1, Pets: []*Pet{{Name: &rex}, {Name: &fido}}},
+		{ID: 2},
+	})
+}
+
+func TestQueryWithFoldedRecordsWithoutPrimaryKey(t *testing.T) {
+	db := setupSchemaDB(t, foldSchema)
+
+	type Pet struct {
+		Name string `db:"name"`
+	}
+	type Person struct {
+		ID   int   `db:"id"`
+		Pets []Pet `db:"pets,hasmany=person_id"`
+	}
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	var persons []Person
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {people.id INTO Person}, {pets.name INTO Person.Pets} FROM people LEFT JOIN pets ON pets.person_id=people.id;`)
+	assert.Equal(t, errors.Cause(err).Error(), `one-to-many records of "Person" expected fields with the pk tag option`)
+}
+
+func TestQueryWithFoldedRecordsForOne(t *testing.T) {
+	db := setupSchemaDB(t, foldSchema)
+
+	type Pet struct {
+		Name string `db:"name"`
+	}
+	type Person struct {
+		ID   int   `db:"id,pk"`
+		Pets []Pet `db:"pets,hasmany=person_id"`
+	}
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	var person Person
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {people.id INTO Person}, {pets.name INTO Person.Pets} FROM people LEFT JOIN pets ON pets.person_id=people.id;`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `one-to-many record "Person.Pets" expected ForMany`)
+}
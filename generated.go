@@ -0,0 +1,94 @@
+package sqlair
+
+import (
+	"reflect"
+	"sort"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// GeneratedRecord is implemented by the methods that sqlair-gen generates for
+// a struct, which scan and bind the columns of the struct without reflection:
+//
+//  //go:generate sqlair-gen -type Person
+//
+// ForOne and ForMany scan the plain columns of a generated record into the
+// destinations returned by SqlairScan, and named arguments are bound from
+// SqlairBind. The columns that need converting, such as JSON, time or
+// converter columns, are still mapped by reflection. The generated columns are
+// checked against the reflected columns whenever a query is created.
+type GeneratedRecord interface {
+	// SqlairColumns returns the columns of the struct.
+	SqlairColumns() []string
+	// SqlairScan returns a pointer to the field of the column, or nil if the
+	// column isn't generated.
+	SqlairScan(column string) interface{}
+	// SqlairBind returns the value of the field of the column, along with
+	// false if the column isn't generated.
+	SqlairBind(column string) (interface{}, bool)
+}
+
+// generatedBinder is the part of GeneratedRecord with a value receiver, which
+// is implemented by both the struct and a pointer to the struct.
+type generatedBinder interface {
+	SqlairBind(column string) (interface{}, bool)
+}
+
+var generatedRecordType = reflect.TypeOf((*GeneratedRecord)(nil)).Elem()
+
+// isGenerated returns true if the struct type has generated methods.
+func isGenerated(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(generatedRecordType)
+}
+
+// checkGenerated returns an error if the columns of the generated methods of
+// the struct type don't match the reflected columns, which happens when the
+// struct changes without running sqlair-gen again, or when the querier uses
+// another tag key or name mapper than the generated methods.
+func checkGenerated(t reflect.Type, refStruct sreflect.ReflectStruct) error {
+	if !isGenerated(t) {
+		return nil
+	}
+	generated := reflect.New(t).Interface().(GeneratedRecord).SqlairColumns()
+
+	matches := len(generated) == len(refStruct.Fields)
+	for _, name := range generated {
+		if _, ok := refStruct.Fields[name]; !ok {
+			matches = false
+			break
+		}
+	}
+	if matches {
+		return nil
+	}
+
+	columns := make([]string, 0, len(refStruct.Fields))
+	for name := range refStruct.Fields {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	sorted := append([]string(nil), generated...)
+	sort.Strings(sorted)
+	return errors.Errorf("generated columns %v don't match columns %v, expected sqlair-gen to be run again", sorted, columns)
+}
+
+// bindGenerated returns the value of the column from the generated methods of
+// the value, along with false if the value doesn't have generated methods for
+// the column.
+func bindGenerated(mapper fieldMapper, value interface{}, column string) (interface{}, bool, error) {
+	binder, ok := value.(generatedBinder)
+	if !ok {
+		return nil, false, nil
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, false, nil
+	}
+	v, ok := binder.SqlairBind(column)
+	if !ok {
+		return nil, false, nil
+	}
+	result, err := mapper.value(v)
+	return result, true, err
+}
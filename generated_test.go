@@ -0,0 +1,213 @@
+package sqlair
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/stretchr/testify/assert"
+)
+
+// genPerson has the methods that sqlair-gen generates, counting the columns
+// that are scanned and bound through them.
+type genPerson struct {
+	ID   int64           `db:"id"`
+	Name string          `db:"name"`
+	Tags json.RawMessage `db:"tags,json"`
+}
+
+var genScans, genBinds int
+
+func (v genPerson) SqlairColumns() []string {
+	return []string{"id", "name", "tags"}
+}
+
+func (v *genPerson) SqlairScan(column string) interface{} {
+	genScans++
+	switch column {
+	case "id":
+		return &v.ID
+	case "name":
+		return &v.Name
+	case "tags":
+		return &v.Tags
+	}
+	return nil
+}
+
+func (v genPerson) SqlairBind(column string) (interface{}, bool) {
+	genBinds++
+	switch column {
+	case "id":
+		return v.ID, true
+	case "name":
+		return v.Name, true
+	}
+	return nil, false
+}
+
+// staleGenPerson has generated methods that are missing a column.
+type staleGenPerson struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func (v staleGenPerson) SqlairColumns() []string                      { return []string{"id"} }
+func (v *staleGenPerson) SqlairScan(column string) interface{}        { return nil }
+func (v staleGenPerson) SqlairBind(column string) (interface{}, bool) { return nil, false }
+
+// upperPerson has generated methods that scan the name in upper case, which
+// tells the generated destinations apart from the reflected ones.
+type upperPerson struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+type upperScanner struct {
+	dest *string
+}
+
+func (s upperScanner) Scan(src interface{}) error {
+	*s.dest = strings.ToUpper(fmt.Sprint(src))
+	return nil
+}
+
+func (v upperPerson) SqlairColumns() []string { return []string{"id", "name"} }
+
+func (v *upperPerson) SqlairScan(column string) interface{} {
+	switch column {
+	case "id":
+		return &v.ID
+	case "name":
+		return upperScanner{dest: &v.Name}
+	}
+	return nil
+}
+
+func (v upperPerson) SqlairBind(column string) (interface{}, bool) { return nil, false }
+
+func TestGeneratedRecord(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, tags TEXT);
+INSERT INTO people VALUES (1, "fred", '["a"]'), (2, "frank", '["b"]');
+`)
+	assert.Nil(t, err)
+
+	genScans, genBinds = 0, 0
+
+	querier := NewQuerier()
+	err = querier.Register(genPerson{})
+	assert.Nil(t, err)
+
+	var persons []genPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {genPerson} FROM people WHERE id>=:id ORDER BY id;`, genPerson{ID: 1})
+	})
+
+	assert.Equal(t, persons, []genPerson{{
+		ID:   1,
+		Name: "fred",
+		Tags: json.RawMessage(`["a"]`),
+	}, {
+		ID:   2,
+		Name: "frank",
+		Tags: json.RawMessage(`["b"]`),
+	}})
+	// The JSON column is scanned by reflection, and the plan locates the
+	// generated columns once.
+	assert.Equal(t, genScans, 6)
+	assert.Equal(t, genBinds, 1)
+}
+
+func TestGeneratedRecordStale(t *testing.T) {
+	err := NewQuerier().Register(staleGenPerson{})
+	assert.Equal(t, err.Error(), `register "staleGenPerson": generated columns [id] don't match columns [id name], expected sqlair-gen to be run again`)
+}
+
+func TestGeneratedRecordWithoutReflection(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT);
+INSERT INTO people VALUES (1, "fred"), (2, "frank");
+`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	var (
+		person  upperPerson
+		persons []upperPerson
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		if err := getter.Query(tx, `SELECT {upperPerson} FROM people WHERE id=1;`); err != nil {
+			return err
+		}
+
+		getter, err = querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {upperPerson} FROM people ORDER BY id;`)
+	})
+
+	// The reflected destination of the name would scan it as it is.
+	assert.Equal(t, person, upperPerson{ID: 1, Name: "FRED"})
+	assert.Equal(t, persons, []upperPerson{{ID: 1, Name: "FRED"}, {ID: 2, Name: "FRANK"}})
+}
+
+func TestGeneratedRecordScanPlan(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT);`)
+	assert.Nil(t, err)
+
+	var persons []upperPerson
+	query, err := NewQuerier().ForMany(&persons)
+	assert.Nil(t, err)
+
+	rows, err := db.Query(`SELECT id, name FROM people;`)
+	assert.Nil(t, err)
+	defer rows.Close()
+	columns, err := rows.ColumnTypes()
+	assert.Nil(t, err)
+
+	element := query.entities[0].(sreflect.ReflectValue)
+	refStruct, err := query.reflect.Reflect(reflect.New(element.Value.Type().Elem()).Interface())
+	assert.Nil(t, err)
+
+	plan, err := query.newScanPlan(columns, []sreflect.ReflectStruct{refStruct.(sreflect.ReflectStruct)}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, plan.generated, []bool{true, true})
+	assert.Equal(t, plan.entities[0].generated, true)
+	assert.Equal(t, plan.entities[0].reflected, false)
+
+	// The entity isn't located by reflection for any row.
+	scanner := plan.scanner(make([]interface{}, 2))
+	_, columnar := scanner.row(query.mapper)
+	assert.Equal(t, scanner.entities[0].IsValid(), false)
+	assert.IsType(t, columnar[1], upperScanner{})
+}
+
+func TestGeneratedRecordStaleQuery(t *testing.T) {
+	var (
+		person  staleGenPerson
+		persons []staleGenPerson
+	)
+	_, err := NewQuerier().ForOne(&person)
+	assert.Equal(t, err.Error(), `reflect: generated columns [id] don't match columns [id name], expected sqlair-gen to be run again`)
+
+	_, err = NewQuerier().ForMany(&persons)
+	assert.Equal(t, err.Error(), `reflect: generated columns [id] don't match columns [id name], expected sqlair-gen to be run again`)
+}
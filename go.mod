@@ -2,11 +2,14 @@ module github.com/SimonRichardson/sqlair
 
 go 1.17
 
+require (
+	github.com/mattn/go-sqlite3 v1.14.11
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.7.0
+)
+
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
-	github.com/mattn/go-sqlite3 v1.14.11 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.7.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
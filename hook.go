@@ -0,0 +1,176 @@
+package sqlair
+
+import "context"
+
+// AddHook appends the hook to the hooks of the querier, rather than replacing
+// them as Hook does. The hooks are called in the order they were added, and
+// the first hook to return an error vetoes the statement, without calling
+// the hooks that follow it.
+//
+//  querier.AddHook(logStatement)
+//  querier.AddHook(denyDeletes)
+//
+func (q *Querier) AddHook(hook Hook) {
+	q.hook = chainHooks(q.hook, hook)
+}
+
+// WithHook returns a copy of the query, with the hook called after the hooks
+// of the querier. Only the returned query calls the hook, so a single code
+// path can add verbose logging without affecting any other queries.
+//
+//  getter, err := querier.ForOne(&person)
+//  ...
+//  err = getter.WithHook(logStatement).Query(tx, stmt, args...)
+//
+func (q Query) WithHook(hook Hook) Query {
+	q.hook = chainHooks(q.hook, hook)
+	return q
+}
+
+// WithContextHook returns a new context that carries the hook. Every statement
+// executed with the context calls the hook, after the hooks of the querier and
+// the query, so a per-request logger receives the statements of its request
+// only:
+//
+//  ctx = sqlair.WithContextHook(ctx, func(stmt string) error {
+//  	logger.Debugf("request %s: %s", requestID, stmt)
+//  	return nil
+//  })
+//  err = getter.QueryContext(ctx, tx, stmt, args...)
+//
+// Hooks that are added to a context that already carries hooks are called
+// after them. As with other hooks, returning an error prevents the statement
+// from being executed.
+func WithContextHook(ctx context.Context, hook Hook) context.Context {
+	existing, _ := ctx.Value(hookKey{}).(Hook)
+	return context.WithValue(ctx, hookKey{}, chainHooks(existing, hook))
+}
+
+type hookKey struct{}
+
+// contextHook returns the hook of the query, followed by the hooks of the
+// context.
+func contextHook(ctx context.Context, hook Hook) Hook {
+	existing, _ := ctx.Value(hookKey{}).(Hook)
+	return chainHooks(hook, existing)
+}
+
+// chainHooks returns a hook that calls each of the hooks in turn, stopping at
+// the first error. Any nil hooks are skipped.
+func chainHooks(hooks ...Hook) Hook {
+	chain := make([]Hook, 0, len(hooks))
+	for _, hook := range hooks {
+		if hook != nil {
+			chain = append(chain, hook)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+	return func(stmt string) error {
+		for _, hook := range chain {
+			if err := hook(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// StatementInfo describes a statement before it's executed, along with the
+// record expressions and named arguments it was compiled from.
+type StatementInfo struct {
+	// Statement is the compiled statement that's sent to the database.
+	Statement string
+	// Records are the record expressions of the statement, in the order
+	// they're written. Statements that have been compiled before report the
+	// records of the first compilation.
+	Records []RecordInfo
+	// Arguments are the names of the named arguments of the statement, in
+	// the order they're first written, without any positional placeholders.
+	Arguments []string
+}
+
+// RecordInfo describes a record expression of a statement.
+type RecordInfo struct {
+	// Entity is the name of the type the record is bound to, which is a
+	// dotted path for nested records.
+	Entity string
+	// Alias is the alias of the record, if it has one.
+	Alias string
+	// Prefix is the table the columns of the record are qualified with,
+	// which is empty if they're not qualified.
+	Prefix string
+	// Fields are the fields and computed columns written in the record, in
+	// the order they're written.
+	Fields []string
+	// Wildcard is true if the record selects every field of the entity,
+	// other than any excluded fields.
+	Wildcard bool
+}
+
+// StatementHook is called with the information of a statement before it's
+// executed. Returning an error prevents the statement from being executed,
+// with the error being returned to the caller.
+type StatementHook func(StatementInfo) error
+
+// OnStatement assigns the statement hook to the querier, which is called
+// along with the hooks of the querier before every query or exec. Unlike a
+// Hook, the statement hook receives the record expressions and the named
+// arguments of the statement, so that tooling can report which entities and
+// columns every statement touches:
+//
+//  querier.OnStatement(func(info sqlair.StatementInfo) error {
+//  	for _, record := range info.Records {
+//  		lineage.Record(record.Entity, record.Fields)
+//  	}
+//  	return nil
+//  })
+//
+func (q *Querier) OnStatement(hook StatementHook) {
+	q.statementHook = hook
+}
+
+// observe calls the statement hook, if there is one, with the information of
+// the statement.
+func (h StatementHook) observe(stmt string, records []recordBinding, arguments []string) error {
+	if h == nil {
+		return nil
+	}
+	info := StatementInfo{
+		Statement: stmt,
+		Arguments: arguments,
+	}
+	for _, record := range records {
+		info.Records = append(info.Records, RecordInfo{
+			Entity:   record.name,
+			Alias:    record.alias,
+			Prefix:   record.prefix,
+			Fields:   record.order,
+			Wildcard: record.wildcard,
+		})
+	}
+	return h(info)
+}
+
+// argumentNames returns the distinct names of the named arguments of the
+// statement.
+func argumentNames(stmt string) ([]string, error) {
+	names, err := parseNames(stmt, 0)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if name.name == "" || seen[name.name] {
+			continue
+		}
+		seen[name.name] = true
+		result = append(result, name.name)
+	}
+	return result, nil
+}
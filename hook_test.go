@@ -0,0 +1,210 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainHooks(t *testing.T) {
+	var called []string
+	hook := func(name string, err error) Hook {
+		return func(stmt string) error {
+			called = append(called, name)
+			return err
+		}
+	}
+
+	assert.Nil(t, chainHooks(nil, nil))
+
+	chain := chainHooks(hook("a", nil), nil, hook("b", errors.New("boom")), hook("c", nil))
+	err := chain("SELECT 1;")
+	assert.Equal(t, err.Error(), "boom")
+	assert.Equal(t, called, []string{"a", "b"})
+}
+
+func TestQuerierAddHook(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var called []string
+
+	querier := NewQuerier()
+	querier.AddHook(func(stmt string) error {
+		called = append(called, "first")
+		return nil
+	})
+	querier.AddHook(func(stmt string) error {
+		called = append(called, "second")
+		return errors.New("vetoed")
+	})
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `DELETE FROM people;`)
+	assert.Equal(t, errors.Cause(err).Error(), "vetoed")
+	assert.Equal(t, called, []string{"first", "second"})
+
+	// Hook replaces the chain.
+	called = nil
+	querier.Hook(func(stmt string) error {
+		called = append(called, "replaced")
+		return nil
+	})
+	_, err = querier.Exec(tx, `DELETE FROM people;`)
+	assert.Nil(t, err)
+	assert.Equal(t, called, []string{"replaced"})
+}
+
+func TestQueryWithHook(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var called []string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		called = append(called, "querier")
+		return nil
+	})
+
+	var person argsPerson
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	verbose := getter.WithHook(func(stmt string) error {
+		called = append(called, stmt)
+		return nil
+	})
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		if err := verbose.Query(tx, `SELECT {argsPerson} FROM people WHERE name="fred";`); err != nil {
+			return err
+		}
+		return getter.Query(tx, `SELECT {argsPerson} FROM people WHERE name="jane";`)
+	})
+
+	assert.Equal(t, person, argsPerson{Name: "jane", Age: 23})
+	assert.Equal(t, called, []string{
+		"querier",
+		`SELECT age, name FROM people WHERE name="fred";`,
+		"querier",
+	})
+}
+
+func TestWithContextHook(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var called []string
+	logger := func(request string) Hook {
+		return func(stmt string) error {
+			called = append(called, request+": "+stmt)
+			return nil
+		}
+	}
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		called = append(called, "querier")
+		return nil
+	})
+
+	var person argsPerson
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	first := WithContextHook(context.Background(), logger("first"))
+	second := WithContextHook(context.Background(), logger("second"))
+	second = WithContextHook(second, logger("audit"))
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		if err := getter.QueryContext(first, tx, `SELECT {argsPerson} FROM people WHERE name="fred";`); err != nil {
+			return err
+		}
+		if _, err := querier.ExecContext(second, tx, `UPDATE people SET age=22 WHERE name="fred";`); err != nil {
+			return err
+		}
+		return getter.Query(tx, `SELECT {argsPerson} FROM people WHERE name="fred";`)
+	})
+
+	assert.Equal(t, person, argsPerson{Name: "fred", Age: 22})
+	assert.Equal(t, called, []string{
+		"querier",
+		`first: SELECT age, name FROM people WHERE name="fred";`,
+		"querier",
+		`second: UPDATE people SET age=22 WHERE name="fred";`,
+		`audit: UPDATE people SET age=22 WHERE name="fred";`,
+		"querier",
+	})
+
+	// The hooks of the context veto the statement, in the same way as the
+	// hooks of the querier.
+	vetoed := WithContextHook(context.Background(), func(stmt string) error {
+		return errors.New("vetoed")
+	})
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.ExecContext(vetoed, tx, `DELETE FROM people;`)
+	assert.Equal(t, errors.Cause(err).Error(), "vetoed")
+}
+
+func TestQuerierOnStatement(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var infos []StatementInfo
+
+	querier := NewQuerier()
+	querier.OnStatement(func(info StatementInfo) error {
+		infos = append(infos, info)
+		return nil
+	})
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		if err != nil {
+			return err
+		}
+		if err := getter.Query(tx, `SELECT {p.name, p.age INTO argsPerson} FROM people AS p WHERE age>:age OR name=:name OR age=:age;`, map[string]interface{}{
+			"age":  30,
+			"name": "fred",
+		}); err != nil {
+			return err
+		}
+		_, err = querier.Exec(tx, `INSERT INTO people SELECT {argsPerson} FROM people WHERE name=:name;`, argsPerson{Name: "jane"})
+		return err
+	})
+
+	assert.Equal(t, infos, []StatementInfo{{
+		Statement: `SELECT p.age, p.name FROM people AS p WHERE age>:age OR name=:name OR age=:age;`,
+		Records: []RecordInfo{{
+			Entity: "argsPerson",
+			Prefix: "p",
+			Fields: []string{"name", "age"},
+		}},
+		Arguments: []string{"age", "name"},
+	}, {
+		Statement: `INSERT INTO people SELECT age, name FROM people WHERE name=:name;`,
+		Records: []RecordInfo{{
+			Entity:   "argsPerson",
+			Wildcard: true,
+		}},
+		Arguments: []string{"name"},
+	}})
+
+	// Returning an error vetoes the statement.
+	querier.OnStatement(func(info StatementInfo) error {
+		return errors.New("vetoed")
+	})
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `DELETE FROM people;`)
+	assert.Equal(t, errors.Cause(err).Error(), "vetoed")
+}
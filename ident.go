@@ -0,0 +1,165 @@
+package sqlair
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Ident is an identifier, such as the name of a column or a table, which is
+// spliced into the statement in place of its named argument rather than being
+// bound as a value. This allows the columns of an ORDER BY or the table of a
+// statement to be chosen at runtime, without building the statement with
+// fmt.Sprintf:
+//
+//  getter.Query(tx, `SELECT {Person} FROM people ORDER BY :sort;`, sql.Named("sort", sqlair.Ident(sort)))
+//
+// Identifiers are strictly validated, they can only hold letters, digits and
+// underscores, and can be qualified with a table or schema (people.name).
+// Anything else returns an IdentError. Every part of the identifier is quoted
+// for the dialect of the querier (see Querier.Dialect).
+//
+// Identifiers can be given as sql.NamedArg values, as positional arguments,
+// or as the values of maps and struct fields. They can't be used to query a
+// prepared Statement, as its statement is fixed when it's prepared.
+type Ident string
+
+// Value implements driver.Valuer, returning an error so that an identifier
+// is never mistaken for a string value.
+func (i Ident) Value() (driver.Value, error) {
+	return nil, errors.Errorf("unexpected identifier %q bound as a value", string(i))
+}
+
+// quote validates the identifier, returning it quoted for the dialect.
+func (i Ident) quote(dialect Dialect) (string, error) {
+	parts := strings.Split(string(i), ".")
+	for k, part := range parts {
+		if !isIdentPart(part) {
+			return "", errors.WithStack(&IdentError{Ident: string(i)})
+		}
+		parts[k] = dialect.quoteIdent(part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+func isIdentPart(part string) bool {
+	if part == "" {
+		return false
+	}
+	for i, r := range part {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Dialect sets the SQL dialect of the database, which decides how identifiers
+// are quoted when they're spliced into a statement (see Ident). Identifiers
+// are quoted with double quotes, other than for MySQL, which uses backticks.
+func (q *Querier) Dialect(dialect Dialect) {
+	q.mapper.dialect = dialect
+}
+
+// quoteIdent quotes a part of an identifier that has already been validated.
+func (d Dialect) quoteIdent(name string) string {
+	if d == MySQL {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+// hasIdent returns true if any of the arguments is an identifier.
+func hasIdent(args []interface{}) bool {
+	for _, arg := range args {
+		if _, ok := arg.(Ident); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// findIdent returns the first identifier of the arguments, either given
+// directly, as a sql.NamedArg or as the value of a map.
+func findIdent(args []interface{}) (Ident, bool) {
+	for _, arg := range args {
+		if values, ok := arg.(Args); ok {
+			arg = map[string]interface{}(values)
+		}
+		switch arg := arg.(type) {
+		case Ident:
+			return arg, true
+		case sql.NamedArg:
+			if ident, ok := arg.Value.(Ident); ok {
+				return ident, true
+			}
+		case map[string]interface{}:
+			for _, value := range arg {
+				if ident, ok := value.(Ident); ok {
+					return ident, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// spliceIdents replaces the named arguments of the statement that are bound to
+// an identifier with the quoted identifier, removing the identifiers from the
+// arguments.
+func spliceIdents(dialect Dialect, stmt string, args []interface{}) (string, []interface{}, error) {
+	idents := make(map[string]string)
+	for _, arg := range args {
+		named, ok := arg.(sql.NamedArg)
+		if !ok {
+			continue
+		}
+		ident, ok := named.Value.(Ident)
+		if !ok {
+			continue
+		}
+		quoted, err := ident.quote(dialect)
+		if err != nil {
+			return "", nil, err
+		}
+		idents[named.Name] = quoted
+	}
+	if len(idents) == 0 {
+		return stmt, args, nil
+	}
+
+	names, err := parseNames(stmt, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	var (
+		builder strings.Builder
+		last    int
+	)
+	for _, name := range names {
+		quoted, ok := idents[name.name]
+		if !ok {
+			continue
+		}
+		builder.WriteString(stmt[last:name.offset])
+		builder.WriteString(quoted)
+		last = name.offset + 1 + len(name.name)
+	}
+	builder.WriteString(stmt[last:])
+
+	result := make([]interface{}, 0, len(args)-len(idents))
+	for _, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			if _, ok := idents[named.Name]; ok {
+				continue
+			}
+		}
+		result = append(result, arg)
+	}
+	return builder.String(), result, nil
+}
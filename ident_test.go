@@ -0,0 +1,128 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type identPerson struct {
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func TestQueryWithIdents(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people (name TEXT, age INTEGER);
+INSERT INTO people (name, age) VALUES ("fred", 21), ("frank", 42), ("jane", 30);
+`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	var persons []identPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {identPerson} FROM people ORDER BY :sort DESC;`, sql.Named("sort", Ident("age")))
+	})
+	assert.Equal(t, persons, []identPerson{
+		{Name: "frank", Age: 42},
+		{Name: "jane", Age: 30},
+		{Name: "fred", Age: 21},
+	})
+
+	// Identifiers are found in maps, alongside the values.
+	persons = nil
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {identPerson} FROM :table WHERE age > :age ORDER BY :sort;`, map[string]interface{}{
+			"table": Ident("people"),
+			"age":   25,
+			"sort":  Ident("people.name"),
+		})
+	})
+	assert.Equal(t, persons, []identPerson{
+		{Name: "frank", Age: 42},
+		{Name: "jane", Age: 30},
+	})
+
+	// Positional identifiers are spliced in the same way.
+	persons = nil
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {identPerson} FROM people WHERE age < ? ORDER BY ?;`, 35, Ident("name"))
+	})
+	assert.Equal(t, persons, []identPerson{
+		{Name: "fred", Age: 21},
+		{Name: "jane", Age: 30},
+	})
+}
+
+func TestQueryWithInvalidIdents(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`CREATE TABLE people (name TEXT, age INTEGER);`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	for _, ident := range []Ident{"", "age DESC", `age"; DROP TABLE people; --`, "1age", "people.", "people..age"} {
+		tx, err := db.Begin()
+		assert.Nil(t, err)
+
+		var persons []identPerson
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		err = getter.Query(tx, `SELECT {identPerson} FROM people ORDER BY :sort;`, sql.Named("sort", ident))
+		assert.True(t, errors.As(err, new(*IdentError)), "%q: %v", ident, err)
+
+		assert.Nil(t, tx.Rollback())
+	}
+}
+
+func TestIdentQuote(t *testing.T) {
+	quoted, err := Ident("people.name").quote(SQLite)
+	assert.Nil(t, err)
+	assert.Equal(t, quoted, `"people"."name"`)
+
+	quoted, err = Ident("people.name").quote(MySQL)
+	assert.Nil(t, err)
+	assert.Equal(t, quoted, "`people`.`name`")
+
+	_, err = Ident("people name").quote(Postgres)
+	assert.Equal(t, err.Error(), `invalid identifier "people name", expected letters, digits and underscores`)
+}
+
+func TestIdentValue(t *testing.T) {
+	_, err := Ident("name").Value()
+	assert.Equal(t, err.Error(), `unexpected identifier "name" bound as a value`)
+}
+
+func TestPrepareQueryWithIdents(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`CREATE TABLE people (name TEXT, age INTEGER);`)
+	assert.Nil(t, err)
+
+	stmt, err := NewQuerier().Prepare(`SELECT {identPerson} FROM people ORDER BY :sort;`, identPerson{})
+	assert.Nil(t, err)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	var persons []identPerson
+	err = stmt.Query(tx, &persons, sql.Named("sort", Ident("age")))
+	assert.Equal(t, err.Error(), `unexpected identifier "age" for prepared statement`)
+}
@@ -0,0 +1,136 @@
+package sqlair
+
+import (
+	"database/sql"
+	"reflect"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// RegisterType registers the concrete type of the value by name, so that
+// interface fields can be scanned into it. Interface fields select the type
+// with the scan tag option:
+//
+//  type Event struct {
+//  	Payload Payload `db:"payload,json,scan=click"`
+//  }
+//
+//  querier.RegisterType("click", &Click{})
+//
+// The value of an interface field is scanned into a new value of the type,
+// using the sql.Scanner of the type, or json if the field is tagged with it,
+// otherwise the column is assigned or converted to the type. The type must
+// implement the interface of the field. NULL columns leave the field nil.
+//
+// Interface fields are bound by their dynamic value. Empty interface fields
+// without a scan type hold the value of the column as it's returned by the
+// driver. Other interface fields must have a scan type, or a converter
+// registered for the interface type (see RegisterConverter).
+func (q *Querier) RegisterType(name string, value interface{}) {
+	// Copy the types, so that existing queries and copies of the querier
+	// aren't affected.
+	types := make(map[string]reflect.Type, len(q.mapper.types)+1)
+	for k, v := range q.mapper.types {
+		types[k] = v
+	}
+	types[name] = reflect.TypeOf(value)
+	q.mapper.types = types
+}
+
+// isInterfaceField returns true if the field is an interface that's scanned
+// by an interfaceScanner, rather than by the database/sql package.
+func (m fieldMapper) isInterfaceField(field sreflect.ReflectField, t reflect.Type) bool {
+	if t.Kind() != reflect.Interface {
+		return false
+	}
+	if _, ok := m.converter(t); ok {
+		return false
+	}
+	return field.Tag.Scan != "" || t.NumMethod() > 0
+}
+
+// interfaceScanner scans a column into a new value of the concrete type of an
+// interface field.
+type interfaceScanner struct {
+	dest  reflect.Value
+	field sreflect.ReflectField
+	// concrete is the type that the column is scanned into, which is nil if
+	// the field doesn't have a registered scan type.
+	concrete reflect.Type
+}
+
+// Scan implements sql.Scanner.
+func (s interfaceScanner) Scan(src interface{}) error {
+	if src == nil {
+		s.dest.Set(reflect.Zero(s.dest.Type()))
+		return nil
+	}
+	if s.concrete == nil {
+		if s.field.Tag.Scan != "" {
+			return errors.Errorf("unknown scan type %q for interface field %q, use RegisterType", s.field.Tag.Scan, s.field.Name)
+		}
+		return errors.Errorf("missing scan type for interface field %q of type %s, use the scan tag option or a converter", s.field.Name, s.dest.Type())
+	}
+
+	typ := s.concrete
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	ptr := reflect.New(typ)
+
+	switch scanner, ok := ptr.Interface().(sql.Scanner); {
+	case s.field.Tag.JSON:
+		if err := (jsonScanner{dest: ptr.Elem()}).Scan(src); err != nil {
+			return err
+		}
+	case ok:
+		if err := scanner.Scan(src); err != nil {
+			return errors.Wrapf(err, "scanning %T to %s", src, s.concrete)
+		}
+	default:
+		if err := assignValue(ptr.Elem(), src); err != nil {
+			return err
+		}
+	}
+
+	value := ptr
+	if s.concrete.Kind() != reflect.Ptr {
+		value = ptr.Elem()
+	}
+	if !value.Type().Implements(s.dest.Type()) {
+		return errors.Errorf("scan type %s does not implement %s of interface field %q", value.Type(), s.dest.Type(), s.field.Name)
+	}
+	s.dest.Set(value)
+	return nil
+}
+
+// assignValue sets the destination to the value of a column, converting it
+// if the types differ. Numbers aren't converted to strings, as the conversion
+// would yield a rune rather than the digits of the number.
+func assignValue(dest reflect.Value, src interface{}) error {
+	v := reflect.ValueOf(src)
+	if b, ok := src.([]byte); ok {
+		// The bytes are owned by the driver, so they're copied.
+		v = reflect.ValueOf(append([]byte(nil), b...))
+	}
+	switch {
+	case v.Type().AssignableTo(dest.Type()):
+		dest.Set(v)
+	case v.Type().ConvertibleTo(dest.Type()) && !(dest.Kind() == reflect.String && isNumberKind(v.Kind())):
+		dest.Set(v.Convert(dest.Type()))
+	default:
+		return errors.Errorf("unable to assign %T to %s", src, dest.Type())
+	}
+	return nil
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package sqlair
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type interfaceShape interface {
+	Area() float64
+}
+
+type interfaceSquare struct {
+	Side float64 `json:"side"`
+}
+
+func (s *interfaceSquare) Area() float64 {
+	return s.Side * s.Side
+}
+
+type interfaceLabel string
+
+func (l interfaceLabel) String() string {
+	return string(l)
+}
+
+type interfaceEvent struct {
+	Name  string         `db:"name"`
+	Shape interfaceShape `db:"shape,json,scan=square"`
+	Label fmt.Stringer   `db:"label,scan=label"`
+	Value interface{}    `db:"value"`
+}
+
+func TestQueryWithInterfaceFields(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`CREATE TABLE events (name TEXT, shape TEXT, label TEXT, value INTEGER);`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+	querier.RegisterType("square", &interfaceSquare{})
+	querier.RegisterType("label", interfaceLabel(""))
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		for _, event := range []interfaceEvent{{
+			Name:  "fred",
+			Shape: &interfaceSquare{Side: 2},
+			Label: interfaceLabel("big"),
+			Value: 42,
+		}, {
+			Name: "frank",
+		}} {
+			if _, err := querier.Exec(tx, `INSERT INTO events (name, shape, label, value) VALUES (:name, :shape, :label, :value);`, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// The dynamic values are bound.
+	var shape, label string
+	err = db.QueryRow(`SELECT shape, label FROM events WHERE name="fred";`).Scan(&shape, &label)
+	assert.Nil(t, err)
+	assert.Equal(t, shape, `{"side":2}`)
+	assert.Equal(t, label, "big")
+
+	var events []interfaceEvent
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&events)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {interfaceEvent} FROM events ORDER BY name DESC;`)
+	})
+	assert.Equal(t, events, []interfaceEvent{{
+		Name:  "fred",
+		Shape: &interfaceSquare{Side: 2},
+		Label: interfaceLabel("big"),
+		Value: int64(42),
+	}, {
+		Name: "frank",
+	}})
+	assert.Equal(t, events[0].Shape.Area(), float64(4))
+}
+
+func TestQueryWithInterfaceFieldConverter(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE events (name TEXT, label TEXT);
+INSERT INTO events (name, label) VALUES ("fred", "big");
+`)
+	assert.Nil(t, err)
+
+	type Event struct {
+		Name  string       `db:"name"`
+		Label fmt.Stringer `db:"label"`
+	}
+
+	querier := NewQuerier()
+	querier.RegisterConverter(reflect.TypeOf((*fmt.Stringer)(nil)).Elem(), nil, func(v interface{}) (interface{}, error) {
+		return interfaceLabel(v.(string)), nil
+	})
+
+	var event Event
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&event)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {Event} FROM events;`)
+	})
+	assert.Equal(t, event, Event{Name: "fred", Label: interfaceLabel("big")})
+}
+
+func TestQueryWithInterfaceFieldErrors(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE events (name TEXT, label TEXT);
+INSERT INTO events (name, label) VALUES ("fred", "big");
+`)
+	assert.Nil(t, err)
+
+	type Event struct {
+		Name  string       `db:"name"`
+		Label fmt.Stringer `db:"label"`
+	}
+	type TaggedEvent struct {
+		Name  string       `db:"name"`
+		Label fmt.Stringer `db:"label,scan=label"`
+	}
+
+	tests := []struct {
+		querier func() *Querier
+		value   interface{}
+		message string
+	}{{
+		querier: NewQuerier,
+		value:   &Event{},
+		message: `missing scan type for interface field "Label" of type fmt.Stringer, use the scan tag option or a converter`,
+	}, {
+		querier: NewQuerier,
+		value:   &TaggedEvent{},
+		message: `unknown scan type "label" for interface field "Label", use RegisterType`,
+	}, {
+		querier: func() *Querier {
+			querier := NewQuerier()
+			querier.RegisterType("label", 0)
+			return querier
+		},
+		value:   &TaggedEvent{},
+		message: `unable to assign string to int`,
+	}, {
+		querier: func() *Querier {
+			querier := NewQuerier()
+			querier.RegisterType("label", "")
+			return querier
+		},
+		value:   &TaggedEvent{},
+		message: `scan type string does not implement fmt.Stringer of interface field "Label"`,
+	}}
+	for _, test := range tests {
+		tx, err := db.Begin()
+		assert.Nil(t, err)
+
+		getter, err := test.querier().ForOne(test.value)
+		assert.Nil(t, err)
+
+		err = getter.Query(tx, `SELECT {`+reflect.TypeOf(test.value).Elem().Name()+`} FROM events;`)
+		assert.Contains(t, err.Error(), test.message)
+
+		assert.Nil(t, tx.Rollback())
+	}
+}
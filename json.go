@@ -0,0 +1,54 @@
+package sqlair
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// marshalJSON marshals the value of a field tagged with the json option. Nil
+// values are stored as NULL.
+func marshalJSON(value reflect.Value) (interface{}, error) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+	}
+	b, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshalling %s to json", value.Type())
+	}
+	return string(b), nil
+}
+
+// jsonScanner unmarshals TEXT and BLOB values into a field tagged with the
+// json option.
+type jsonScanner struct {
+	dest reflect.Value
+}
+
+// Scan implements sql.Scanner.
+func (s jsonScanner) Scan(src interface{}) error {
+	// Always start from the zero value, so that maps and slices aren't
+	// merged with any existing values.
+	s.dest.Set(reflect.Zero(s.dest.Type()))
+
+	var b []byte
+	switch t := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		b = t
+	case string:
+		b = []byte(t)
+	default:
+		return errors.Errorf("unexpected json type %T", src)
+	}
+
+	if err := json.Unmarshal(b, s.dest.Addr().Interface()); err != nil {
+		return errors.Wrapf(err, "unmarshalling json to %s", s.dest.Type())
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package sqlair
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryWithJSONFields(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name     TEXT,
+	tags     TEXT,
+	metadata BLOB,
+	address  TEXT
+);
+	`)
+	assert.Nil(t, err)
+
+	type Address struct {
+		City string `json:"city"`
+	}
+	type Person struct {
+		Name     string            `db:"name"`
+		Tags     []string          `db:"tags,json"`
+		Metadata map[string]string `db:"metadata,json"`
+		Address  *Address          `db:"address,json"`
+	}
+
+	querier := NewQuerier()
+
+	var (
+		raw     sql.NullString
+		persons []Person
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		for _, person := range []Person{
+			{Name: "fred", Tags: []string{"a", "b"}, Metadata: map[string]string{"x": "y"}, Address: &Address{City: "london"}},
+			{Name: "frank"},
+		} {
+			if _, err := querier.Exec(tx, "INSERT INTO test(name, tags, metadata, address) VALUES (:name, :tags, :metadata, :address);", person); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.QueryRow("SELECT address FROM test WHERE name='fred';").Scan(&raw); err != nil {
+			return err
+		}
+
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person} FROM test ORDER BY name DESC;`)
+	})
+
+	assert.Equal(t, raw, sql.NullString{String: `{"city":"london"}`, Valid: true})
+	assert.Equal(t, persons, []Person{
+		{Name: "fred", Tags: []string{"a", "b"}, Metadata: map[string]string{"x": "y"}, Address: &Address{City: "london"}},
+		{Name: "frank"},
+	})
+}
+
+func TestJSONScannerInvalid(t *testing.T) {
+	var tags []string
+	scanner := jsonScanner{
+		dest: reflect.ValueOf(&tags).Elem(),
+	}
+	err := scanner.Scan(int64(1))
+	assert.Equal(t, err.Error(), "unexpected json type int64")
+
+	err = scanner.Scan("{")
+	assert.Equal(t, err.Error(), "unmarshalling json to []string: unexpected end of JSON input")
+}
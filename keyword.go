@@ -0,0 +1,77 @@
+package sqlair
+
+import (
+	"strings"
+)
+
+func isKeywordChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+// isStatementKeyword returns true if the keyword starts a statement that can
+// follow the common table expressions of a WITH clause.
+func isStatementKeyword(word string) bool {
+	switch word {
+	case "SELECT", "VALUES", "INSERT", "REPLACE", "UPDATE", "DELETE":
+		return true
+	}
+	return false
+}
+
+// keywordOffset is a keyword along with its offsets within a statement.
+type keywordOffset struct {
+	word       string
+	start, end int
+}
+
+// clauseKeywords returns the words of the statement that aren't nested in
+// parentheses, in upper case, along with their offsets. Quoted strings,
+// comments, named arguments and qualified names are skipped, so that only
+// the words that can start a clause are returned.
+func clauseKeywords(stmt string) []keywordOffset {
+	var (
+		keywords []keywordOffset
+		depth    int
+	)
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(stmt, i, c)
+		case strings.HasPrefix(stmt[i:], "--"):
+			i = skipUntil(stmt, i, "\n")
+		case strings.HasPrefix(stmt[i:], "/*"):
+			i = skipUntil(stmt, i, "*/")
+		case c == '(' || c == '{':
+			depth++
+			i++
+		case c == ')' || c == '}':
+			depth--
+			i++
+		case isKeywordChar(c):
+			start := i
+			for i < len(stmt) && (isKeywordChar(stmt[i]) || (stmt[i] >= '0' && stmt[i] <= '9')) {
+				i++
+			}
+			if depth > 0 || (start > 0 && (stmt[start-1] == ':' || stmt[start-1] == '.' || stmt[start-1] == '@' || stmt[start-1] == '$')) {
+				continue
+			}
+			keywords = append(keywords, keywordOffset{
+				word:  strings.ToUpper(stmt[start:i]),
+				start: start,
+				end:   i,
+			})
+		default:
+			i++
+		}
+	}
+	return keywords
+}
+
+// min returns the smaller of the offsets.
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
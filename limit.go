@@ -0,0 +1,54 @@
+package sqlair
+
+import (
+	"strings"
+)
+
+// LimitOne sets whether the statements of ForOne queries are limited to a
+// single row. As ForOne only populates its destinations from the first row,
+// a SELECT statement without a LIMIT clause has LIMIT 1 added to it, so that
+// the database doesn't return rows that would be discarded:
+//
+//  SELECT {Person} FROM people WHERE age>:age ORDER BY name;
+//
+// becomes
+//
+//  SELECT age, name FROM people WHERE age>:age ORDER BY name LIMIT 1;
+//
+// The LIMIT clause is placed before any OFFSET or locking (FOR UPDATE)
+// clause, which is where SQLite, Postgres and MySQL all expect it. Statements
+// that already have a LIMIT or FETCH clause, and statements that aren't a
+// single SELECT, are left untouched.
+func (q *Querier) LimitOne(enabled bool) {
+	q.limitOne = enabled
+}
+
+// limitOneStatement adds LIMIT 1 to the SELECT statement, unless it already
+// limits its rows.
+func limitOneStatement(stmt string) string {
+	if !isSingleSelect(stmt) {
+		return stmt
+	}
+
+	end := len(strings.TrimRight(stmt, " \t\r\n;"))
+	insert := end
+	for _, keyword := range clauseKeywords(stmt[:end]) {
+		switch keyword.word {
+		case "LIMIT", "FETCH":
+			return stmt
+		case "OFFSET", "FOR":
+			insert = min(insert, keyword.start)
+		}
+	}
+
+	if insert < end {
+		return stmt[:insert] + "LIMIT 1 " + stmt[insert:]
+	}
+	// A trailing line comment would swallow the clause, so the clause is
+	// placed on a line of its own.
+	separator := " "
+	if strings.Contains(stmt[strings.LastIndex(stmt[:end], "\n")+1:end], "--") {
+		separator = "\n"
+	}
+	return stmt[:end] + separator + "LIMIT 1" + stmt[end:]
+}
@@ -0,0 +1,80 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitOneStatement(t *testing.T) {
+	for stmt, expected := range map[string]string{
+		`SELECT name FROM people;`:                                `SELECT name FROM people LIMIT 1;`,
+		`SELECT name FROM people ORDER BY name`:                   `SELECT name FROM people ORDER BY name LIMIT 1`,
+		`SELECT name FROM people LIMIT 10;`:                       `SELECT name FROM people LIMIT 10;`,
+		`SELECT name FROM people limit 10;`:                       `SELECT name FROM people limit 10;`,
+		`SELECT name FROM people FETCH FIRST 2 ROWS ONLY;`:        `SELECT name FROM people FETCH FIRST 2 ROWS ONLY;`,
+		`SELECT name FROM people OFFSET 5;`:                       `SELECT name FROM people LIMIT 1 OFFSET 5;`,
+		`SELECT name FROM people WHERE id=1 FOR UPDATE;`:          `SELECT name FROM people WHERE id=1 LIMIT 1 FOR UPDATE;`,
+		`SELECT name FROM (SELECT name FROM people LIMIT 5) p;`:   `SELECT name FROM (SELECT name FROM people LIMIT 5) p LIMIT 1;`,
+		`SELECT name FROM people WHERE name='LIMIT';`:             `SELECT name FROM people WHERE name='LIMIT' LIMIT 1;`,
+		"SELECT name FROM people -- everyone\n":                   "SELECT name FROM people -- everyone\nLIMIT 1\n",
+		`WITH p AS (SELECT name FROM people) SELECT name FROM p;`: `WITH p AS (SELECT name FROM people) SELECT name FROM p LIMIT 1;`,
+		`INSERT INTO people SELECT name FROM archive;`:            `INSERT INTO people SELECT name FROM archive;`,
+		`SELECT 1; SELECT 2;`:                                     `SELECT 1; SELECT 2;`,
+	} {
+		assert.Equal(t, limitOneStatement(stmt), expected, stmt)
+	}
+}
+
+func TestQuerierLimitOne(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var statements []string
+
+	querier := NewQuerier()
+	querier.LimitOne(true)
+	querier.Hook(func(stmt string) error {
+		statements = append(statements, stmt)
+		return nil
+	})
+
+	var (
+		person  argsPerson
+		persons []argsPerson
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		if err != nil {
+			return err
+		}
+		if err := getter.Query(tx, `SELECT {argsPerson} FROM people ORDER BY age DESC;`); err != nil {
+			return err
+		}
+
+		// ForMany queries aren't limited.
+		getter, err = querier.ForMany(&persons)
+		if err != nil {
+			return err
+		}
+		if err := getter.Query(tx, `SELECT {argsPerson} FROM people ORDER BY age DESC;`); err != nil {
+			return err
+		}
+
+		// Prepared statements are limited when they populate a single
+		// destination.
+		stmt, err := querier.Prepare(`SELECT {argsPerson} FROM people WHERE age<:age;`, argsPerson{})
+		if err != nil {
+			return err
+		}
+		return stmt.Query(tx, &person, map[string]interface{}{"age": 22})
+	})
+
+	assert.Equal(t, person, argsPerson{Name: "fred", Age: 21})
+	assert.Len(t, persons, 3)
+	assert.Equal(t, statements, []string{
+		"SELECT age, name FROM people ORDER BY age DESC LIMIT 1;",
+		"SELECT age, name FROM people ORDER BY age DESC;",
+		"SELECT age, name FROM people WHERE age<:age LIMIT 1;",
+	})
+}
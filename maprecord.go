@@ -0,0 +1,108 @@
+package sqlair
+
+import (
+	"reflect"
+	"strings"
+)
+
+// M is a map destination, for queries that don't declare a struct for their
+// columns. Record expressions select the columns of a map destination by the
+// name M, or the alias of the map (see As):
+//
+//  row := sqlair.M{}
+//  getter, err := querier.ForOne(&row)
+//  ...
+//  err = getter.Query(tx, `SELECT {test.* INTO M} FROM test WHERE id=:id;`, args)
+//
+// A wildcard selects every column of the table ({test.* INTO M} becomes
+// test.*), and the columns of a record are selected as they're written
+// ({test.name, test.age INTO M} becomes test.name, test.age). Every column is
+// stored in the map by the name that the database returns for it, so columns
+// of the same name overwrite each other. Maps of a named type are selected by
+// the name of their type instead of M.
+//
+// As M is a map[string]interface{}, it can also be passed as the named
+// arguments of a statement (see Args).
+type M = map[string]interface{}
+
+// mapEntityName returns the name that record expressions select a map
+// destination with.
+func mapEntityName(typ reflect.Type, alias string) string {
+	switch {
+	case alias != "":
+		return alias
+	case typ.Name() != "":
+		return typ.Name()
+	}
+	return "M"
+}
+
+// compileMapStatement expands the record expressions of the statement for a
+// map destination. As a map doesn't have any fields, the records are expanded
+// into the columns that are written in them.
+func compileMapStatement(stmt, name string) (string, []recordBinding, error) {
+	offset := indexOfRecordArgs(stmt)
+	if offset < 0 {
+		return stmt, nil, nil
+	}
+	records, err := parseRecords(stmt, offset)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		builder strings.Builder
+		last    int
+		columns []string
+	)
+	for i, record := range records {
+		text := stmt[record.start:record.end]
+		switch {
+		case record.where:
+			return "", nil, syntaxError(stmt, record.start, "unexpected where clause %q without a filter argument", text)
+		case record.orderBy:
+			return "", nil, syntaxError(stmt, record.start, "unexpected order clause %q outside of a query", text)
+		case record.entityName() != name:
+			return "", nil, &offsetError{
+				err: &UnknownEntityError{
+					Name: record.entityName(),
+				},
+				offset: record.start,
+			}
+		case record.table:
+			return "", nil, syntaxError(stmt, record.start, "unexpected table placeholder %q for map destination", text)
+		case len(record.exclude) > 0:
+			return "", nil, syntaxError(stmt, record.start, "unexpected exclude in record expression %q for map destination", text)
+		}
+
+		if record.wildcard {
+			columns = append(columns, qualifyColumn(record.qualifier, "*"))
+		}
+		for _, name := range record.order {
+			if expr, ok := record.expressions[name]; ok {
+				columns = append(columns, expr+" AS "+name)
+				continue
+			}
+			columns = append(columns, qualifyColumn(record.qualifier, name))
+		}
+		// The records of a record expression that selects from more than
+		// one table share its span.
+		if i+1 < len(records) && records[i+1].start == record.start {
+			continue
+		}
+
+		builder.WriteString(stmt[last:record.start])
+		builder.WriteString(strings.Join(columns, ", "))
+		last = record.end
+		columns = columns[:0]
+	}
+	builder.WriteString(stmt[last:])
+	return builder.String(), records, nil
+}
+
+func qualifyColumn(qualifier, column string) string {
+	if qualifier == "" {
+		return column
+	}
+	return qualifier + "." + column
+}
@@ -0,0 +1,101 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileMapStatement(t *testing.T) {
+	for stmt, expected := range map[string]string{
+		`SELECT {M} FROM test;`:                                   `SELECT * FROM test;`,
+		`SELECT {test.* INTO M} FROM test;`:                       `SELECT test.* FROM test;`,
+		`SELECT {t.name, t.age INTO M} FROM test AS t;`:           `SELECT t.name, t.age FROM test AS t;`,
+		`SELECT {test.name, COUNT(*) AS total INTO M} FROM test;`: `SELECT test.name, COUNT(*) AS total FROM test;`,
+		`SELECT name FROM test;`:                                  `SELECT name FROM test;`,
+	} {
+		compiled, _, err := compileMapStatement(stmt, "M")
+		assert.Nil(t, err, stmt)
+		assert.Equal(t, compiled, expected, stmt)
+	}
+}
+
+func TestCompileMapStatementErrors(t *testing.T) {
+	for stmt, expected := range map[string]string{
+		`SELECT {Person} FROM test;`:                   `no entity found with the name "Person"`,
+		`SELECT {M.table} FROM test;`:                  `unexpected table placeholder "{M.table}" for map destination`,
+		`SELECT {test.* INTO M EXCEPT age} FROM test;`: `unexpected exclude in record expression "{test.* INTO M EXCEPT age}" for map destination`,
+	} {
+		_, _, err := compileMapStatement(stmt, "M")
+		if assert.NotNil(t, err, stmt) {
+			assert.Contains(t, err.Error(), expected, stmt)
+		}
+	}
+}
+
+func TestQueryWithMapRecord(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	row := M{}
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&row)
+		if err != nil {
+			return err
+		}
+		return getter.Query(tx, `SELECT {people.* INTO M} FROM people WHERE name=:name;`, sql.Named("name", "fred"))
+	})
+
+	assert.Equal(t, row, M{
+		"name": "fred",
+		"age":  int64(21),
+	})
+	assert.Equal(t, processedStmt, "SELECT people.* FROM people WHERE name=:name;")
+}
+
+func TestQueryWithAliasedMapRecord(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	type Row map[string]interface{}
+
+	var (
+		named   = Row{}
+		aliased = map[string]interface{}{}
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		querier := NewQuerier()
+
+		getter, err := querier.ForOne(&named)
+		if err != nil {
+			return err
+		}
+		if err := getter.Query(tx, `SELECT {p.name INTO Row} FROM people AS p WHERE name="jane";`); err != nil {
+			return err
+		}
+
+		getter, err = querier.ForOne(As("person", &aliased))
+		if err != nil {
+			return err
+		}
+		if err := getter.Query(tx, `SELECT {p.age, p.name INTO person} FROM people AS p WHERE name="frank";`); err != nil {
+			return err
+		}
+
+		// The record must select the map by its alias.
+		err = getter.Query(tx, `SELECT {p.age INTO M} FROM people AS p;`)
+		assert.True(t, errors.As(err, new(*UnknownEntityError)))
+		return nil
+	})
+
+	assert.Equal(t, named, Row{"name": "jane"})
+	assert.Equal(t, aliased, map[string]interface{}{"name": "frank", "age": int64(42)})
+}
@@ -0,0 +1,338 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/SimonRichardson/sqlair"
+	"github.com/pkg/errors"
+)
+
+// DefaultTable is the name of the table that records the applied migrations,
+// unless another is set with Migrator.Table.
+const DefaultTable = "schema_migrations"
+
+// Migration is a change to the schema of the database. A migration is either
+// SQL, which can contain several statements, or a Go function, which runs in
+// the transaction of the migration.
+//
+//  migrations.Migration{
+//  	Version: 1,
+//  	Name:    "create people",
+//  	Up:      `CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT);`,
+//  	Down:    `DROP TABLE people;`,
+//  }
+//
+// The SQL of a migration is executed as it's written, without expanding
+// record expressions or named arguments.
+type Migration struct {
+	// Version orders the migrations, and must be unique and greater than
+	// zero.
+	Version int64
+	Name    string
+	// Up and UpFunc apply the migration. Only one of them can be set.
+	Up     string
+	UpFunc func(*sqlair.TxQuerier) error
+	// Down and DownFunc revert the migration. If neither is set, the
+	// migration can't be reverted.
+	Down     string
+	DownFunc func(*sqlair.TxQuerier) error
+}
+
+// Reversible returns true if the migration can be reverted.
+func (m Migration) Reversible() bool {
+	return m.Down != "" || m.DownFunc != nil
+}
+
+// IrreversibleError is returned when a migration without a down migration
+// has to be reverted.
+type IrreversibleError struct {
+	Version int64
+	Name    string
+}
+
+func (e *IrreversibleError) Error() string {
+	return fmt.Sprintf("migration %d %q can't be reverted", e.Version, e.Name)
+}
+
+// CodeIrreversible is the code of an IrreversibleError.
+const CodeIrreversible sqlair.ErrorCode = "irreversible_migration"
+
+// Code returns CodeIrreversible.
+func (e *IrreversibleError) Code() sqlair.ErrorCode {
+	return CodeIrreversible
+}
+
+// Migrator applies and reverts migrations, recording the version of every
+// applied migration in a bookkeeping table. Every migration runs in its own
+// transaction, along with the update of the bookkeeping table, so a failed
+// migration leaves no trace on databases with transactional DDL.
+type Migrator struct {
+	db         *sql.DB
+	querier    *sqlair.Querier
+	migrations []Migration
+	table      string
+	dryRun     bool
+}
+
+// New creates a Migrator for the migrations, which are sorted by version.
+func New(db *sql.DB, querier *sqlair.Querier, migrations ...Migration) (*Migrator, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	for i, migration := range sorted {
+		if migration.Version <= 0 {
+			return nil, errors.Errorf("migration %q has invalid version %d", migration.Name, migration.Version)
+		}
+		if i > 0 && sorted[i-1].Version == migration.Version {
+			return nil, errors.Errorf("duplicate migration version %d", migration.Version)
+		}
+		if (migration.Up == "") == (migration.UpFunc == nil) {
+			return nil, errors.Errorf("migration %d %q expected either Up or UpFunc", migration.Version, migration.Name)
+		}
+		if migration.Down != "" && migration.DownFunc != nil {
+			return nil, errors.Errorf("migration %d %q expected either Down or DownFunc", migration.Version, migration.Name)
+		}
+	}
+
+	return &Migrator{
+		db:         db,
+		querier:    querier,
+		migrations: sorted,
+		table:      DefaultTable,
+	}, nil
+}
+
+// Table sets the name of the bookkeeping table.
+func (m *Migrator) Table(name string) {
+	m.table = name
+}
+
+// DryRun sets whether the migrator only reports the migrations that would be
+// applied or reverted, without running them.
+func (m *Migrator) DryRun(dryRun bool) {
+	m.dryRun = dryRun
+}
+
+// Applied returns the versions of the applied migrations, in order.
+func (m *Migrator) Applied(ctx context.Context) ([]int64, error) {
+	var versions []int64
+	err := m.readTx(ctx, func(tx *sqlair.TxQuerier) error {
+		var err error
+		versions, err = m.applied(tx)
+		return err
+	})
+	return versions, err
+}
+
+// Pending returns the migrations that haven't been applied, in order.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	return m.up(ctx, m.latest(), true)
+}
+
+// Up applies every migration that hasn't been applied, in order, returning
+// the migrations that were applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.UpTo(ctx, m.latest())
+}
+
+// UpTo applies the migrations that haven't been applied, up to and including
+// the version.
+func (m *Migrator) UpTo(ctx context.Context, version int64) ([]Migration, error) {
+	return m.up(ctx, version, m.dryRun)
+}
+
+// Down reverts the latest applied migration, returning the migration that
+// was reverted.
+func (m *Migrator) Down(ctx context.Context) ([]Migration, error) {
+	var previous int64
+	err := m.readTx(ctx, func(tx *sqlair.TxQuerier) error {
+		versions, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		if len(versions) > 1 {
+			previous = versions[len(versions)-2]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.DownTo(ctx, previous)
+}
+
+// DownTo reverts the applied migrations that are after the version, latest
+// first. A version of zero reverts every migration.
+func (m *Migrator) DownTo(ctx context.Context, version int64) ([]Migration, error) {
+	var reverting []Migration
+	err := m.readTx(ctx, func(tx *sqlair.TxQuerier) error {
+		versions, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		for i := len(versions) - 1; i >= 0 && versions[i] > version; i-- {
+			migration, ok := m.migration(versions[i])
+			if !ok {
+				return errors.Errorf("applied migration %d not found", versions[i])
+			}
+			if !migration.Reversible() {
+				return errors.WithStack(&IrreversibleError{
+					Version: migration.Version,
+					Name:    migration.Name,
+				})
+			}
+			reverting = append(reverting, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if m.dryRun {
+		return reverting, nil
+	}
+
+	for i, migration := range reverting {
+		if err := m.run(ctx, migration, migration.Down, migration.DownFunc,
+			`DELETE FROM `+m.table+` WHERE version=:version;`); err != nil {
+			return reverting[:i], errors.Wrapf(err, "reverting migration %d %q", migration.Version, migration.Name)
+		}
+	}
+	return reverting, nil
+}
+
+func (m *Migrator) up(ctx context.Context, version int64, dryRun bool) ([]Migration, error) {
+	var pending []Migration
+	err := m.readTx(ctx, func(tx *sqlair.TxQuerier) error {
+		versions, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		applied := make(map[int64]bool, len(versions))
+		for _, v := range versions {
+			applied[v] = true
+		}
+		for _, migration := range m.migrations {
+			if migration.Version <= version && !applied[migration.Version] {
+				pending = append(pending, migration)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return pending, nil
+	}
+
+	for i, migration := range pending {
+		if err := m.run(ctx, migration, migration.Up, migration.UpFunc,
+			`INSERT INTO `+m.table+` (version, name, applied_at) VALUES (:version, :name, :applied_at);`); err != nil {
+			return pending[:i], errors.Wrapf(err, "applying migration %d %q", migration.Version, migration.Name)
+		}
+	}
+	return pending, nil
+}
+
+// run executes the SQL or function of a migration, along with the statement
+// that records it, in a transaction.
+func (m *Migrator) run(ctx context.Context, migration Migration, stmt string, fn func(*sqlair.TxQuerier) error, record string) error {
+	return m.querier.Tx(ctx, m.db, func(tx *sqlair.TxQuerier) error {
+		if err := m.createTable(tx); err != nil {
+			return err
+		}
+		if fn != nil {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		} else if stmt != "" {
+			if _, err := tx.Tx().ExecContext(tx.Context(), stmt); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		_, err := tx.Exec(record, appliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC(),
+		})
+		return err
+	})
+}
+
+// errReadOnly rolls back the transactions that only read the bookkeeping
+// table, so that a dry run never changes the database.
+var errReadOnly = errors.New("read only")
+
+// readTx runs the function in a transaction that's always rolled back, after
+// making sure that the bookkeeping table exists.
+func (m *Migrator) readTx(ctx context.Context, fn func(*sqlair.TxQuerier) error) error {
+	err := m.querier.Tx(ctx, m.db, func(tx *sqlair.TxQuerier) error {
+		if err := m.createTable(tx); err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return errReadOnly
+	})
+	if err == errReadOnly {
+		return nil
+	}
+	return err
+}
+
+func (m *Migrator) createTable(tx *sqlair.TxQuerier) error {
+	_, err := tx.Tx().ExecContext(tx.Context(), `CREATE TABLE IF NOT EXISTS `+m.table+` (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+);`)
+	return errors.Wrap(err, "creating migrations table")
+}
+
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+func (m *Migrator) applied(tx *sqlair.TxQuerier) ([]int64, error) {
+	var applied []appliedMigration
+	query, err := m.querier.ForMany(&applied)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := tx.Query(query, `SELECT {appliedMigration} FROM `+m.table+` ORDER BY version;`); err != nil {
+		return nil, errors.Wrap(err, "reading applied migrations")
+	}
+
+	versions := make([]int64, len(applied))
+	for i, migration := range applied {
+		versions[i] = migration.Version
+	}
+	return versions, nil
+}
+
+func (m *Migrator) migration(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func (m *Migrator) latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
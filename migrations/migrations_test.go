@@ -0,0 +1,167 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/SimonRichardson/sqlair"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDB(t testing.TB) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	// Every connection to an in-memory database is a new database.
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+var testMigrations = []Migration{{
+	Version: 2,
+	Name:    "add people age",
+	Up:      `ALTER TABLE people ADD COLUMN age INTEGER NOT NULL DEFAULT 0;`,
+	Down:    `ALTER TABLE people DROP COLUMN age;`,
+}, {
+	Version: 1,
+	Name:    "create people",
+	Up: `CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT);
+CREATE INDEX idx_people_name ON people (name);`,
+	Down: `DROP TABLE people;`,
+}, {
+	Version: 3,
+	Name:    "insert fred",
+	UpFunc: func(tx *sqlair.TxQuerier) error {
+		_, err := tx.Exec(`INSERT INTO people (id, name, age) VALUES (?, ?, ?);`, 1, "fred", 21)
+		return err
+	},
+	DownFunc: func(tx *sqlair.TxQuerier) error {
+		_, err := tx.Exec(`DELETE FROM people WHERE name=?;`, "fred")
+		return err
+	},
+}}
+
+func versions(migrations []Migration) []int64 {
+	result := make([]int64, len(migrations))
+	for i, migration := range migrations {
+		result[i] = migration.Version
+	}
+	return result
+}
+
+func countPeople(t *testing.T, db *sql.DB) int {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM people;`).Scan(&count)
+	assert.Nil(t, err)
+	return count
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := setupDB(t)
+
+	migrator, err := New(db, sqlair.NewQuerier(), testMigrations...)
+	assert.Nil(t, err)
+
+	applied, err := migrator.UpTo(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, versions(applied), []int64{1, 2})
+
+	pending, err := migrator.Pending(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, versions(pending), []int64{3})
+
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, versions(applied), []int64{3})
+	assert.Equal(t, countPeople(t, db), 1)
+
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, 0)
+
+	reverted, err := migrator.Down(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, versions(reverted), []int64{3})
+	assert.Equal(t, countPeople(t, db), 0)
+
+	reverted, err = migrator.DownTo(ctx, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, versions(reverted), []int64{2, 1})
+
+	versions, err := migrator.Applied(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 0)
+
+	_, err = db.Exec(`SELECT * FROM people;`)
+	assert.NotNil(t, err)
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	ctx := context.Background()
+	db := setupDB(t)
+
+	migrator, err := New(db, sqlair.NewQuerier(), testMigrations...)
+	assert.Nil(t, err)
+	migrator.Table("migrations")
+
+	_, err = migrator.UpTo(ctx, 1)
+	assert.Nil(t, err)
+
+	migrator.DryRun(true)
+
+	applied, err := migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, versions(applied), []int64{2, 3})
+
+	reverted, err := migrator.DownTo(ctx, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, versions(reverted), []int64{1})
+
+	versions, err := migrator.Applied(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, versions, []int64{1})
+	assert.Equal(t, countPeople(t, db), 0)
+}
+
+func TestMigratorFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := setupDB(t)
+
+	migrator, err := New(db, sqlair.NewQuerier(), Migration{
+		Version: 1,
+		Name:    "create people",
+		Up:      `CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT);`,
+	}, Migration{
+		Version: 2,
+		Name:    "broken",
+		Up: `CREATE TABLE locations (id INTEGER PRIMARY KEY);
+INSERT INTO missing (id) VALUES (1);`,
+	})
+	assert.Nil(t, err)
+
+	applied, err := migrator.Up(ctx)
+	assert.Equal(t, err.Error(), `applying migration 2 "broken": no such table: missing`)
+	assert.Equal(t, versions(applied), []int64{1})
+
+	// The failed migration is rolled back.
+	_, err = db.Exec(`SELECT * FROM locations;`)
+	assert.NotNil(t, err)
+
+	_, err = migrator.Down(ctx)
+	assert.True(t, errors.As(err, new(*IrreversibleError)))
+	assert.Equal(t, sqlair.CodeOf(err), CodeIrreversible)
+}
+
+func TestNewWithInvalidMigrations(t *testing.T) {
+	_, err := New(nil, sqlair.NewQuerier(), Migration{Version: 1, Up: "x"}, Migration{Version: 1, Up: "y"})
+	assert.Equal(t, err.Error(), "duplicate migration version 1")
+
+	_, err = New(nil, sqlair.NewQuerier(), Migration{Version: 0, Name: "zero", Up: "x"})
+	assert.Equal(t, err.Error(), `migration "zero" has invalid version 0`)
+
+	_, err = New(nil, sqlair.NewQuerier(), Migration{Version: 1, Name: "empty"})
+	assert.Equal(t, err.Error(), `migration 1 "empty" expected either Up or UpFunc`)
+}
@@ -0,0 +1,115 @@
+package sqlair
+
+import (
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// nestedEntities returns the entities along with a new entity for every
+// record that binds into a nested struct field of an entity, for example
+// `{location.* INTO Person.Address}`. The nested entity is named after the
+// full path of the record, so that expansion and mapping treat it like any
+// other entity.
+//
+// The path is made up of the Go field names, so a nested struct can be
+// excluded from the parent columns with a "-" tag and still be bound.
+func (q Query) nestedEntities(records []recordBinding, entities []sreflect.ReflectStruct) ([]sreflect.ReflectStruct, error) {
+	result := entities
+	seen := make(map[string]bool)
+	for _, record := range records {
+		parts := strings.Split(record.name, ".")
+		if len(parts) == 1 || seen[record.entityName()] {
+			continue
+		}
+		seen[record.entityName()] = true
+
+		var (
+			parent sreflect.ReflectStruct
+			found  bool
+		)
+		for _, entity := range entities {
+			if entity.Name == parts[0] {
+				parent = entity
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, &offsetError{
+				err: &UnknownEntityError{
+					Name: parts[0],
+				},
+				offset: record.start,
+			}
+		}
+
+		nested, err := q.resolvePath(parent, parts[1:])
+		if err != nil {
+			return nil, &offsetError{
+				err:    err,
+				offset: record.start,
+			}
+		}
+		nested.Name = record.entityName()
+
+		// Copy the entities so that we never append to the slice owned by the
+		// caller.
+		if len(result) == len(entities) {
+			result = append([]sreflect.ReflectStruct(nil), entities...)
+		}
+		result = append(result, nested)
+	}
+	return result, nil
+}
+
+// resolvePath walks the struct fields of the entity by name, returning the
+// reflected nested struct. Nil struct pointers along the path are allocated.
+func (q Query) resolvePath(entity sreflect.ReflectStruct, path []string) (sreflect.ReflectStruct, error) {
+	value := entity.Value
+	for i, name := range path {
+		field := value.FieldByName(name)
+		if !field.IsValid() {
+			return sreflect.ReflectStruct{}, &MissingFieldError{
+				Entity: strings.Join(append([]string{entity.Name}, path[:i]...), "."),
+				Field:  name,
+			}
+		}
+		// A one-to-many relation binds the record to a new child, which is
+		// folded into the slice of the parent once it's scanned.
+		if field.Kind() == reflect.Slice {
+			relation, err := q.relationField(value, name)
+			if err != nil {
+				return sreflect.ReflectStruct{}, err
+			}
+			if relation != nil {
+				if i != len(path)-1 {
+					return sreflect.ReflectStruct{}, errors.Errorf("unexpected field after one-to-many field %q", name)
+				}
+				value = reflect.New(relationElem(relation.Value.Type())).Elem()
+				break
+			}
+		}
+		if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct {
+			if field.IsNil() {
+				if !field.CanSet() {
+					return sreflect.ReflectStruct{}, errors.Errorf("unable to allocate field %q", name)
+				}
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		if field.Kind() != reflect.Struct {
+			return sreflect.ReflectStruct{}, errors.Errorf("expected field %q to be a struct, got %q", name, field.Kind())
+		}
+		value = field
+	}
+
+	info, err := q.reflect.Reflect(value.Addr().Interface())
+	if err != nil {
+		return sreflect.ReflectStruct{}, err
+	}
+	return info.(sreflect.ReflectStruct), nil
+}
@@ -0,0 +1,113 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// nestedSchema is a table of people with the location that they live in.
+const nestedSchema = `
+CREATE TABLE people(
+	name     TEXT,
+	age      INTEGER,
+	location INTEGER
+);
+CREATE TABLE location(
+	id   INTEGER,
+	city TEXT
+);
+INSERT INTO people(name, age, location) values ("fred", 21, 1), ("frank", 42, 2), ("jane", 23, 1);
+INSERT INTO location(id, city) values (1, "london"), (2, "paris");
+`
+
+func TestQueryWithNestedRecord(t *testing.T) {
+	db := setupSchemaDB(t, nestedSchema)
+
+	type Address struct {
+		City string `db:"city"`
+	}
+	type Person struct {
+		Name    string  `db:"name"`
+		Age     int     `db:"age"`
+		Address Address `db:"-"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person}, {location.* INTO Person.Address} FROM people INNER JOIN location ON people.location=location.id WHERE people.name=:name;`, map[string]interface{}{
+			"name": "fred",
+		})
+	})
+
+	assert.Equal(t, person, Person{Name: "fred", Age: 21, Address: Address{City: "london"}})
+
+	expected := "SELECT people.age, people.name, location.city FROM people INNER JOIN location ON people.location=location.id WHERE people.name=:name;"
+	assert.Equal(t, processedStmt, expected)
+}
+
+func TestQueryWithNestedPointerRecordSlice(t *testing.T) {
+	db := setupSchemaDB(t, nestedSchema)
+
+	type Address struct {
+		City string `db:"city"`
+	}
+	type Person struct {
+		Name    string   `db:"name"`
+		Address *Address `db:"-"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.name INTO Person}, {location.* INTO Person.Address} FROM people INNER JOIN location ON people.location=location.id ORDER BY people.name;`)
+	})
+
+	assert.Equal(t, persons, []Person{
+		{Name: "frank", Address: &Address{City: "paris"}},
+		{Name: "fred", Address: &Address{City: "london"}},
+		{Name: "jane", Address: &Address{City: "london"}},
+	})
+	assert.True(t, persons[1].Address != persons[2].Address)
+}
+
+func TestQueryWithNestedRecordMissingField(t *testing.T) {
+	db := setupSchemaDB(t, nestedSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+
+	var person Person
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {location.* INTO Person.Address} FROM location;`)
+	assert.Equal(t, errors.Cause(err).Error(), `field "Address" not found in entity "Person"`)
+
+	err = getter.Query(tx, `SELECT {location.* INTO Person.Name} FROM location;`)
+	assert.Equal(t, errors.Cause(err).Error(), `expected field "Name" to be a struct, got "string"`)
+}
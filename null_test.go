@@ -0,0 +1,136 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNullableValue(t *testing.T) {
+	assert.Nil(t, nullableValue(&sql.NullString{}))
+	assert.Nil(t, nullableValue(&sql.NullInt64{}))
+	assert.Nil(t, nullableValue(&nullBool{}))
+	assert.Nil(t, nullableValue(&sql.NullFloat64{}))
+	assert.Nil(t, nullableValue(new([]byte)))
+
+	assert.Equal(t, nullableValue(&sql.NullString{String: "fred", Valid: true}), "fred")
+	assert.Equal(t, nullableValue(&sql.NullInt64{Int64: 42, Valid: true}), int64(42))
+	assert.Equal(t, nullableValue(&nullBool{Bool: true, Valid: true}), true)
+	assert.Equal(t, nullableValue(&sql.NullFloat64{Float64: 4.2, Valid: true}), 4.2)
+	assert.Equal(t, nullableValue(&[]byte{1}), []byte{1})
+}
+
+func TestQueryWithMapAndNull(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO test(name, age) values ("fred", NULL);
+	`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	person := make(map[string]interface{})
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, "SELECT name, age FROM test WHERE name=:name;", map[string]interface{}{
+			"name": "fred",
+		})
+	})
+
+	assert.Equal(t, person, map[string]interface{}{
+		"name": "fred",
+		"age":  nil,
+	})
+}
+
+func TestQueryWithStructAndNullableFields(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name     TEXT,
+	age      INTEGER,
+	location INTEGER
+);
+CREATE TABLE location(
+	id   INTEGER,
+	city TEXT
+);
+INSERT INTO people(name, age, location) values ("fred", 21, 1), ("frank", NULL, 2);
+INSERT INTO location(id, city) values (1, "london");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  *int64 `db:"age"`
+	}
+	type Location struct {
+		City sql.NullString `db:"city"`
+	}
+
+	querier := NewQuerier()
+
+	var (
+		persons   []Person
+		locations []Location
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons, &locations)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person}, {location.* INTO Location} FROM people LEFT OUTER JOIN location ON people.location=location.id ORDER BY people.name;`)
+	})
+
+	age := int64(21)
+	assert.Equal(t, persons, []Person{
+		{Name: "frank"},
+		{Name: "fred", Age: &age},
+	})
+	assert.Equal(t, locations, []Location{
+		{},
+		{City: sql.NullString{String: "london", Valid: true}},
+	})
+}
+
+func TestExecWithNullableFields(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER
+);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name *string `db:"name"`
+		Age  *int64  `db:"age"`
+	}
+
+	querier := NewQuerier()
+
+	name := "fred"
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := querier.Exec(tx, "INSERT INTO test(name, age) VALUES (:name, :age);", Person{Name: &name}); err != nil {
+			return err
+		}
+
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person} FROM test;`)
+	})
+
+	assert.Equal(t, person, Person{Name: &name})
+}
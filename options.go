@@ -0,0 +1,127 @@
+package sqlair
+
+import (
+	"reflect"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+)
+
+// QueryOption configures a single query created by ForOne or ForMany. Options
+// are passed along with the values of the query:
+//
+//  getter, err := querier.ForOne(&person, sqlair.Strict())
+//
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	strict         bool
+	lenient        bool
+	capacity       int
+	replace        bool
+	excludeDeleted bool
+	includeDeleted bool
+	limitOne       bool
+	partial        bool
+	defaults       map[string]interface{}
+}
+
+// Strict returns an option that errors if any field of the destination types
+// didn't receive a column, as well as if any column didn't have a destination
+// field. This catches typos in tags and schemas that have drifted from the
+// types.
+func Strict() QueryOption {
+	return func(o *queryOptions) {
+		o.strict = true
+	}
+}
+
+// Lenient returns an option that discards any column that doesn't have a
+// destination field, rather than returning an error. This is useful when
+// querying views that have more columns than the destination types.
+//
+// Lenient can be combined with Strict, in which case every field of the
+// destination types must still receive a column.
+func Lenient() QueryOption {
+	return func(o *queryOptions) {
+		o.lenient = true
+	}
+}
+
+// Capacity returns an option that grows the destination slices of ForMany, so
+// that they can hold the number of rows without reallocating while scanning.
+// Slices that already have the spare capacity are left untouched, which allows
+// a slice to be reused by truncating it:
+//
+//  persons = persons[:0]
+//  getter, err := querier.ForMany(&persons, sqlair.Capacity(1000))
+//
+// The capacity is only a hint, more rows than the capacity can still be
+// scanned.
+func Capacity(n int) QueryOption {
+	return func(o *queryOptions) {
+		o.capacity = n
+	}
+}
+
+// Replace returns an option that truncates the destination slices of ForMany
+// before every query, so that the slices only hold the rows of the latest
+// query. Without it, the rows are appended to the slices, keeping the rows of
+// any previous queries:
+//
+//  getter, err := querier.ForMany(&persons, sqlair.Replace())
+//
+// The backing arrays of the slices are reused, so the elements of a previous
+// query are overwritten.
+func Replace() QueryOption {
+	return func(o *queryOptions) {
+		o.replace = true
+	}
+}
+
+// Defaults returns an option that binds the named arguments that aren't
+// provided by any argument of the query, rather than returning a
+// MissingArgumentError. A missing named argument is bound to its value in the
+// defaults, or to NULL if the defaults don't have it:
+//
+//  getter, err := querier.ForMany(&persons, sqlair.Defaults(sqlair.M{"limit": 10}))
+//  ...
+//  err = getter.Query(tx, `SELECT {Person} FROM people WHERE :name IS NULL OR name=:name LIMIT :limit;`, args)
+//
+// This allows dynamic statements to leave optional named arguments unset.
+// Dotted named arguments are looked up in the defaults by their full name
+// (page.offset). Passing nil binds every missing named argument to NULL.
+func Defaults(defaults map[string]interface{}) QueryOption {
+	return func(o *queryOptions) {
+		o.partial = true
+		if o.defaults == nil {
+			o.defaults = make(map[string]interface{}, len(defaults))
+		}
+		for name, value := range defaults {
+			o.defaults[name] = value
+		}
+	}
+}
+
+// truncateSlices sets the length of the destination slices of the query to
+// zero.
+func (q Query) truncateSlices() {
+	for _, entity := range q.entities {
+		if value, ok := entity.(sreflect.ReflectValue); ok && value.Value.Kind() == reflect.Slice {
+			value.Value.SetLen(0)
+		}
+	}
+}
+
+// splitOptions separates the query options from the values.
+func splitOptions(values []interface{}) ([]interface{}, queryOptions) {
+	var options queryOptions
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		if option, ok := value.(QueryOption); ok {
+			option(&options)
+			continue
+		}
+		result = append(result, value)
+	}
+	return result, options
+}
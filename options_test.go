@@ -0,0 +1,354 @@
+package sqlair
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// optionsSchema is a table of two people.
+const optionsSchema = `
+CREATE TABLE people(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO people(name, age) values ("fred", 21), ("frank", 42);
+`
+
+func TestSplitOptions(t *testing.T) {
+	var person struct{}
+	values, options := splitOptions([]interface{}{&person, Strict()})
+	assert.Equal(t, values, []interface{}{&person})
+	assert.True(t, options.strict)
+
+	values, options = splitOptions([]interface{}{&person})
+	assert.Equal(t, values, []interface{}{&person})
+	assert.False(t, options.strict)
+}
+
+func TestQueryWithStrictMapping(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	querier := NewQuerier()
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person, Strict())
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person} FROM people WHERE name=:name;`, map[string]interface{}{
+			"name": "fred",
+		})
+	})
+
+	assert.Equal(t, person, Person{Name: "fred", Age: 21})
+}
+
+func TestQueryWithStrictMappingMissingColumn(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"agee"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []Person
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForMany(&persons, Strict())
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT name FROM people;`)
+	assert.Equal(t, errors.Cause(err).Error(), `missing column for field "agee" in entity "Person"`)
+
+	// Without the strict option, the field is left as the zero value.
+	getter, err = querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT name FROM people;`)
+	assert.Nil(t, err)
+	assert.Equal(t, persons, []Person{{Name: "fred"}, {Name: "frank"}})
+}
+
+func TestQueryWithStrictMappingExcludedField(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name     string `db:"name"`
+		Age      int    `db:"age"`
+		Password string `db:"password"`
+	}
+
+	querier := NewQuerier()
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person, Strict())
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {Person EXCEPT password} FROM people WHERE name=:name;`, map[string]interface{}{
+			"name": "frank",
+		})
+	})
+
+	assert.Equal(t, person, Person{Name: "frank", Age: 42})
+}
+
+func TestQueryWithLenientMapping(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []Person
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT name, age FROM people ORDER BY name;`)
+	assert.Equal(t, errors.Cause(err).Error(), `missing destination name "age" in types [Person]`)
+
+	getter, err = querier.ForMany(&persons, Lenient())
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT name, age, age * 2 AS double FROM people ORDER BY name;`)
+	assert.Nil(t, err)
+	assert.Equal(t, persons, []Person{{Name: "frank"}, {Name: "fred"}})
+}
+
+func TestQueryWithLenientAndStrictMapping(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name  string `db:"name"`
+		Email string `db:"email"`
+	}
+
+	querier := NewQuerier()
+
+	var person Person
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&person, Lenient(), Strict())
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT name, age FROM people;`)
+	assert.Equal(t, errors.Cause(err).Error(), `missing column for field "email" in entity "Person"`)
+}
+
+func TestQueryWithLenientAliasedColumns(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+	querier.AliasColumns(true)
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person, Lenient())
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person}, age FROM people WHERE name=:name;`, map[string]interface{}{
+			"name": "fred",
+		})
+	})
+
+	assert.Equal(t, person, Person{Name: "fred"})
+}
+
+func TestQueryManyWithCapacity(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons, Capacity(10))
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person} FROM people ORDER BY people.name;`)
+	})
+
+	assert.Equal(t, persons, []Person{
+		{Name: "frank", Age: 42},
+		{Name: "fred", Age: 21},
+	})
+	assert.Equal(t, cap(persons), 10)
+}
+
+func TestQueryManyWithCapacityReusesSlice(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	querier := NewQuerier()
+
+	persons := make([]Person, 1, 5)
+	backing := &persons[:1][0]
+	persons = persons[:0]
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons, Capacity(2))
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person} FROM people ORDER BY people.name;`)
+	})
+
+	assert.Equal(t, len(persons), 2)
+	assert.Equal(t, cap(persons), 5)
+	assert.True(t, &persons[0] == backing)
+}
+
+func TestQueryManyWithReplace(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	querier := NewQuerier()
+
+	var appended, replaced []Person
+	appendGetter, err := querier.ForMany(&appended)
+	assert.Nil(t, err)
+	replaceGetter, err := querier.ForMany(&replaced, Replace())
+	assert.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		runTx(t, db, func(tx *sql.Tx) error {
+			if err := appendGetter.Query(tx, `SELECT {people.* INTO Person} FROM people WHERE people.name=:name;`, Person{Name: "fred"}); err != nil {
+				return err
+			}
+			return replaceGetter.Query(tx, `SELECT {people.* INTO Person} FROM people WHERE people.name=:name;`, Person{Name: "fred"})
+		})
+	}
+
+	assert.Equal(t, appended, []Person{
+		{Name: "fred", Age: 21},
+		{Name: "fred", Age: 21},
+	})
+	assert.Equal(t, replaced, []Person{
+		{Name: "fred", Age: 21},
+	})
+
+	// A cached query replaces the slice in the same way.
+	cached := replaceGetter.Cached(time.Minute)
+	for i := 0; i < 2; i++ {
+		runTx(t, db, func(tx *sql.Tx) error {
+			return cached.Query(tx, `SELECT {people.* INTO Person} FROM people ORDER BY people.name;`)
+		})
+	}
+	assert.Equal(t, replaced, []Person{
+		{Name: "frank", Age: 42},
+		{Name: "fred", Age: 21},
+	})
+}
+
+func TestGrowSlice(t *testing.T) {
+	values := []int{1, 2}
+	slice := reflect.ValueOf(&values).Elem()
+
+	growSlice(slice, 3)
+	assert.Equal(t, values, []int{1, 2})
+	assert.Equal(t, cap(values), 5)
+
+	growSlice(slice, 2)
+	assert.Equal(t, cap(values), 5)
+}
+
+func TestQueryWithDefaults(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	querier := NewQuerier()
+
+	stmt := `SELECT {Person} FROM people WHERE (:name IS NULL OR name=:name) AND age>=:age ORDER BY name;`
+
+	var persons []Person
+	getter, err := querier.ForMany(&persons, Defaults(M{"age": 0}), Replace())
+	assert.Nil(t, err)
+
+	// The missing name is bound to NULL, and the missing age to its default.
+	runTx(t, db, func(tx *sql.Tx) error {
+		return getter.Query(tx, stmt, M{})
+	})
+	assert.Equal(t, persons, []Person{
+		{Name: "frank", Age: 42},
+		{Name: "fred", Age: 21},
+	})
+
+	// The arguments take precedence over the defaults.
+	runTx(t, db, func(tx *sql.Tx) error {
+		return getter.Query(tx, stmt, M{"name": "fred", "age": 20})
+	})
+	assert.Equal(t, persons, []Person{
+		{Name: "fred", Age: 21},
+	})
+
+	// Without arguments, every named argument is bound to its default.
+	runTx(t, db, func(tx *sql.Tx) error {
+		return getter.Query(tx, stmt)
+	})
+	assert.Equal(t, persons, []Person{
+		{Name: "frank", Age: 42},
+		{Name: "fred", Age: 21},
+	})
+}
+
+func TestQueryWithoutDefaults(t *testing.T) {
+	db := setupSchemaDB(t, optionsSchema)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []Person
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	err = getter.Query(tx, `SELECT {Person} FROM people WHERE :name IS NULL OR name=:name;`, M{})
+	assert.True(t, errors.As(err, new(*MissingArgumentError)))
+}
@@ -0,0 +1,24 @@
+package sqlair
+
+// ColumnOrder defines the order that the columns of a record expression are
+// expanded in.
+type ColumnOrder int
+
+const (
+	// SortedOrder expands the columns of a record sorted by name. This is the
+	// default order.
+	SortedOrder ColumnOrder = iota
+	// DeclarationOrder expands the columns of a wildcard record in the order
+	// that the fields are declared in the struct, and the columns of a
+	// non-wildcard record in the order that they're written.
+	DeclarationOrder
+)
+
+// ColumnOrder changes the order that the columns of a record expression are
+// expanded in.
+//
+// Changing the column order resets the statement cache for this querier.
+func (q *Querier) ColumnOrder(order ColumnOrder) {
+	q.order = order
+	q.stmtCache = newStatementCache()
+}
@@ -0,0 +1,104 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryWithDeclarationOrder(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name     TEXT,
+	age      INTEGER,
+	location INTEGER
+);
+CREATE TABLE location(
+	id   INTEGER,
+	city TEXT
+);
+INSERT INTO people(name, age, location) values ("fred", 21, 1);
+INSERT INTO location(id, city) values (1, "london");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name     string `db:"name"`
+		Location int    `db:"location"`
+		Age      int    `db:"age"`
+	}
+	type Location struct {
+		ID   int    `db:"id"`
+		City string `db:"city"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.ColumnOrder(DeclarationOrder)
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var (
+		person   Person
+		location Location
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person, &location)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person}, {location.city, location.id INTO Location} FROM people INNER JOIN location ON people.location=location.id;`)
+	})
+
+	assert.Equal(t, person, Person{Name: "fred", Location: 1, Age: 21})
+	assert.Equal(t, location, Location{ID: 1, City: "london"})
+
+	expected := "SELECT people.name, people.location, people.age, location.city, location.id FROM people INNER JOIN location ON people.location=location.id;"
+	assert.Equal(t, processedStmt, expected)
+}
+
+func TestQueryWithDeclarationOrderAndExpressions(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO people(name, age) values ("fred", 21), ("fred", 23);
+	`)
+	assert.Nil(t, err)
+
+	type Summary struct {
+		Total int    `db:"total"`
+		Name  string `db:"name"`
+		Age   int    `db:"age"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.ColumnOrder(DeclarationOrder)
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var summary Summary
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&summary)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.*, COUNT(*) AS total INTO Summary EXCEPT age} FROM people GROUP BY people.name;`)
+	})
+
+	assert.Equal(t, summary, Summary{Total: 2, Name: "fred"})
+
+	expected := "SELECT people.name, COUNT(*) AS total FROM people GROUP BY people.name;"
+	assert.Equal(t, processedStmt, expected)
+}
@@ -0,0 +1,129 @@
+package sqlair
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// OrderBy orders the rows of a query by a column. The OrderBy arguments of a
+// query replace the order clause of the statement ({order}), which allows the
+// rows to be sorted by a column that's chosen at runtime, such as the sort
+// parameter of a request:
+//
+//  getter.Query(tx, `SELECT {Person} FROM people {order} LIMIT 10;`, sqlair.OrderBy{Column: sort, Desc: true})
+//
+// The column must be one of the columns of the destinations of the query,
+// otherwise an OrderByError is returned, so the column can't be used to inject
+// SQL into the statement. The rows are ordered by the columns in the order
+// that they're passed, and a slice of OrderBy can be passed as well. The order
+// clause is removed if there aren't any OrderBy arguments.
+type OrderBy struct {
+	Column string
+	// Desc orders the rows in descending order, rather than ascending order.
+	Desc bool
+}
+
+// expandOrderBy replaces the order clauses of the statement with the columns
+// of the OrderBy arguments, which are removed from the arguments.
+func (q Query) expandOrderBy(stmt string, args []interface{}) (string, []interface{}, error) {
+	var (
+		orders []OrderBy
+		rest   = make([]interface{}, 0, len(args))
+	)
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case OrderBy:
+			orders = append(orders, arg)
+		case []OrderBy:
+			orders = append(orders, arg...)
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if !strings.Contains(stmt, "{order}") {
+		if len(rest) != len(args) {
+			return "", nil, errors.Errorf("missing {order} clause for OrderBy arguments")
+		}
+		return stmt, args, nil
+	}
+
+	records, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var clause string
+	if len(orders) > 0 {
+		columns, err := q.destinationColumns()
+		if err != nil {
+			return "", nil, err
+		}
+		terms := make([]string, len(orders))
+		for i, order := range orders {
+			index := sort.SearchStrings(columns, order.Column)
+			if index == len(columns) || columns[index] != order.Column {
+				return "", nil, errors.WithStack(&OrderByError{
+					Column:  order.Column,
+					Columns: columns,
+				})
+			}
+			terms[i] = order.Column
+			if order.Desc {
+				terms[i] += " DESC"
+			}
+		}
+		clause = "ORDER BY " + strings.Join(terms, ", ")
+	}
+
+	var (
+		builder strings.Builder
+		last    int
+	)
+	for _, record := range records {
+		if !record.orderBy {
+			continue
+		}
+		builder.WriteString(stmt[last:record.start])
+		builder.WriteString(clause)
+		last = record.end
+	}
+	builder.WriteString(stmt[last:])
+	return builder.String(), rest, nil
+}
+
+// destinationColumns returns the sorted columns of the struct destinations of
+// the query.
+func (q Query) destinationColumns() ([]string, error) {
+	seen := make(map[string]bool)
+	for _, entity := range q.entities {
+		var refStruct sreflect.ReflectStruct
+		switch entity := entity.(type) {
+		case sreflect.ReflectStruct:
+			refStruct = entity
+		case sreflect.ReflectValue:
+			typ := entity.Value.Type()
+			if typ.Kind() != reflect.Slice || typ.Elem().Kind() != reflect.Struct {
+				continue
+			}
+			info, err := q.reflect.Reflect(reflect.New(typ.Elem()).Interface())
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			refStruct = info.(sreflect.ReflectStruct)
+		}
+		for name := range refStruct.Fields {
+			seen[name] = true
+		}
+	}
+
+	columns := make([]string, 0, len(seen))
+	for name := range seen {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	return columns, nil
+}
@@ -0,0 +1,97 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type orderPerson struct {
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func TestQueryWithOrderBy(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people (name TEXT, age INTEGER);
+INSERT INTO people (name, age) VALUES ("fred", 21), ("frank", 42), ("jane", 21);
+`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	query := func(stmt string, args ...interface{}) []string {
+		var persons []orderPerson
+		runTx(t, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForMany(&persons)
+			assert.Nil(t, err)
+
+			return getter.Query(tx, stmt, args...)
+		})
+		names := make([]string, len(persons))
+		for i, person := range persons {
+			names[i] = person.Name
+		}
+		return names
+	}
+
+	names := query(`SELECT {orderPerson} FROM people {order};`, OrderBy{Column: "name", Desc: true})
+	assert.Equal(t, names, []string{"jane", "fred", "frank"})
+
+	names = query(`SELECT {orderPerson} FROM people {order};`, OrderBy{Column: "age"}, OrderBy{Column: "name", Desc: true})
+	assert.Equal(t, names, []string{"jane", "fred", "frank"})
+
+	names = query(`SELECT {orderPerson} FROM people WHERE age<:age {order} LIMIT 1;`, map[string]interface{}{
+		"age": 30,
+	}, []OrderBy{{Column: "name"}})
+	assert.Equal(t, names, []string{"fred"})
+
+	// The order clause is removed without any OrderBy arguments.
+	names = query(`SELECT {orderPerson} FROM people WHERE age>? {order};`, 30)
+	assert.Equal(t, names, []string{"frank"})
+
+	// A single destination is validated in the same way.
+	var person orderPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {orderPerson} FROM people {order} LIMIT 1;`, OrderBy{Column: "age", Desc: true})
+	})
+	assert.Equal(t, person.Name, "frank")
+}
+
+func TestQueryWithOrderByErrors(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`CREATE TABLE people (name TEXT, age INTEGER);`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	var persons []orderPerson
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {orderPerson} FROM people {order};`, OrderBy{Column: "age; DROP TABLE people"})
+	assert.True(t, errors.As(err, new(*OrderByError)))
+	assert.Equal(t, err.Error(), `unexpected order by column "age; DROP TABLE people", expected one of age, name
+	statement: SELECT {orderPerson} FROM people {order};`)
+
+	err = getter.Query(tx, `SELECT {orderPerson} FROM people;`, OrderBy{Column: "age"})
+	assert.Equal(t, err.Error(), `missing {order} clause for OrderBy arguments
+	statement: SELECT {orderPerson} FROM people;`)
+
+	_, err = querier.Exec(tx, `DELETE FROM people {order};`)
+	assert.Equal(t, err.Error(), `unexpected order clause "{order}" outside of a query at 1:20
+	DELETE FROM people {order};
+	                   ^`)
+}
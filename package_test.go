@@ -2,18 +2,50 @@ package sqlair
 
 import (
 	"database/sql"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func setupDB(t *testing.T) *sql.DB {
+func setupDB(t testing.TB) *sql.DB {
 	db, err := sql.Open("sqlite3", ":memory:")
 	assert.Nil(t, err)
 	return db
 }
 
-func runTx(t *testing.T, db *sql.DB, fn func(*sql.Tx) error) {
+// peopleSchema is the table of people that most tests query.
+const peopleSchema = `
+CREATE TABLE people(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO people(name, age) values ("fred", 21), ("frank", 42), ("jane", 23);
+`
+
+// setupSchemaDB creates a database in memory with the schema, which is
+// executed with the arguments.
+func setupSchemaDB(t testing.TB, schema string, args ...interface{}) *sql.DB {
+	db := setupDB(t)
+
+	_, err := db.Exec(schema, args...)
+	assert.Nil(t, err)
+	return db
+}
+
+// setupFileDB creates a database on disk with the schema, rather than in
+// memory, so that every connection of the pool sees the same tables.
+func setupFileDB(t testing.TB, schema string) *sql.DB {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(schema)
+	assert.Nil(t, err)
+	return db
+}
+
+func runTx(t testing.TB, db *sql.DB, fn func(*sql.Tx) error) {
 	tx, err := db.Begin()
 	assert.Nil(t, err)
 
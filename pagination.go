@@ -0,0 +1,176 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// Keyset describes a page of rows for keyset pagination, where the next page
+// starts directly after the last row of the previous page, rather than at an
+// offset. The cost of a page doesn't grow with the number of pages before it,
+// as long as the columns are indexed.
+type Keyset struct {
+	// Columns order the rows, and must identify a row uniquely, such as a
+	// name followed by an id. Columns can be qualified with the table
+	// (p.name), but must match the fields of the destination.
+	Columns []string
+	// Limit is the maximum number of rows of the page.
+	Limit int
+	// Descending orders the rows in descending order, rather than
+	// ascending.
+	Descending bool
+	// After is the cursor returned for the previous page, which is empty for
+	// the first page.
+	After string
+}
+
+// Page executes a statement that returns rows, appending a page of rows to
+// the destination slice of a query created by ForMany. The statement is
+// extended with a WHERE predicate that starts the page after the cursor,
+// along with the ORDER BY and LIMIT clauses of the page, so it must not have
+// either of those clauses.
+//
+//  getter, err := querier.ForMany(&persons)
+//  ...
+//  keyset := sqlair.Keyset{Columns: []string{"name", "id"}, Limit: 100}
+//  for {
+//  	persons = persons[:0]
+//  	keyset.After, err = getter.Page(tx, `SELECT {Person} FROM people;`, keyset)
+//  	if err != nil || keyset.After == "" {
+//  		break
+//  	}
+//  }
+//
+// The statement of the second page becomes:
+//
+//  SELECT {Person} FROM people WHERE (name, id) > (:sqlair_cursor_0, :sqlair_cursor_1) ORDER BY name, id LIMIT 100;
+//
+// The returned cursor is an opaque string for the page after this one, which
+// is empty if this is the last page.
+func (q Query) Page(tx *sql.Tx, stmt string, keyset Keyset, args ...interface{}) (string, error) {
+	return q.PageContext(context.Background(), tx, stmt, keyset, args...)
+}
+
+// PageContext executes a statement that returns a page of rows. See Page.
+func (q Query) PageContext(ctx context.Context, tx *sql.Tx, stmt string, keyset Keyset, args ...interface{}) (string, error) {
+	if len(keyset.Columns) == 0 {
+		return "", errors.Errorf("expected at least one keyset column")
+	}
+	if keyset.Limit <= 0 {
+		return "", errors.Errorf("expected keyset limit greater than zero, got %d", keyset.Limit)
+	}
+	if len(q.entities) == 0 || q.entities[0].Kind() != reflect.Slice {
+		return "", errors.Errorf("expected query with a destination slice, see ForMany")
+	}
+	slice := q.entities[0].(sreflect.ReflectValue).Value
+
+	element, err := q.reflect.Reflect(reflect.New(slice.Type().Elem()).Interface())
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	fields, err := keysetFields(element.(sreflect.ReflectStruct), keyset.Columns)
+	if err != nil {
+		return "", err
+	}
+
+	columns := strings.Join(keyset.Columns, ", ")
+	comparison, direction := ">", ""
+	if keyset.Descending {
+		comparison, direction = "<", " DESC"
+	}
+
+	if keyset.After != "" {
+		values, err := decodeCursor(keyset.After, fields)
+		if err != nil {
+			return "", err
+		}
+		names := make([]string, len(values))
+		for i, value := range values {
+			name := fmt.Sprintf("sqlair_cursor_%d", i)
+			names[i] = ":" + name
+			args = append(args, sql.Named(name, value))
+		}
+		stmt = addSelectPredicate(stmt, fmt.Sprintf("(%s) %s (%s)", columns, comparison, strings.Join(names, ", ")))
+	}
+
+	order := make([]string, len(keyset.Columns))
+	for i, column := range keyset.Columns {
+		order[i] = column + direction
+	}
+	end := contentEnd(stmt)
+	stmt = fmt.Sprintf("%s ORDER BY %s LIMIT %d%s", stmt[:end], strings.Join(order, ", "), keyset.Limit, trailingText(stmt[end:]))
+
+	start := slice.Len()
+	if err := q.QueryContext(ctx, tx, stmt, args...); err != nil {
+		return "", err
+	}
+	if slice.Len()-start < keyset.Limit {
+		return "", nil
+	}
+	return encodeCursor(slice.Index(slice.Len()-1), fields)
+}
+
+// keysetFields returns the fields of the element for the keyset columns.
+func keysetFields(element sreflect.ReflectStruct, columns []string) ([]sreflect.ReflectField, error) {
+	fields := make([]sreflect.ReflectField, len(columns))
+	for i, column := range columns {
+		name := column
+		if index := strings.LastIndex(column, "."); index >= 0 {
+			name = column[index+1:]
+		}
+		field, ok := element.Fields[name]
+		if !ok {
+			return nil, errors.WithStack(&MissingFieldError{
+				Entity: element.Name,
+				Field:  name,
+			})
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// encodeCursor returns the cursor of the row, which holds the values of the
+// keyset fields.
+func encodeCursor(row reflect.Value, fields []sreflect.ReflectField) (string, error) {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i] = sreflect.FieldByIndex(row, field.Index).Interface()
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the values of the keyset fields from the cursor, with
+// the types of the fields.
+func decodeCursor(cursor string, fields []sreflect.ReflectField) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Errorf("invalid cursor %q", cursor)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil || len(raw) != len(fields) {
+		return nil, errors.Errorf("invalid cursor %q", cursor)
+	}
+
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		value := reflect.New(field.Value.Type())
+		if err := json.Unmarshal(raw[i], value.Interface()); err != nil {
+			return nil, errors.Errorf("invalid cursor %q", cursor)
+		}
+		values[i] = value.Elem().Interface()
+	}
+	return values, nil
+}
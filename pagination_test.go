@@ -0,0 +1,121 @@
+package sqlair
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pagePerson struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestPage(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT);`)
+	assert.Nil(t, err)
+	for i, name := range []string{"fred", "frank", "alice", "bob", "alice", "carol", "dave"} {
+		_, err := db.Exec(`INSERT INTO people VALUES (?, ?);`, i+1, name)
+		assert.Nil(t, err)
+	}
+
+	querier := NewQuerier()
+	runTx(t, db, func(tx *sql.Tx) error {
+		var persons []pagePerson
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		var pages [][]string
+		keyset := Keyset{Columns: []string{"name", "id"}, Limit: 3}
+		for {
+			persons = persons[:0]
+			keyset.After, err = getter.Page(tx, `SELECT {pagePerson} FROM people WHERE id <> :id;`, keyset, map[string]interface{}{"id": 2})
+			assert.Nil(t, err)
+
+			var page []string
+			for _, person := range persons {
+				page = append(page, fmt.Sprintf("%s:%d", person.Name, person.ID))
+			}
+			pages = append(pages, page)
+			if keyset.After == "" {
+				break
+			}
+		}
+		assert.Equal(t, pages, [][]string{
+			{"alice:3", "alice:5", "bob:4"},
+			{"carol:6", "dave:7", "fred:1"},
+			nil,
+		})
+
+		persons = persons[:0]
+		keyset = Keyset{Columns: []string{"p.id"}, Limit: 4, Descending: true}
+		keyset.After, err = getter.Page(tx, `SELECT {p.* INTO pagePerson} FROM people AS p`, keyset)
+		assert.Nil(t, err)
+		assert.NotEqual(t, keyset.After, "")
+		keyset.After, err = getter.Page(tx, `SELECT {p.* INTO pagePerson} FROM people AS p`, keyset)
+		assert.Nil(t, err)
+		assert.Equal(t, keyset.After, "")
+
+		ids := make([]int, len(persons))
+		for i, person := range persons {
+			ids[i] = person.ID
+		}
+		assert.Equal(t, ids, []int{7, 6, 5, 4, 3, 2, 1})
+		return nil
+	})
+}
+
+func TestPageWithTrailingComment(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT);
+INSERT INTO people VALUES (1, "fred"), (2, "frank"), (3, "jane");
+`)
+	assert.Nil(t, err)
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+	runTx(t, db, func(tx *sql.Tx) error {
+		var persons []pagePerson
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		keyset := Keyset{Columns: []string{"id"}, Limit: 2}
+		keyset.After, err = getter.Page(tx, "SELECT {pagePerson} FROM people -- every person\n;", keyset)
+		assert.Nil(t, err)
+		assert.Equal(t, processedStmt, "SELECT id, name FROM people ORDER BY id LIMIT 2\n-- every person\n;")
+
+		_, err = getter.Page(tx, "SELECT {pagePerson} FROM people -- every person\n;", keyset)
+		assert.Nil(t, err)
+		assert.Equal(t, processedStmt, "SELECT id, name FROM people WHERE (id) > (:sqlair_cursor_0) ORDER BY id LIMIT 2\n-- every person\n;")
+		assert.Equal(t, persons, []pagePerson{{ID: 1, Name: "fred"}, {ID: 2, Name: "frank"}, {ID: 3, Name: "jane"}})
+		return nil
+	})
+}
+
+func TestPageErrors(t *testing.T) {
+	querier := NewQuerier()
+
+	var persons []pagePerson
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	_, err = getter.Page(nil, `SELECT {pagePerson} FROM people;`, Keyset{Columns: []string{"missing"}, Limit: 1})
+	assert.Equal(t, err.Error(), `field "missing" not found in entity "pagePerson"`)
+
+	_, err = getter.Page(nil, `SELECT {pagePerson} FROM people;`, Keyset{Columns: []string{"id"}, Limit: 1, After: "!"})
+	assert.Equal(t, err.Error(), `invalid cursor "!"`)
+
+	_, err = getter.Page(nil, `SELECT {pagePerson} FROM people;`, Keyset{Columns: []string{"id"}})
+	assert.Equal(t, err.Error(), "expected keyset limit greater than zero, got 0")
+}
@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ampersandRecord returns the start of the ampersand record expression at the
+// ampersand, and the offset of its AS keyword, or -1 if the record isn't
+// selected as a column. The record starts at the column or expression that's
+// written before AS (p.name AS &Person.name), but never before the limit. It
+// returns false if the ampersand isn't a record expression, such as the
+// bitwise AND of a & b.
+func ampersandRecord(stmt string, amp, limit int) (int, int, bool) {
+	if amp > 0 {
+		if prev, _ := utf8.DecodeLastRuneInString(stmt[:amp]); isIdent(prev) || prev == ')' || prev == ']' || isQuote(byte(prev)) {
+			return 0, 0, false
+		}
+	}
+
+	// The entity is always followed by a field or a wildcard (&Person.*).
+	i := amp + 1
+	entity := consumeIdent(stmt, i)
+	if entity == i || entity >= len(stmt) || stmt[entity] != '.' {
+		return 0, 0, false
+	}
+	if i = entity + 1; i >= len(stmt) || (stmt[i] != '*' && consumeIdent(stmt, i) == i) {
+		return 0, 0, false
+	}
+
+	// Look back for the AS keyword, and the column or expression before it.
+	as := strings.TrimRightFunc(stmt[:amp], unicode.IsSpace)
+	if len(as) < 2 || !strings.EqualFold(as[len(as)-2:], "as") {
+		return amp, -1, true
+	}
+	before := strings.TrimRightFunc(as[:len(as)-2], unicode.IsSpace)
+	if len(before) == len(as)-2 {
+		// The AS is the end of another word (alias).
+		return amp, -1, true
+	}
+	start := startOfColumn(before)
+	if start < limit || start == len(before) {
+		return amp, -1, true
+	}
+	return start, len(as) - 2, true
+}
+
+// consumeIdent returns the offset directly after the identifier starting at
+// the offset.
+func consumeIdent(stmt string, offset int) int {
+	for offset < len(stmt) {
+		r, size := utf8.DecodeRuneInString(stmt[offset:])
+		if !isIdent(r) {
+			break
+		}
+		offset += size
+	}
+	return offset
+}
+
+// startOfColumn returns the start of the column or expression that the text
+// ends with. A column is a dotted path that can be quoted (p.name or "p".*),
+// and an expression ends with a function call (COUNT(*)). Only the last term
+// of any other expression belongs to the column (b of a + b).
+func startOfColumn(text string) int {
+	i := len(text)
+	for i > 0 {
+		c := text[i-1]
+		switch {
+		case c == ')':
+			// Skip back over the arguments of the function call, to the
+			// name of the function.
+			depth := 0
+			for i > 0 {
+				i--
+				switch text[i] {
+				case ')':
+					depth++
+				case '(':
+					depth--
+				}
+				if depth == 0 {
+					break
+				}
+			}
+		case isQuote(c) || c == ']':
+			quote := c
+			if c == ']' {
+				quote = '['
+			}
+			index := strings.LastIndexByte(text[:i-1], quote)
+			if index < 0 {
+				return i
+			}
+			i = index
+		case c == '.' || c == '*':
+			i--
+		default:
+			r, size := utf8.DecodeLastRuneInString(text[:i])
+			if !isIdent(r) {
+				return i
+			}
+			i -= size
+		}
+	}
+	return i
+}
+
+// parseAmpersandRecord parses the ampersand record expression at the offset,
+// which is the alternative form of a record expression:
+//
+//  &Person.*                 {Person}
+//  &Person.name              {name INTO Person}
+//  p.* AS &Person.*          {p.* INTO Person}
+//  p.name AS &Person.name    {p.name INTO Person}
+//  COUNT(*) AS &Stats.total  {COUNT(*) AS total INTO Stats}
+//
+func parseAmpersandRecord(stmt string, offset int) (*RecordExpr, error) {
+	// Locate the ampersand of the record, which follows the column of the
+	// record, if it has one.
+	amp, as := offset, -1
+	for i := offset; i < len(stmt); i++ {
+		if stmt[i] != '&' {
+			continue
+		}
+		if start, keyword, ok := ampersandRecord(stmt, i, offset); ok && start == offset {
+			amp, as = i, keyword
+			break
+		}
+	}
+
+	lexer := NewLexer(stmt, amp)
+	lexer.Next()
+	entity := lexer.Next()
+	lexer.Next()
+	field := lexer.Next()
+
+	record := &RecordExpr{
+		Pos:       PositionOf(stmt, offset),
+		End:       lexer.pos,
+		Text:      stmt[offset:lexer.pos.Offset],
+		Entity:    entity.Value,
+		Ampersand: true,
+	}
+	if as < 0 {
+		if field.Kind != ASTERISK {
+			record.Columns = []Column{{
+				Pos:  field.Pos,
+				Name: field.Value,
+			}}
+		}
+		return record, nil
+	}
+
+	// The column is written before AS, which is either a path to a column of
+	// a table or an expression.
+	text := strings.TrimSpace(stmt[offset:as])
+	var (
+		path   word
+		isPath = true
+	)
+	columnLexer := NewLexer(stmt[:offset+len(text)], offset)
+	for token := columnLexer.Next(); token.Kind != EOF; token = columnLexer.Next() {
+		switch token.Kind {
+		case IDENT, STRING, DOT, ASTERISK:
+			path = append(path, token)
+		default:
+			isPath = false
+		}
+	}
+
+	column := Column{
+		Pos:  record.Pos,
+		Name: field.Value,
+	}
+	parts := path.path()
+	name := parts[len(parts)-1].text
+	switch {
+	case isPath && len(parts) <= 3 && (name == column.Name || (name == "*" && field.Kind == ASTERISK)):
+		switch len(parts) {
+		case 3:
+			column.Schema = parts[0].text
+			column.Prefix = parts[1].text
+			column.Qualifier = parts[0].raw + "." + parts[1].raw
+		case 2:
+			column.Prefix = parts[0].text
+			column.Qualifier = parts[0].raw
+		}
+	case field.Kind == ASTERISK || name == "*":
+		return nil, errorf(record.Pos, "unexpected wildcard in record expression %q, expected both columns to be wildcards", record.Text)
+	default:
+		column.Expr = text
+	}
+	record.Columns = []Column{column}
+	return record, nil
+}
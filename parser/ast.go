@@ -0,0 +1,227 @@
+package parser
+
+import (
+	"strings"
+)
+
+// Node is a node of a parsed statement: a Statement, a *RecordExpr or a
+// *Column. The String of a node is its canonical form, which parses back into
+// an equal node.
+type Node interface {
+	Position() Position
+	String() string
+}
+
+// Statement is a statement along with the record expressions found within
+// it.
+type Statement struct {
+	// Text is the statement as it's written.
+	Text string
+	// Records are the record expressions of the statement, in the order
+	// they're written.
+	Records []*RecordExpr
+}
+
+// Parse parses every record expression of the statement.
+func Parse(stmt string) (*Statement, error) {
+	records, err := ParseRecords(stmt, IndexOfRecord(stmt, 0))
+	if err != nil {
+		return nil, err
+	}
+	return &Statement{
+		Text:    stmt,
+		Records: records,
+	}, nil
+}
+
+// Position returns the start of the statement.
+func (s *Statement) Position() Position {
+	return PositionOf(s.Text, 0)
+}
+
+// String returns the statement with each of its record expressions written in
+// their canonical form.
+func (s *Statement) String() string {
+	var (
+		builder strings.Builder
+		last    int
+	)
+	for _, record := range s.Records {
+		builder.WriteString(s.Text[last:record.Pos.Offset])
+		builder.WriteString(record.String())
+		last = record.End.Offset
+	}
+	builder.WriteString(s.Text[last:])
+	return builder.String()
+}
+
+// RecordExpr is a record expression found within a statement, written either
+// with braces or with an ampersand:
+//
+//  {people.* INTO Person AS p EXCEPT password}
+//  {p.name, p.age INTO Person pp}
+//  people.* AS &Person.*
+//
+type RecordExpr struct {
+	// Pos is the position of the opening brace, or of the start of an
+	// ampersand record.
+	Pos Position
+	// End is the position directly after the closing brace, or the end of
+	// an ampersand record.
+	End Position
+	// Text is the text found between the braces, without any quotes, or the
+	// text of an ampersand record as it's written.
+	Text string
+	// Columns are the columns written before INTO. The shorthand form of a
+	// record expression ({Person}) has no columns.
+	Columns []Column
+	// Entity is the name of the type the record is bound to.
+	Entity string
+	// Alias is the optional alias of the record.
+	Alias string
+	// ShortAlias is the optional alias written directly after the entity
+	// ({p.* INTO Person pp}), which names the record in the aliases of its
+	// columns in place of its table.
+	ShortAlias string
+	// Exclude are the fields excluded from a wildcard record.
+	Exclude []string
+	// Table is true if the record is a table placeholder ({Person.table}),
+	// which is replaced with the table of the entity rather than its columns.
+	Table bool
+	// Where is true if the record is a where clause ({where Filters}), which
+	// is replaced with the conditions of a filter struct rather than its
+	// columns.
+	Where bool
+	// Order is true if the record is an order clause ({order}), which is
+	// replaced with the columns of the OrderBy arguments of a query.
+	Order bool
+	// Depth is the number of parentheses the record is nested in. Records
+	// of subqueries and of the common table expressions of a WITH clause
+	// have a depth greater than zero.
+	Depth int
+	// Ampersand is true if the record is written in the ampersand form
+	// (&Person.*) rather than with braces.
+	Ampersand bool
+}
+
+// Position returns the position of the record.
+func (r *RecordExpr) Position() Position {
+	return r.Pos
+}
+
+// String returns the record expression in its canonical form, in the same
+// form that it's written in:
+//
+//  {people.*, people.name INTO Person AS p EXCEPT password}
+//  p.name AS &Person.name
+//
+func (r *RecordExpr) String() string {
+	if r.Ampersand {
+		return r.ampersandString()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("{")
+	switch {
+	case r.Order:
+		builder.WriteString("order")
+	case r.Where:
+		builder.WriteString("where " + r.Entity)
+	case r.Table:
+		builder.WriteString(r.Entity + ".table")
+	default:
+		if len(r.Columns) > 0 {
+			columns := make([]string, len(r.Columns))
+			for i, column := range r.Columns {
+				columns[i] = column.String()
+			}
+			builder.WriteString(strings.Join(columns, ", ") + " INTO ")
+		}
+		builder.WriteString(r.Entity)
+		if r.ShortAlias != "" {
+			builder.WriteString(" " + r.ShortAlias)
+		}
+		if r.Alias != "" {
+			builder.WriteString(" AS " + r.Alias)
+		}
+		if len(r.Exclude) > 0 {
+			builder.WriteString(" EXCEPT " + strings.Join(r.Exclude, ", "))
+		}
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+// ampersandString returns the ampersand form of the record, which selects at
+// most one column.
+func (r *RecordExpr) ampersandString() string {
+	if len(r.Columns) == 0 {
+		return "&" + r.Entity + ".*"
+	}
+	column := r.Columns[0]
+	target := "&" + r.Entity + "." + column.Name
+	switch {
+	case column.Expr != "":
+		return column.Expr + " AS " + target
+	case column.Qualifier == "" && column.Name != "*":
+		return target
+	}
+	return qualify(column.Qualifier, column.Name) + " AS " + target
+}
+
+// Column is a column of a record expression. A column is either a path to a
+// field of a table (people.name or people.*), or an expression that's bound to
+// a field by its alias (COUNT(*) AS total). The table can be qualified with a
+// schema, and either of them can be quoted ("main"."people".*, `people`.* or
+// [people].*).
+type Column struct {
+	Pos Position
+	// Schema is the schema or attached database of the table, which is empty
+	// unless the table is qualified with it (main.people.name).
+	Schema string
+	// Prefix is the table of the column, which is empty for columns without
+	// a table and for expressions. Quotes are removed from the table.
+	Prefix string
+	// Qualifier is the schema and table as they're written in the statement,
+	// along with any quotes ("main"."people"), which is used when the
+	// record expression is expanded.
+	Qualifier string
+	// Name is the name of the field, which is "*" for a wildcard.
+	Name string
+	// Expr is the expression of a computed column.
+	Expr string
+}
+
+// Position returns the position of the column.
+func (c *Column) Position() Position {
+	return c.Pos
+}
+
+// String returns the column as it's written in a record expression.
+func (c *Column) String() string {
+	if c.Expr != "" {
+		return c.Expr + " AS " + quoteName(c.Name)
+	}
+	return qualify(c.Qualifier, quoteName(c.Name))
+}
+
+// IsWildcard returns true if the column selects every field.
+func (c Column) IsWildcard() bool {
+	return c.Expr == "" && c.Name == "*"
+}
+
+func qualify(qualifier, name string) string {
+	if qualifier == "" {
+		return name
+	}
+	return qualifier + "." + name
+}
+
+// quoteName quotes the name of a field if it isn't an identifier, so that it
+// parses back into the same name.
+func quoteName(name string) string {
+	if name == "*" || (name != "" && strings.IndexFunc(name, func(r rune) bool { return !isIdent(r) }) < 0) {
+		return name
+	}
+	return `"` + name + `"`
+}
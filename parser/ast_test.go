@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// canonical clears the positions and text of the record, which differ
+// between a record and its canonical form.
+func canonical(record *RecordExpr) *RecordExpr {
+	result := *record
+	result.Pos, result.End, result.Text, result.Depth = Position{}, Position{}, "", 0
+	result.Columns = nil
+	for _, column := range record.Columns {
+		column.Pos = Position{}
+		result.Columns = append(result.Columns, column)
+	}
+	return &result
+}
+
+func TestRecordExprString(t *testing.T) {
+	tests := []struct {
+		input, expected string
+	}{
+		{`{Person}`, `{Person}`},
+		{`{Person AS p EXCEPT password,  email}`, `{Person AS p EXCEPT password, email}`},
+		{`{people.*   people.name INTO Person AS p}`, `{people.*, people.name INTO Person AS p}`},
+		{`{"main"."people".name, 'people.age' INTO Person}`, `{"main"."people".name, people.age INTO Person}`},
+		{`{COUNT(*) AS total, SUM(p.age) AS "sum of ages" INTO Stats}`, `{COUNT(*) AS total, SUM(p.age) AS "sum of ages" INTO Stats}`},
+		{`{p.name INTO Person  pp AS mother}`, `{p.name INTO Person pp AS mother}`},
+		{`{Person.table}`, `{Person.table}`},
+		{`{where Filters}`, `{where Filters}`},
+		{`{order}`, `{order}`},
+		{`&Person.*`, `&Person.*`},
+		{`&Person.name`, `&Person.name`},
+		{`name AS &Person.name`, `&Person.name`},
+		{`"p".* AS &Person.*`, `"p".* AS &Person.*`},
+		{`main.p.name AS &Person.name`, `main.p.name AS &Person.name`},
+		{`COUNT(p.id) AS &Stats.total`, `COUNT(p.id) AS &Stats.total`},
+	}
+	for _, test := range tests {
+		records, err := ParseRecords(test.input, 0)
+		assert.Nil(t, err, test.input)
+		assert.Len(t, records, 1, test.input)
+		assert.Equal(t, records[0].String(), test.expected, test.input)
+
+		// The canonical form parses back into the same record.
+		reparsed, err := ParseRecords(test.expected, 0)
+		assert.Nil(t, err, test.expected)
+		assert.Len(t, reparsed, 1, test.expected)
+		assert.Equal(t, canonical(reparsed[0]), canonical(records[0]), test.expected)
+		assert.Equal(t, reparsed[0].String(), test.expected)
+	}
+}
+
+func TestStatementString(t *testing.T) {
+	stmt, err := Parse(`SELECT {people.*  INTO Person}, p.name   AS &Person.name, (SELECT {Location} FROM location) FROM people AS p WHERE {where Filters};`)
+	assert.Nil(t, err)
+	assert.Len(t, stmt.Records, 4)
+	assert.Equal(t, stmt.String(), `SELECT {people.* INTO Person}, p.name AS &Person.name, (SELECT {Location} FROM location) FROM people AS p WHERE {where Filters};`)
+
+	reparsed, err := Parse(stmt.String())
+	assert.Nil(t, err)
+	assert.Equal(t, reparsed.String(), stmt.String())
+	for i, record := range reparsed.Records {
+		assert.Equal(t, record.Depth, stmt.Records[i].Depth)
+		assert.Equal(t, canonical(record), canonical(stmt.Records[i]))
+	}
+}
+
+func TestParseWithoutRecords(t *testing.T) {
+	stmt, err := Parse(`SELECT a & b FROM people WHERE id=:id;`)
+	assert.Nil(t, err)
+	assert.Len(t, stmt.Records, 0)
+	assert.Equal(t, stmt.String(), `SELECT a & b FROM people WHERE id=:id;`)
+	assert.Equal(t, stmt.Position(), Position{Offset: 0, Line: 1, Column: 1})
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse(`SELECT {Person FROM people`)
+	assert.Equal(t, err.Error(), `missing closing brace for record expression "Person FROM people"`)
+	assert.Equal(t, err.(*Error).Pos.Offset, 7)
+}
+
+func TestInspect(t *testing.T) {
+	stmt, err := Parse("SELECT {p.name, p.age INTO Person},\n  &Location.* FROM people AS p;")
+	assert.Nil(t, err)
+
+	var visited []string
+	Inspect(stmt, func(node Node) bool {
+		switch n := node.(type) {
+		case nil:
+			visited = append(visited, "end")
+		case *Statement:
+			visited = append(visited, "statement")
+		default:
+			visited = append(visited, n.String()+" at "+n.Position().String())
+		}
+		return true
+	})
+	assert.Equal(t, visited, []string{
+		"statement",
+		"{p.name, p.age INTO Person} at 1:8",
+		"p.name at 1:9",
+		"end",
+		"p.age at 1:17",
+		"end",
+		"end",
+		"&Location.* at 2:3",
+		"end",
+		"end",
+	})
+}
+
+type entityVisitor struct {
+	entities *[]string
+}
+
+func (v entityVisitor) Visit(node Node) Visitor {
+	if record, ok := node.(*RecordExpr); ok {
+		*v.entities = append(*v.entities, record.Entity)
+		// Skip the columns of the record.
+		return nil
+	}
+	return v
+}
+
+func TestWalkSkipsChildren(t *testing.T) {
+	stmt, err := Parse(`SELECT {p.name INTO Person}, &Location.city FROM people AS p;`)
+	assert.Nil(t, err)
+
+	var entities []string
+	Walk(entityVisitor{entities: &entities}, stmt)
+	assert.Equal(t, entities, []string{"Person", "Location"})
+}
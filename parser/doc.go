@@ -0,0 +1,15 @@
+// Package parser parses the record expressions of sqlair statements, so that
+// tools other than sqlair itself, such as linters and editor plugins, can
+// understand the statements without executing them.
+//
+// Parse returns a Statement holding each of the record expressions of a
+// statement, in either of their forms:
+//
+//  SELECT {people.* INTO Person AS p EXCEPT password} FROM people;
+//  SELECT p.name AS &Person.name FROM people AS p;
+//
+// The nodes of a statement are traversed with Walk or Inspect, and every node
+// is written back in its canonical form by its String method, which parses
+// into an equal node. Positions are given as offsets, lines and columns
+// within the statement.
+package parser
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Position is the location of a token within a statement. Lines and columns
+// start at 1, and the column is counted in bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// PositionOf returns the position of the offset within the input.
+func PositionOf(input string, offset int) Position {
+	if offset > len(input) {
+		offset = len(input)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return Position{
+		Offset: offset,
+		Line:   strings.Count(input[:offset], "\n") + 1,
+		Column: offset - strings.LastIndex(input[:offset], "\n"),
+	}
+}
+
+// Lexer splits a statement into tokens.
+type Lexer struct {
+	input string
+	pos   Position
+}
+
+// NewLexer creates a new Lexer for the input, starting at the offset.
+func NewLexer(input string, offset int) *Lexer {
+	return &Lexer{
+		input: input,
+		pos:   PositionOf(input, offset),
+	}
+}
+
+// Next returns the next token of the input, returning an EOF token once the
+// input has been consumed.
+func (l *Lexer) Next() Token {
+	start := l.pos
+	if start.Offset >= len(l.input) {
+		return Token{Kind: EOF, Pos: start}
+	}
+
+	char := l.input[start.Offset]
+	r, size := utf8.DecodeRuneInString(l.input[start.Offset:])
+
+	var kind TokenKind
+	switch {
+	case unicode.IsSpace(r):
+		kind = WS
+		l.consume(unicode.IsSpace)
+	case isIdent(r):
+		kind = IDENT
+		l.consume(isIdent)
+	case isQuote(char):
+		kind = l.quoted(char)
+	case char == '[' && l.startsWord():
+		kind = l.quoted(char)
+	case l.param():
+		kind = PARAM
+	default:
+		if op, ok := operators[l.peek(2)]; ok {
+			kind = op
+			l.advance(2)
+			break
+		}
+		var ok bool
+		if kind, ok = punctuation[char]; !ok {
+			kind = UNKNOWN
+		}
+		l.advance(size)
+	}
+	return Token{
+		Kind:  kind,
+		Value: l.input[start.Offset:l.pos.Offset],
+		Pos:   start,
+	}
+}
+
+// quoted consumes a quoted string. If the terminating quote isn't found before
+// the end of the record expression, then the string is illegal.
+func (l *Lexer) quoted(quote byte) TokenKind {
+	terminator := quote
+	if quote == '[' {
+		terminator = ']'
+	}
+	l.advance(1)
+	for l.pos.Offset < len(l.input) {
+		switch l.input[l.pos.Offset] {
+		case terminator:
+			if l.peek(2) == string([]byte{terminator, terminator}) {
+				l.advance(2)
+				continue
+			}
+			l.advance(1)
+			return STRING
+		case '}':
+			return ILLEGAL
+		}
+		_, size := utf8.DecodeRuneInString(l.input[l.pos.Offset:])
+		l.advance(size)
+	}
+	return ILLEGAL
+}
+
+// param consumes a parameter, returning false if the current position doesn't
+// start one. Named arguments start with a colon, an at sign or a dollar sign,
+// and can be dotted paths (:person.name), whereas positional placeholders are
+// a question mark or a dollar sign followed by an optional number.
+func (l *Lexer) param() bool {
+	char := l.input[l.pos.Offset]
+	rest := l.input[l.pos.Offset+1:]
+	r, _ := utf8.DecodeRuneInString(rest)
+	switch {
+	case char == '?':
+	case (char == ':' || char == '@' || char == '$') && isIdent(r):
+	default:
+		return false
+	}
+	l.advance(1)
+	l.consume(isIdent)
+	if char == ':' {
+		// Named arguments can reach into the fields of their argument.
+		for l.peek(1) == "." {
+			r, _ := utf8.DecodeRuneInString(l.input[l.pos.Offset+1:])
+			if !isIdent(r) {
+				break
+			}
+			l.advance(1)
+			l.consume(isIdent)
+		}
+	}
+	return true
+}
+
+// peek returns the next n bytes of the input, without consuming them.
+func (l *Lexer) peek(n int) string {
+	end := l.pos.Offset + n
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+	return l.input[l.pos.Offset:end]
+}
+
+// startsWord returns true if the current position starts a word, rather than
+// following an identifier or a closing bracket. Square brackets only quote
+// identifiers at the start of a word, so that subscripts such as tags[1] are
+// left alone.
+func (l *Lexer) startsWord() bool {
+	if l.pos.Offset == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(l.input[:l.pos.Offset])
+	return !isIdent(prev) && prev != ']' && prev != ')' && !isQuote(byte(prev))
+}
+
+func (l *Lexer) consume(predicate func(rune) bool) {
+	for l.pos.Offset < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos.Offset:])
+		if !predicate(r) {
+			return
+		}
+		l.advance(size)
+	}
+}
+
+// advance moves the position forward by n bytes, keeping track of the lines
+// and columns.
+func (l *Lexer) advance(n int) {
+	for i := 0; i < n && l.pos.Offset < len(l.input); i++ {
+		if l.input[l.pos.Offset] == '\n' {
+			l.pos.Line++
+			l.pos.Column = 1
+		} else {
+			l.pos.Column++
+		}
+		l.pos.Offset++
+	}
+}
+
+func isIdent(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsNumber(r) || r == '_'
+}
+
+// isQuote returns true if the character quotes a string or an identifier.
+// Square brackets are handled apart, as they're only quotes at the start of
+// a word.
+func isQuote(char byte) bool {
+	return char == '"' || char == '\'' || char == '`'
+}
@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lex(input string, offset int) []Token {
+	lexer := NewLexer(input, offset)
+	var tokens []Token
+	for {
+		token := lexer.Next()
+		if token.Kind == EOF {
+			return tokens
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+func TestLexer(t *testing.T) {
+	tokens := lex(`{p.name, COUNT(*) INTO Person}`, 0)
+
+	var kinds []TokenKind
+	for _, token := range tokens {
+		kinds = append(kinds, token.Kind)
+	}
+	assert.Equal(t, kinds, []TokenKind{
+		LBRACE, IDENT, DOT, IDENT, COMMA, WS, IDENT, LPAREN, ASTERISK, RPAREN, WS, IDENT, WS, IDENT, RBRACE,
+	})
+	assert.Equal(t, tokens[6], Token{Kind: IDENT, Value: "COUNT", Pos: Position{Offset: 9, Line: 1, Column: 10}})
+}
+
+func TestLexerQuoted(t *testing.T) {
+	tokens := lex(`'foo.*' "bar`, 0)
+	assert.Equal(t, tokens, []Token{
+		{Kind: STRING, Value: `'foo.*'`, Pos: Position{Offset: 0, Line: 1, Column: 1}},
+		{Kind: WS, Value: " ", Pos: Position{Offset: 7, Line: 1, Column: 8}},
+		{Kind: ILLEGAL, Value: `"bar`, Pos: Position{Offset: 8, Line: 1, Column: 9}},
+	})
+	assert.Equal(t, tokens[0].Text(), "foo.*")
+	assert.Equal(t, tokens[2].Text(), "bar")
+}
+
+func TestLexerPositions(t *testing.T) {
+	tokens := lex("SELECT\n  {Person}", 9)
+	assert.Equal(t, tokens[0].Pos, Position{Offset: 9, Line: 2, Column: 3})
+	assert.Equal(t, tokens[1].Pos, Position{Offset: 10, Line: 2, Column: 4})
+}
+
+func TestPositionOf(t *testing.T) {
+	assert.Equal(t, PositionOf("a\nbc", 3), Position{Offset: 3, Line: 2, Column: 2})
+	assert.Equal(t, PositionOf("a", 10), Position{Offset: 1, Line: 1, Column: 2})
+	assert.Equal(t, PositionOf("a", -1), Position{Offset: 0, Line: 1, Column: 1})
+}
+
+func TestLexerOperators(t *testing.T) {
+	tokens := lex(`a=b == c != d <> e < f <= g > h >= i | j || k ~ l << m >> n :: o; p[1] : q`, 0)
+
+	var kinds []TokenKind
+	for _, token := range tokens {
+		if token.Kind != WS {
+			kinds = append(kinds, token.Kind)
+		}
+	}
+	assert.Equal(t, kinds, []TokenKind{
+		IDENT, EQ, IDENT, EQ, IDENT, NEQ, IDENT, NEQ, IDENT, LT, IDENT, LTE, IDENT, GT, IDENT, GTE, IDENT,
+		BITOR, IDENT, CONCAT, IDENT, BITNOT, IDENT, LSHIFT, IDENT, RSHIFT, IDENT, DCOLON, IDENT, SEMICOLON,
+		IDENT, LBRACKET, IDENT, RBRACKET, COLON, IDENT,
+	})
+	assert.Equal(t, tokens[4], Token{Kind: EQ, Value: "==", Pos: Position{Offset: 4, Line: 1, Column: 5}})
+}
+
+func TestLexerParams(t *testing.T) {
+	tokens := lex(`id=:id AND name=:person.name AND age>@age AND city=$city AND a=? AND b=?2 AND c=$1 AND d::int`, 0)
+
+	var params []string
+	for _, token := range tokens {
+		if token.Kind == PARAM {
+			params = append(params, token.Value)
+		}
+	}
+	assert.Equal(t, params, []string{":id", ":person.name", "@age", "$city", "?", "?2", "$1"})
+	assert.Equal(t, tokens[2], Token{Kind: PARAM, Value: ":id", Pos: Position{Offset: 3, Line: 1, Column: 4}})
+}
+
+func TestLexerEscapedQuotes(t *testing.T) {
+	tokens := lex(`'o''neil' "a""b"`, 0)
+	assert.Equal(t, tokens, []Token{
+		{Kind: STRING, Value: `'o''neil'`, Pos: Position{Offset: 0, Line: 1, Column: 1}},
+		{Kind: WS, Value: " ", Pos: Position{Offset: 9, Line: 1, Column: 10}},
+		{Kind: STRING, Value: `"a""b"`, Pos: Position{Offset: 10, Line: 1, Column: 11}},
+	})
+	assert.Equal(t, tokens[0].Text(), "o'neil")
+	assert.Equal(t, tokens[2].Text(), `a"b`)
+}
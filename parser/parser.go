@@ -0,0 +1,427 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is a syntax error found when parsing a statement.
+type Error struct {
+	Message string
+	Pos     Position
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func errorf(pos Position, format string, args ...interface{}) error {
+	return &Error{
+		Message: fmt.Sprintf(format, args...),
+		Pos:     pos,
+	}
+}
+
+// ParseRecords parses the record expressions of the statement, starting with
+// the record expression at the offset. Both forms of record expressions can be
+// mixed in a statement ({Person} and &Person.*).
+func ParseRecords(stmt string, offset int) ([]*RecordExpr, error) {
+	var (
+		records []*RecordExpr
+		depth   int
+		last    int
+	)
+	for offset >= 0 && offset < len(stmt) {
+		var (
+			record *RecordExpr
+			err    error
+		)
+		if stmt[offset] == '{' {
+			record, err = parseRecord(stmt, offset)
+		} else {
+			record, err = parseAmpersandRecord(stmt, offset)
+		}
+		if err != nil {
+			return nil, err
+		}
+		depth += nesting(stmt, last, offset)
+		record.Depth = depth
+		records = append(records, record)
+
+		last = record.End.Offset
+		offset = IndexOfRecord(stmt, last)
+	}
+	return records, nil
+}
+
+// IndexOfRecord returns the offset of the next record expression of the
+// statement, starting at the offset, or -1 if there isn't one. Record
+// expressions are found anywhere in the statement, including subqueries and
+// common table expressions, but braces within quoted strings, quoted
+// identifiers and comments are skipped. Ampersand record expressions
+// (&Person.*) start at the column that's written before their AS keyword, if
+// they have one.
+func IndexOfRecord(stmt string, offset int) int {
+	for i := offset; i < len(stmt); {
+		switch c := stmt[i]; {
+		case c == '{':
+			return i
+		case c == '&':
+			if start, _, ok := ampersandRecord(stmt, i, offset); ok {
+				return start
+			}
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(stmt, i, c)
+		case strings.HasPrefix(stmt[i:], "--"):
+			i = skipUntil(stmt, i, "\n")
+		case strings.HasPrefix(stmt[i:], "/*"):
+			i = skipUntil(stmt, i, "*/")
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// nesting returns the change in the number of open parentheses between the
+// offsets of the statement, skipping quoted strings and comments.
+func nesting(stmt string, start, end int) int {
+	var depth int
+	for i := start; i < end && i < len(stmt); {
+		switch c := stmt[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(stmt, i, c)
+		case strings.HasPrefix(stmt[i:], "--"):
+			i = skipUntil(stmt, i, "\n")
+		case strings.HasPrefix(stmt[i:], "/*"):
+			i = skipUntil(stmt, i, "*/")
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		default:
+			i++
+		}
+	}
+	return depth
+}
+
+// skipQuoted returns the offset directly after the closing quote of the
+// quoted string starting at the offset. Quotes are escaped by doubling them.
+func skipQuoted(stmt string, offset int, quote byte) int {
+	for i := offset + 1; i < len(stmt); i++ {
+		if stmt[i] != quote {
+			continue
+		}
+		if i+1 < len(stmt) && stmt[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(stmt)
+}
+
+// skipUntil returns the offset directly after the terminator, starting at the
+// offset.
+func skipUntil(stmt string, offset int, terminator string) int {
+	if index := strings.Index(stmt[offset:], terminator); index >= 0 {
+		return offset + index + len(terminator)
+	}
+	return len(stmt)
+}
+
+// word is a run of tokens that aren't separated by white space.
+type word []Token
+
+func (w word) text() string {
+	var text string
+	for _, token := range w {
+		text += token.Text()
+	}
+	return text
+}
+
+// pathPart is a part of a dotted path, both without and with its quotes.
+type pathPart struct {
+	text, raw string
+}
+
+// path splits the word into the parts of a dotted path. Quoted identifiers
+// keep their quotes in the raw text of the part, other than quoted strings
+// holding a whole path ('people.name'), which are split into their parts.
+func (w word) path() []pathPart {
+	var (
+		parts   []pathPart
+		current pathPart
+	)
+	for _, token := range w {
+		switch {
+		case token.Kind == DOT:
+			parts = append(parts, current)
+			current = pathPart{}
+		case token.Kind == STRING && strings.Contains(token.Text(), "."):
+			names := strings.Split(token.Text(), ".")
+			for i, name := range names {
+				current.text += name
+				current.raw += name
+				if i < len(names)-1 {
+					parts = append(parts, current)
+					current = pathPart{}
+				}
+			}
+		default:
+			current.text += token.Text()
+			current.raw += token.Value
+		}
+	}
+	return append(parts, current)
+}
+
+func (w word) isKeyword(keywords ...string) bool {
+	if len(w) != 1 || w[0].Kind != IDENT {
+		return false
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(w[0].Value, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinWords(words []word) string {
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.text()
+	}
+	return strings.Join(texts, " ")
+}
+
+func parseRecord(stmt string, offset int) (*RecordExpr, error) {
+	lexer := NewLexer(stmt, offset)
+	open := lexer.Next()
+
+	record := &RecordExpr{
+		Pos: open.Pos,
+	}
+
+	// Lex the tokens of the record, splitting them into words.
+	var (
+		words   []word
+		current word
+		illegal *Token
+	)
+	for {
+		token := lexer.Next()
+		switch token.Kind {
+		case RBRACE:
+			record.End = lexer.pos
+		case EOF:
+			return nil, errorf(record.Pos, "missing closing brace for record expression %q", record.Text)
+		case UNKNOWN, BITAND, LBRACE, LBRACKET, RBRACKET, SEMICOLON, COLON, PARAM:
+			return nil, errorf(token.Pos, "unexpected struct name at %d in record expression %q", token.Pos.Offset-offset, stmt[offset+1:token.Pos.Offset+1])
+		case ILLEGAL:
+			if illegal == nil {
+				illegal = &token
+			}
+		}
+		if token.Kind == RBRACE {
+			break
+		}
+
+		record.Text += token.Text()
+		if token.Kind == WS {
+			if len(current) > 0 {
+				words = append(words, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, token)
+	}
+	if len(current) > 0 {
+		words = append(words, current)
+	}
+
+	if illegal != nil {
+		return nil, errorf(record.Pos, "missing quote %q terminator for record expression %q", illegal.Value[:1], record.Text)
+	}
+
+	// Split off any excluded fields, which always come last in the record
+	// expression: `{Person EXCEPT password}`.
+	for i, w := range words {
+		if !w.isKeyword("except", "excluding") {
+			continue
+		}
+		record.Exclude = strings.FieldsFunc(joinWords(words[i+1:]), func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+		if len(record.Exclude) == 0 {
+			return nil, errorf(record.Pos, "missing excluded fields in record expression %q", record.Text)
+		}
+		words = words[:i]
+		break
+	}
+
+	// Split off the alias of the record, which allows the same type to be
+	// used more than once: `{m.* INTO Person AS mother}`.
+	if num := len(words); num > 2 && words[num-2].isKeyword("as") {
+		record.Alias = words[num-1].text()
+		words = words[:num-2]
+	}
+
+	switch num := len(words); {
+	case num == 1 && record.Alias == "" && len(record.Exclude) == 0 && words[0].text() == "order":
+		record.Order = true
+	case num == 1:
+		record.Entity = words[0].text()
+
+		// The table of an entity is written as a field of the entity. Nested
+		// records are named by the Go fields, which are never lower case, so
+		// the two can't be confused.
+		if entity := strings.TrimSuffix(record.Entity, ".table"); entity != record.Entity && record.Alias == "" && len(record.Exclude) == 0 {
+			record.Entity = entity
+			record.Table = true
+		}
+	case num == 2 && words[0].isKeyword("where") && record.Alias == "" && len(record.Exclude) == 0:
+		record.Entity = words[1].text()
+		record.Where = true
+	case num > 1 && words[num-2].isKeyword("into"):
+		record.Entity = words[num-1].text()
+
+		var err error
+		if record.Columns, err = parseColumns(record, words[:num-2]); err != nil {
+			return nil, err
+		}
+	case num > 2 && words[num-3].isKeyword("into") && !words[num-1].isKeyword("as", "into"):
+		// The short alias of the record replaces the table in the aliases of
+		// its columns: `{p.* INTO Person pp}`.
+		short := words[num-1]
+		if len(short) != 1 || short[0].Kind != IDENT {
+			return nil, errorf(short[0].Pos, "unexpected short alias %q in record expression %q", short.text(), record.Text)
+		}
+		record.Entity = words[num-2].text()
+		record.ShortAlias = short.text()
+
+		var err error
+		if record.Columns, err = parseColumns(record, words[:num-3]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errorf(record.Pos, "unexpected record expression %q", record.Text)
+	}
+	return record, nil
+}
+
+// parseColumns parses the columns of a record expression, which are separated
+// by commas or white space. Commas within parentheses belong to expressions.
+func parseColumns(record *RecordExpr, words []word) ([]Column, error) {
+	var (
+		items [][]word
+		item  []word
+		depth int
+	)
+	for _, w := range words {
+		var current word
+		for _, token := range w {
+			switch token.Kind {
+			case LPAREN:
+				depth++
+			case RPAREN:
+				depth--
+			case COMMA:
+				if depth == 0 {
+					if len(current) > 0 {
+						item = append(item, current)
+						current = nil
+					}
+					items = append(items, item)
+					item = nil
+					continue
+				}
+			}
+			current = append(current, token)
+		}
+		if len(current) > 0 {
+			item = append(item, current)
+		}
+	}
+	items = append(items, item)
+
+	var columns []Column
+	for _, item := range items {
+		if len(item) == 0 {
+			continue
+		}
+
+		// Computed columns are written as `expression AS field`.
+		if num := len(item); num > 2 && item[num-2].isKeyword("as") {
+			expression := joinWords(item[:num-2])
+			if !balanced(item[:num-2]) {
+				return nil, errorf(item[0][0].Pos, "unbalanced parentheses in expression %q for record expression %q", expression, record.Text)
+			}
+			columns = append(columns, Column{
+				Pos:  item[0][0].Pos,
+				Name: item[num-1].text(),
+				Expr: expression,
+			})
+			continue
+		}
+		if isExpression(item) {
+			return nil, errorf(item[0][0].Pos, "missing alias for expression %q in record expression %q", joinWords(item), record.Text)
+		}
+
+		for _, w := range item {
+			parts := w.path()
+			if len(parts) > 3 {
+				return nil, errorf(w[0].Pos, "unexpected field %q in record expression %q", w.text(), record.Text)
+			}
+			column := Column{
+				Pos:  w[0].Pos,
+				Name: strings.TrimSpace(parts[len(parts)-1].text),
+			}
+			switch len(parts) {
+			case 3:
+				column.Schema = parts[0].text
+				column.Prefix = parts[1].text
+				column.Qualifier = parts[0].raw + "." + parts[1].raw
+			case 2:
+				column.Prefix = parts[0].text
+				column.Qualifier = parts[0].raw
+			}
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+func balanced(words []word) bool {
+	var depth int
+	for _, w := range words {
+		for _, token := range w {
+			switch token.Kind {
+			case LPAREN:
+				depth++
+			case RPAREN:
+				depth--
+			}
+		}
+	}
+	return depth == 0
+}
+
+func isExpression(words []word) bool {
+	for _, w := range words {
+		for _, token := range w {
+			switch token.Kind {
+			case LPAREN, RPAREN, PLUS, MINUS, SLASH, PERCENT, DCOLON, EQ, NEQ, LT, LTE, GT, GTE, BITOR, BITNOT, LSHIFT, RSHIFT, CONCAT:
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,334 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecords(t *testing.T) {
+	stmt := `SELECT {p.name, p.age INTO Person AS p}, {Location EXCEPT id} FROM people`
+	records, err := ParseRecords(stmt, 7)
+	assert.Nil(t, err)
+	assert.Equal(t, records, []*RecordExpr{{
+		Pos:    Position{Offset: 7, Line: 1, Column: 8},
+		End:    Position{Offset: 39, Line: 1, Column: 40},
+		Text:   "p.name, p.age INTO Person AS p",
+		Entity: "Person",
+		Alias:  "p",
+		Columns: []Column{
+			{Pos: Position{Offset: 8, Line: 1, Column: 9}, Prefix: "p", Qualifier: "p", Name: "name"},
+			{Pos: Position{Offset: 16, Line: 1, Column: 17}, Prefix: "p", Qualifier: "p", Name: "age"},
+		},
+	}, {
+		Pos:     Position{Offset: 41, Line: 1, Column: 42},
+		End:     Position{Offset: 61, Line: 1, Column: 62},
+		Text:    "Location EXCEPT id",
+		Entity:  "Location",
+		Exclude: []string{"id"},
+	}})
+}
+
+func TestParseRecordsWithExpressions(t *testing.T) {
+	stmt := `{COUNT(*) AS total, MAX(age, 1) + 1 AS max_age INTO Stats}`
+	records, err := ParseRecords(stmt, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[0].Columns, []Column{
+		{Pos: Position{Offset: 1, Line: 1, Column: 2}, Name: "total", Expr: "COUNT(*)"},
+		{Pos: Position{Offset: 20, Line: 1, Column: 21}, Name: "max_age", Expr: "MAX(age, 1) + 1"},
+	})
+}
+
+func TestParseRecordsWithOperators(t *testing.T) {
+	records, err := ParseRecords(`{age >= 18 AS adult, age::text AS label, first || last AS name INTO Person}`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[0].Columns, []Column{
+		{Pos: Position{Offset: 1, Line: 1, Column: 2}, Name: "adult", Expr: "age >= 18"},
+		{Pos: Position{Offset: 21, Line: 1, Column: 22}, Name: "label", Expr: "age::text"},
+		{Pos: Position{Offset: 41, Line: 1, Column: 42}, Name: "name", Expr: "first || last"},
+	})
+}
+
+func TestParseRecordsWithShortAlias(t *testing.T) {
+	records, err := ParseRecords(`{p.name, p.age INTO Person pp AS mother EXCEPT age}`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[0].Entity, "Person")
+	assert.Equal(t, records[0].ShortAlias, "pp")
+	assert.Equal(t, records[0].Alias, "mother")
+	assert.Equal(t, records[0].Exclude, []string{"age"})
+	assert.Len(t, records[0].Columns, 2)
+
+	_, err = ParseRecords(`{p.* INTO Person 'pp'}`, 0)
+	assert.Equal(t, err.Error(), `unexpected short alias "pp" in record expression "p.* INTO Person pp"`)
+	assert.Equal(t, err.(*Error).Pos.Offset, 17)
+}
+
+func TestParseRecordsWithQuotes(t *testing.T) {
+	records, err := ParseRecords(`{'foo.*' INTO Foo}`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[0].Columns, []Column{
+		{Pos: Position{Offset: 1, Line: 1, Column: 2}, Prefix: "foo", Qualifier: "foo", Name: "*"},
+	})
+	assert.True(t, records[0].Columns[0].IsWildcard())
+}
+
+func TestParseRecordsSkipsQuotesAndComments(t *testing.T) {
+	stmt := `SELECT '{x}', "{y}", {Person} FROM (SELECT {Location} FROM l) -- {z}
+/* {w} */ WHERE data='it''s {v}';`
+	records, err := ParseRecords(stmt, IndexOfRecord(stmt, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, len(records), 2)
+	assert.Equal(t, records[0].Entity, "Person")
+	assert.Equal(t, records[0].Pos.Offset, 21)
+	assert.Equal(t, records[1].Entity, "Location")
+	assert.Equal(t, records[1].Pos.Offset, 43)
+}
+
+func TestParseRecordsWithCommonTableExpressions(t *testing.T) {
+	stmt := `WITH adults AS (SELECT {Person} FROM people WHERE age > 18),
+	owners AS (SELECT owner_id FROM pets WHERE id IN (SELECT {pets.id INTO Pet} FROM pets))
+SELECT {adults.* INTO Person}, '(' FROM adults WHERE id IN (SELECT owner_id FROM owners) AND {Location} IS NULL;`
+	records, err := ParseRecords(stmt, IndexOfRecord(stmt, 0))
+	assert.Nil(t, err)
+
+	var depths []int
+	for _, record := range records {
+		depths = append(depths, record.Depth)
+	}
+	assert.Equal(t, depths, []int{1, 2, 0, 0})
+}
+
+func TestIndexOfRecord(t *testing.T) {
+	assert.Equal(t, IndexOfRecord(`INSERT INTO x SELECT {Person} FROM y`, 0), 21)
+	assert.Equal(t, IndexOfRecord(`SELECT '{"a":1}' -- {Person}`, 0), -1)
+	assert.Equal(t, IndexOfRecord("SELECT `{a}`, {Person}", 0), 14)
+	assert.Equal(t, IndexOfRecord(`SELECT {Person}, {Location}`, 8), 17)
+}
+
+func TestParseRecordsWithQualifiedTables(t *testing.T) {
+	stmt := `{"main"."people".name, "main"."people".age INTO Person}, {other.pets.* INTO Pet}, {[people].id, ` + "`people`.name" + ` INTO Person}`
+	records, err := ParseRecords(stmt, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[0].Columns, []Column{
+		{Pos: Position{Offset: 1, Line: 1, Column: 2}, Schema: "main", Prefix: "people", Qualifier: `"main"."people"`, Name: "name"},
+		{Pos: Position{Offset: 23, Line: 1, Column: 24}, Schema: "main", Prefix: "people", Qualifier: `"main"."people"`, Name: "age"},
+	})
+	assert.Equal(t, records[1].Columns, []Column{
+		{Pos: Position{Offset: 58, Line: 1, Column: 59}, Schema: "other", Prefix: "pets", Qualifier: "other.pets", Name: "*"},
+	})
+	assert.Equal(t, records[2].Columns, []Column{
+		{Pos: Position{Offset: 83, Line: 1, Column: 84}, Prefix: "people", Qualifier: "[people]", Name: "id"},
+		{Pos: Position{Offset: 96, Line: 1, Column: 97}, Prefix: "people", Qualifier: "`people`", Name: "name"},
+	})
+
+	// Square brackets are only quotes at the start of a word.
+	_, err = ParseRecords(`{tags[1] AS tag INTO Tag}`, 0)
+	assert.Equal(t, err.Error(), `unexpected struct name at 5 in record expression "tags["`)
+}
+
+func TestParseRecordsWithTables(t *testing.T) {
+	records, err := ParseRecords(`{Person} FROM {Person.table} JOIN {Person.Address.table}`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[1], &RecordExpr{
+		Pos:    Position{Offset: 14, Line: 1, Column: 15},
+		End:    Position{Offset: 28, Line: 1, Column: 29},
+		Text:   "Person.table",
+		Entity: "Person",
+		Table:  true,
+	})
+	assert.Equal(t, records[2].Entity, "Person.Address")
+	assert.True(t, records[2].Table)
+	assert.False(t, records[0].Table)
+}
+
+func TestParseRecordsWithWhereClauses(t *testing.T) {
+	records, err := ParseRecords(`{Person} FROM people {WHERE Filters} ORDER BY name`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[1], &RecordExpr{
+		Pos:    Position{Offset: 21, Line: 1, Column: 22},
+		End:    Position{Offset: 36, Line: 1, Column: 37},
+		Text:   "WHERE Filters",
+		Entity: "Filters",
+		Where:  true,
+	})
+	assert.False(t, records[0].Where)
+}
+
+func TestParseRecordsWithOrderClauses(t *testing.T) {
+	records, err := ParseRecords(`{Person} FROM people {order} LIMIT 10`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[1], &RecordExpr{
+		Pos:   Position{Offset: 21, Line: 1, Column: 22},
+		End:   Position{Offset: 28, Line: 1, Column: 29},
+		Text:  "order",
+		Order: true,
+	})
+
+	// Types are never lower case keywords, so only {order} is an order
+	// clause.
+	records, err = ParseRecords(`{Order}`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[0].Entity, "Order")
+	assert.False(t, records[0].Order)
+}
+
+func TestParseRecordsErrors(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		message string
+		offset  int
+	}{{
+		stmt:    `{test Person}`,
+		message: `unexpected record expression "test Person"`,
+	}, {
+		stmt:    `{'test.name INTO Person}`,
+		message: `missing quote "'" terminator for record expression "test.name INTO Person"`,
+	}, {
+		stmt:    `{Person;}`,
+		message: `unexpected struct name at 7 in record expression "Person;"`,
+		offset:  7,
+	}, {
+		stmt:    `{id = :id INTO Person}`,
+		message: `unexpected struct name at 6 in record expression "id = :"`,
+		offset:  6,
+	}, {
+		stmt:    `{age >= 18 INTO Person}`,
+		message: `missing alias for expression "age >= 18" in record expression "age >= 18 INTO Person"`,
+		offset:  1,
+	}, {
+		stmt:    `{Person`,
+		message: `missing closing brace for record expression "Person"`,
+	}, {
+		stmt:    `{a.b.c.d INTO Person}`,
+		message: `unexpected field "a.b.c.d" in record expression "a.b.c.d INTO Person"`,
+		offset:  1,
+	}, {
+		stmt:    `{age + 1 INTO Person}`,
+		message: `missing alias for expression "age + 1" in record expression "age + 1 INTO Person"`,
+		offset:  1,
+	}, {
+		stmt:    `{where Filters AS f}`,
+		message: `unexpected record expression "where Filters AS f"`,
+	}, {
+		stmt:    `{Person EXCEPT}`,
+		message: `missing excluded fields in record expression "Person EXCEPT"`,
+	}}
+	for _, test := range tests {
+		_, err := ParseRecords(test.stmt, 0)
+		assert.Equal(t, err.Error(), test.message, test.stmt)
+		assert.Equal(t, err.(*Error).Pos.Offset, test.offset, test.stmt)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	path, err := ParsePath("person.address.city")
+	assert.Nil(t, err)
+	assert.Equal(t, path, []string{"person", "address", "city"})
+
+	path, err = ParsePath("name")
+	assert.Nil(t, err)
+	assert.Equal(t, path, []string{"name"})
+}
+
+func TestParsePathErrors(t *testing.T) {
+	_, err := ParsePath("person.")
+	assert.Equal(t, err.Error(), `unexpected "" in path "person."`)
+
+	_, err = ParsePath("person..name")
+	assert.Equal(t, err.Error(), `unexpected "." in path "person..name"`)
+
+	_, err = ParsePath("person name")
+	assert.Equal(t, err.Error(), `unexpected " " in path "person name"`)
+	assert.Equal(t, err.(*Error).Pos.Offset, 6)
+}
+
+func TestParseAmpersandRecords(t *testing.T) {
+	stmt := `SELECT &Person.*, p.name AS &Person.name, "p".* AS &Person.*, COUNT(p.id) AS &Stats.total, {Location} FROM people AS p`
+	records, err := ParseRecords(stmt, IndexOfRecord(stmt, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, records, []*RecordExpr{{
+		Pos:       Position{Offset: 7, Line: 1, Column: 8},
+		End:       Position{Offset: 16, Line: 1, Column: 17},
+		Text:      "&Person.*",
+		Entity:    "Person",
+		Ampersand: true,
+	}, {
+		Pos:       Position{Offset: 18, Line: 1, Column: 19},
+		End:       Position{Offset: 40, Line: 1, Column: 41},
+		Text:      "p.name AS &Person.name",
+		Entity:    "Person",
+		Ampersand: true,
+		Columns: []Column{
+			{Pos: Position{Offset: 18, Line: 1, Column: 19}, Prefix: "p", Qualifier: "p", Name: "name"},
+		},
+	}, {
+		Pos:       Position{Offset: 42, Line: 1, Column: 43},
+		End:       Position{Offset: 60, Line: 1, Column: 61},
+		Text:      `"p".* AS &Person.*`,
+		Entity:    "Person",
+		Ampersand: true,
+		Columns: []Column{
+			{Pos: Position{Offset: 42, Line: 1, Column: 43}, Prefix: "p", Qualifier: `"p"`, Name: "*"},
+		},
+	}, {
+		Pos:       Position{Offset: 62, Line: 1, Column: 63},
+		End:       Position{Offset: 89, Line: 1, Column: 90},
+		Text:      "COUNT(p.id) AS &Stats.total",
+		Entity:    "Stats",
+		Ampersand: true,
+		Columns: []Column{
+			{Pos: Position{Offset: 62, Line: 1, Column: 63}, Name: "total", Expr: "COUNT(p.id)"},
+		},
+	}, {
+		Pos:    Position{Offset: 91, Line: 1, Column: 92},
+		End:    Position{Offset: 101, Line: 1, Column: 102},
+		Text:   "Location",
+		Entity: "Location",
+	}})
+}
+
+func TestParseAmpersandRecordsErrorsMismatchedWildcard(t *testing.T) {
+	_, err := ParseRecords(`SELECT p.name AS &Person.*`, 7)
+	assert.Equal(t, err.Error(), `unexpected wildcard in record expression "p.name AS &Person.*", expected both columns to be wildcards`)
+}
+
+func TestIndexOfAmpersandRecord(t *testing.T) {
+	assert.Equal(t, IndexOfRecord(`SELECT &Person.* FROM people`, 0), 7)
+	assert.Equal(t, IndexOfRecord(`SELECT p.name AS &Person.name FROM people AS p`, 0), 7)
+	assert.Equal(t, IndexOfRecord(`SELECT a + b AS &Sum.total`, 0), 11)
+	// Bitwise ANDs aren't record expressions.
+	assert.Equal(t, IndexOfRecord(`SELECT flags&mask, flags & 1, flags&p.mask FROM people`, 0), -1)
+	assert.Equal(t, IndexOfRecord(`SELECT '&Person.*' -- &Person.*`, 0), -1)
+}
+
+func TestParseRecordsWithMultipleTables(t *testing.T) {
+	records, err := ParseRecords(`{test.name, test.age, "main".other.city INTO Person}`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, records[0].Columns, []Column{
+		{Pos: Position{Offset: 1, Line: 1, Column: 2}, Prefix: "test", Qualifier: "test", Name: "name"},
+		{Pos: Position{Offset: 12, Line: 1, Column: 13}, Prefix: "test", Qualifier: "test", Name: "age"},
+		{Pos: Position{Offset: 22, Line: 1, Column: 23}, Schema: "main", Prefix: "other", Qualifier: `"main".other`, Name: "city"},
+	})
+}
+
+func TestParseRecordsMultipleLines(t *testing.T) {
+	stmt := "SELECT {p.name,\n\tp.age\r\n\tINTO Person},\n  COUNT(*) AS &Stats.total\nFROM people AS p;"
+	records, err := ParseRecords(stmt, IndexOfRecord(stmt, 0))
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, records[0].Pos, Position{Offset: 7, Line: 1, Column: 8})
+	assert.Equal(t, records[0].End, Position{Offset: 37, Line: 3, Column: 14})
+	assert.Equal(t, records[0].Columns[0].Pos, Position{Offset: 8, Line: 1, Column: 9})
+	assert.Equal(t, records[0].Columns[1].Pos, Position{Offset: 17, Line: 2, Column: 2})
+	assert.Equal(t, records[1].Pos, Position{Offset: 41, Line: 4, Column: 3})
+	assert.Equal(t, records[1].End, Position{Offset: 65, Line: 4, Column: 27})
+
+	// The positions of the lexer agree with the positions of the offsets.
+	for _, record := range records {
+		assert.Equal(t, PositionOf(stmt, record.Pos.Offset), record.Pos)
+		assert.Equal(t, PositionOf(stmt, record.End.Offset), record.End)
+	}
+
+	_, err = ParseRecords("SELECT\n\t{Person\n\tAS}", 8)
+	assert.Equal(t, err.(*Error).Pos, Position{Offset: 8, Line: 2, Column: 2})
+}
@@ -0,0 +1,25 @@
+package parser
+
+// ParsePath parses a dotted path of identifiers, such as person.address.city,
+// which is used by record expressions and named arguments to reach into
+// nested types.
+func ParsePath(input string) ([]string, error) {
+	lexer := NewLexer(input, 0)
+
+	var path []string
+	for {
+		token := lexer.Next()
+		if token.Kind != IDENT {
+			return nil, errorf(token.Pos, "unexpected %q in path %q", token.Value, input)
+		}
+		path = append(path, token.Value)
+
+		switch next := lexer.Next(); next.Kind {
+		case EOF:
+			return path, nil
+		case DOT:
+		default:
+			return nil, errorf(next.Pos, "unexpected %q in path %q", next.Value, input)
+		}
+	}
+}
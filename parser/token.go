@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TokenKind is the kind of a lexed token.
+type TokenKind int
+
+const (
+	// UNKNOWN is a rune that the lexer doesn't know about.
+	UNKNOWN TokenKind = iota
+	// ILLEGAL is a token that couldn't be lexed, for example a quoted string
+	// without a terminating quote.
+	ILLEGAL
+	EOF
+	WS
+
+	// IDENT is a run of letters, digits and underscores.
+	IDENT
+	// STRING is a quoted string or identifier, which is quoted with single
+	// quotes, double quotes, backticks or square brackets. Quotes within the
+	// string are escaped by doubling them ('o''neil').
+	STRING
+	// PARAM is a parameter of a statement, which is either a named argument
+	// (:name, :person.name, @name or $name) or a positional placeholder (?,
+	// ?1 or $1).
+	PARAM
+
+	LBRACE   // {
+	RBRACE   // }
+	LPAREN   // (
+	RPAREN   // )
+	COMMA    // ,
+	DOT      // .
+	ASTERISK // *
+	BITAND   // &
+	PLUS     // +
+	MINUS    // -
+	SLASH    // /
+	PERCENT  // %
+
+	SEMICOLON // ;
+	COLON     // :
+	DCOLON    // ::
+	LBRACKET  // [
+	RBRACKET  // ]
+	EQ        // = or ==
+	NEQ       // != or <>
+	LT        // <
+	LTE       // <=
+	GT        // >
+	GTE       // >=
+	BITOR     // |
+	BITNOT    // ~
+	LSHIFT    // <<
+	RSHIFT    // >>
+	CONCAT    // ||
+)
+
+var tokenNames = map[TokenKind]string{
+	UNKNOWN:  "UNKNOWN",
+	ILLEGAL:  "ILLEGAL",
+	EOF:      "EOF",
+	WS:       "WS",
+	IDENT:    "IDENT",
+	STRING:   "STRING",
+	LBRACE:   "{",
+	RBRACE:   "}",
+	LPAREN:   "(",
+	RPAREN:   ")",
+	COMMA:    ",",
+	DOT:      ".",
+	ASTERISK: "*",
+	BITAND:   "&",
+	PLUS:     "+",
+	MINUS:    "-",
+	SLASH:    "/",
+	PERCENT:  "%",
+	PARAM:    "PARAM",
+
+	SEMICOLON: ";",
+	COLON:     ":",
+	DCOLON:    "::",
+	LBRACKET:  "[",
+	RBRACKET:  "]",
+	EQ:        "=",
+	NEQ:       "!=",
+	LT:        "<",
+	LTE:       "<=",
+	GT:        ">",
+	GTE:       ">=",
+	BITOR:     "|",
+	BITNOT:    "~",
+	LSHIFT:    "<<",
+	RSHIFT:    ">>",
+	CONCAT:    "||",
+}
+
+func (k TokenKind) String() string {
+	if name, ok := tokenNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+var punctuation = map[byte]TokenKind{
+	'{': LBRACE,
+	'}': RBRACE,
+	'(': LPAREN,
+	')': RPAREN,
+	',': COMMA,
+	'.': DOT,
+	'*': ASTERISK,
+	'&': BITAND,
+	'+': PLUS,
+	'-': MINUS,
+	'/': SLASH,
+	'%': PERCENT,
+	';': SEMICOLON,
+	':': COLON,
+	'[': LBRACKET,
+	']': RBRACKET,
+	'=': EQ,
+	'<': LT,
+	'>': GT,
+	'|': BITOR,
+	'~': BITNOT,
+}
+
+// operators are the tokens of two characters, which are lexed in preference
+// to their first character (<= rather than <).
+var operators = map[string]TokenKind{
+	"::": DCOLON,
+	"==": EQ,
+	"!=": NEQ,
+	"<>": NEQ,
+	"<=": LTE,
+	">=": GTE,
+	"<<": LSHIFT,
+	">>": RSHIFT,
+	"||": CONCAT,
+}
+
+// Token is a lexed token, along with the position it was found at.
+type Token struct {
+	Kind  TokenKind
+	Value string
+	Pos   Position
+}
+
+// Text returns the value of the token. Quoted strings are returned without
+// their quotes, and with any escaped quotes unescaped.
+func (t Token) Text() string {
+	switch t.Kind {
+	case STRING:
+		quote := t.Value[len(t.Value)-1:]
+		return strings.ReplaceAll(t.Value[1:len(t.Value)-1], quote+quote, quote)
+	case ILLEGAL:
+		if len(t.Value) > 0 && (isQuote(t.Value[0]) || t.Value[0] == '[') {
+			return t.Value[1:]
+		}
+	}
+	return t.Value
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%s(%q) at %s", t.Kind, t.Value, t.Pos)
+}
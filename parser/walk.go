@@ -0,0 +1,53 @@
+package parser
+
+// Visitor visits the nodes of a statement with Walk. If the visitor returned
+// by Visit is nil, the children of the node aren't visited.
+type Visitor interface {
+	Visit(node Node) Visitor
+}
+
+// Walk traverses the node in depth-first order: it calls v.Visit(node), and
+// then walks each of the children of the node with the returned visitor,
+// followed by a call of Visit(nil). The records of a statement are its
+// children, and the columns of a record are its children.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Statement:
+		for _, record := range n.Records {
+			Walk(v, record)
+		}
+	case *RecordExpr:
+		for i := range n.Columns {
+			Walk(v, &n.Columns[i])
+		}
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the node in depth-first order, calling f for each node
+// and then f(nil) once its children have been visited. If f returns false,
+// the children of the node aren't visited.
+//
+//  parser.Inspect(stmt, func(node parser.Node) bool {
+//  	if record, ok := node.(*parser.RecordExpr); ok {
+//  		fmt.Println(record.Entity)
+//  	}
+//  	return true
+//  })
+//
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
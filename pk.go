@@ -0,0 +1,135 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// Get populates the struct with the row of its table that has the primary
+// key, returning sql.ErrNoRows if there isn't one. The primary key is made up
+// of the fields with the pk tag option, and composite keys are passed in the
+// order that the fields are declared in.
+//
+//  type Person struct {
+//  	ID   int64  `db:"id,pk"`
+//  	Name string `db:"name"`
+//  }
+//
+//  var person Person
+//  err := querier.Get(tx, &person, 1)
+//
+// The table is named by the TableName method of the struct, if it has one
+// (see TableNamer), then by the table of its struct tag, otherwise by the
+// name of the type.
+func (q *Querier) Get(tx *sql.Tx, value interface{}, keys ...interface{}) error {
+	return q.GetContext(context.Background(), tx, value, keys...)
+}
+
+// GetContext populates the struct with the row of its table that has the
+// primary key. See Get.
+func (q *Querier) GetContext(ctx context.Context, tx *sql.Tx, value interface{}, keys ...interface{}) error {
+	dest := reflect.ValueOf(value)
+	if dest.Kind() != reflect.Ptr || dest.IsNil() || dest.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("expected pointer to struct, got %T", value)
+	}
+
+	table, refStruct, err := q.reflectTable(value)
+	if err != nil {
+		return err
+	}
+	where, err := primaryKeyPredicate(table, refStruct, keys)
+	if err != nil {
+		return err
+	}
+
+	// The rows are read into a slice, so that a missing row can be told
+	// apart from a row of zero values.
+	rows := reflect.New(reflect.SliceOf(dest.Elem().Type()))
+	query, err := q.ForMany(rows.Interface())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	stmt := fmt.Sprintf("SELECT {%s} FROM %s WHERE %s;", refStruct.Name, table, where)
+	if err := query.QueryContext(ctx, tx, stmt, keys...); err != nil {
+		return err
+	}
+
+	if rows.Elem().Len() == 0 {
+		return errors.WithStack(sql.ErrNoRows)
+	}
+	dest.Elem().Set(rows.Elem().Index(0))
+	return nil
+}
+
+// Exists returns true if the table of the struct has a row with the primary
+// key. See Get.
+//
+//  ok, err := querier.Exists(tx, &Person{}, 1)
+//
+func (q *Querier) Exists(tx *sql.Tx, value interface{}, keys ...interface{}) (bool, error) {
+	return q.ExistsContext(context.Background(), tx, value, keys...)
+}
+
+// ExistsContext returns true if the table of the struct has a row with the
+// primary key. See Get.
+func (q *Querier) ExistsContext(ctx context.Context, tx *sql.Tx, value interface{}, keys ...interface{}) (bool, error) {
+	table, refStruct, err := q.reflectTable(value)
+	if err != nil {
+		return false, err
+	}
+	where, err := primaryKeyPredicate(table, refStruct, keys)
+	if err != nil {
+		return false, err
+	}
+
+	if column, _, ok := softDeleteField(refStruct); ok && q.excludeDeleted {
+		where += " AND " + column + " IS NULL"
+	}
+
+	var count int
+	query, err := q.ForOne(&count)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	stmt := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s;", table, where)
+	if err := query.QueryContext(ctx, tx, stmt, keys...); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// primaryKeyColumns returns the columns of the primary key of the struct, in
+// the order that they're declared in.
+func primaryKeyColumns(refStruct sreflect.ReflectStruct) []string {
+	var columns []string
+	for _, name := range refStruct.DeclaredFieldNames() {
+		if refStruct.Fields[name].Tag.PrimaryKey {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// primaryKeyPredicate returns the WHERE predicate that matches the primary
+// key of the struct to the keys, as positional arguments.
+func primaryKeyPredicate(table string, refStruct sreflect.ReflectStruct, keys []interface{}) (string, error) {
+	columns := primaryKeyColumns(refStruct)
+	if len(columns) == 0 {
+		return "", errors.Errorf("no primary key for table %q, expected fields with the pk tag option", table)
+	}
+	if len(keys) != len(columns) {
+		return "", errors.Errorf("expected %d primary key values for table %q, got %d", len(columns), table, len(keys))
+	}
+
+	predicates := make([]string, len(columns))
+	for i, column := range columns {
+		predicates[i] = column + " = ?"
+	}
+	return strings.Join(predicates, " AND "), nil
+}
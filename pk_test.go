@@ -0,0 +1,94 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type pkPerson struct {
+	ID   int64  `db:"id,pk"`
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func (pkPerson) TableName() string {
+	return "people"
+}
+
+type pkMembership struct {
+	Team   string `db:"team,pk"`
+	Person int64  `db:"person_id,pk"`
+	Role   string `db:"role"`
+}
+
+func TestGetByPrimaryKey(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, age INTEGER);
+INSERT INTO people VALUES (1, "fred", 21), (2, "frank", 42);
+`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+	runTx(t, db, func(tx *sql.Tx) error {
+		var person pkPerson
+		err := querier.Get(tx, &person, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, person, pkPerson{ID: 2, Name: "frank", Age: 42})
+
+		err = querier.Get(tx, &person, 3)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+
+		ok, err := querier.Exists(tx, &pkPerson{}, 1)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		ok, err = querier.Exists(tx, pkPerson{}, 3)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		return nil
+	})
+}
+
+func TestGetByCompositePrimaryKey(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE pk_membership (team TEXT, person_id INTEGER, role TEXT, PRIMARY KEY (team, person_id));
+INSERT INTO pk_membership VALUES ("red", 1, "captain"), ("red", 2, "player"), ("blue", 1, "coach");
+`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+	runTx(t, db, func(tx *sql.Tx) error {
+		var membership pkMembership
+		err := querier.Get(tx, &membership, "blue", 1)
+		assert.Nil(t, err)
+		assert.Equal(t, membership, pkMembership{Team: "blue", Person: 1, Role: "coach"})
+
+		ok, err := querier.Exists(tx, &pkMembership{}, "blue", 2)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		return nil
+	})
+}
+
+func TestGetByPrimaryKeyErrors(t *testing.T) {
+	type NoKey struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+	err := querier.Get(nil, &NoKey{}, 1)
+	assert.Equal(t, err.Error(), `no primary key for table "no_key", expected fields with the pk tag option`)
+
+	err = querier.Get(nil, &pkMembership{}, "red")
+	assert.Equal(t, err.Error(), `expected 2 primary key values for table "pk_membership", got 1`)
+
+	err = querier.Get(nil, pkPerson{}, 1)
+	assert.Equal(t, err.Error(), `expected pointer to struct, got sqlair.pkPerson`)
+}
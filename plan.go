@@ -0,0 +1,240 @@
+package sqlair
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// scanPlan holds the location of the field of every column of a query, so that
+// every row can be scanned into newly allocated elements without reflecting
+// over them.
+type scanPlan struct {
+	elements []reflect.Type
+	entities []entityPath
+	targets  []columnTarget
+	// generated is true for the targets that are scanned using the
+	// generated methods of the entity, see GeneratedRecord.
+	generated []bool
+}
+
+// entityPath locates an entity from the element it belongs to. Nested entities
+// are found by walking the struct fields of the path.
+type entityPath struct {
+	element int
+	path    []string
+	// index is the index of every struct field of the path, so that the
+	// entity is located without looking up the fields by name.
+	index [][]int
+	// child is the element type of a one-to-many relation at the end of the
+	// path, which is scanned into a new child for every row and folded into
+	// the slice of the parent.
+	child reflect.Type
+	// generated is true if the entity has generated methods, and reflected
+	// is true if any of its columns are scanned by reflection.
+	generated bool
+	reflected bool
+}
+
+func (q Query) newScanPlan(columns []*sql.ColumnType, elements []sreflect.ReflectStruct, records []recordBinding) (*scanPlan, error) {
+	entities, err := q.bindEntities(records, elements)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := q.columnTargets(columns, entities, records)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &scanPlan{
+		elements: make([]reflect.Type, len(elements)),
+		entities: make([]entityPath, len(entities)),
+		targets:  targets,
+	}
+	for i, element := range elements {
+		plan.elements[i] = element.Value.Type()
+	}
+
+	// Bound entities start with the elements, followed by any nested
+	// entities, which are named after the record they're bound to.
+	for i := range elements {
+		plan.entities[i] = entityPath{element: i}
+	}
+	for i := len(elements); i < len(entities); i++ {
+		for _, record := range records {
+			if record.entityName() != entities[i].Name {
+				continue
+			}
+			parts := strings.Split(record.name, ".")
+			for j := range elements {
+				if entities[j].Name == parts[0] {
+					child, err := q.foldedChild(plan.elements[j], parts[1:])
+					if err != nil {
+						return nil, err
+					}
+					index, err := fieldIndexes(plan.elements[j], parts[1:])
+					if err != nil {
+						return nil, err
+					}
+					plan.entities[i] = entityPath{
+						element: j,
+						path:    parts[1:],
+						index:   index,
+						child:   child,
+					}
+					break
+				}
+			}
+			break
+		}
+	}
+
+	// The generated methods of a zero entity locate the columns that they
+	// scan, so that the remaining columns are scanned by reflection.
+	zero := make([]GeneratedRecord, len(entities))
+	for i := range plan.entities {
+		entity := &plan.entities[i]
+		if entity.child == nil && isGenerated(entities[i].Value.Type()) {
+			entity.generated = true
+			zero[i] = reflect.New(entities[i].Value.Type()).Interface().(GeneratedRecord)
+		}
+		entity.reflected = entity.child != nil
+	}
+	plan.generated = make([]bool, len(targets))
+	for i, target := range targets {
+		if target.entity < 0 {
+			continue
+		}
+		entity := &plan.entities[target.entity]
+		if entity.generated {
+			typ := entities[target.entity].Value.Type()
+			plan.generated[i] = q.mapper.plain(target.field, typ.FieldByIndex(target.field.Index).Type) &&
+				zero[target.entity].SqlairScan(target.column) != nil
+		}
+		if !plan.generated[i] {
+			entity.reflected = true
+		}
+	}
+	return plan, nil
+}
+
+// fieldIndexes returns the index of every struct field of the path through
+// the struct type.
+func fieldIndexes(t reflect.Type, path []string) ([][]int, error) {
+	index := make([][]int, len(path))
+	for i, name := range path {
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return nil, errors.Errorf("expected field %q in %q", name, t.Name())
+		}
+		index[i] = field.Index
+		if t = field.Type; t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	return index, nil
+}
+
+// scanner returns a rowScanner for the plan, which scans into the columnar
+// slice.
+func (p *scanPlan) scanner(columnar []interface{}) *rowScanner {
+	return &rowScanner{
+		plan:     p,
+		pointers: make([]reflect.Value, len(p.elements)),
+		values:   make([]reflect.Value, len(p.elements)),
+		entities: make([]reflect.Value, len(p.entities)),
+		records:  make([]GeneratedRecord, len(p.entities)),
+		columnar: columnar,
+	}
+}
+
+// rowScanner holds the buffers used to scan every row of a query, so that
+// they're only allocated once.
+type rowScanner struct {
+	plan     *scanPlan
+	pointers []reflect.Value
+	values   []reflect.Value
+	entities []reflect.Value
+	records  []GeneratedRecord
+	columnar []interface{}
+	folds    []foldColumn
+}
+
+// row allocates a new value for every element, returning the values along
+// with the scan destinations of the columns. The returned slices are only
+// valid until the next row. The columns of generated entities are scanned
+// into the destinations of their generated methods, without locating the
+// fields by reflection.
+func (s *rowScanner) row(mapper fieldMapper) ([]reflect.Value, []interface{}) {
+	for i, element := range s.plan.elements {
+		s.pointers[i] = reflect.New(element)
+		s.values[i] = s.pointers[i].Elem()
+	}
+
+	for i, entity := range s.plan.entities {
+		if entity.child != nil {
+			s.entities[i] = reflect.New(entity.child).Elem()
+			continue
+		}
+		ptr := s.pointers[entity.element]
+		for _, index := range entity.index {
+			ptr = fieldPointer(ptr, index)
+		}
+		if entity.generated {
+			s.records[i] = ptr.Interface().(GeneratedRecord)
+		}
+		if entity.reflected {
+			s.entities[i] = ptr.Elem()
+		}
+	}
+
+	s.folds = s.folds[:0]
+	for i, target := range s.plan.targets {
+		if target.entity < 0 {
+			s.columnar[i] = new(interface{})
+			continue
+		}
+		if s.plan.generated[i] {
+			s.columnar[i] = s.records[target.entity].SqlairScan(target.column)
+			continue
+		}
+		field := target.field
+		field.Value = sreflect.FieldByIndex(s.entities[target.entity], field.Index)
+		s.columnar[i] = mapper.destination(field)
+		if s.plan.entities[target.entity].child != nil {
+			s.columnar[i] = s.foldDestination(target.entity, field.Value, s.columnar[i])
+		}
+	}
+	return s.values, s.columnar
+}
+
+// fieldPointer returns a pointer to the struct field of the index, through
+// the struct pointer. Nil struct pointers along the way are allocated.
+func fieldPointer(ptr reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		field := ptr.Elem().Field(i)
+		if field.Kind() != reflect.Ptr {
+			ptr = field.Addr()
+			continue
+		}
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		ptr = field
+	}
+	return ptr
+}
+
+// indirect dereferences a struct pointer, allocating it if it's nil.
+func indirect(value reflect.Value) reflect.Value {
+	if value.Kind() != reflect.Ptr {
+		return value
+	}
+	if value.IsNil() {
+		value.Set(reflect.New(value.Type().Elem()))
+	}
+	return value.Elem()
+}
@@ -0,0 +1,82 @@
+package sqlair
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryManyWithEmbeddedPointer(t *testing.T) {
+	db := setupSchemaDB(t, nestedSchema)
+
+	type Location struct {
+		ID int `db:"location"`
+	}
+	type Person struct {
+		*Location
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+
+	var persons []Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.name, people.location INTO Person} FROM people ORDER BY people.name;`)
+	})
+
+	// Every row is scanned into its own element, so the embedded pointers
+	// must never be shared.
+	assert.Equal(t, persons, []Person{
+		{Location: &Location{ID: 2}, Name: "frank"},
+		{Location: &Location{ID: 1}, Name: "fred"},
+		{Location: &Location{ID: 1}, Name: "jane"},
+	})
+	assert.True(t, persons[1].Location != persons[2].Location)
+}
+
+func BenchmarkForMany(b *testing.B) {
+	db := setupDB(b)
+	db.SetMaxOpenConns(1)
+
+	var values []string
+	for i := 0; i < 1000; i++ {
+		values = append(values, fmt.Sprintf("(\"person%d\", %d, %d)", i, i, i%10))
+	}
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name     TEXT,
+	age      INTEGER,
+	location INTEGER
+);
+INSERT INTO people(name, age, location) VALUES ` + strings.Join(values, ", ") + `;`)
+	assert.Nil(b, err)
+
+	type Person struct {
+		Name     string `db:"name"`
+		Age      int    `db:"age"`
+		Location int    `db:"location"`
+	}
+
+	querier := NewQuerier()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var persons []Person
+		runTx(b, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForMany(&persons)
+			if err != nil {
+				return err
+			}
+			return getter.Query(tx, `SELECT {people.* INTO Person} FROM people;`)
+		})
+		if len(persons) != 1000 {
+			b.Fatalf("expected 1000 persons, got %d", len(persons))
+		}
+	}
+}
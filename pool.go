@@ -0,0 +1,68 @@
+package sqlair
+
+import "sync"
+
+// bufferPool reuses the scan destination slices of queries. The slices are
+// pooled by their capacity, in size classes of powers of two, so that the
+// pool doesn't grow with the statements that are executed.
+type bufferPool struct {
+	pools [bufferClasses]sync.Pool
+}
+
+const (
+	// minBufferClass is the capacity of the smallest size class, as a power
+	// of two, and slices with more columns than the largest size class aren't
+	// pooled.
+	minBufferClass = 3
+	bufferClasses  = 8
+)
+
+func newBufferPool() *bufferPool {
+	return &bufferPool{}
+}
+
+// Get returns a slice of length n.
+func (p *bufferPool) Get(n int) *[]interface{} {
+	class := bufferClass(n)
+	if class >= bufferClasses {
+		buffer := make([]interface{}, n)
+		return &buffer
+	}
+	if buffer, ok := p.pools[class].Get().(*[]interface{}); ok {
+		*buffer = (*buffer)[:n]
+		return buffer
+	}
+	buffer := make([]interface{}, n, 1<<(class+minBufferClass))
+	return &buffer
+}
+
+// Put returns the slice to the pool of its size class. The values of the
+// slice are cleared, so that the pool never holds on to the destinations.
+func (p *bufferPool) Put(buffer *[]interface{}) {
+	for i := range *buffer {
+		(*buffer)[i] = nil
+	}
+	// Only the slices of the pool are returned to it, which have the
+	// capacity of their size class.
+	c := cap(*buffer)
+	if class := bufferClass(c); class < bufferClasses && c == 1<<(class+minBufferClass) {
+		p.pools[class].Put(buffer)
+	}
+}
+
+// bufferClass returns the size class of a slice of length n.
+func bufferClass(n int) int {
+	class := 0
+	for 1<<(class+minBufferClass) < n {
+		class++
+	}
+	return class
+}
+
+// namesPool reuses the named argument bindings of statements.
+var namesPool = sync.Pool{
+	New: func() interface{} {
+		names := make([]nameBinding, 0, 8)
+		return &names
+	},
+}
@@ -0,0 +1,90 @@
+package sqlair
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferPool(t *testing.T) {
+	pool := newBufferPool()
+
+	buffer := pool.Get(2)
+	assert.Equal(t, len(*buffer), 2)
+	assert.Equal(t, cap(*buffer), 8)
+
+	name := "fred"
+	(*buffer)[0] = &name
+	pool.Put(buffer)
+
+	// Buffers are cleared when returned, so that they never hold on to the
+	// destinations of a query.
+	assert.Equal(t, *buffer, []interface{}{nil, nil})
+
+	assert.Equal(t, len(*pool.Get(3)), 3)
+	assert.Equal(t, cap(*pool.Get(9)), 16)
+
+	// Buffers that are larger than the largest size class aren't pooled.
+	large := pool.Get(2000)
+	assert.Equal(t, len(*large), 2000)
+	pool.Put(large)
+}
+
+func TestBufferClass(t *testing.T) {
+	assert.Equal(t, bufferClass(0), 0)
+	assert.Equal(t, bufferClass(8), 0)
+	assert.Equal(t, bufferClass(9), 1)
+	assert.Equal(t, bufferClass(1024), 7)
+	assert.Equal(t, bufferClass(1025), 8)
+}
+
+func TestAppendNames(t *testing.T) {
+	names, err := appendNames(make([]nameBinding, 0, 4), "SELECT * FROM people WHERE name=:name;", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, names, []nameBinding{
+		{':', "name", 32},
+	})
+	assert.Equal(t, cap(names), 4)
+}
+
+func BenchmarkForOne(b *testing.B) {
+	db := setupDB(b)
+	db.SetMaxOpenConns(1)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name     TEXT,
+	age      INTEGER,
+	location INTEGER
+);
+INSERT INTO people(name, age, location) VALUES ("fred", 21, 1), ("frank", 42, 2);
+	`)
+	assert.Nil(b, err)
+
+	type Person struct {
+		Name     string `db:"name"`
+		Age      int    `db:"age"`
+		Location int    `db:"location"`
+	}
+
+	querier := NewQuerier()
+
+	var person Person
+	tx, err := db.Begin()
+	assert.Nil(b, err)
+	defer tx.Rollback()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getter, err := querier.ForOne(&person)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := getter.Query(tx, `SELECT {people.* INTO Person} FROM people WHERE people.name=:name;`, map[string]interface{}{
+			"name": "fred",
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// Preload loads the related rows of structs that have already been loaded,
+// with a query for every relation. The relations are named by their Go
+// fields, which are tagged with either the hasmany or belongsto option:
+//
+//  type Person struct {
+//  	ID        int64    `db:"id,pk"`
+//  	AddressID int64    `db:"address_id"`
+//  	Address   *Address `db:"address,belongsto=address_id"`
+//  	Pets      []Pet    `db:"pets,hasmany=person_id"`
+//  }
+//
+//  err := querier.Preload(tx, &people, "Pets", "Address")
+//
+// A hasmany relation names the column of the child table that holds the
+// primary key of the parent, so the pets are loaded with:
+//
+//  SELECT {Pet} FROM pet WHERE person_id IN (:sqlair_preload_0, :sqlair_preload_1);
+//
+// A belongsto relation names the column of the parent that holds the primary
+// key of the related row, which is loaded by its primary key. The tables are
+// found in the same way as Get. The relation fields of every parent are
+// replaced, and are left empty when there isn't a related row.
+func (q *Querier) Preload(tx *sql.Tx, value interface{}, relations ...string) error {
+	return q.PreloadContext(context.Background(), tx, value, relations...)
+}
+
+// PreloadContext loads the related rows of structs that have already been
+// loaded. See Preload.
+func (q *Querier) PreloadContext(ctx context.Context, tx *sql.Tx, value interface{}, relations ...string) error {
+	dest := reflect.ValueOf(value)
+	if dest.Kind() != reflect.Ptr || dest.IsNil() {
+		return errors.Errorf("expected pointer to struct or slice of structs, got %T", value)
+	}
+
+	var parents []reflect.Value
+	switch elem := dest.Elem(); elem.Kind() {
+	case reflect.Struct:
+		parents = append(parents, elem)
+	case reflect.Slice:
+		for i := 0; i < elem.Len(); i++ {
+			parent := elem.Index(i)
+			if parent.Kind() == reflect.Ptr {
+				if parent.IsNil() {
+					continue
+				}
+				parent = parent.Elem()
+			}
+			parents = append(parents, parent)
+		}
+	default:
+		return errors.Errorf("expected pointer to struct or slice of structs, got %T", value)
+	}
+
+	_, refStruct, err := q.reflectTable(value)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range relations {
+		relation, ok := refStruct.Relations[name]
+		if !ok {
+			return errors.Errorf("unknown relation %q of %q, expected field with the hasmany or belongsto tag option", name, refStruct.Name)
+		}
+		if len(parents) == 0 {
+			continue
+		}
+		if relation.Tag.HasMany != "" {
+			err = q.preloadHasMany(ctx, tx, refStruct, relation, parents)
+		} else {
+			err = q.preloadBelongsTo(ctx, tx, refStruct, relation, parents)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "preloading %q", name)
+		}
+	}
+	return nil
+}
+
+// preloadHasMany loads the children of the parents, setting the slice of
+// children of every parent.
+func (q *Querier) preloadHasMany(ctx context.Context, tx *sql.Tx, parent sreflect.ReflectStruct, relation sreflect.ReflectField, parents []reflect.Value) error {
+	_, pk, err := singlePrimaryKey(parent)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]reflect.Value, len(parents))
+	for i, value := range parents {
+		keys[i] = sreflect.FieldByIndex(value, pk.Index)
+	}
+	children, child, err := q.loadRelated(ctx, tx, relationElem(relation.Value.Type()), relation.Tag.HasMany, keys)
+	if err != nil {
+		return err
+	}
+	fk, ok := child.Fields[relation.Tag.HasMany]
+	if !ok {
+		return errors.WithStack(&MissingFieldError{
+			Entity: child.Name,
+			Field:  relation.Tag.HasMany,
+		})
+	}
+
+	sliceType := relation.Value.Type()
+	groups := make(map[string]reflect.Value)
+	for i := 0; i < children.Len(); i++ {
+		value := children.Index(i)
+		key, ok := relationKey(sreflect.FieldByIndex(value, fk.Index))
+		if !ok {
+			continue
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = reflect.Zero(sliceType)
+		}
+		if sliceType.Elem().Kind() == reflect.Ptr {
+			value = value.Addr()
+		}
+		groups[key] = reflect.Append(group, value)
+	}
+
+	for i, value := range parents {
+		field := sreflect.FieldByIndex(value, relation.Index)
+		field.Set(reflect.Zero(sliceType))
+		if key, ok := relationKey(keys[i]); ok {
+			if group, ok := groups[key]; ok {
+				field.Set(group)
+			}
+		}
+	}
+	return nil
+}
+
+// preloadBelongsTo loads the related rows of the parents, setting the
+// related struct of every parent.
+func (q *Querier) preloadBelongsTo(ctx context.Context, tx *sql.Tx, parent sreflect.ReflectStruct, relation sreflect.ReflectField, parents []reflect.Value) error {
+	fk, ok := parent.Fields[relation.Tag.BelongsTo]
+	if !ok {
+		return errors.WithStack(&MissingFieldError{
+			Entity: parent.Name,
+			Field:  relation.Tag.BelongsTo,
+		})
+	}
+
+	fieldType := relation.Value.Type()
+	relatedType := fieldType
+	if relatedType.Kind() == reflect.Ptr {
+		relatedType = relatedType.Elem()
+	}
+	_, related, err := q.reflectTable(reflect.New(relatedType).Interface())
+	if err != nil {
+		return err
+	}
+	column, pk, err := singlePrimaryKey(related)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]reflect.Value, len(parents))
+	for i, value := range parents {
+		keys[i] = sreflect.FieldByIndex(value, fk.Index)
+	}
+	rows, _, err := q.loadRelated(ctx, tx, relatedType, column, keys)
+	if err != nil {
+		return err
+	}
+
+	byKey := make(map[string]reflect.Value)
+	for i := 0; i < rows.Len(); i++ {
+		value := rows.Index(i)
+		if key, ok := relationKey(sreflect.FieldByIndex(value, pk.Index)); ok {
+			byKey[key] = value
+		}
+	}
+
+	for i, value := range parents {
+		field := sreflect.FieldByIndex(value, relation.Index)
+		field.Set(reflect.Zero(fieldType))
+
+		key, ok := relationKey(keys[i])
+		if !ok {
+			continue
+		}
+		row, ok := byKey[key]
+		if !ok {
+			continue
+		}
+		// Every parent gets its own copy of the related struct, so that
+		// changing one doesn't change the others.
+		if fieldType.Kind() == reflect.Ptr {
+			ptr := reflect.New(relatedType)
+			ptr.Elem().Set(row)
+			row = ptr
+		}
+		field.Set(row)
+	}
+	return nil
+}
+
+// loadRelated returns the rows of the table of the struct type where the
+// column is one of the keys, along with the reflected struct.
+func (q *Querier) loadRelated(ctx context.Context, tx *sql.Tx, typ reflect.Type, column string, keys []reflect.Value) (reflect.Value, sreflect.ReflectStruct, error) {
+	table, refStruct, err := q.reflectTable(reflect.New(typ).Interface())
+	if err != nil {
+		return reflect.Value{}, sreflect.ReflectStruct{}, err
+	}
+
+	var (
+		names []string
+		args  []interface{}
+	)
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		k, ok := relationKey(key)
+		if !ok || seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		name := fmt.Sprintf("sqlair_preload_%d", len(names))
+		names = append(names, ":"+name)
+		args = append(args, sql.Named(name, indirectKey(key).Interface()))
+	}
+
+	rows := reflect.New(reflect.SliceOf(typ))
+	if len(names) == 0 {
+		return rows.Elem(), refStruct, nil
+	}
+
+	query, err := q.ForMany(rows.Interface())
+	if err != nil {
+		return reflect.Value{}, sreflect.ReflectStruct{}, errors.WithStack(err)
+	}
+	stmt := fmt.Sprintf("SELECT {%s} FROM %s WHERE %s IN (%s);", refStruct.Name, table, column, strings.Join(names, ", "))
+	if err := query.QueryContext(ctx, tx, stmt, args...); err != nil {
+		return reflect.Value{}, sreflect.ReflectStruct{}, err
+	}
+	return rows.Elem(), refStruct, nil
+}
+
+// singlePrimaryKey returns the column and field of the primary key of the
+// struct, which must be made up of a single column.
+func singlePrimaryKey(refStruct sreflect.ReflectStruct) (string, sreflect.ReflectField, error) {
+	columns := primaryKeyColumns(refStruct)
+	if len(columns) != 1 {
+		return "", sreflect.ReflectField{}, errors.Errorf("expected a single primary key column for %q, got %d", refStruct.Name, len(columns))
+	}
+	return columns[0], refStruct.Fields[columns[0]], nil
+}
+
+// indirectKey dereferences the pointers of a key.
+func indirectKey(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	return value
+}
+
+// relationKey returns the key that relates the rows of two tables, which is
+// false if the key is NULL. The key is formatted, so that keys of different
+// integer types are related.
+func relationKey(value reflect.Value) (string, bool) {
+	value = indirectKey(value)
+	if !value.IsValid() || value.Kind() == reflect.Ptr {
+		return "", false
+	}
+	key := value.Interface()
+	if valuer, ok := key.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil || v == nil {
+			return "", false
+		}
+		key = v
+	}
+	return fmt.Sprint(key), true
+}
@@ -0,0 +1,120 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type preloadPet struct {
+	ID       int64  `db:"id,pk"`
+	PersonID int    `db:"person_id"`
+	Name     string `db:"name"`
+}
+
+func (preloadPet) TableName() string {
+	return "pets"
+}
+
+type preloadAddress struct {
+	ID   int64  `db:"id,pk"`
+	City string `db:"city"`
+}
+
+func (preloadAddress) TableName() string {
+	return "addresses"
+}
+
+type preloadPerson struct {
+	ID        int64           `db:"id,pk"`
+	Name      string          `db:"name"`
+	AddressID *int64          `db:"address_id"`
+	Address   *preloadAddress `db:"address,belongsto=address_id"`
+	Pets      []preloadPet    `db:"pets,hasmany=person_id"`
+}
+
+func (preloadPerson) TableName() string {
+	return "people"
+}
+
+// preloadSchema is a table of people with their pets and addresses.
+const preloadSchema = `
+CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, address_id INTEGER);
+CREATE TABLE pets (id INTEGER PRIMARY KEY, person_id INTEGER, name TEXT);
+CREATE TABLE addresses (id INTEGER PRIMARY KEY, city TEXT);
+INSERT INTO people VALUES (1, "fred", 1), (2, "frank", NULL), (3, "jane", 1);
+INSERT INTO pets VALUES (1, 1, "rex"), (2, 3, "tom"), (3, 1, "fido");
+INSERT INTO addresses VALUES (1, "london"), (2, "paris");
+`
+
+func TestPreload(t *testing.T) {
+	db := setupSchemaDB(t, preloadSchema)
+
+	var stmts []string
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		stmts = append(stmts, stmt)
+		return nil
+	})
+
+	var persons []preloadPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		err = getter.Query(tx, `SELECT {preloadPerson} FROM people ORDER BY id;`)
+		assert.Nil(t, err)
+
+		return querier.Preload(tx, &persons, "Pets", "Address")
+	})
+
+	one := int64(1)
+	assert.Equal(t, persons, []preloadPerson{{
+		ID:        1,
+		Name:      "fred",
+		AddressID: &one,
+		Address:   &preloadAddress{ID: 1, City: "london"},
+		Pets:      []preloadPet{{ID: 1, PersonID: 1, Name: "rex"}, {ID: 3, PersonID: 1, Name: "fido"}},
+	}, {
+		ID:   2,
+		Name: "frank",
+	}, {
+		ID:        3,
+		Name:      "jane",
+		AddressID: &one,
+		Address:   &preloadAddress{ID: 1, City: "london"},
+		Pets:      []preloadPet{{ID: 2, PersonID: 3, Name: "tom"}},
+	}})
+	assert.Equal(t, stmts[1:], []string{
+		"SELECT id, name, person_id FROM pets WHERE person_id IN (:sqlair_preload_0, :sqlair_preload_1, :sqlair_preload_2);",
+		"SELECT city, id FROM addresses WHERE id IN (:sqlair_preload_0);",
+	})
+}
+
+func TestPreloadStruct(t *testing.T) {
+	db := setupSchemaDB(t, preloadSchema)
+
+	querier := NewQuerier()
+
+	person := preloadPerson{ID: 3}
+	runTx(t, db, func(tx *sql.Tx) error {
+		return querier.Preload(tx, &person, "Pets")
+	})
+
+	assert.Equal(t, person.Pets, []preloadPet{{ID: 2, PersonID: 3, Name: "tom"}})
+}
+
+func TestPreloadUnknownRelation(t *testing.T) {
+	db := setupSchemaDB(t, preloadSchema)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	persons := []preloadPerson{{ID: 1}}
+	err = querier.Preload(tx, &persons, "Name")
+	assert.Equal(t, err.Error(), `unknown relation "Name" of "preloadPerson", expected field with the hasmany or belongsto tag option`)
+}
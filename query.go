@@ -1,95 +1,219 @@
 package sqlair
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/SimonRichardson/sqlair/parser"
 	sreflect "github.com/SimonRichardson/sqlair/reflect"
 	"github.com/pkg/errors"
 )
 
 const (
-	// AliasPrefix is a prefix used to decode the mappings from column name.
+	// AliasPrefix is the default prefix used to decode the mappings from
+	// column name (see Querier.ColumnAlias).
 	AliasPrefix = "_pfx_"
-	// AliasSeparator is a separator used to decode the mappings from column
-	// name.
+	// AliasSeparator is the default separator used to decode the mappings
+	// from column name (see Querier.ColumnAlias).
 	AliasSeparator = "_sfx_"
 )
 
-// Hook is used to analyze the queries that are being queried.
-type Hook func(string)
+// Hook is used to analyze the queries that are being queried. Returning an
+// error from the hook prevents the statement from being executed, with the
+// error being returned to the caller.
+type Hook func(string) error
 
+// Querier creates queries and executes statements. A Querier is safe for
+// concurrent use once it's configured: its caches are shared by every
+// goroutine, but the methods that configure it, such as Hook or
+// RegisterConverter, must not be called while it's in use. Use Derive or Copy
+// to change the configuration of a querier that's already in use.
 type Querier struct {
-	reflect   *sreflect.ReflectCache
-	hook      Hook
-	stmtCache *statementCache
+	reflect        *sreflect.ReflectCache
+	hook           Hook
+	statementHook  StatementHook
+	slowQuery      slowQueryHook
+	queryHook      QueryHook
+	argCheck       argumentCheck
+	retry          RetryPolicy
+	router         *Router
+	commenter      Commenter
+	mapper         fieldMapper
+	order          ColumnOrder
+	aliasAll       bool
+	columnAlias    columnAlias
+	excludeDeleted bool
+	limitOne       bool
+	stmtCache      *statementCache
+	buffers        *bufferPool
+	results        *resultCache
 }
 
 // NewQuerier creates a new querier for selecting queries.
 func NewQuerier() *Querier {
 	return &Querier{
 		reflect:   sreflect.NewReflectCache(),
-		hook:      func(s string) {},
+		hook:      func(s string) error { return nil },
 		stmtCache: newStatementCache(),
+		buffers:   newBufferPool(),
+		results:   newResultCache(),
 	}
 }
 
 // Hook assigns the hook to the querier. Each hook call precedes the actual
 // query and outputs the compiled statement that's actually used in a query or
-// exec.
+// exec. If the hook returns an error, the statement is vetoed and never
+// reaches the database.
 //
+// Hook replaces any existing hooks of the querier, see AddHook to chain
+// several hooks together.
 func (q *Querier) Hook(hook Hook) {
 	q.hook = hook
 }
 
+// TagKey changes the struct tag key used to locate the column names of fields
+// from the default "db" tag. This allows types that are already tagged for
+// other libraries to be used without retagging.
+//
+// Changing the tag key resets the reflect, statement and result caches for
+// this querier.
+func (q *Querier) TagKey(key string) {
+	config := q.reflect.Config()
+	config.TagKey = key
+	q.reflect = sreflect.NewReflectCacheWithConfig(config)
+	q.stmtCache = newStatementCache()
+	q.results = newResultCache()
+}
+
+// NameMapper changes how fields without a tag are mapped to column names. By
+// default field names are mapped to snake case (see reflect.SnakeCase).
+//
+// Changing the name mapper resets the reflect, statement and result caches
+// for this querier.
+func (q *Querier) NameMapper(mapper sreflect.NameMapper) {
+	config := q.reflect.Config()
+	config.NameMapper = mapper
+	q.reflect = sreflect.NewReflectCacheWithConfig(config)
+	q.stmtCache = newStatementCache()
+	q.results = newResultCache()
+}
+
+// AliasColumns changes whether every column of an expanded record is given an
+// alias. By default only the columns that are found in more than one entity
+// are aliased, which relies on the entity fields to detect the overlap. When
+// enabled, every column is aliased with the position of the record in the
+// statement and the columns are scanned purely by the alias.
+//
+// Changing the column aliasing resets the statement cache for this querier.
+func (q *Querier) AliasColumns(enabled bool) {
+	q.aliasAll = enabled
+	q.stmtCache = newStatementCache()
+}
+
 // ForOne creates a query for a set of given types. The values will be populated
 // from the SQL query once executed.
 //
+// If the value is a map, every column is stored in the map using the column
+// name as the key. NULL values, including those of expressions without a
+// declared type, are stored as the zero value of the map element, which is nil
+// for map[string]interface{}. Maps with other elements, such as
+// map[string]string or map[string]int64, have every value converted to the
+// element type, returning a ConversionError if a value can't be converted.
+//
+// Structs can be mixed with scalars, such as a count. The columns of the
+// structs are located by name, and the remaining columns are scanned into the
+// scalars in the order that they're passed:
+//
+//  getter, err := querier.ForOne(&person, &count)
+//  ...
+//  err = getter.Query(tx, `SELECT {Person}, COUNT(pets.id) FROM people ...;`)
+//
+// Query options (see QueryOption) can be passed along with the values.
+//
 // It should be noted that the query can be cached and the query can be called
 // multiple times.
 func (q *Querier) ForOne(values ...interface{}) (Query, error) {
+	values, options := splitOptions(values)
+	options.excludeDeleted = q.excludeDeleted
 	entities, err := q.reflectValues(values...)
 	if err != nil {
-		return Query{}, nil
+		return Query{}, err
 	}
 	query := Query{
-		entities:  entities,
-		hook:      q.hook,
-		stmtCache: q.stmtCache,
-		reflect:   q.reflect,
+		entities:      entities,
+		hook:          q.hook,
+		statementHook: q.statementHook,
+		slowQuery:     q.slowQuery,
+		queryHook:     q.queryHook,
+		argCheck:      q.argCheck,
+		commenter:     q.commenter,
+		mapper:        q.mapper,
+		order:         q.order,
+		aliasAll:      q.aliasAll,
+		columnAlias:   q.columnAlias,
+		options:       options,
+		stmtCache:     q.stmtCache,
+		buffers:       q.buffers,
+		results:       q.results,
+		reflect:       q.reflect,
+		running:       new(int32),
 	}
 	if len(values) == 0 {
-		query.executePlan = query.defaultScan
+		query.executePlan = Query.defaultScan
 		return query, nil
 	}
+	query.options.limitOne = q.limitOne
 
-	// We can expect that all entity types are homogeneous as there is a guard
-	// in reflectValues method.
-	switch entities[0].Kind() {
-	case reflect.Struct:
-		structs := make([]sreflect.ReflectStruct, len(values))
-		for i, entity := range entities {
-			structs[i] = entity.(sreflect.ReflectStruct)
-		}
-
-		query.executePlan = func(tx *sql.Tx, stmt string, args []interface{}) error {
-			return query.structScan(tx, stmt, args, structs)
+	// Structs and scalars can be mixed, but a map holds every column, so it
+	// must be the only value.
+	var (
+		structs []sreflect.ReflectStruct
+		scalars []reflect.Value
+		maps    int
+	)
+	for _, entity := range entities {
+		switch entity := entity.(type) {
+		case sreflect.ReflectStruct:
+			structs = append(structs, entity)
+		case sreflect.ReflectValue:
+			if entity.Value.Kind() == reflect.Map {
+				maps++
+				continue
+			}
+			scalars = append(scalars, entity.Value)
 		}
+	}
 
-	case reflect.Map:
+	switch {
+	case maps > 0:
 		if len(values) > 1 {
 			return Query{}, errors.Errorf("expected one map for query, got %d", len(values))
 		}
-		query.executePlan = func(tx *sql.Tx, stmt string, args []interface{}) error {
-			return query.mapScan(tx, stmt, args, entities[0].(sreflect.ReflectValue))
+		entity := entities[0].(sreflect.ReflectValue)
+		_, alias := unwrapAlias(values[0])
+		name := mapEntityName(entity.Value.Type(), alias)
+		query.executePlan = func(q Query, ctx context.Context, tx *sql.Tx, stmt string, args []interface{}) error {
+			return q.mapScan(ctx, tx, stmt, args, entity, name)
+		}
+
+	case len(structs) > 0:
+		query.executePlan = func(q Query, ctx context.Context, tx *sql.Tx, stmt string, args []interface{}) error {
+			return q.structScan(ctx, tx, stmt, args, structs, scalars)
 		}
 
 	default:
-		query.executePlan = query.defaultScan
+		query.executePlan = Query.defaultScan
 	}
 	return query, nil
 }
@@ -102,23 +226,44 @@ type reflectSlice struct {
 // ForMany creates a query based on the slice input. The values will be
 // populated from the SQL query once executed.
 //
+// The rows are appended to the slices, so reusing the slices across queries
+// accumulates the rows of every query. Pass the Replace option to truncate the
+// slices before every query instead.
+//
+// Query options (see QueryOption) can be passed along with the values.
+//
 // It should be noted that the query can be cached and the query can be called
 // multiple times.
 func (q *Querier) ForMany(values ...interface{}) (Query, error) {
+	values, options := splitOptions(values)
+	options.excludeDeleted = q.excludeDeleted
 	if len(values) == 0 {
 		return Query{}, errors.Errorf("expected at least one argument")
 	}
 
 	entities, err := q.reflectValues(values...)
 	if err != nil {
-		return Query{}, nil
+		return Query{}, err
 	}
 
 	query := Query{
-		entities:  entities,
-		hook:      q.hook,
-		stmtCache: q.stmtCache,
-		reflect:   q.reflect,
+		entities:      entities,
+		hook:          q.hook,
+		statementHook: q.statementHook,
+		slowQuery:     q.slowQuery,
+		queryHook:     q.queryHook,
+		argCheck:      q.argCheck,
+		commenter:     q.commenter,
+		mapper:        q.mapper,
+		order:         q.order,
+		aliasAll:      q.aliasAll,
+		columnAlias:   q.columnAlias,
+		options:       options,
+		stmtCache:     q.stmtCache,
+		buffers:       q.buffers,
+		results:       q.results,
+		reflect:       q.reflect,
+		running:       new(int32),
 	}
 
 	refSlice := make([]reflectSlice, len(entities))
@@ -134,12 +279,19 @@ func (q *Querier) ForMany(values ...interface{}) (Query, error) {
 			// Grab the base type reflection.
 			element, err := q.reflect.Reflect(virtual.Interface())
 			if err != nil {
-				return Query{}, errors.Errorf("expected slice but got %q", entity.Kind())
+				return Query{}, errors.Wrap(err, "reflect")
 			}
 			elementRefStruct, ok := element.(sreflect.ReflectStruct)
 			if !ok {
 				return Query{}, errors.Errorf("expected slice T to be struct")
 			}
+			if err := checkGenerated(base, elementRefStruct); err != nil {
+				return Query{}, errors.Wrap(err, "reflect")
+			}
+
+			if _, alias := unwrapAlias(values[i]); alias != "" {
+				elementRefStruct.Name = alias
+			}
 
 			refSlice[i] = reflectSlice{
 				slice:   refValue,
@@ -151,26 +303,115 @@ func (q *Querier) ForMany(values ...interface{}) (Query, error) {
 		}
 	}
 
-	query.executePlan = func(tx *sql.Tx, stmt string, args []interface{}) error {
-		return query.sliceStructScan(tx, stmt, args, refSlice)
+	query.executePlan = func(q Query, ctx context.Context, tx *sql.Tx, stmt string, args []interface{}) error {
+		return q.sliceStructScan(ctx, tx, stmt, args, refSlice)
 	}
 
 	return query, nil
 }
 
 // Exec executes a query that doesn't return rows. Named arguments can be
-// used within the statement.
+// used within the statement. Record expressions are expanded with the structs
+// of the arguments, so that rows can be copied between tables:
+//
+//  _, err := querier.Exec(tx, `INSERT INTO archive SELECT {Person} FROM people WHERE age>:age;`, person)
+//
 func (q *Querier) Exec(tx *sql.Tx, stmt string, args ...interface{}) (sql.Result, error) {
-	namedArgs, err := constructNamedArguments(stmt, args)
+	return q.ExecContext(context.Background(), tx, stmt, args...)
+}
+
+// ExecContext executes a query that doesn't return rows, using the context
+// for cancellation and for any statement comments. Named arguments can be
+// used within the statement.
+func (q *Querier) ExecContext(ctx context.Context, tx *sql.Tx, stmt string, args ...interface{}) (sql.Result, error) {
+	filtered, args, err := expandWhereClauses(q.reflect, q.mapper, stmt, args)
+	if err != nil {
+		return nil, statementError(err, stmt, "")
+	}
+	locked, lock, err := q.optimisticLockStatement(filtered, args)
+	if err != nil {
+		return nil, err
+	}
+	expanded, records, err := q.expandExecRecords(locked, args)
+	if err != nil {
+		return nil, rewrittenStatementError(err, stmt, locked, "")
+	}
+	var arguments []string
+	if q.statementHook != nil {
+		if arguments, err = argumentNames(expanded); err != nil {
+			return nil, rewrittenStatementError(err, stmt, expanded, "")
+		}
+	}
+
+	rewritten, namedArgs, err := constructNamedArguments(q.reflect.Config(), q.mapper, q.argCheck, expanded, args)
+	if err != nil {
+		return nil, errors.Wrap(rewrittenStatementError(err, stmt, expanded, ""), "constructing named arguments")
+	}
+	return q.exec(ctx, tx, rewritten, namedArgs, records, arguments, lock)
+}
+
+// exec executes the compiled statement with the named arguments, calling the
+// hooks of the querier before and after it's executed. If the statement is
+// optimistically locked, the lock is checked against the affected rows.
+func (q *Querier) exec(ctx context.Context, tx *sql.Tx, stmt string, namedArgs []interface{}, records []recordBinding, arguments []string, lock *optimisticLock) (sql.Result, error) {
+	stmt = annotateStatement(ctx, stmt, q.commenter)
+
+	if hook := contextHook(ctx, q.hook); hook != nil {
+		if err := hook(stmt); err != nil {
+			return nil, errors.Wrap(err, "hook")
+		}
+	}
+	if err := q.statementHook.observe(stmt, records, arguments); err != nil {
+		return nil, errors.Wrap(err, "statement hook")
+	}
+
+	start := time.Now()
+	defer q.slowQuery.observe(stmt, start)
+
+	result, err := tx.ExecContext(ctx, stmt, namedArgs...)
 	if err != nil {
-		return nil, errors.Wrap(err, "constructing named arguments")
+		q.queryHook.observe(ctx, stmt, start, 0, err)
+		return result, err
+	}
+	if q.queryHook != nil {
+		// Not every driver reports the affected rows, in which case none are
+		// reported.
+		rows, _ := result.RowsAffected()
+		q.queryHook.observe(ctx, stmt, start, rows, nil)
+	}
+	if lock == nil {
+		return result, nil
+	}
+	return result, lock.check(result)
+}
+
+// expandExecRecords expands the record expressions of a statement that
+// doesn't return rows, using the structs of the arguments as the entities. The
+// records of the statement are returned along with the expanded statement.
+func (q *Querier) expandExecRecords(stmt string, args []interface{}) (string, []recordBinding, error) {
+	if indexOfRecordArgs(stmt) < 0 {
+		return stmt, nil, nil
 	}
 
-	if q.hook != nil {
-		q.hook(stmt)
+	var entities []sreflect.ReflectStruct
+	for _, arg := range args {
+		if typ := structType(arg); typ == nil || typ.Kind() != reflect.Struct {
+			continue
+		}
+		structs, err := q.registerStructs([]interface{}{arg})
+		if err != nil {
+			return "", nil, err
+		}
+		entities = append(entities, structs...)
 	}
 
-	return tx.Exec(stmt, namedArgs...)
+	query := Query{
+		order:       q.order,
+		aliasAll:    q.aliasAll,
+		columnAlias: q.columnAlias,
+		reflect:     q.reflect,
+	}
+	return query.compileStatement(stmt, entities)
 }
 
 func (q *Querier) reflectValues(values ...interface{}) ([]sreflect.ReflectInfo, error) {
@@ -178,36 +419,84 @@ func (q *Querier) reflectValues(values ...interface{}) ([]sreflect.ReflectInfo,
 	for i, value := range values {
 		var err error
 
+		value, alias := unwrapAlias(value)
 		if entities[i], err = q.reflect.Reflect(value); err != nil {
 			return nil, errors.Wrap(err, "reflect")
 		}
-
-		// Ensure that all the types are the same. This is a current
-		// restriction to reduce complications later on. Given enough time and
-		// energy we can implement this at a later date.
-		if i > 1 && entities[i-1].Kind() != entities[i].Kind() {
-			return nil, errors.Errorf("expected all input values to be of the same kind %q, got %q", entities[i-1].Kind(), entities[i].Kind())
+		refStruct, ok := entities[i].(sreflect.ReflectStruct)
+		if !ok {
+			continue
+		}
+		if err := checkGenerated(refStruct.Value.Type(), refStruct); err != nil {
+			return nil, errors.Wrap(err, "reflect")
+		}
+		if alias != "" {
+			refStruct.Name = alias
+			entities[i] = refStruct
 		}
 	}
 	return entities, nil
 }
 
 // Copy returns a new Querier with a new hook and statement cache, but keeping
-// the existing reflect cache..
+// the existing reflect cache, commenter and time format.
 func (q *Querier) Copy() *Querier {
 	return &Querier{
-		reflect:   q.reflect,
-		hook:      func(s string) {},
-		stmtCache: newStatementCache(),
+		reflect:        q.reflect,
+		hook:           func(s string) error { return nil },
+		argCheck:       q.argCheck,
+		retry:          q.retry,
+		router:         q.router,
+		commenter:      q.commenter,
+		mapper:         q.mapper,
+		order:          q.order,
+		aliasAll:       q.aliasAll,
+		columnAlias:    q.columnAlias,
+		excludeDeleted: q.excludeDeleted,
+		limitOne:       q.limitOne,
+		stmtCache:      newStatementCache(),
+		buffers:        newBufferPool(),
+		results:        newResultCache(),
 	}
 }
 
+// Query executes statements into the destinations it was created for (see
+// ForOne and ForMany). As the destinations are populated by every execution,
+// a query must only be executed by one goroutine at a time, otherwise
+// ErrConcurrentQuery is returned. Create a query for each goroutine, with its
+// own destinations, or use a prepared Statement, which binds the destinations
+// on every call.
 type Query struct {
-	entities    []sreflect.ReflectInfo
-	hook        Hook
-	executePlan func(*sql.Tx, string, []interface{}) error
-	stmtCache   *statementCache
-	reflect     *sreflect.ReflectCache
+	entities      []sreflect.ReflectInfo
+	hook          Hook
+	statementHook StatementHook
+	slowQuery     slowQueryHook
+	queryHook     QueryHook
+	argCheck      argumentCheck
+	commenter     Commenter
+	mapper        fieldMapper
+	order         ColumnOrder
+	aliasAll      bool
+	columnAlias   columnAlias
+	options       queryOptions
+	executePlan   func(Query, context.Context, *sql.Tx, string, []interface{}) error
+	prepared      *cachedStmt
+	cacheTTL      time.Duration
+	stmtCache     *statementCache
+	buffers       *bufferPool
+	results       *resultCache
+	reflect       *sreflect.ReflectCache
+	// scanned counts the rows scanned by the query, if it's not nil.
+	scanned *int64
+	// arguments are the names of the named arguments of the statement, which
+	// are only parsed for the statement hook.
+	arguments []string
+	// running is set while the query is executing, so that the destinations
+	// aren't populated by two goroutines at once.
+	running *int32
+	// explained captures the compiled statement instead of executing it, if
+	// it's not nil (see Explain).
+	explained *explainedStatement
 }
 
 // Query executes a query that returns rows. Query will attempt to parse the
@@ -256,6 +545,67 @@ type Query struct {
 //
 //  SELECT people.age, people.name, location.city FROM people INNER JOIN location ON people.location=location.id WHERE location.id=:loc_id AND people.name=:name
 //
+// The fields of a single type can be selected from more than one table, as
+// long as every field is only selected from one of them.
+//
+//  SELECT {people.name, people.age, location.city INTO Person} FROM people INNER JOIN location ...;
+//
+// The same type can be used more than once in a statement (self-joins) by
+// giving each record an alias. The values are bound to the aliases either by
+// wrapping them with As, or by the order of the records in the statement.
+//
+//  SELECT {m.* INTO Person AS mother}, {f.* INTO Person AS father} FROM people AS m, people AS f WHERE ...;
+//
+// Columns that are selected by more than one record are aliased with the
+// table of their record, so that they're scanned into the right type. A short
+// alias written after the type replaces the table in the aliases, which gives
+// columns without a table, such as the columns of a view or computed columns,
+// a name to be told apart by.
+//
+//  SELECT {name, age INTO Person p}, {COUNT(*) AS name INTO Stats s} FROM adults ...;
+//
+// Computed columns, such as aggregates, can be bound to a field by aliasing
+// the expression with the column name of the field.
+//
+//  SELECT {COUNT(*) AS count, MAX(age) AS max_age INTO Stats} FROM people;
+//
+// The table of a record can be quoted, with double quotes, backticks or square
+// brackets, and qualified with a schema or an attached SQLite database. The
+// table is written as it is when the record is expanded.
+//
+//  SELECT {"main"."people".* INTO Person}, {other.people.* INTO Person AS remote} FROM ...;
+//
+// The table of a record can also be the alias of a subquery, a view or a
+// common table expression, which is expanded and aliased in the same way. The
+// records within a subquery keep the names of their columns, so that they can
+// be selected again by the alias.
+//
+//  SELECT {p.* INTO Person} FROM (SELECT {people.* INTO Person} FROM people WHERE age > 18) AS p;
+//
+// The table of a type can be written as a record as well, so that statements
+// don't hard code the table names. The table is named in the same way as for
+// Get, by the TableName method of the type or its struct tag.
+//
+//  type Person struct {
+//  	_    struct{} `sqlair:"table=people"`
+//  	Name string   `db:"name"`
+//  }
+//
+//  SELECT {Person} FROM {Person.table};
+//
+// The type must be one of the values of the query, or for Exec, one of the
+// arguments.
+//
+// Records can also be written with an ampersand, where every field or
+// wildcard is a record of its own, and the column of a table or an expression
+// is bound to a field with AS. Both forms can be mixed within a statement.
+//
+//  SELECT &Person.*, &Person.name FROM people;
+//  SELECT p.* AS &Person.*, COUNT(*) AS &Stats.total, {l.city INTO Location} FROM ...;
+//
+// A bitwise AND isn't mistaken for a record, as long as the ampersand follows
+// a column or a value (flags&mask), or is followed by white space.
+//
 // Named Arguments
 //
 // Named arguments allow the expressing of fields via the name, rather than
@@ -273,18 +623,108 @@ type Query struct {
 //  	"name": "fred",
 //  }
 //
+// Several maps or types can be passed, which are merged together. This allows
+// filters and pagination to live in separate types. The first argument that
+// has a name provides the value, and it's an error for two arguments to have
+// different values for the same name.
+//
+//  querier.Query(tx, "SELECT {Person} FROM people WHERE name=:name LIMIT :limit;", filter, page)
+//
+// Numbered arguments (?NNN) are bound from a slice, where ?1 is the first
+// element of the slice. The same number can be used more than once.
+//
+//  querier.Query(tx, "SELECT {Person} FROM people WHERE name=?1 OR nickname=?1 OR age=?2;", []interface{}{"fred", 21})
+//
+// Positional placeholders (?) can be mixed with named arguments. The maps and
+// types for the named arguments come first, and the remaining arguments fill
+// the positional placeholders in order. The number of remaining arguments must
+// match the number of positional placeholders.
+//
+//  querier.Query(tx, "SELECT {Person} FROM people WHERE name=:name AND age>?;", filter, 21)
+//
+// Dotted named arguments reach into nested maps and struct fields, so that
+// the arguments don't need to be flattened into a single type.
+//
+//  querier.Query(tx, "SELECT {Person} FROM people WHERE name=:person.name AND city=:address.city;", filter)
+//
+// Values of sql.NamedArg can be passed anywhere in the arguments, and take
+// precedence over the maps and types that have the same name. It's an error
+// to pass the same sql.NamedArg name more than once with different values.
+//
+//  querier.Query(tx, "SELECT {Person} FROM people WHERE name=:name AND age>:age;", filter, sql.Named("age", 21))
+//
+// The WHERE clause of a search with optional filters can be built from a
+// filter type, which is written as {where Filters}. Every field of the filter
+// is a condition on the column of the same name, joined with AND, other than
+// nil pointer fields, which are left out. The WHERE clause is removed if none
+// of the fields are set.
+//
+//  type Filters struct {
+//  	Name *string `db:"name"`
+//  	City *string `db:"city"`
+//  }
+//
+//  querier.Query(tx, "SELECT {Person} FROM people {where Filters} ORDER BY name;", Filters{Name: &name})
+//
+// In the same way, the rows can be ordered by columns that are chosen at
+// runtime with an order clause, which is written as {order}. The OrderBy
+// arguments are validated against the columns of the destinations.
+//
+//  querier.Query(tx, "SELECT {Person} FROM people {order};", sqlair.OrderBy{Column: sort, Desc: true})
+//
 // See https://www.sqlite.org/c3ref/bind_blob.html for more information on
 // named arguments in SQLite.
 func (q Query) Query(tx *sql.Tx, stmt string, args ...interface{}) error {
-	namedArgs, err := constructNamedArguments(stmt, args)
+	return q.QueryContext(context.Background(), tx, stmt, args...)
+}
+
+// QueryContext executes a query that returns rows, using the context for
+// cancellation and for any statement comments. See Query for the supported
+// statement syntax.
+func (q Query) QueryContext(ctx context.Context, tx *sql.Tx, stmt string, args ...interface{}) error {
+	if q.running != nil {
+		if !atomic.CompareAndSwapInt32(q.running, 0, 1) {
+			return errors.WithStack(ErrConcurrentQuery)
+		}
+		defer atomic.StoreInt32(q.running, 0)
+	}
+
+	filtered := stmt
+	if q.prepared == nil {
+		var err error
+		if filtered, args, err = expandWhereClauses(q.reflect, q.mapper, stmt, args); err != nil {
+			return statementError(err, stmt, "")
+		}
+		expanded := filtered
+		if filtered, args, err = q.expandOrderBy(expanded, args); err != nil {
+			return rewrittenStatementError(err, stmt, expanded, "")
+		}
+	}
+	if q.statementHook != nil {
+		var err error
+		if q.arguments, err = argumentNames(filtered); err != nil {
+			return rewrittenStatementError(err, stmt, filtered, "")
+		}
+	}
+	if q.options.excludeDeleted && !q.options.includeDeleted && q.prepared == nil {
+		filtered = q.excludeDeletedStatement(filtered)
+	}
+	rewritten, namedArgs, err := constructNamedArguments(q.reflect.Config(), q.mapper, q.argCheck.withDefaults(q.options), filtered, args)
 	if err != nil {
-		return errors.Wrap(err, "constructing named arguments")
+		return errors.Wrap(rewrittenStatementError(err, stmt, filtered, ""), "constructing named arguments")
+	}
+	// Explained queries leave the destinations alone.
+	if q.options.replace && q.explained == nil {
+		q.truncateSlices()
 	}
-	return q.executePlan(tx, stmt, namedArgs)
+	if q.cacheTTL > 0 {
+		return q.cachedQuery(ctx, tx, rewritten, namedArgs)
+	}
+	return q.executePlan(q, ctx, tx, rewritten, namedArgs)
 }
 
-func (q Query) defaultScan(tx *sql.Tx, stmt string, args []interface{}) error {
-	rows, columns, err := q.query(tx, stmt, args)
+func (q Query) defaultScan(ctx context.Context, tx *sql.Tx, stmt string, args []interface{}) error {
+	rows, columns, err := q.query(ctx, tx, stmt, args, nil)
 	if err != nil {
 		return err
 	}
@@ -307,14 +747,25 @@ func (q Query) defaultScan(tx *sql.Tx, stmt string, args []interface{}) error {
 	return q.scanOne(rows, columnar)
 }
 
-func (q Query) mapScan(tx *sql.Tx, stmt string, args []interface{}, entity sreflect.ReflectValue) error {
-	rows, columns, err := q.query(tx, stmt, args)
+func (q Query) mapScan(ctx context.Context, tx *sql.Tx, stmt string, args []interface{}, entity sreflect.ReflectValue, name string) error {
+	// The records of a map are expanded into the columns written in them,
+	// which is cheap enough not to be cached, and keeps the statement cache
+	// to the statements compiled for structs.
+	compiledStmt, fields, err := compileMapStatement(stmt, name)
+	if err != nil {
+		return statementError(err, stmt, "")
+	}
+
+	rows, columns, err := q.query(ctx, tx, compiledStmt, args, fields)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 
-	columnar := make([]interface{}, len(columns))
+	buffer := q.buffers.Get(len(columns))
+	defer q.buffers.Put(buffer)
+
+	columnar := *buffer
 	for i, column := range columns {
 		columnar[i] = zeroScanType(column.DatabaseTypeName())
 	}
@@ -322,37 +773,29 @@ func (q Query) mapScan(tx *sql.Tx, stmt string, args []interface{}, entity srefl
 		return err
 	}
 
+	elemType := entity.Value.Type().Elem()
 	for i, column := range columns {
 		columnName := column.Name()
 		colRef := reflect.ValueOf(columnName)
-		entity.Value.SetMapIndex(colRef, reflect.Indirect(reflect.ValueOf(columnar[i])))
+
+		// NULL values are stored as the zero value of the map element, which
+		// is nil for map[string]interface{}. Other values are converted to the
+		// map element, so that maps of a single type can be used.
+		v := nullableValue(columnar[i])
+		value, ok := convertValue(v, elemType)
+		if !ok {
+			return errors.WithStack(&ConversionError{
+				Column: columnName,
+				Value:  v,
+				Type:   elemType.String(),
+			})
+		}
+		entity.Value.SetMapIndex(colRef, value)
 	}
 
 	return nil
 }
 
-func zeroScanType(t string) interface{} {
-	switch strings.ToUpper(t) {
-	case "TEXT":
-		var a string
-		return &a
-	case "INTEGER":
-		var a int64
-		return &a
-	case "BOOL":
-		var a bool
-		return &a
-	case "REAL", "NUMERIC":
-		var a float64
-		return &a
-	case "BLOB":
-		var a []byte
-		return &a
-	default:
-		panic("unexpected type: " + t)
-	}
-}
-
 func (q Query) compileStatement(stmt string, entities []sreflect.ReflectStruct) (string, []recordBinding, error) {
 	var fields []recordBinding
 	if offset := indexOfRecordArgs(stmt); offset >= 0 {
@@ -361,51 +804,100 @@ func (q Query) compileStatement(stmt string, entities []sreflect.ReflectStruct)
 		if err != nil {
 			return "", nil, err
 		}
+		// Where and order clauses are expanded from the arguments of a
+		// query, so any that remain can't be expanded.
+		for _, field := range fields {
+			switch {
+			case field.where:
+				return "", nil, syntaxError(stmt, field.start, "unexpected where clause %q without a filter argument", stmt[field.start:field.end])
+			case field.orderBy:
+				return "", nil, syntaxError(stmt, field.start, "unexpected order clause %q outside of a query", stmt[field.start:field.end])
+			}
+		}
+
+		if entities, err = q.bindEntities(fields, entities); err != nil {
+			return "", nil, err
+		}
 
 		// Workout if any of the entities have overlapping fields.
 		intersections := fieldIntersections(entities)
 
-		stmt, err = expandRecords(stmt, fields, entities, intersections)
+		stmt, err = expandRecords(stmt, fields, entities, intersections, expansion{
+			order:    q.order,
+			aliasAll: q.aliasAll,
+			alias:    q.columnAlias,
+			config:   q.reflect.Config(),
+		})
 		if err != nil {
 			return "", nil, err
 		}
+
+		// Table placeholders don't select any columns, so they play no part
+		// in scanning.
+		fields = withoutTables(fields)
 	}
 	return stmt, fields, nil
 }
 
-func (q Query) structScan(tx *sql.Tx, stmt string, args []interface{}, entities []sreflect.ReflectStruct) error {
+// bindEntities returns the entities that the records are bound to, including
+// any aliased or nested entities.
+func (q Query) bindEntities(records []recordBinding, entities []sreflect.ReflectStruct) ([]sreflect.ReflectStruct, error) {
+	entities, err := aliasEntities(records, entities)
+	if err != nil {
+		return nil, err
+	}
+	return q.nestedEntities(records, entities)
+}
+
+// structScan scans a single row into the structs, with any columns that don't
+// belong to the structs scanned into the scalars in order.
+func (q Query) structScan(ctx context.Context, tx *sql.Tx, stmt string, args []interface{}, entities []sreflect.ReflectStruct, scalars []reflect.Value) error {
 	var (
 		compiledStmt string
 		fields       []recordBinding
 	)
-	if cached, ok := q.stmtCache.Get(stmt); ok {
+	if q.prepared != nil {
+		compiledStmt = q.prepared.stmt
+		fields = q.prepared.fields
+	} else if cached, ok := q.stmtCache.Get(stmt); ok {
 		compiledStmt = cached.stmt
 		fields = cached.fields
 	} else {
 		var err error
 		compiledStmt, fields, err = q.compileStatement(stmt, entities)
 		if err != nil {
-			return err
+			return statementError(err, stmt, "")
 		}
 	}
 
-	rows, columns, err := q.query(tx, compiledStmt, args)
+	if err := q.checkUnfolded(fields, entities); err != nil {
+		return statementError(err, stmt, compiledStmt)
+	}
+	entities, err := q.bindEntities(fields, entities)
 	if err != nil {
-		return err
+		return statementError(err, stmt, compiledStmt)
 	}
-	defer rows.Close()
 
-	columnar, err := q.structMapping(columns, entities, fields)
+	rows, columns, err := q.query(ctx, tx, compiledStmt, args, fields)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
+
+	buffer := q.buffers.Get(len(columns))
+	defer q.buffers.Put(buffer)
+
+	columnar := *buffer
+	if err := q.structMapping(columnar, columns, entities, fields, scalars); err != nil {
+		return statementError(err, stmt, compiledStmt)
+	}
 
 	if err := q.scanOne(rows, columnar); err != nil {
-		return err
+		return statementError(err, stmt, compiledStmt)
 	}
 
 	// Only cache the statement if it differs from the original.
-	if stmt != compiledStmt {
+	if q.prepared == nil && stmt != compiledStmt {
 		q.stmtCache.Set(stmt, cachedStmt{
 			stmt:   compiledStmt,
 			fields: fields,
@@ -415,72 +907,191 @@ func (q Query) structScan(tx *sql.Tx, stmt string, args []interface{}, entities
 	return nil
 }
 
-func (q Query) sliceStructScan(tx *sql.Tx, stmt string, args []interface{}, slice []reflectSlice) error {
+func (q Query) sliceStructScan(ctx context.Context, tx *sql.Tx, stmt string, args []interface{}, slice []reflectSlice) error {
 	elements := make([]sreflect.ReflectStruct, len(slice))
 	for i, ref := range slice {
 		elements[i] = ref.element
 	}
-	compiledStmt, fields, err := q.compileStatement(stmt, elements)
-	if err != nil {
-		return err
+	var (
+		compiledStmt string
+		fields       []recordBinding
+	)
+	if q.prepared != nil {
+		compiledStmt = q.prepared.stmt
+		fields = q.prepared.fields
+	} else {
+		var err error
+		compiledStmt, fields, err = q.compileStatement(stmt, elements)
+		if err != nil {
+			return statementError(err, stmt, "")
+		}
 	}
 
-	rows, columns, err := q.query(tx, compiledStmt, args)
+	rows, columns, err := q.query(ctx, tx, compiledStmt, args, fields)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 
+	buffer := q.buffers.Get(len(columns))
+	defer q.buffers.Put(buffer)
+
+	if err := q.scanSlices(rows, columns, *buffer, fields, slice); err != nil {
+		return statementError(err, stmt, compiledStmt)
+	}
+	return rows.Err()
+}
+
+// scanSlices appends every row of the current result set to the slices,
+// scanning the columns into the columnar buffer.
+func (q Query) scanSlices(rows *queryRows, columns []*sql.ColumnType, columnar []interface{}, fields []recordBinding, slice []reflectSlice) error {
+	elements := make([]sreflect.ReflectStruct, len(slice))
+	for i, ref := range slice {
+		elements[i] = ref.element
+	}
+
+	if q.options.capacity > 0 {
+		for _, refSlice := range slice {
+			growSlice(refSlice.slice.Value, q.options.capacity)
+		}
+	}
+
+	// The scan plan is computed from the first row, so that the fields of
+	// every row are located without reflecting over the elements again.
+	// Rows of a one-to-many join are folded into the parents, which are
+	// identified by their primary keys.
+	var (
+		scanner *rowScanner
+		folds   *folder
+	)
 	for rows.Next() {
-		refStructs := make([]sreflect.ReflectStruct, len(elements))
-		for i, element := range elements {
-			base := reflect.New(element.Value.Type())
-			refInfo, err := q.reflect.Reflect(base.Interface())
+		if scanner == nil {
+			plan, err := q.newScanPlan(columns, elements, fields)
 			if err != nil {
 				return err
 			}
-			refStruct, ok := refInfo.(sreflect.ReflectStruct)
-			if !ok {
-				return errors.Errorf("expected struct found %q", refInfo.Kind())
+			if folds, err = newFolder(plan, elements); err != nil {
+				return err
 			}
-			refStructs[i] = refStruct
-		}
-
-		columnar, err := q.structMapping(columns, elements, fields)
-		if err != nil {
-			return err
+			scanner = plan.scanner(columnar)
 		}
 
+		values, columnar := scanner.row(q.mapper)
 		if err := rows.Scan(columnar...); err != nil {
 			return err
 		}
 
+		if folds != nil {
+			folds.add(slice, values, scanner)
+			continue
+		}
 		for k, refSlice := range slice {
 			sliceVal := refSlice.slice.Value
-			sliceVal.Set(reflect.Append(sliceVal, elements[k].Value))
+			sliceVal.Set(reflect.Append(sliceVal, values[k]))
 		}
 	}
-	return rows.Err()
+	return nil
+}
+
+// growSlice ensures that the slice has the spare capacity for n elements,
+// reallocating the backing array only if it's too small.
+func growSlice(slice reflect.Value, n int) {
+	length := slice.Len()
+	if slice.Cap()-length >= n {
+		return
+	}
+	grown := reflect.MakeSlice(slice.Type(), length, length+n)
+	reflect.Copy(grown, slice)
+	slice.Set(grown)
+}
+
+// structMapping fills the columnar slice with the scan destination of every
+// column. The columns that aren't found in the entities, such as aggregates,
+// are scanned into the scalars in order, if there are any.
+func (q Query) structMapping(columnar []interface{}, columns []*sql.ColumnType, entities []sreflect.ReflectStruct, fields []recordBinding, scalars []reflect.Value) error {
+	query := q
+	if len(scalars) > 0 {
+		query.options.lenient = true
+	}
+	targets, err := query.columnTargets(columns, entities, fields)
+	if err != nil {
+		return err
+	}
+
+	// The generated methods of the entities scan the plain columns, without
+	// reflection (see GeneratedRecord).
+	records := make([]GeneratedRecord, len(entities))
+	for i, entity := range entities {
+		if isGenerated(entity.Value.Type()) {
+			records[i] = entity.Value.Addr().Interface().(GeneratedRecord)
+		}
+	}
+
+	var scalar int
+	for i, target := range targets {
+		if target.entity >= 0 {
+			if record := records[target.entity]; record != nil && q.mapper.plain(target.field, target.field.Value.Type()) {
+				if dest := record.SqlairScan(target.column); dest != nil {
+					columnar[i] = dest
+					continue
+				}
+			}
+			columnar[i] = q.mapper.destination(target.field)
+			continue
+		}
+		if len(scalars) == 0 {
+			columnar[i] = new(interface{})
+			continue
+		}
+		if scalar == len(scalars) {
+			if q.options.lenient {
+				columnar[i] = new(interface{})
+				continue
+			}
+			return errors.Errorf("expected %d columns for scalar values, got column %q", len(scalars), columns[i].Name())
+		}
+		columnar[i] = scalars[scalar].Addr().Interface()
+		scalar++
+	}
+	if scalar < len(scalars) {
+		return errors.Errorf("expected %d columns for scalar values, got %d", len(scalars), scalar)
+	}
+	return nil
+}
+
+// columnTarget is the field of an entity that a column is scanned into. The
+// entity is the index of the entity, which is -1 if the column is discarded.
+type columnTarget struct {
+	entity int
+	field  sreflect.ReflectField
+	// column is the name of the field in the entity, without any prefix.
+	column string
 }
 
-func (q Query) structMapping(columns []*sql.ColumnType, entities []sreflect.ReflectStruct, fields []recordBinding) ([]interface{}, error) {
+// columnTargets locates the entity field of every column.
+func (q Query) columnTargets(columns []*sql.ColumnType, entities []sreflect.ReflectStruct, fields []recordBinding) ([]columnTarget, error) {
+	if q.aliasAll && len(fields) > 0 {
+		return q.positionalTargets(columns, entities, fields)
+	}
+
+	mapped := make(map[string]map[string]struct{})
+
 	// Traverse the entities available, this is where it becomes very difficult
 	// for use. As the sql library doesn't provide the namespaced columns for
 	// us to inspect, so if you have overlapping column names it becomes hard
 	// to know where to locate that information, without a SQL AST.
-	columnar := make([]interface{}, len(columns))
+	targets := make([]columnTarget, len(columns))
 	for i, column := range columns {
-		columnName := column.Name()
-
-		var prefix string
-		if strings.HasPrefix(columnName, AliasPrefix) {
-			parts := strings.Split(columnName[len(AliasPrefix):], AliasSeparator)
-			prefix = parts[0]
-			columnName = parts[1]
+		prefix, columnName, aliased, err := q.columnAlias.decode(column.Name())
+		if err != nil {
+			return nil, err
+		}
+		if aliased && !hasRecordPrefix(fields, prefix) {
+			return nil, q.columnAlias.collision(column.Name())
 		}
 
 		var found bool
-		for _, entity := range entities {
+		for j, entity := range entities {
 			field, ok := entity.Fields[columnName]
 			if !ok {
 				continue
@@ -488,7 +1099,7 @@ func (q Query) structMapping(columns []*sql.ColumnType, entities []sreflect.Refl
 			if prefix != "" {
 				var bindingFound bool
 				for _, binding := range fields {
-					if binding.name == entity.Name && binding.prefix == prefix {
+					if binding.entityName() == entity.Name && binding.aliasName() == prefix && binding.selects(columnName) {
 						bindingFound = true
 						break
 					}
@@ -498,38 +1109,228 @@ func (q Query) structMapping(columns []*sql.ColumnType, entities []sreflect.Refl
 				}
 			}
 
-			columnar[i] = field.Value.Addr().Interface()
+			targets[i] = columnTarget{
+				entity: j,
+				field:  field,
+				column: columnName,
+			}
+			markMapped(mapped, entity.Name, columnName)
 			found = true
 			break
 		}
 		if !found {
-			return nil, errors.Errorf("missing destination name %q in types %v", column.Name(), entityNames(q.entities))
+			if q.options.lenient {
+				targets[i] = columnTarget{entity: -1}
+				continue
+			}
+			return nil, &MissingDestinationError{
+				Column: column.Name(),
+				Types:  entityNames(entities),
+			}
 		}
 	}
-	return columnar, nil
-}
-
-func (q Query) query(tx *sql.Tx, stmt string, args []interface{}) (*sql.Rows, []*sql.ColumnType, error) {
-	// Call the hook, before making the query.
-	if q.hook != nil {
-		q.hook(stmt)
-	}
-
-	rows, err := tx.Query(stmt, args...)
-	if err != nil {
-		return nil, nil, err
+	if q.options.strict {
+		if err := checkMapped(entities, fields, mapped); err != nil {
+			return nil, err
+		}
 	}
+	return targets, nil
+}
 
-	// Grab the columns of the rows returned.
-	columns, err := rows.ColumnTypes()
-	if err != nil {
-		rows.Close()
-		return nil, nil, err
+func hasRecordPrefix(records []recordBinding, prefix string) bool {
+	for _, record := range records {
+		if record.aliasName() == prefix {
+			return true
+		}
 	}
-	return rows, columns, nil
+	return false
 }
 
-func (q Query) scanOne(rows *sql.Rows, args []interface{}) error {
+// positionalTargets locates the entity field of every column using the
+// position of the record found in the column alias.
+func (q Query) positionalTargets(columns []*sql.ColumnType, entities []sreflect.ReflectStruct, fields []recordBinding) ([]columnTarget, error) {
+	mapped := make(map[string]map[string]struct{})
+	targets := make([]columnTarget, len(columns))
+	for i, column := range columns {
+		columnName := column.Name()
+		prefix, name, aliased, err := q.columnAlias.decode(columnName)
+		if err != nil {
+			return nil, err
+		}
+		if !aliased {
+			if q.options.lenient {
+				targets[i] = columnTarget{entity: -1}
+				continue
+			}
+			return nil, errors.Errorf("expected column %q to be aliased", columnName)
+		}
+		position, err := strconv.Atoi(prefix)
+		if err != nil || position < 0 || position >= len(fields) {
+			return nil, q.columnAlias.collision(columnName)
+		}
+
+		record := fields[position]
+		var found bool
+		for j, entity := range entities {
+			if entity.Name != record.entityName() {
+				continue
+			}
+			field, ok := entity.Fields[name]
+			if !ok {
+				break
+			}
+			targets[i] = columnTarget{
+				entity: j,
+				field:  field,
+				column: name,
+			}
+			markMapped(mapped, entity.Name, name)
+			found = true
+			break
+		}
+		if !found {
+			if q.options.lenient {
+				targets[i] = columnTarget{entity: -1}
+				continue
+			}
+			return nil, &MissingDestinationError{
+				Column: columnName,
+				Types:  entityNames(entities),
+			}
+		}
+	}
+	if q.options.strict {
+		if err := checkMapped(entities, fields, mapped); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+func markMapped(mapped map[string]map[string]struct{}, entity, field string) {
+	if _, ok := mapped[entity]; !ok {
+		mapped[entity] = make(map[string]struct{})
+	}
+	mapped[entity][field] = struct{}{}
+}
+
+// checkMapped ensures that every field of the entities received a column,
+// other than the fields that have been explicitly excluded by a record.
+func checkMapped(entities []sreflect.ReflectStruct, records []recordBinding, mapped map[string]map[string]struct{}) error {
+	for _, entity := range entities {
+		for _, name := range entity.FieldNames() {
+			if _, ok := mapped[entity.Name][name]; ok || isExcluded(records, entity.Name, name) {
+				continue
+			}
+			return &MissingColumnError{
+				Entity: entity.Name,
+				Field:  name,
+			}
+		}
+	}
+	return nil
+}
+
+func isExcluded(records []recordBinding, entity, field string) bool {
+	for _, record := range records {
+		if record.entityName() != entity {
+			continue
+		}
+		if _, ok := record.exclude[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (q Query) query(ctx context.Context, tx *sql.Tx, stmt string, args []interface{}, records []recordBinding) (*queryRows, []*sql.ColumnType, error) {
+	if q.options.limitOne {
+		stmt = limitOneStatement(stmt)
+	}
+	if q.explained != nil {
+		q.explained.stmt = stmt
+		q.explained.args = args
+		q.explained.records = records
+		return nil, nil, errExplained
+	}
+
+	stmt = annotateStatement(ctx, stmt, q.commenter)
+
+	// Call the hook, before making the query.
+	if hook := contextHook(ctx, q.hook); hook != nil {
+		if err := hook(stmt); err != nil {
+			return nil, nil, errors.Wrap(err, "hook")
+		}
+	}
+	if err := q.statementHook.observe(stmt, records, q.arguments); err != nil {
+		return nil, nil, errors.Wrap(err, "statement hook")
+	}
+
+	start := time.Now()
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		q.slowQuery.observe(stmt, start)
+		q.queryHook.observe(ctx, stmt, start, 0, err)
+		return nil, nil, err
+	}
+
+	// Grab the columns of the rows returned.
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		q.slowQuery.observe(stmt, start)
+		q.queryHook.observe(ctx, stmt, start, 0, err)
+		return nil, nil, err
+	}
+	return &queryRows{
+		Rows: rows,
+		done: func(count int64, err error) {
+			q.slowQuery.observe(stmt, start)
+			q.queryHook.observe(ctx, stmt, start, count, err)
+		},
+		scanned: q.scanned,
+	}, columns, nil
+}
+
+// queryRows wraps the sql.Rows so that the duration of a statement includes
+// the time taken to read all the rows, and so that the scanned rows can be
+// counted.
+type queryRows struct {
+	*sql.Rows
+	done    func(int64, error)
+	scanned *int64
+	// count is the number of rows scanned, and err is the first error
+	// scanning them, which are reported once the rows are closed.
+	count int64
+	err   error
+}
+
+// Scan scans the current row, counting the row if it's scanned.
+func (r *queryRows) Scan(dest ...interface{}) error {
+	if err := r.Rows.Scan(dest...); err != nil {
+		if r.err == nil {
+			r.err = err
+		}
+		return err
+	}
+	r.count++
+	if r.scanned != nil {
+		*r.scanned++
+	}
+	return nil
+}
+
+// Close closes the underlying rows, before reporting the statement duration.
+func (r *queryRows) Close() error {
+	err := r.Rows.Close()
+	if r.err == nil {
+		r.err = r.Rows.Err()
+	}
+	r.done(r.count, r.err)
+	return err
+}
+
+func (q Query) scanOne(rows *queryRows, args []interface{}) error {
 	for rows.Next() {
 		if err := rows.Scan(args...); err != nil {
 			return err
@@ -539,12 +1340,10 @@ func (q Query) scanOne(rows *sql.Rows, args []interface{}) error {
 	return rows.Err()
 }
 
-func entityNames(entities []sreflect.ReflectInfo) []string {
-	var names []string
-	for _, entity := range entities {
-		if rs, ok := entity.(sreflect.ReflectStruct); ok {
-			names = append(names, rs.Name)
-		}
+func entityNames(entities []sreflect.ReflectStruct) []string {
+	names := make([]string, len(entities))
+	for i, entity := range entities {
+		names[i] = entity.Name
 	}
 	return names
 }
@@ -566,32 +1365,15 @@ var prefixes = map[rune]bindCharPredicate{
 	'?': numeric,
 }
 
-// indexOfInputNamedArgs returns the potential starting index of a named argument
-// within the statement contains the named args prefix.
-// This can return a false positives.
-func indexOfInputNamedArgs(stmt string) int {
-	// Let's be explicit that we've found something, we could just use the
-	// res to see if it's moved, but that's more cryptic.
-	var found bool
-	res := len(stmt) + 1
-	for prefix := range prefixes {
-		if index := strings.IndexRune(stmt, prefix); index >= 0 && index < res {
-			res = index
-			found = true
-		}
-	}
-	if found {
-		return res
-	}
-	return -1
-}
-
 type nameBinding struct {
 	prefix rune
 	name   string
+	// offset is the index of the prefix within the statement.
+	offset int
 }
 
-// parseNames extracts the named arguments from a given statement.
+// parseNames extracts the named arguments from a given statement, in the order
+// they appear in, starting at the offset.
 //
 // Spec: https://www.sqlite.org/c3ref/bind_blob.html
 //
@@ -606,338 +1388,1035 @@ type nameBinding struct {
 //  - NNN represents an integer literal
 //  - VVV represents an alphanumeric identifier.
 //
+// The statement is scanned once. Quoted strings, quoted identifiers and
+// comments are skipped, so that they never contain named arguments. Positional
+// placeholders (?) are returned without a name.
 func parseNames(stmt string, offset int) ([]nameBinding, error) {
-	var names []nameBinding
-
-	// Use the offset to jump ahead of the statement.
-	for i := offset; i < len(stmt); i++ {
-		r := rune(stmt[i])
-		if predicate, ok := prefixes[r]; ok {
-			// We need to special case empty '?' as they're valid, but are not
-			// valid binds.
-			if r == '?' && i+1 < len(stmt) && isNameTerminator(rune(stmt[i+1])) {
-				continue
-			}
+	return appendNames(nil, stmt, offset)
+}
+
+// appendNames appends the named arguments of the statement to the names, so
+// that the bindings can be parsed into a reused slice.
+func appendNames(names []nameBinding, stmt string, offset int) ([]nameBinding, error) {
+	for i := offset; i < len(stmt); {
+		char := stmt[i]
+		switch {
+		case char == '\'' || char == '"' || char == '`':
+			i = skipQuoted(stmt, i, char)
+			continue
+		case char == '-' && i+1 < len(stmt) && stmt[i+1] == '-':
+			i = skipUntil(stmt, i+2, "\n")
+			continue
+		case char == '/' && i+1 < len(stmt) && stmt[i+1] == '*':
+			i = skipUntil(stmt, i+2, "*/")
+			continue
+		case char == ':' && i+1 < len(stmt) && stmt[i+1] == ':':
+			// Skip over type casts (value::type).
+			i += 2
+			continue
+		}
 
-			// Consume the following runes, until you locate a breaking value.
-			var name string
-			for i = i + 1; i < len(stmt); i++ {
-				char := rune(stmt[i])
+		predicate, ok := prefixes[rune(char)]
+		if !ok {
+			i++
+			continue
+		}
 
-				if predicate(char) {
-					name += string(char)
+		// Consume the following runes, until we locate one that can't be
+		// part of the name.
+		end := i + 1
+		for end < len(stmt) {
+			r, size := utf8.DecodeRuneInString(stmt[end:])
+			if r == '.' && char != '?' && end > i+1 && end+1 < len(stmt) {
+				// Dotted names reach into nested maps and structs
+				// (:person.name).
+				if next, _ := utf8.DecodeRuneInString(stmt[end+1:]); predicate(next) {
+					end += size
 					continue
 				}
-				if isNameTerminator(char) {
-					break
-				}
-				return nil, errors.Errorf("unexpected named argument found in statement %q", stmt)
 			}
-			names = append(names, nameBinding{
-				prefix: r,
-				name:   name,
-			})
-
-			// Locate the index of the next name. We use this to skip over
-			// any complexities.
-			if i >= len(stmt) {
-				// We're done processing the stmt.
+			if !predicate(r) {
 				break
 			}
-			index := indexOfInputNamedArgs(stmt[i:])
-			if index == -1 {
-				// No additional names, skip.
-				break
-			}
-			// We want to reduce the index by 1, so that we also pick up the
-			// prefix, otherwise we skip over it.
-			i += (index - 1)
+			end += size
+		}
+
+		// Empty '?' are positional placeholders, which are recorded without
+		// a name.
+		if end == i+1 && char != '?' {
+			return nil, syntaxError(stmt, i, "unexpected named argument found in statement %q", stmt)
 		}
+
+		names = append(names, nameBinding{
+			prefix: rune(char),
+			name:   stmt[i+1 : end],
+			offset: i,
+		})
+		i = end
 	}
-	sort.Slice(names, func(i int, j int) bool {
-		return names[i].name < names[j].name
-	})
 	return names, nil
 }
 
-func isNameTerminator(a rune) bool {
-	return unicode.IsSpace(a) || a == ',' || a == ';' || a == '=' || a == ')'
+// skipQuoted returns the index directly after the closing quote of the quoted
+// string starting at the offset. Quotes are escaped by doubling them.
+func skipQuoted(stmt string, offset int, quote byte) int {
+	for i := offset + 1; i < len(stmt); i++ {
+		if stmt[i] != quote {
+			continue
+		}
+		if i+1 < len(stmt) && stmt[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(stmt)
+}
+
+// skipUntil returns the index directly after the terminator, starting at the
+// offset.
+func skipUntil(stmt string, offset int, terminator string) int {
+	if index := strings.Index(stmt[offset:], terminator); index >= 0 {
+		return offset + index + len(terminator)
+	}
+	return len(stmt)
 }
 
-func constructInputNamedArgs(arg interface{}, names []nameBinding) ([]sql.NamedArg, error) {
+// namedSource provides the values of named arguments from a map or a struct.
+type namedSource struct {
+	// typ is the type of the source, which is empty for maps.
+	typ   string
+	value interface{}
+}
+
+func newNamedSource(config sreflect.Config, arg interface{}) (namedSource, error) {
+	if args, ok := arg.(Args); ok {
+		return namedSource{
+			value: map[string]interface{}(args),
+		}, nil
+	}
+
 	t := reflect.TypeOf(arg)
 	k := t.Kind()
 	switch {
-	case k == reflect.Map && t.Key().Kind() == reflect.String:
+	case k == reflect.Map:
+		// The map is converted once, rather than on every look up.
 		m, ok := convertMapStringInterface(arg)
 		if !ok {
-			return nil, errors.Errorf("map type: %T not supported", arg)
+			return namedSource{}, errors.WithStack(&ArgumentKeyError{
+				Type: fmt.Sprintf("%T", arg),
+				Key:  t.Key().String(),
+			})
+		}
+		return namedSource{
+			value: m,
+		}, nil
+
+	case k == reflect.Array || k == reflect.Slice:
+		// Slices provide the numbered arguments (?NNN), so they can't be
+		// bytes, which are a single value.
+		if t.Elem().Kind() == reflect.Uint8 {
+			return namedSource{}, errors.Errorf("%q not supported", k.String())
+		}
+		return namedSource{
+			typ:   fmt.Sprintf("%T", arg),
+			value: arg,
+		}, nil
+	default:
+		ref, err := config.Reflect(reflect.ValueOf(arg))
+		if err != nil {
+			return namedSource{}, err
+		}
+		if _, ok := ref.(sreflect.ReflectStruct); !ok {
+			return namedSource{}, errors.Errorf("%q not supported", k)
+		}
+		return namedSource{
+			typ:   fmt.Sprintf("%T", arg),
+			value: arg,
+		}, nil
+	}
+}
+
+// lookup walks the path through the nested maps and struct fields of the
+// source, returning the value found at the end of the path. The value is
+// omitted if it's from a zero valued field that's tagged with omitempty.
+func (s namedSource) lookup(config sreflect.Config, mapper fieldMapper, path []string) (value interface{}, found, omitted bool, err error) {
+	name := strings.Join(path, ".")
+
+	value = s.value
+	for i, part := range path {
+		last := i == len(path)-1
+		if value == nil {
+			return nil, false, false, nil
+		}
+
+		// Numbered arguments (?1) are the elements of a slice, starting at
+		// one.
+		if rv := reflect.ValueOf(value); (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 1 || index > rv.Len() {
+				return nil, false, false, nil
+			}
+			v := rv.Index(index - 1).Interface()
+			if !last {
+				value = v
+				continue
+			}
+			result, err := mapper.value(v)
+			if err != nil {
+				return nil, false, false, errors.Wrapf(err, "index %d", index)
+			}
+			return result, true, false, nil
 		}
-		nameValues := make([]sql.NamedArg, len(names))
-		for k, name := range names {
-			if value, ok := m[name.name]; ok {
-				nameValues[k] = sql.Named(name.name, value)
+
+		if m, ok := convertMapStringInterface(value); ok {
+			v, ok := m[part]
+			if !ok {
+				return nil, false, false, nil
+			}
+			if !last {
+				value = v
 				continue
 			}
+			result, err := mapper.value(v)
+			if err != nil {
+				return nil, false, false, errors.Wrapf(err, "key %q", name)
+			}
+			return result, true, false, nil
+		}
 
-			return nil, errors.Errorf("key %q missing from map", name.name)
+		if last {
+			result, ok, err := bindGenerated(mapper, value, part)
+			if err != nil {
+				return nil, false, false, errors.Wrapf(err, "field %q", name)
+			}
+			if ok {
+				return result, true, false, nil
+			}
 		}
-		return nameValues, nil
 
-	case k == reflect.Array || k == reflect.Slice:
-		return nil, errors.Errorf("%q not supported", k.String())
-	default:
-		ref, err := sreflect.Reflect(reflect.ValueOf(arg))
+		ref, err := config.Reflect(reflect.ValueOf(value))
 		if err != nil {
-			return nil, err
+			return nil, false, false, err
 		}
 		refStruct, ok := ref.(sreflect.ReflectStruct)
 		if !ok {
-			return nil, errors.Errorf("%q not supported", k)
+			return nil, false, false, nil
+		}
+		field, ok := refStruct.Fields[part]
+		if !ok {
+			return nil, false, false, nil
+		}
+		if !last {
+			value = field.Value.Interface()
+			continue
 		}
+		result, err := mapper.argument(field)
+		if err != nil {
+			return nil, false, false, errors.Wrapf(err, "field %q", name)
+		}
+		return result, true, field.Tag.OmitEmpty && field.Value.IsZero(), nil
+	}
+	return nil, false, false, nil
+}
 
-		nameValues := make([]sql.NamedArg, len(names))
-		for k, name := range names {
-			if field, ok := refStruct.Fields[name.name]; ok {
-				fieldValue := field.Value.Interface()
-				nameValues[k] = sql.Named(name.name, fieldValue)
+// isNamedSource returns true if the argument is a map or a struct that can
+// provide the values of named arguments, rather than being a value itself.
+func isNamedSource(arg interface{}) bool {
+	if arg == nil {
+		return false
+	}
+	switch arg.(type) {
+	case sql.NamedArg, *sql.NamedArg, driver.Valuer:
+		return false
+	}
+	t := reflect.TypeOf(arg)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		// Maps with keys that can't name an argument are sources as well, so
+		// that they're reported rather than bound as a value.
+		return true
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Struct:
+		return !isTimeType(t) && !sreflect.IsValuer(t)
+	}
+	return false
+}
+
+// constructInputNamedArgs resolves the named arguments from the sources. The
+// sources are consulted in order, so the first source that has a name
+// provides its value. If more than one source has the same name, then the
+// values must be equal. Names bound from zero valued omitempty fields are
+// reported to the argument check.
+func constructInputNamedArgs(config sreflect.Config, mapper fieldMapper, check argumentCheck, args []interface{}, names []nameBinding) ([]sql.NamedArg, error) {
+	sources := make([]namedSource, len(args))
+	for i, arg := range args {
+		var err error
+		if sources[i], err = newNamedSource(config, arg); err != nil {
+			return nil, err
+		}
+	}
+
+	nameValues := make([]sql.NamedArg, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if _, ok := seen[name.name]; ok || name.name == "" {
+			continue
+		}
+		seen[name.name] = struct{}{}
+
+		// Dotted names reach into nested maps and structs (:person.name).
+		path, err := parser.ParsePath(name.name)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var (
+			value   interface{}
+			found   bool
+			omitted bool
+		)
+		for _, source := range sources {
+			v, ok, empty, err := source.lookup(config, mapper, path)
+			if err != nil {
+				return nil, err
+			} else if !ok {
+				continue
+			}
+			if !found {
+				value, found, omitted = v, true, empty
 				continue
 			}
+			if !reflect.DeepEqual(value, v) {
+				return nil, &ConflictingArgumentError{
+					Name: name.name,
+				}
+			}
+		}
+		if !found {
+			if value, found = check.missing(name.name); !found {
+				return nil, missingArgument(name.name, sources)
+			}
+		}
+		if omitted {
+			if err := check.report(&EmptyArgumentError{Name: name.name}); err != nil {
+				return nil, err
+			}
+		}
+		nameValues = append(nameValues, sql.Named(bindName(name.name), value))
+	}
+	return nameValues, nil
+}
+
+// bindName returns the name a named argument is bound to in the statement
+// sent to the database. Dotted names aren't valid parameters, so the dots are
+// replaced. Numbered arguments (?NNN) are bound by name, so that they never
+// clash with the indexes SQLite gives to named arguments.
+func bindName(name string) string {
+	if isNumbered(name) {
+		return "n__" + name
+	}
+	return strings.ReplaceAll(name, ".", "__")
+}
 
-			return nil, errors.Errorf("field %q missing from type %T", name.name, arg)
+// isNumbered returns true if the name is of a numbered argument (?NNN).
+func isNumbered(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !numeric(r) {
+			return false
 		}
+	}
+	return true
+}
 
-		return nameValues, nil
+// rewriteNames replaces the dotted named arguments of the statement with their
+// bind names, and the positional placeholders with named arguments. SQLite
+// gives named arguments the next free parameter index, which can be the index
+// of a positional placeholder, so positional arguments are always bound by
+// name when they're mixed with named arguments. The statement is returned
+// untouched if there's nothing to rewrite.
+func rewriteNames(stmt string, names []nameBinding) (string, error) {
+	var (
+		changed    bool
+		positional int
+	)
+	bound := make(map[string]string, len(names))
+	for _, name := range names {
+		original, bind := name.name, bindName(name.name)
+		if original == "" {
+			positional++
+			original, bind = "?", positionalName(positional)
+		}
+		if other, ok := bound[bind]; ok && other != original {
+			return "", &offsetError{
+				err:    errors.Errorf("named argument %q collides with %q", original, other),
+				offset: name.offset,
+			}
+		}
+		bound[bind] = original
+		changed = changed || bind != name.name
+	}
+	if !changed {
+		return stmt, nil
 	}
+
+	var (
+		builder strings.Builder
+		last    int
+	)
+	positional = 0
+	for _, name := range names {
+		if name.name == "" {
+			positional++
+			builder.WriteString(stmt[last:name.offset])
+			builder.WriteString(":" + positionalName(positional))
+			last = name.offset + 1
+			continue
+		}
+		// Numbered arguments are rewritten as named arguments, otherwise
+		// the prefix of the named argument is kept.
+		start := name.offset + 1
+		builder.WriteString(stmt[last:name.offset])
+		if name.prefix == '?' {
+			builder.WriteRune(':')
+		} else {
+			builder.WriteRune(name.prefix)
+		}
+		builder.WriteString(bindName(name.name))
+		last = start + len(name.name)
+	}
+	builder.WriteString(stmt[last:])
+	return builder.String(), nil
 }
 
-func constructNamedArguments(stmt string, args []interface{}) ([]interface{}, error) {
-	var names []nameBinding
-	if offset := indexOfInputNamedArgs(stmt); offset >= 0 {
-		var err error
-		if names, err = parseNames(stmt, offset); err != nil {
-			return nil, err
+// positionalName returns the name that the nth positional placeholder is
+// bound to.
+func positionalName(n int) string {
+	return "p__" + strconv.Itoa(n)
+}
+
+func missingArgument(name string, sources []namedSource) error {
+	if len(sources) == 1 {
+		return &MissingArgumentError{
+			Name: name,
+			Type: sources[0].typ,
+		}
+	}
+	types := make([]string, len(sources))
+	for i, source := range sources {
+		types[i] = source.typ
+		if types[i] == "" {
+			types[i] = "map"
 		}
 	}
+	return &MissingArgumentError{
+		Name: name,
+		Type: strings.Join(types, " and "),
+	}
+}
+
+// constructNamedArguments returns the arguments of the statement, with the
+// named arguments resolved from the maps and structs passed in. The statement
+// is returned with any dotted named arguments rewritten.
+func constructNamedArguments(config sreflect.Config, mapper fieldMapper, check argumentCheck, stmt string, args []interface{}) (string, []interface{}, error) {
+	buffer := namesPool.Get().(*[]nameBinding)
+	names, err := appendNames((*buffer)[:0], stmt, 0)
+	defer func() {
+		*buffer = names[:0]
+		namesPool.Put(buffer)
+	}()
+	if err != nil {
+		return "", nil, err
+	}
 
-	// Ensure we have arguments if we have names.
-	if len(args) == 0 && len(names) > 0 {
-		return nil, errors.Errorf("expected arguments for named parameters")
+	var positional int
+	for _, name := range names {
+		if name.name == "" {
+			positional++
+		}
+	}
+	// Statements without named arguments are passed through untouched,
+	// unless an identifier has to be spliced into the statement.
+	if len(names) == positional && !hasIdent(args) {
+		return stmt, args, nil
 	}
 
-	var inputs []sql.NamedArg
-	if len(names) > 0 && len(args) >= 1 {
-		// TODO: We may have colliding sql.NamedArgs already sent in, if that's
-		// the case we should document whom wins.
+	rewritten, err := rewriteNames(stmt, names)
+	if err != nil {
+		return "", nil, err
+	}
 
-		// Select the first argument and check if it's a map or struct.
-		var err error
-		if inputs, err = constructInputNamedArgs(args[0], names); err != nil {
+	// Named arguments passed in by the caller take precedence over the maps
+	// and structs, so the names they provide aren't resolved from them.
+	args, explicit, err := splitNamedArgs(args)
+	if err != nil {
+		if offset := indexOfName(names, argumentName(err)); offset >= 0 {
+			err = &offsetError{
+				err:    err,
+				offset: offset,
+			}
+		}
+		return "", nil, err
+	}
+	unresolved := names
+	if len(explicit) > 0 {
+		unresolved = make([]nameBinding, 0, len(names))
+		for _, name := range names {
+			if name.name == "" || indexOfNamedArg(explicit, name.name) < 0 {
+				unresolved = append(unresolved, name)
+			}
+		}
+	}
+
+	// The first argument is always used for the named arguments, along with
+	// any maps or structs that directly follow it. If the caller provided all
+	// the names, then only maps and structs are used.
+	var sources int
+	if len(unresolved) > positional {
+		// Ensure we have arguments if we have names, unless the names can be
+		// bound to their defaults.
+		if len(args) > 0 {
+			sources = 1
+		} else if !check.partial {
+			return "", nil, errors.Errorf("expected arguments for named parameters")
+		}
+	}
+	for sources < len(args) && isNamedSource(args[sources]) {
+		sources++
+	}
+	inputs, err := constructInputNamedArgs(config, mapper, check, args[:sources], unresolved)
+	if err == nil {
+		err = check.unused(config, args[:sources], explicit, names)
+	}
+	if err != nil {
+		if offset := indexOfName(names, argumentName(err)); offset >= 0 {
+			err = &offsetError{
+				err:    err,
+				offset: offset,
+			}
+		}
+		return "", nil, err
+	}
+
+	// The remaining arguments fill the positional placeholders in order.
+	args = args[sources:]
+	if len(args) != positional {
+		return "", nil, errors.Errorf("expected %d positional arguments, got %d", positional, len(args))
+	}
+
+	named, err := orderNamedArgs(names, inputs, explicit)
+	if err != nil {
+		if offset := indexOfName(names, argumentName(err)); offset >= 0 {
+			err = &offsetError{
+				err:    err,
+				offset: offset,
+			}
+		}
+		return "", nil, err
+	}
+
+	result := make([]interface{}, 0, len(args)+len(named))
+	for i, arg := range args {
+		result = append(result, sql.Named(positionalName(i+1), arg))
+	}
+	for _, arg := range named {
+		result = append(result, arg)
+	}
+	return spliceIdents(mapper.dialect, rewritten, result)
+}
+
+// orderNamedArgs returns a single named argument for every distinct bind name,
+// in the order that the names first appear in the statement, so a name that's
+// used more than once (name=:name OR alias=:name) is bound once. Drivers that
+// require one sql.NamedArg per parameter then receive exactly one. Named
+// arguments passed in by the caller that aren't in the statement follow,
+// untouched, so that the driver can report them. Arguments that are bound to
+// the same name must have equal values.
+func orderNamedArgs(names []nameBinding, inputs, explicit []sql.NamedArg) ([]sql.NamedArg, error) {
+	values := make(map[string]sql.NamedArg, len(inputs)+len(explicit))
+	bind := func(arg sql.NamedArg, name string) error {
+		if other, ok := values[arg.Name]; ok {
+			if !reflect.DeepEqual(other.Value, arg.Value) {
+				return &ConflictingArgumentError{
+					Name: name,
+				}
+			}
+			return nil
+		}
+		values[arg.Name] = arg
+		return nil
+	}
+	for _, arg := range inputs {
+		if err := bind(arg, arg.Name); err != nil {
+			return nil, err
+		}
+	}
+	unbound := make([]sql.NamedArg, 0, len(explicit))
+	for _, arg := range explicit {
+		name := arg.Name
+		if indexOfName(names, arg.Name) >= 0 {
+			arg = sql.Named(bindName(arg.Name), arg.Value)
+		} else {
+			unbound = append(unbound, arg)
+		}
+		if err := bind(arg, name); err != nil {
 			return nil, err
 		}
-		// Drop the first argument, as that's used for named arguments.
-		args = args[1:]
 	}
 
-	// Put the named arguments at the end of the query.
-	for _, input := range inputs {
-		args = append(args, input)
+	result := make([]sql.NamedArg, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, name := range names {
+		if name.name == "" {
+			continue
+		}
+		bound := bindName(name.name)
+		if _, ok := seen[bound]; ok {
+			continue
+		}
+		seen[bound] = struct{}{}
+		if arg, ok := values[bound]; ok {
+			result = append(result, arg)
+		}
+	}
+	for _, arg := range unbound {
+		if _, ok := seen[arg.Name]; ok {
+			continue
+		}
+		seen[arg.Name] = struct{}{}
+		result = append(result, arg)
+	}
+	return result, nil
+}
+
+// splitNamedArgs separates the sql.NamedArg values from the arguments. It's
+// an error for the same name to be passed more than once with different
+// values.
+func splitNamedArgs(args []interface{}) ([]interface{}, []sql.NamedArg, error) {
+	var (
+		result   = args
+		explicit []sql.NamedArg
+	)
+	for i, arg := range args {
+		var named sql.NamedArg
+		switch a := arg.(type) {
+		case sql.NamedArg:
+			named = a
+		case *sql.NamedArg:
+			if a == nil {
+				return nil, nil, errors.Errorf("unexpected nil named argument")
+			}
+			named = *a
+		default:
+			if explicit != nil {
+				result = append(result, arg)
+			}
+			continue
+		}
+		if explicit == nil {
+			result = make([]interface{}, i, len(args))
+			copy(result, args[:i])
+		}
+		if index := indexOfNamedArg(explicit, named.Name); index >= 0 {
+			if !reflect.DeepEqual(explicit[index].Value, named.Value) {
+				return nil, nil, &ConflictingArgumentError{
+					Name: named.Name,
+				}
+			}
+			continue
+		}
+		explicit = append(explicit, named)
+	}
+	return result, explicit, nil
+}
+
+// indexOfNamedArg returns the index of the named argument with the name, or -1
+// if there isn't one.
+func indexOfNamedArg(args []sql.NamedArg, name string) int {
+	for i, arg := range args {
+		if arg.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// argumentName returns the name of the named argument that caused the error,
+// if there is one.
+func argumentName(err error) string {
+	var missingErr *MissingArgumentError
+	if errors.As(err, &missingErr) {
+		return missingErr.Name
+	}
+	var conflictErr *ConflictingArgumentError
+	if errors.As(err, &conflictErr) {
+		return conflictErr.Name
+	}
+	var emptyErr *EmptyArgumentError
+	if errors.As(err, &emptyErr) {
+		return emptyErr.Name
+	}
+	return ""
+}
+
+// indexOfName returns the offset of the first binding with the name, or -1 if
+// there isn't one.
+func indexOfName(names []nameBinding, name string) int {
+	if name == "" {
+		return -1
 	}
-	return args, nil
+	for _, binding := range names {
+		if binding.name == name {
+			return binding.offset
+		}
+	}
+	return -1
 }
 
 // convertMapStringInterface attempts to convert v to map[string]interface{}.
 // Unlike v.(map[string]interface{}), this function works on named types that
-// are convertible to map[string]interface{} as well.
+// are convertible to map[string]interface{} as well. Maps of other values with
+// string keys, such as map[string]string, are copied into a new map, as are
+// maps with integer keys, which are keyed by the decimal of the integer so
+// that they provide the numbered arguments (?NNN).
 func convertMapStringInterface(v interface{}) (map[string]interface{}, bool) {
 	var m map[string]interface{}
 	mType := reflect.TypeOf(m)
 	t := reflect.TypeOf(v)
-	if !t.ConvertibleTo(mType) {
+	if t == nil {
+		return nil, false
+	}
+	if t.ConvertibleTo(mType) {
+		return reflect.ValueOf(v).Convert(mType).Interface().(map[string]interface{}), true
+	}
+	if t.Kind() != reflect.Map || !isArgumentKey(t.Key().Kind()) {
 		return nil, false
 	}
-	return reflect.ValueOf(v).Convert(mType).Interface().(map[string]interface{}), true
+
+	rv := reflect.ValueOf(v)
+	m = make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		var name string
+		switch key := iter.Key(); key.Kind() {
+		case reflect.String:
+			name = key.String()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			name = strconv.FormatUint(key.Uint(), 10)
+		default:
+			name = strconv.FormatInt(key.Int(), 10)
+		}
+		m[name] = iter.Value().Interface()
+	}
+	return m, true
+}
+
+// isArgumentKey returns true if the keys of a map of the kind can name an
+// argument.
+func isArgumentKey(k reflect.Kind) bool {
+	switch k {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
 }
 
 // indexOfRecordArgs returns the potential starting index of a record argument
-// if the statement contains the record args offset position.
+// if the statement contains the record args offset position. Braces within
+// quoted strings and comments aren't record arguments.
 func indexOfRecordArgs(stmt string) int {
-	return strings.IndexRune(stmt, '{')
+	return parser.IndexOfRecord(stmt, 0)
 }
 
 type recordBinding struct {
-	name       string
-	prefix     string
-	fields     map[string]struct{}
-	wildcard   bool
+	name  string
+	alias string
+	// prefix is the table of the record without any quotes, which is used
+	// to alias the columns. Tables qualified with a schema are prefixed with
+	// the schema, so that the same table of two schemas can be told apart.
+	prefix string
+	// qualifier is the table as it's written in the statement, along with
+	// any schema and quotes.
+	qualifier string
+	// short is the short alias of the record ({p.* INTO Person pp}), which
+	// is used to alias the columns in place of the prefix.
+	short   string
+	fields  map[string]struct{}
+	exclude map[string]struct{}
+	// expressions holds the computed columns of the record, keyed by the
+	// field they're bound to.
+	expressions map[string]string
+	// order holds the fields and computed columns in the order they're
+	// written in the record expression.
+	order    []string
+	wildcard bool
+	// table is true for a table placeholder ({Person.table}), which is
+	// replaced with the table of the entity.
+	table bool
+	// where is true for a where clause ({where Filters}), which is replaced
+	// with the conditions of a filter before the statement is compiled.
+	where bool
+	// orderBy is true for an order clause ({order}), which is replaced with
+	// the columns of the OrderBy arguments before the statement is compiled.
+	orderBy bool
+	// depth is the number of parentheses the record is nested in, which is
+	// greater than zero for records of subqueries and common table
+	// expressions.
+	depth      int
 	start, end int
 }
 
+// aliasName returns the name that the columns of the record are aliased
+// with, which is the short alias of the record if it has one, or its prefix.
+func (f recordBinding) aliasName() string {
+	if f.short != "" {
+		return f.short
+	}
+	return f.prefix
+}
+
+// selects returns true if the record selects the field, either by name or by
+// a wildcard.
+func (f recordBinding) selects(name string) bool {
+	if _, ok := f.fields[name]; ok {
+		return true
+	}
+	if _, ok := f.expressions[name]; ok {
+		return true
+	}
+	_, excluded := f.exclude[name]
+	return f.wildcard && !excluded
+}
+
+// entityName returns the name of the entity the record is bound to, which is
+// the alias of the record if it has one.
+func (f recordBinding) entityName() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
 func (f recordBinding) translate(expantion int) int {
 	return expantion - (f.end - f.start)
 }
 
+// parseRecords parses the record expressions of the statement, starting with
+// the record expression at the offset, into record bindings.
 func parseRecords(stmt string, offset int) ([]recordBinding, error) {
-	// TODO: Create a tokenizer and AST for a record bindings. For now we'll
-	// just walk over them in a handrolled style, until the need arises.
+	exprs, err := parser.ParseRecords(stmt, offset)
+	if err != nil {
+		if parseErr, ok := err.(*parser.Error); ok {
+			return nil, &SyntaxError{
+				Message:   parseErr.Message,
+				Statement: stmt,
+				Position:  parseErr.Pos,
+			}
+		}
+		return nil, errors.WithStack(err)
+	}
 
-	var records []recordBinding
-	for i := offset; i < len(stmt); i++ {
-		r := rune(stmt[i])
-		if r != '{' {
-			return records, nil
+	records := make([]recordBinding, 0, len(exprs))
+	for _, expr := range exprs {
+		bindings, err := bindRecord(stmt, expr)
+		if err != nil {
+			return nil, err
 		}
+		records = append(records, bindings...)
+	}
+	return records, nil
+}
 
-		// Parse the Record syntax `{Record}` or optionally `{test.* INTO Record}`
-		// We can consider this being the tokenizer.
-		var record string
-		quotes := make(map[rune]int)
-	inner:
-		for i = i + 1; i < len(stmt); i++ {
-			char := rune(stmt[i])
+// bindRecord converts a parsed record expression into record bindings. The
+// columns of a record expression can belong to more than one table
+// ({p.name, a.city INTO Person}), in which case there's a binding for every
+// table, in the order the tables are first written. The bindings share the
+// span of the record expression, so they're expanded together.
+func bindRecord(stmt string, expr *parser.RecordExpr) ([]recordBinding, error) {
+	newBinding := func() recordBinding {
+		return recordBinding{
+			name:    expr.Entity,
+			alias:   expr.Alias,
+			short:   expr.ShortAlias,
+			fields:  make(map[string]struct{}),
+			table:   expr.Table,
+			where:   expr.Where,
+			orderBy: expr.Order,
+			depth:   expr.Depth,
+			start:   expr.Pos.Offset,
+			end:     expr.End.Offset,
+		}
+	}
 
-			switch {
-			case unicode.IsLetter(char) || unicode.IsSpace(char) || unicode.IsNumber(char) || unicode.IsDigit(char):
-				fallthrough
-			case char == '_', char == ',', char == '.', char == '*':
-				record += string(char)
-			case char == '"' || char == '\'':
-				if quotes[char] == 0 {
-					quotes[char]++
-					continue
-				}
+	var (
+		records []recordBinding
+		// tables holds the index of the binding of every table.
+		tables = make(map[string]int)
+		// selected holds the table that selects every field, so that a
+		// field can't be selected from two tables.
+		selected = make(map[string]string)
+	)
+	binding := func(prefix string) *recordBinding {
+		index, ok := tables[prefix]
+		if !ok {
+			index = len(records)
+			tables[prefix] = index
+			record := newBinding()
+			record.prefix = prefix
+			records = append(records, record)
+		}
+		return &records[index]
+	}
 
-				// peek forward.
-				if i+1 < len(stmt) {
-					peek := rune(stmt[i+1])
-					if unicode.IsSpace(peek) || peek == '.' {
-						quotes[char]++
-						continue
-					}
-				}
-				return nil, errors.Errorf("unexpected quoted string at %d in record expression %q", i-offset, stmt[offset+1:i+1])
-			case char == '}':
-				break inner
+	// The bindings are created up front, so that computed columns belong to
+	// the first table, wherever they're written.
+	for _, column := range expr.Columns {
+		if column.Expr == "" {
+			binding(columnPrefix(column))
+		}
+	}
+	if len(records) == 0 {
+		// The shorthand form `{Person}` selects every field.
+		binding("").wildcard = len(expr.Columns) == 0 && !expr.Table && !expr.Where && !expr.Order
+	}
+	if len(records) > 1 && expr.ShortAlias != "" {
+		return nil, syntaxError(stmt, expr.Pos.Offset, "unexpected short alias %q for record expression %q of more than one table", expr.ShortAlias, expr.Text)
+	}
 
-			default:
-				return nil, errors.Errorf("unexpected struct name at %d in record expression %q", i-offset, stmt[offset+1:i+1])
+	for i, column := range expr.Columns {
+		// Computed columns (`COUNT(*) AS total`) are kept apart from the
+		// fields, as they're not bound to a table.
+		if column.Expr != "" {
+			record := &records[0]
+			if record.expressions == nil {
+				record.expressions = make(map[string]string)
 			}
+			if _, ok := record.expressions[column.Name]; ok {
+				return nil, syntaxError(stmt, record.start, "duplicate expression field %q in record expression %q", column.Name, expr.Text)
+			}
+			record.expressions[column.Name] = column.Expr
+			record.order = append(record.order, column.Name)
+			continue
 		}
 
-		// The following parses the fields, so that we know what to fill in the
-		// record.
-		// This is more akin to a parser, over a series of runes in a string.
-		var (
-			fields       = make(map[string]struct{})
-			wildcard     bool
-			name, prefix string
-		)
-
-		parts := strings.Split(strings.TrimSpace(record), " ")
-		if num := len(parts); num == 1 {
-			name = parts[0]
-			wildcard = true
-		} else if num > 1 && strings.ToLower(parts[num-2]) == "into" {
-			name = parts[num-1]
-			// Some limitations, all prefixes have to match.
-			for _, part := range parts[:num-2] {
-				// We want to normalize all the fields in a record. We do this
-				// by removing any white space.
-				field := strings.TrimSuffix(strings.TrimSpace(part), ",")
-				// We always expect 2 values.
-				fieldParts := strings.Split(field, ".")
-				if num := len(fieldParts); num == 0 || num > 2 {
-					return nil, errors.Errorf("unexpected field %q in record expression %q", field, record)
-				} else if num == 1 {
-					// Ensure we always have two field parts, as that will make
-					// the logic below a lot simpler.
-					fieldParts = []string{"", fieldParts[0]}
-				}
-				if len(fields) != 0 && prefix != fieldParts[0] {
-					return nil, errors.Errorf("unexpected table name %q in field %q for record expression %q", fieldParts[0], field, record)
-				}
-				prefix = fieldParts[0]
+		prefix := columnPrefix(column)
+		if other, ok := selected[column.Name]; ok && other != prefix {
+			return nil, syntaxError(stmt, column.Pos.Offset, "duplicate field %q in field %q for record expression %q", column.Name, columnPath(expr.Columns[i]), expr.Text)
+		}
+		selected[column.Name] = prefix
 
-				fieldValue := strings.TrimSpace(fieldParts[1])
-				if fieldValue == "*" {
-					wildcard = true
-				}
-				fields[fieldValue] = struct{}{}
-			}
-		} else {
-			return nil, errors.Errorf("unexpected record expression %q", record)
+		record := binding(prefix)
+		if record.qualifier == "" {
+			record.qualifier = column.Qualifier
+		}
+		if column.IsWildcard() {
+			record.wildcard = true
+		} else if _, ok := record.fields[column.Name]; !ok {
+			record.order = append(record.order, column.Name)
 		}
+		record.fields[column.Name] = struct{}{}
+	}
 
-		// This is a very basic algorithm.
-		for char, amount := range quotes {
-			if amount%2 != 0 {
-				return nil, errors.Errorf("missing quote %q terminator for record expression %q", string(char), record)
+	if len(expr.Exclude) > 0 {
+		for i := range records {
+			record := &records[i]
+			if !record.wildcard {
+				return nil, syntaxError(stmt, record.start, "unexpected excluded fields in non-wildcard record expression %q", expr.Text)
+			}
+			record.exclude = make(map[string]struct{})
+			for _, field := range expr.Exclude {
+				record.exclude[field] = struct{}{}
 			}
 		}
+	}
+	return records, nil
+}
 
-		records = append(records, recordBinding{
-			name:     strings.TrimSpace(name),
-			prefix:   prefix,
-			fields:   fields,
-			wildcard: wildcard,
-			start:    offset,
-			end:      i + 1,
-		})
+// columnPrefix returns the prefix of the columns of the table of the column.
+// Tables qualified with a schema are prefixed with the schema as well.
+func columnPrefix(column parser.Column) string {
+	if column.Schema != "" {
+		return column.Schema + "_" + column.Prefix
+	}
+	return column.Prefix
+}
 
-		if i >= len(stmt) {
-			// We're done processing the stmt.
-			break
-		}
-		index := indexOfRecordArgs(stmt[i:])
-		if index == -1 {
-			// No additional names, skip.
-			break
-		}
-		// We want to reduce the index by 1, so that we also pick up the
-		// prefix, otherwise we skip over it.
-		offset = i + index
-		i = offset - 1
+// columnPath returns the column as it's written in the record expression.
+func columnPath(column parser.Column) string {
+	if column.Qualifier == "" {
+		return column.Name
 	}
-	return records, nil
+	return column.Qualifier + "." + column.Name
 }
 
-func expandRecords(stmt string, records []recordBinding, entities []sreflect.ReflectStruct, intersections map[string]map[string]struct{}) (string, error) {
-	var offset int
-	for _, record := range records {
+// expansion defines how the columns of a record are expanded.
+type expansion struct {
+	order    ColumnOrder
+	aliasAll bool
+	alias    columnAlias
+	// config is used to name the tables of table placeholders.
+	config sreflect.Config
+}
+
+func expandRecords(stmt string, records []recordBinding, entities []sreflect.ReflectStruct, intersections map[string]map[string]struct{}, options expansion) (string, error) {
+	var (
+		offset, position int
+		// grouped holds the columns of the bindings of a record expression
+		// that selects from more than one table, which share its span.
+		grouped []string
+	)
+	for i, record := range records {
+		// Record the offset of the record expression with any error, so that
+		// the error can point at the offending record.
+		fail := func(err error) (string, error) {
+			return "", &offsetError{
+				err:    err,
+				offset: record.start,
+			}
+		}
 
 		var found bool
 		for _, entity := range entities {
-			if record.name != entity.Name {
+			if record.entityName() != entity.Name {
 				continue
 			}
 
-			// Locate any field intersections from the records that's been
-			// pre-computed.
-			entityInter := intersections[entity.Name]
+			if record.table {
+				table := tableName(options.config, entity)
+				stmt = stmt[:offset+record.start] + table + stmt[offset+record.end:]
+				offset += record.translate(len(table))
 
-			var names []string
-			if record.wildcard {
-				// If we're wildcarded, just grab all the names.
-				for name := range entity.Fields {
-					names = append(names, constructFieldNameAlias(name, record, entityInter))
-				}
-			} else {
-				// If we're not wildcarded, go through all the binding fields
-				// and locate the entity field for the Record.
-				for name := range record.fields {
-					if _, ok := entity.Fields[name]; !ok {
-						return "", errors.Errorf("field %q not found in entity %q", name, entity.Name)
-					}
-					names = append(names, constructFieldNameAlias(name, record, entityInter))
-				}
+				found = true
+				break
 			}
 
-			if len(names) == 0 {
-				return "", errors.Errorf("no fields found in record %q expression", entity.Name)
+			// The field intersections of the records have been
+			// pre-computed.
+			columns, err := recordColumns(record, entity, intersections[entity.Name], position, options)
+			if err != nil {
+				return fail(err)
+			}
+			for _, column := range columns {
+				grouped = append(grouped, column.expression)
 			}
-			sort.Strings(names)
-			recordList := strings.Join(names, ", ")
+			position++
+			if i+1 < len(records) && records[i+1].start == record.start {
+				found = true
+				break
+			}
+
+			recordList := strings.Join(grouped, ", ")
 			stmt = stmt[:offset+record.start] + recordList + stmt[offset+record.end:]
+			grouped = grouped[:0]
 
 			// Translate the offset to take into account the new expantions.
 			offset += record.translate(len(recordList))
@@ -947,22 +2426,155 @@ func expandRecords(stmt string, records []recordBinding, entities []sreflect.Ref
 		}
 
 		if !found {
-			return "", errors.Errorf("no entity found with the name %q", record.name)
+			return fail(&UnknownEntityError{
+				Name: record.entityName(),
+			})
 		}
 	}
 
 	return stmt, nil
 }
 
-func constructFieldNameAlias(name string, record recordBinding, intersection map[string]struct{}) string {
-	if record.prefix == "" {
+// recordColumn is a column selected by a record expression, along with the
+// field of the entity that it's bound to.
+type recordColumn struct {
+	field      string
+	expression string
+}
+
+// recordColumns returns the columns that the record selects from the entity,
+// in the order that they're written into the statement.
+func recordColumns(record recordBinding, entity sreflect.ReflectStruct, entityInter map[string]struct{}, position int, options expansion) ([]recordColumn, error) {
+	var fields []string
+	if record.wildcard {
+		// Ensure that the excluded fields exist, so that typos are caught
+		// rather than silently selecting the field.
+		for name := range record.exclude {
+			if _, ok := entity.Fields[name]; !ok {
+				return nil, &MissingFieldError{
+					Entity:   entity.Name,
+					Field:    name,
+					Excluded: true,
+				}
+			}
+		}
+
+		// If we're wildcarded, just grab all the names. Fields bound to
+		// computed columns are skipped, as they're added below.
+		for _, name := range entity.DeclaredFieldNames() {
+			if _, ok := record.exclude[name]; ok {
+				continue
+			}
+			if _, ok := record.expressions[name]; ok {
+				continue
+			}
+			fields = append(fields, name)
+		}
+		for _, name := range record.order {
+			if _, ok := record.expressions[name]; ok {
+				fields = append(fields, name)
+			}
+		}
+	} else {
+		// If we're not wildcarded, go through all the binding fields and
+		// locate the entity field for the Record.
+		fields = record.order
+	}
+
+	columns := make([]recordColumn, 0, len(fields))
+	for _, name := range fields {
+		if _, ok := entity.Fields[name]; !ok {
+			return nil, &MissingFieldError{
+				Entity: entity.Name,
+				Field:  name,
+			}
+		}
+		var column string
+		expression, ok := record.expressions[name]
+		switch {
+		case record.depth > 0:
+			// The columns of subqueries and common table expressions are
+			// read by the enclosing statement, so they keep their names
+			// rather than being aliased.
+			column = constructNestedColumn(name, expression, record)
+		case options.aliasAll:
+			column = constructPositionalAlias(options.alias, position, name, expression, record)
+		case ok:
+			column = constructExpressionAlias(options.alias, expression, name, record, entityInter)
+		default:
+			column = constructFieldNameAlias(options.alias, name, record, entityInter)
+		}
+		columns = append(columns, recordColumn{
+			field:      name,
+			expression: column,
+		})
+	}
+
+	if len(columns) == 0 {
+		return nil, errors.Errorf("no fields found in record %q expression", entity.Name)
+	}
+	if options.order == SortedOrder {
+		sort.Slice(columns, func(i, j int) bool {
+			return columns[i].expression < columns[j].expression
+		})
+	}
+	return columns, nil
+}
+
+// withoutTables returns the records without any table placeholders.
+func withoutTables(records []recordBinding) []recordBinding {
+	result := records[:0:0]
+	for _, record := range records {
+		if !record.table {
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+func constructFieldNameAlias(columnAlias columnAlias, name string, record recordBinding, intersection map[string]struct{}) string {
+	if record.aliasName() == "" {
 		return name
 	}
 	var alias string
 	if _, ok := intersection[name]; ok {
-		alias = " AS " + AliasPrefix + record.prefix + AliasSeparator + name
+		alias = " AS " + columnAlias.encode(record.aliasName(), name)
+	}
+	return qualifyColumn(record.qualifier, name) + alias
+}
+
+func constructExpressionAlias(columnAlias columnAlias, expression, name string, record recordBinding, intersection map[string]struct{}) string {
+	alias := name
+	if _, ok := intersection[name]; ok && record.aliasName() != "" {
+		alias = columnAlias.encode(record.aliasName(), name)
+	}
+	return expression + " AS " + alias
+}
+
+// constructNestedColumn returns the column of a nested record without an
+// alias, other than the field name of a computed column.
+func constructNestedColumn(name, expression string, record recordBinding) string {
+	switch {
+	case expression != "":
+		return expression + " AS " + name
+	case record.prefix != "":
+		return record.qualifier + "." + name
+	}
+	return name
+}
+
+// constructPositionalAlias aliases the column with the position of the record
+// in the statement, so that the column can always be located without relying
+// on the field intersections.
+func constructPositionalAlias(columnAlias columnAlias, position int, name, expression string, record recordBinding) string {
+	column := expression
+	if column == "" {
+		column = name
+		if record.prefix != "" {
+			column = record.qualifier + "." + name
+		}
 	}
-	return record.prefix + "." + name + alias
+	return column + " AS " + columnAlias.encode(strconv.Itoa(position), name)
 }
 
 func fieldIntersections(entities []sreflect.ReflectStruct) map[string]map[string]struct{} {
@@ -2,6 +2,8 @@ package sqlair
 
 import (
 	"database/sql"
+	"github.com/pkg/errors"
+	"strings"
 	"testing"
 
 	"github.com/SimonRichardson/sqlair/reflect"
@@ -13,20 +15,64 @@ func TestParseNames(t *testing.T) {
 	names, err := parseNames("SELECT :name FROM @table WHERE $id=1 AND ?42=2 AND ?=3;", 0)
 	assert.Nil(t, err)
 	assert.Equal(t, names, []nameBinding{
-		{'?', "42"},
-		{'$', "id"},
-		{':', "name"},
-		{'@', "table"},
+		{':', "name", 7},
+		{'@', "table", 18},
+		{'$', "id", 31},
+		{'?', "42", 41},
+		{'?', "", 51},
 	})
 }
 
+func TestParseNamesSkipsQuotesAndComments(t *testing.T) {
+	names, err := parseNames(`SELECT ':skip', "@skip", 'it''s :skip' -- :skip
+FROM test /* $skip */ WHERE created::date=:date AND name=:name||'x';`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, names, []nameBinding{
+		{':', "date", 90},
+		{':', "name", 105},
+	})
+}
+
+func TestParseNamesWithOffset(t *testing.T) {
+	names, err := parseNames("SELECT :name, :age", 12)
+	assert.Nil(t, err)
+	assert.Equal(t, names, []nameBinding{
+		{':', "age", 14},
+	})
+}
+
+func TestParseNamesErrorsMissingName(t *testing.T) {
+	_, err := parseNames("SELECT * FROM test WHERE name=: ;", 0)
+	assert.Equal(t, err.Error(), `unexpected named argument found in statement "SELECT * FROM test WHERE name=: ;"`)
+}
+
+func BenchmarkParseNames(b *testing.B) {
+	var builder strings.Builder
+	builder.WriteString("SELECT * FROM people WHERE ")
+	for i := 0; i < 500; i++ {
+		if i > 0 {
+			builder.WriteString(" OR ")
+		}
+		builder.WriteString("(name = :name AND age > @age AND location = $location)")
+	}
+	builder.WriteString(";")
+	stmt := builder.String()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseNames(stmt, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestConstructNamedArgsWithMap(t *testing.T) {
-	namedArgs, err := constructInputNamedArgs(map[string]interface{}{
+	namedArgs, err := constructInputNamedArgs(reflect.DefaultConfig(), fieldMapper{}, argumentCheck{}, []interface{}{map[string]interface{}{
 		"name": "meshuggah",
 		"age":  42,
-	}, []nameBinding{
-		{':', "name"},
-		{'@', "age"},
+	}}, []nameBinding{
+		{':', "name", 0},
+		{'@', "age", 0},
 	})
 	assert.Nil(t, err)
 	assert.Equal(t, namedArgs, []sql.NamedArg{
@@ -43,9 +89,9 @@ func TestConstructInputNamedArgsWithStruct(t *testing.T) {
 		Name: "meshuggah",
 		Age:  42,
 	}
-	namedArgs, err := constructInputNamedArgs(arg, []nameBinding{
-		{':', "name"},
-		{'@', "age"},
+	namedArgs, err := constructInputNamedArgs(reflect.DefaultConfig(), fieldMapper{}, argumentCheck{}, []interface{}{arg}, []nameBinding{
+		{':', "name", 0},
+		{'@', "age", 0},
 	})
 	assert.Nil(t, err)
 	assert.Equal(t, namedArgs, []sql.NamedArg{
@@ -68,8 +114,9 @@ CREATE TABLE test(
 	var processedStmts []string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmts = append(processedStmts, stmt)
+		return nil
 	})
 
 	runTx(t, db, func(tx *sql.Tx) error {
@@ -117,8 +164,9 @@ CREATE TABLE test(
 	var processedStmts []string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmts = append(processedStmts, stmt)
+		return nil
 	})
 
 	type Person struct {
@@ -173,8 +221,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	person := make(map[string]interface{})
@@ -212,8 +261,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var count int
@@ -248,8 +298,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var count int
@@ -291,8 +342,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -331,8 +383,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -371,8 +424,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -411,8 +465,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -451,8 +506,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -482,6 +538,46 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	assert.Equal(t, ok, true)
 }
 
+func TestQueryWithStructUsesCacheForNewValues(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	querier := NewQuerier()
+
+	// The reflect cache must never hold on to the values of a previous
+	// query.
+	var fred, frank Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		for name, person := range map[string]*Person{"fred": &fred, "frank": &frank} {
+			getter, err := querier.ForOne(person)
+			assert.Nil(t, err)
+
+			if err := getter.Query(tx, `SELECT {test.* INTO Person} FROM test WHERE test.name=:name;`, map[string]interface{}{
+				"name": name,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	assert.Equal(t, fred, Person{Name: "fred", Age: 21})
+	assert.Equal(t, frank, Person{Name: "frank", Age: 42})
+}
+
 func TestQueryWithStructUsesCacheOverNumerousTx(t *testing.T) {
 	db := setupDB(t)
 
@@ -502,8 +598,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -565,8 +662,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -612,8 +710,9 @@ INSERT INTO location(id, city) values (1, "london"), (2, "paris");
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -664,8 +763,9 @@ INSERT INTO location(id, city) values (1, "london"), (2, "paris");
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -718,8 +818,9 @@ INSERT INTO location(id, city) values (1, "london"), (2, "paris");
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var person Person
@@ -763,8 +864,9 @@ INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var persons []Person
@@ -817,8 +919,9 @@ INSERT INTO location(id, city) values (1, "london"), (2, "paris");
 	var processedStmt string
 
 	querier := NewQuerier()
-	querier.Hook(func(stmt string) {
+	querier.Hook(func(stmt string) error {
 		processedStmt = stmt
+		return nil
 	})
 
 	var persons []Person
@@ -850,12 +953,14 @@ func TestParseRecords(t *testing.T) {
 	bindings, err := parseRecords(stmt, indexOfRecordArgs(stmt))
 	assert.Nil(t, err)
 	assert.Equal(t, bindings, []recordBinding{{
-		name:     "Person",
-		prefix:   "test",
-		fields:   map[string]struct{}{"*": {}, "name": {}, "age": {}},
-		wildcard: true,
-		start:    7,
-		end:      48,
+		name:      "Person",
+		prefix:    "test",
+		qualifier: "test",
+		fields:    map[string]struct{}{"*": {}, "name": {}, "age": {}},
+		order:     []string{"name", "age"},
+		wildcard:  true,
+		start:     7,
+		end:       48,
 	}})
 }
 
@@ -864,26 +969,30 @@ func TestParseMultipleRecords(t *testing.T) {
 	bindings, err := parseRecords(stmt, indexOfRecordArgs(stmt))
 	assert.Nil(t, err)
 	assert.Equal(t, bindings, []recordBinding{{
-		name:     "Person",
-		prefix:   "test",
-		fields:   map[string]struct{}{"*": {}, "name": {}, "age": {}},
-		wildcard: true,
-		start:    7,
-		end:      48,
+		name:      "Person",
+		prefix:    "test",
+		qualifier: "test",
+		fields:    map[string]struct{}{"*": {}, "name": {}, "age": {}},
+		order:     []string{"name", "age"},
+		wildcard:  true,
+		start:     7,
+		end:       48,
 	}, {
-		name:     "Foo",
-		prefix:   "foo",
-		fields:   map[string]struct{}{"*": {}},
-		wildcard: true,
-		start:    50,
-		end:      68,
+		name:      "Foo",
+		prefix:    "foo",
+		qualifier: "foo",
+		fields:    map[string]struct{}{"*": {}},
+		wildcard:  true,
+		start:     50,
+		end:       68,
 	}, {
-		name:     "Other",
-		prefix:   "other",
-		fields:   map[string]struct{}{"*": {}},
-		wildcard: true,
-		start:    70,
-		end:      92,
+		name:      "Other",
+		prefix:    "other",
+		qualifier: "other",
+		fields:    map[string]struct{}{"*": {}},
+		wildcard:  true,
+		start:     70,
+		end:       92,
 	}, {
 		name:     "Another",
 		prefix:   "",
@@ -916,16 +1025,18 @@ func TestExpandFields(t *testing.T) {
 	stmt := `SELECT {test.* INTO Person}, {x INTO Other}, {y INTO Another} FROM test WHERE test.name=:name;`
 
 	fields := []recordBinding{{
-		name:     "Person",
-		wildcard: true,
-		start:    7,
-		end:      27,
-		prefix:   "test",
+		name:      "Person",
+		wildcard:  true,
+		start:     7,
+		end:       27,
+		prefix:    "test",
+		qualifier: "test",
 	}, {
 		name: "Other",
 		fields: map[string]struct{}{
 			"x": {},
 		},
+		order: []string{"x"},
 		start: 29,
 		end:   43,
 	}, {
@@ -933,6 +1044,7 @@ func TestExpandFields(t *testing.T) {
 		fields: map[string]struct{}{
 			"y": {},
 		},
+		order: []string{"y"},
 		start: 45,
 		end:   61,
 	}}
@@ -962,9 +1074,1247 @@ func TestExpandFields(t *testing.T) {
 		},
 	}
 
-	res, err := expandRecords(stmt, fields, entities, intersections)
+	res, err := expandRecords(stmt, fields, entities, intersections, expansion{})
 	assert.Nil(t, err)
 
 	expected := "SELECT test.age, test.name AS _pfx_test_sfx_name, x, y FROM test WHERE test.name=:name;"
 	assert.Equal(t, res, expected)
 }
+
+func TestQueryWithRecordsInSubqueries(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER,
+	data TEXT
+);
+CREATE TABLE archive(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO test(name, age, data) values ("fred", 21, '{"a":1}'), ("frank", 42, '{}');
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := querier.Exec(tx, `INSERT INTO archive (age, name) SELECT {Person} FROM test WHERE data='{"a":1}' AND name=:name;`, Person{Name: "fred"})
+		return err
+	})
+	assert.Equal(t, processedStmt, `INSERT INTO archive (age, name) SELECT age, name FROM test WHERE data='{"a":1}' AND name=:name;`)
+
+	var persons []Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `
+WITH old AS (SELECT {Person} FROM test WHERE age > 30) -- {ignored}
+SELECT {Person} FROM test WHERE name IN (SELECT {archive.name INTO Person} FROM archive) OR name IN (SELECT name FROM old);`)
+	})
+	assert.Equal(t, persons, []Person{
+		{Name: "fred", Age: 21},
+		{Name: "frank", Age: 42},
+	})
+	assert.Equal(t, processedStmt, `
+WITH old AS (SELECT age, name FROM test WHERE age > 30) -- {ignored}
+SELECT age, name FROM test WHERE name IN (SELECT archive.name FROM archive) OR name IN (SELECT name FROM old);`)
+}
+
+func TestQueryWithCommonTableExpressions(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	id   INTEGER,
+	name TEXT,
+	age  INTEGER
+);
+CREATE TABLE pets(
+	id       INTEGER,
+	owner_id INTEGER,
+	name     TEXT
+);
+INSERT INTO people(id, name, age) values (1, "fred", 21), (2, "frank", 12);
+INSERT INTO pets(id, owner_id, name) values (1, 1, "rex"), (2, 2, "tom");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	type Pet struct {
+		ID      int    `db:"id"`
+		OwnerID int    `db:"owner_id"`
+		Name    string `db:"name"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var (
+		persons []Person
+		pets    []Pet
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons, &pets)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `
+WITH adults AS (SELECT {people.* INTO Person} FROM people WHERE age > 18)
+SELECT {adults.* INTO Person}, {pets.* INTO Pet} FROM adults JOIN pets ON pets.owner_id = adults.id;`)
+	})
+	assert.Equal(t, persons, []Person{{ID: 1, Name: "fred", Age: 21}})
+	assert.Equal(t, pets, []Pet{{ID: 1, OwnerID: 1, Name: "rex"}})
+
+	// The columns of the common table expression aren't aliased, as they're
+	// read by the statement rather than scanned.
+	assert.Equal(t, processedStmt, `
+WITH adults AS (SELECT people.age, people.id, people.name FROM people WHERE age > 18)
+SELECT adults.age, adults.id AS _pfx_adults_sfx_id, adults.name AS _pfx_adults_sfx_name, pets.id AS _pfx_pets_sfx_id, pets.name AS _pfx_pets_sfx_name, pets.owner_id FROM adults JOIN pets ON pets.owner_id = adults.id;`)
+}
+
+func TestQueryWithDerivedTables(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name     TEXT,
+	age      INTEGER,
+	location INTEGER
+);
+CREATE TABLE location(
+	id   INTEGER,
+	name TEXT
+);
+CREATE VIEW adults AS SELECT name, age, location FROM people WHERE age >= 18;
+INSERT INTO people(name, age, location) values ("fred", 21, 1), ("frank", 42, 2), ("jim", 12, 2);
+INSERT INTO location(id, name) values (1, "home"), (2, "work");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	type Location struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	query := func(stmt string) ([]Person, []Location) {
+		var (
+			persons   []Person
+			locations []Location
+		)
+		runTx(t, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForMany(&persons, &locations, Strict())
+			assert.Nil(t, err)
+
+			return getter.Query(tx, stmt)
+		})
+		return persons, locations
+	}
+
+	// The alias of a subquery is the table of its records, and the columns
+	// that overlap are aliased with it, in the same way as a table.
+	persons, locations := query(`SELECT {p.* INTO Person}, {l.* INTO Location} FROM (SELECT name, age, location FROM people WHERE age > 18) AS p JOIN (SELECT * FROM location) l ON p.location=l.id ORDER BY p.age;`)
+	assert.Equal(t, persons, []Person{{Name: "fred", Age: 21}, {Name: "frank", Age: 42}})
+	assert.Equal(t, locations, []Location{{ID: 1, Name: "home"}, {ID: 2, Name: "work"}})
+	assert.Equal(t, processedStmt, "SELECT p.age, p.name AS _pfx_p_sfx_name, l.id, l.name AS _pfx_l_sfx_name FROM (SELECT name, age, location FROM people WHERE age > 18) AS p JOIN (SELECT * FROM location) l ON p.location=l.id ORDER BY p.age;")
+
+	// The records of the subquery keep their column names, so that they're
+	// read by the alias.
+	persons, locations = query(`SELECT {p.* INTO Person}, {l.* INTO Location} FROM (SELECT {people.* INTO Person}, location FROM people WHERE age < 18) AS p JOIN location AS l ON p.location=l.id;`)
+	assert.Equal(t, persons, []Person{{Name: "jim", Age: 12}})
+	assert.Equal(t, locations, []Location{{ID: 2, Name: "work"}})
+	assert.Equal(t, processedStmt, "SELECT p.age, p.name AS _pfx_p_sfx_name, l.id, l.name AS _pfx_l_sfx_name FROM (SELECT people.age, people.name, location FROM people WHERE age < 18) AS p JOIN location AS l ON p.location=l.id;")
+
+	// The alias of a view.
+	persons, locations = query(`SELECT a.* AS &Person.*, l.* AS &Location.* FROM adults AS a JOIN location AS l ON a.location=l.id ORDER BY a.age DESC;`)
+	assert.Equal(t, persons, []Person{{Name: "frank", Age: 42}, {Name: "fred", Age: 21}})
+	assert.Equal(t, locations, []Location{{ID: 2, Name: "work"}, {ID: 1, Name: "home"}})
+	assert.Equal(t, processedStmt, "SELECT a.age, a.name AS _pfx_a_sfx_name, l.id, l.name AS _pfx_l_sfx_name FROM adults AS a JOIN location AS l ON a.location=l.id ORDER BY a.age DESC;")
+
+	// Positional aliases don't depend on the alias of the subquery.
+	querier.AliasColumns(true)
+	persons, locations = query(`SELECT {p.* INTO Person}, {l.* INTO Location} FROM (SELECT {people.* INTO Person}, location FROM people WHERE age < 18) AS p JOIN location AS l ON p.location=l.id;`)
+	assert.Equal(t, persons, []Person{{Name: "jim", Age: 12}})
+	assert.Equal(t, locations, []Location{{ID: 2, Name: "work"}})
+	assert.Equal(t, processedStmt, "SELECT p.age AS _pfx_0_sfx_age, p.name AS _pfx_0_sfx_name, l.id AS _pfx_1_sfx_id, l.name AS _pfx_1_sfx_name FROM (SELECT people.age, people.name, location FROM people WHERE age < 18) AS p JOIN location AS l ON p.location=l.id;")
+}
+
+func TestQueryWithQualifiedTables(t *testing.T) {
+	db := setupDB(t)
+	// Attached databases belong to the connection.
+	db.SetMaxOpenConns(1)
+
+	_, err := db.Exec(`
+ATTACH DATABASE ':memory:' AS other;
+CREATE TABLE main.people(
+	id   INTEGER,
+	name TEXT
+);
+CREATE TABLE other.people(
+	id   INTEGER,
+	name TEXT
+);
+INSERT INTO main.people(id, name) values (1, "fred");
+INSERT INTO other.people(id, name) values (1, "frank");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var local, remote Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&local, As("remote", &remote))
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {"main"."people".* INTO Person}, {other.people.* INTO Person AS remote} FROM "main"."people" JOIN other.people ON other.people.id = "main"."people".id;`)
+	})
+	assert.Equal(t, local, Person{ID: 1, Name: "fred"})
+	assert.Equal(t, remote, Person{ID: 1, Name: "frank"})
+
+	expected := `SELECT "main"."people".id AS _pfx_main_people_sfx_id, "main"."people".name AS _pfx_main_people_sfx_name, other.people.id AS _pfx_other_people_sfx_id, other.people.name AS _pfx_other_people_sfx_name FROM "main"."people" JOIN other.people ON other.people.id = "main"."people".id;`
+	assert.Equal(t, processedStmt, expected)
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&local)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, "SELECT {`people`.id, [people].name INTO Person} FROM other.people;")
+	})
+	assert.Equal(t, local, Person{ID: 1, Name: "frank"})
+	// The table is written as it's first found in the record.
+	assert.Equal(t, processedStmt, "SELECT `people`.id, `people`.name FROM other.people;")
+}
+
+type tablePerson struct {
+	_    struct{} `sqlair:"table=people"`
+	ID   int      `db:"id,pk"`
+	Name string   `db:"name"`
+}
+
+type tablePet struct {
+	ID      int `db:"id,pk"`
+	OwnerID int `db:"owner_id"`
+}
+
+func (tablePet) TableName() string {
+	return "pets"
+}
+
+func TestQueryWithTablePlaceholders(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	id   INTEGER,
+	name TEXT
+);
+CREATE TABLE pets(
+	id       INTEGER,
+	owner_id INTEGER
+);
+INSERT INTO people(id, name) values (1, "fred"), (2, "frank");
+INSERT INTO pets(id, owner_id) values (1, 2);
+	`)
+	assert.Nil(t, err)
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var (
+		persons []tablePerson
+		pets    []tablePet
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons, &pets)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {p.* INTO tablePerson}, {pets.* INTO tablePet} FROM {tablePerson.table} AS p JOIN {tablePet.table} ON pets.owner_id = p.id;`)
+	})
+	assert.Equal(t, persons, []tablePerson{{ID: 2, Name: "frank"}})
+	assert.Equal(t, pets, []tablePet{{ID: 1, OwnerID: 2}})
+	assert.Equal(t, processedStmt, `SELECT p.id AS _pfx_p_sfx_id, p.name, pets.id AS _pfx_pets_sfx_id, pets.owner_id FROM people AS p JOIN pets ON pets.owner_id = p.id;`)
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := querier.Exec(tx, `DELETE FROM {tablePerson.table} WHERE id=:id;`, tablePerson{ID: 1})
+		return err
+	})
+	assert.Equal(t, processedStmt, `DELETE FROM people WHERE id=:id;`)
+
+	// The table of the struct tag is used to get a row by its primary key.
+	var person tablePerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		return querier.Get(tx, &person, 2)
+	})
+	assert.Equal(t, person, tablePerson{ID: 2, Name: "frank"})
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+	err = getter.Query(tx, `SELECT {tablePerson} FROM {Location.table};`)
+	assert.True(t, errors.As(err, new(*UnknownEntityError)))
+}
+
+func TestHookVetoesStatement(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
+	`)
+	assert.Nil(t, err)
+
+	errDenied := errors.New("denied")
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		if strings.HasPrefix(stmt, "DELETE") {
+			return errDenied
+		}
+		return nil
+	})
+
+	var count int
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := querier.Exec(tx, "DELETE FROM test;")
+		assert.True(t, errors.Is(err, errDenied))
+
+		getter, err := querier.ForOne(&count)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, "SELECT COUNT(name) FROM test;")
+	})
+	assert.Equal(t, count, 2)
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&count)
+		assert.Nil(t, err)
+
+		err = getter.Query(tx, "DELETE FROM test RETURNING name;")
+		assert.True(t, errors.Is(err, errDenied))
+		return nil
+	})
+}
+
+func TestQueryWithTagKey(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"x" sql:"name"`
+		Age  int    `db:"y" sql:"age"`
+	}
+
+	var processedStmts []string
+
+	querier := NewQuerier()
+	querier.TagKey("sql")
+	querier.Hook(func(stmt string) error {
+		processedStmts = append(processedStmts, stmt)
+		return nil
+	})
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := querier.Exec(tx, "UPDATE test SET age=:age WHERE name=:name;", Person{Name: "fred", Age: 22}); err != nil {
+			return err
+		}
+
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person} FROM test WHERE test.name=:name;`, Person{Name: "fred"})
+	})
+
+	assert.Equal(t, person, Person{Name: "fred", Age: 22})
+
+	expected := []string{
+		"UPDATE test SET age=:age WHERE name=:name;",
+		"SELECT test.age, test.name FROM test WHERE test.name=:name;",
+	}
+	assert.Equal(t, processedStmts, expected)
+}
+
+func TestQueryWithTagKeyAfterCompiling(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name      TEXT,
+	full_name TEXT
+);
+INSERT INTO test(name, full_name) values ("fred", "fred bloggs");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name" sql:"full_name"`
+	}
+
+	querier := NewQuerier()
+
+	query := func() Person {
+		var person Person
+		runTx(t, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForOne(&person)
+			assert.Nil(t, err)
+
+			return getter.Query(tx, `SELECT {test.* INTO Person} FROM test;`)
+		})
+		return person
+	}
+
+	assert.Equal(t, query(), Person{Name: "fred"})
+
+	// The statement is compiled again for the tag key.
+	querier.TagKey("sql")
+	assert.Equal(t, query(), Person{Name: "fred bloggs"})
+}
+
+func TestQueryWithNameMapper(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	full_name TEXT,
+	age       INTEGER
+);
+INSERT INTO test(full_name, age) values ("fred", 21), ("frank", 42);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		FullName string
+		Age      int
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person} FROM test WHERE test.full_name=:full_name;`, Person{FullName: "fred"})
+	})
+
+	assert.Equal(t, person, Person{FullName: "fred", Age: 21})
+
+	expected := "SELECT test.age, test.full_name FROM test WHERE test.full_name=:full_name;"
+	assert.Equal(t, processedStmt, expected)
+}
+
+func TestQueryWithNameMapperAfterCompiling(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	fullname  TEXT,
+	full_name TEXT
+);
+INSERT INTO test(fullname, full_name) values ("fred", "fred bloggs");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		FullName string
+	}
+
+	querier := NewQuerier()
+
+	query := func() Person {
+		var person Person
+		runTx(t, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForOne(&person)
+			assert.Nil(t, err)
+
+			return getter.Query(tx, `SELECT {test.* INTO Person} FROM test;`)
+		})
+		return person
+	}
+
+	assert.Equal(t, query(), Person{FullName: "fred bloggs"})
+
+	// The statement is compiled again for the name mapper.
+	querier.NameMapper(strings.ToLower)
+	assert.Equal(t, query(), Person{FullName: "fred"})
+}
+
+func TestQueryWithExcludedField(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name     string `db:"name"`
+		Age      int    `db:"age"`
+		Computed string `db:"-"`
+	}
+
+	var processedStmts []string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmts = append(processedStmts, stmt)
+		return nil
+	})
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := querier.Exec(tx, "UPDATE test SET age=:age WHERE name=:name;", Person{Name: "fred", Age: 22, Computed: "ignored"})
+		assert.Nil(t, err)
+
+		_, err = querier.Exec(tx, "UPDATE test SET name=:computed WHERE name=:name;", Person{Name: "fred", Computed: "ignored"})
+		assert.Equal(t, errors.Cause(err).Error(), `field "computed" missing from type sqlair.Person`)
+
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person} FROM test WHERE test.name=:name;`, Person{Name: "fred"})
+	})
+
+	assert.Equal(t, person, Person{Name: "fred", Age: 22})
+
+	expected := []string{
+		"UPDATE test SET age=:age WHERE name=:name;",
+		"SELECT test.age, test.name FROM test WHERE test.name=:name;",
+	}
+	assert.Equal(t, processedStmts, expected)
+}
+
+func TestQueryWithEmbeddedStruct(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name       TEXT,
+	created_at TEXT,
+	updated_at TEXT
+);
+INSERT INTO test(name, created_at, updated_at) values ("fred", "monday", "tuesday");
+	`)
+	assert.Nil(t, err)
+
+	type Timestamps struct {
+		CreatedAt string `db:"created_at"`
+		UpdatedAt string `db:"updated_at"`
+	}
+	type Person struct {
+		Timestamps
+		Name string `db:"name"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person} FROM test WHERE test.created_at=:created_at;`, Person{
+			Timestamps: Timestamps{CreatedAt: "monday"},
+		})
+	})
+
+	assert.Equal(t, person, Person{
+		Timestamps: Timestamps{CreatedAt: "monday", UpdatedAt: "tuesday"},
+		Name:       "fred",
+	})
+
+	expected := "SELECT test.created_at, test.name, test.updated_at FROM test WHERE test.created_at=:created_at;"
+	assert.Equal(t, processedStmt, expected)
+}
+
+func TestParseRecordsWithExclusions(t *testing.T) {
+	stmt := `SELECT {Person EXCEPT password, secret}, {test.* INTO Other EXCLUDING name} FROM test;`
+	bindings, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Nil(t, err)
+	assert.Equal(t, bindings, []recordBinding{{
+		name:     "Person",
+		prefix:   "",
+		fields:   map[string]struct{}{},
+		exclude:  map[string]struct{}{"password": {}, "secret": {}},
+		wildcard: true,
+		start:    7,
+		end:      39,
+	}, {
+		name:      "Other",
+		prefix:    "test",
+		qualifier: "test",
+		fields:    map[string]struct{}{"*": {}},
+		exclude:   map[string]struct{}{"name": {}},
+		wildcard:  true,
+		start:     41,
+		end:       75,
+	}})
+}
+
+func TestParseRecordsErrorsExclusionsWithoutWildcard(t *testing.T) {
+	stmt := `SELECT {test.name INTO Person EXCEPT age} FROM test;`
+	_, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Equal(t, err.Error(), `unexpected excluded fields in non-wildcard record expression "test.name INTO Person EXCEPT age"`)
+}
+
+func TestParseRecordsErrorsMissingExclusions(t *testing.T) {
+	stmt := `SELECT {Person EXCEPT} FROM test;`
+	_, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Equal(t, err.Error(), `missing excluded fields in record expression "Person EXCEPT"`)
+}
+
+func TestQueryWithExcludedRecordFields(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name     TEXT,
+	age      INTEGER,
+	password TEXT
+);
+INSERT INTO test(name, age, password) values ("fred", 21, "secret");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name     string `db:"name"`
+		Age      int    `db:"age"`
+		Password string `db:"password"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person EXCEPT password} FROM test;`)
+	})
+
+	assert.Equal(t, person, Person{Name: "fred", Age: 21})
+
+	expected := "SELECT test.age, test.name FROM test;"
+	assert.Equal(t, processedStmt, expected)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {Person EXCEPT passwd} FROM test;`)
+	assert.Equal(t, errors.Cause(err).Error(), `excluded field "passwd" not found in entity "Person"`)
+}
+
+func TestParseRecordsWithExpressions(t *testing.T) {
+	stmt := `SELECT {COUNT(*) AS total, MAX(age) AS max_age, COALESCE(MIN(age), 0) AS min_age INTO Stats} FROM people;`
+	bindings, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Nil(t, err)
+	assert.Equal(t, bindings, []recordBinding{{
+		name:   "Stats",
+		fields: map[string]struct{}{},
+		expressions: map[string]string{
+			"total":   "COUNT(*)",
+			"max_age": "MAX(age)",
+			"min_age": "COALESCE(MIN(age), 0)",
+		},
+		order: []string{"total", "max_age", "min_age"},
+		start: 7,
+		end:   92,
+	}})
+}
+
+func TestParseRecordsErrorsExpressionWithoutAlias(t *testing.T) {
+	stmt := `SELECT {people.name, COUNT(*) INTO Stats} FROM people;`
+	_, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Equal(t, err.Error(), `missing alias for expression "COUNT(*)" in record expression "people.name, COUNT(*) INTO Stats"`)
+}
+
+func TestParseRecordsErrorsUnbalancedExpression(t *testing.T) {
+	stmt := `SELECT {COUNT(* AS total INTO Stats} FROM people;`
+	_, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Equal(t, err.Error(), `unbalanced parentheses in expression "COUNT(*" for record expression "COUNT(* AS total INTO Stats"`)
+}
+
+func TestQueryWithComputedColumns(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO people(name, age) values ("fred", 21), ("frank", 42), ("jane", 23);
+	`)
+	assert.Nil(t, err)
+
+	type Stats struct {
+		Count  int `db:"count"`
+		MaxAge int `db:"max_age"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var stats Stats
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&stats)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {COUNT(*) AS count, MAX(age) AS max_age INTO Stats} FROM people WHERE age>:age;`, map[string]interface{}{
+			"age": 22,
+		})
+	})
+
+	assert.Equal(t, stats, Stats{Count: 2, MaxAge: 42})
+
+	expected := "SELECT COUNT(*) AS count, MAX(age) AS max_age FROM people WHERE age>:age;"
+	assert.Equal(t, processedStmt, expected)
+}
+
+func TestQueryWithComputedColumnsAndFields(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO people(name, age) values ("fred", 21), ("frank", 42), ("fred", 23);
+	`)
+	assert.Nil(t, err)
+
+	type Summary struct {
+		Name  string `db:"name"`
+		Count int    `db:"count"`
+	}
+
+	querier := NewQuerier()
+
+	var summaries []Summary
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&summaries)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.*, COUNT(people.age) AS count INTO Summary} FROM people GROUP BY people.name ORDER BY people.name;`)
+	})
+
+	assert.Equal(t, summaries, []Summary{
+		{Name: "frank", Count: 1},
+		{Name: "fred", Count: 2},
+	})
+}
+
+func TestQueryWithAliasedColumns(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	id   INTEGER,
+	name TEXT
+);
+CREATE TABLE pets(
+	owner INTEGER,
+	name  TEXT
+);
+INSERT INTO people(id, name) values (1, "fred"), (2, "frank");
+INSERT INTO pets(owner, name) values (1, "rex"), (2, "tiddles");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	type Pet struct {
+		Name  string `db:"name"`
+		Count int    `db:"count"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.AliasColumns(true)
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var (
+		persons []Person
+		pets    []Pet
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons, &pets)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person}, {pets.name, COUNT(*) AS count INTO Pet} FROM people INNER JOIN pets ON people.id=pets.owner GROUP BY people.id ORDER BY people.id;`)
+	})
+
+	assert.Equal(t, persons, []Person{{ID: 1, Name: "fred"}, {ID: 2, Name: "frank"}})
+	assert.Equal(t, pets, []Pet{{Name: "rex", Count: 1}, {Name: "tiddles", Count: 1}})
+
+	expected := "SELECT people.id AS _pfx_0_sfx_id, people.name AS _pfx_0_sfx_name, COUNT(*) AS _pfx_1_sfx_count, pets.name AS _pfx_1_sfx_name FROM people INNER JOIN pets ON people.id=pets.owner GROUP BY people.id ORDER BY people.id;"
+	assert.Equal(t, processedStmt, expected)
+}
+
+func TestQueryWithAliasedColumnsRequiresAlias(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	id   INTEGER,
+	name TEXT
+);
+INSERT INTO people(id, name) values (1, "fred");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+	querier.AliasColumns(true)
+
+	var person Person
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {Person}, id AS name FROM people;`)
+	assert.Equal(t, errors.Cause(err).Error(), `expected column "name" to be aliased`)
+}
+
+func TestQueryWithStructAndScalars(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	id   INTEGER,
+	name TEXT
+);
+CREATE TABLE pets(
+	id        INTEGER,
+	person_id INTEGER,
+	age       INTEGER
+);
+INSERT INTO people(id, name) values (1, "fred"), (2, "frank");
+INSERT INTO pets(id, person_id, age) values (1, 1, 3), (2, 1, 5), (3, 2, 1);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+
+	var (
+		person Person
+		count  int
+		oldest int64
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person, &count, &oldest)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT COUNT(pets.id), {people.* INTO Person}, MAX(pets.age) FROM people INNER JOIN pets ON pets.person_id=people.id WHERE people.name=:name GROUP BY people.id;`, Person{Name: "fred"})
+	})
+	assert.Equal(t, person, Person{ID: 1, Name: "fred"})
+	assert.Equal(t, count, 2)
+	assert.Equal(t, oldest, int64(5))
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&person, &count)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {people.* INTO Person} FROM people WHERE people.name=:name;`, Person{Name: "fred"})
+	assert.Equal(t, err.Error(), `expected 1 columns for scalar values, got 0
+	statement: SELECT {people.* INTO Person} FROM people WHERE people.name=:name;
+	compiled: SELECT people.id, people.name FROM people WHERE people.name=:name;`)
+
+	err = getter.Query(tx, `SELECT {people.* INTO Person}, 1, 2 FROM people WHERE people.name=:name;`, Person{Name: "fred"})
+	assert.Equal(t, err.Error(), `expected 1 columns for scalar values, got column "2"
+	statement: SELECT {people.* INTO Person}, 1, 2 FROM people WHERE people.name=:name;
+	compiled: SELECT people.id, people.name, 1, 2 FROM people WHERE people.name=:name;`)
+
+	_, err = querier.ForOne(&person, &map[string]interface{}{})
+	assert.Equal(t, err.Error(), "expected one map for query, got 2")
+}
+
+func TestQueryWithAmpersandRecords(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name     TEXT,
+	age      INTEGER,
+	location INTEGER
+);
+CREATE TABLE location(
+	id   INTEGER,
+	city TEXT
+);
+INSERT INTO people(name, age, location) values ("fred", 21, 1), ("frank", 42, 2), ("jane", 23, 1);
+INSERT INTO location(id, city) values (1, "london"), (2, "paris");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	type Location struct {
+		ID   int    `db:"id"`
+		City string `db:"city"`
+	}
+	type Stats struct {
+		Total int `db:"total"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var (
+		person   Person
+		location Location
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person, &location)
+		assert.Nil(t, err)
+
+		// The ampersand form can be mixed with the brace form.
+		return getter.Query(tx, `SELECT p.* AS &Person.*, l.city AS &Location.city, {l.id INTO Location} FROM people AS p JOIN location AS l ON p.location=l.id WHERE p.name=:name;`, sql.Named("name", "frank"))
+	})
+	assert.Equal(t, person, Person{Name: "frank", Age: 42})
+	assert.Equal(t, location, Location{ID: 2, City: "paris"})
+	assert.Equal(t, processedStmt, "SELECT p.age, p.name, l.city, l.id FROM people AS p JOIN location AS l ON p.location=l.id WHERE p.name=:name;")
+
+	var stats Stats
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&stats)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT COUNT(*) AS &Stats.total FROM people WHERE location=:id;`, sql.Named("id", 1))
+	})
+	assert.Equal(t, stats, Stats{Total: 2})
+	assert.Equal(t, processedStmt, "SELECT COUNT(*) AS total FROM people WHERE location=:id;")
+
+	var persons []Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT &Person.name, &Person.age FROM people WHERE age>:age ORDER BY name;`, sql.Named("age", 22))
+	})
+	assert.Equal(t, persons, []Person{
+		{Name: "frank", Age: 42},
+		{Name: "jane", Age: 23},
+	})
+	assert.Equal(t, processedStmt, "SELECT name, age FROM people WHERE age>:age ORDER BY name;")
+}
+
+func TestQueryWithAmpersandRecordUnknownEntity(t *testing.T) {
+	db := setupDB(t)
+
+	type Person struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+
+	var person Person
+	getter, err := querier.ForOne(&person)
+	assert.Nil(t, err)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	err = getter.Query(tx, `SELECT &Location.* FROM location;`)
+	assert.True(t, errors.As(err, new(*UnknownEntityError)))
+}
+
+func TestParseRecordsWithMultipleTables(t *testing.T) {
+	stmt := `SELECT {test.name, COUNT(*) AS total, other.city, test.age INTO Person} FROM test JOIN other;`
+	bindings, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Nil(t, err)
+	assert.Equal(t, bindings, []recordBinding{{
+		name:        "Person",
+		prefix:      "test",
+		qualifier:   "test",
+		fields:      map[string]struct{}{"name": {}, "age": {}},
+		expressions: map[string]string{"total": "COUNT(*)"},
+		order:       []string{"name", "total", "age"},
+		start:       7,
+		end:         71,
+	}, {
+		name:      "Person",
+		prefix:    "other",
+		qualifier: "other",
+		fields:    map[string]struct{}{"city": {}},
+		order:     []string{"city"},
+		start:     7,
+		end:       71,
+	}})
+}
+
+func TestParseRecordsWithMultipleTablesErrorsDuplicateField(t *testing.T) {
+	stmt := `SELECT {test.name, other.name INTO Person} FROM test JOIN other;`
+	_, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Equal(t, err.Error(), `duplicate field "name" in field "other.name" for record expression "test.name, other.name INTO Person"`)
+}
+
+func TestQueryWithMultipleTablesInRecord(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name     TEXT,
+	age      INTEGER,
+	location INTEGER
+);
+CREATE TABLE location(
+	id   INTEGER,
+	name TEXT,
+	city TEXT
+);
+INSERT INTO people(name, age, location) values ("fred", 21, 1), ("frank", 42, 2);
+INSERT INTO location(id, name, city) values (1, "home", "london"), (2, "work", "paris");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+		City string `db:"city"`
+	}
+	type Location struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var (
+		person   Person
+		location Location
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person, &location)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {p.name, p.age, l.city INTO Person}, {l.* INTO Location} FROM people AS p JOIN location AS l ON p.location=l.id WHERE p.name=:name;`, sql.Named("name", "frank"))
+	})
+	assert.Equal(t, person, Person{Name: "frank", Age: 42, City: "paris"})
+	assert.Equal(t, location, Location{ID: 2, Name: "work"})
+	assert.Equal(t, processedStmt, "SELECT p.age, p.name AS _pfx_p_sfx_name, l.city, l.id, l.name AS _pfx_l_sfx_name FROM people AS p JOIN location AS l ON p.location=l.id WHERE p.name=:name;")
+
+	// Map destinations select the columns of every table.
+	row := M{}
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&row)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {p.name, l.city INTO M} FROM people AS p JOIN location AS l ON p.location=l.id WHERE p.name=:name;`, sql.Named("name", "fred"))
+	})
+	assert.Equal(t, row, M{"name": "fred", "city": "london"})
+	assert.Equal(t, processedStmt, "SELECT p.name, l.city FROM people AS p JOIN location AS l ON p.location=l.id WHERE p.name=:name;")
+}
+
+func TestParseRecordsWithShortAlias(t *testing.T) {
+	stmt := `SELECT {p.name, p.age INTO Person pp AS mother} FROM people AS p;`
+	records, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, records[0].short, "pp")
+	assert.Equal(t, records[0].prefix, "p")
+	assert.Equal(t, records[0].aliasName(), "pp")
+	assert.Equal(t, records[0].entityName(), "mother")
+}
+
+func TestParseRecordsWithShortAliasErrorsMultipleTables(t *testing.T) {
+	stmt := `SELECT {p.name, l.city INTO Person pp} FROM people AS p JOIN location AS l;`
+	_, err := parseRecords(stmt, indexOfRecordArgs(stmt))
+	assert.Equal(t, err.Error(), `unexpected short alias "pp" for record expression "p.name, l.city INTO Person pp" of more than one table`)
+}
+
+func TestQueryWithShortAlias(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name     TEXT,
+	age      INTEGER,
+	location INTEGER
+);
+CREATE TABLE location(
+	id   INTEGER,
+	name TEXT
+);
+CREATE VIEW adults AS SELECT name, age, location FROM people WHERE age >= 18;
+INSERT INTO people(name, age, location) values ("fred", 21, 1), ("frank", 42, 2), ("jim", 12, 2);
+INSERT INTO location(id, name) values (1, "home"), (2, "work");
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	type Location struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	var (
+		persons   []Person
+		locations []Location
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons, &locations)
+		assert.Nil(t, err)
+
+		// Neither the columns of the view nor the computed columns have a
+		// table, so they're only aliased by the short aliases of the records.
+		return getter.Query(tx, `SELECT {name, age INTO Person a}, {location AS id, (SELECT l.name FROM location AS l WHERE l.id=location) AS name INTO Location loc} FROM adults ORDER BY age;`)
+	})
+	assert.Equal(t, persons, []Person{{Name: "fred", Age: 21}, {Name: "frank", Age: 42}})
+	assert.Equal(t, locations, []Location{{ID: 1, Name: "home"}, {ID: 2, Name: "work"}})
+	assert.Equal(t, processedStmt, "SELECT age, name AS _pfx_a_sfx_name, (SELECT l.name FROM location AS l WHERE l.id=location) AS _pfx_loc_sfx_name, location AS id FROM adults ORDER BY age;")
+
+	// The short alias replaces the table in the aliases of its columns.
+	var (
+		person   Person
+		location Location
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person, &location)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {people.* INTO Person owner}, {location.* INTO Location} FROM people JOIN location ON people.location=location.id WHERE people.name=:name;`, sql.Named("name", "jim"))
+	})
+	assert.Equal(t, person, Person{Name: "jim", Age: 12})
+	assert.Equal(t, location, Location{ID: 2, Name: "work"})
+	assert.Equal(t, processedStmt, "SELECT people.age, people.name AS _pfx_owner_sfx_name, location.id, location.name AS _pfx_location_sfx_name FROM people JOIN location ON people.location=location.id WHERE people.name=:name;")
+}
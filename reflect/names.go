@@ -0,0 +1,71 @@
+package reflect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NameMapper maps the name of a struct field to a column name. It's used for
+// fields that don't have a tag.
+type NameMapper func(string) string
+
+// SnakeCase maps a field name to snake case, taking into account any
+// initialisms: "CreatedAt" becomes "created_at" and "UserID" becomes
+// "user_id".
+func SnakeCase(name string) string {
+	return strings.Join(splitWords(name), "_")
+}
+
+// CamelCase maps a field name to lower camel case: "CreatedAt" becomes
+// "createdAt" and "ID" becomes "id".
+func CamelCase(name string) string {
+	words := splitWords(name)
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+// LowerCase maps a field name to lower case: "CreatedAt" becomes "createdat".
+func LowerCase(name string) string {
+	return strings.ToLower(name)
+}
+
+// splitWords splits a field name into lower case words. A new word begins at
+// an upper case rune that follows a lower case rune or digit, or at the last
+// upper case rune of an initialism that is followed by a lower case rune.
+func splitWords(name string) []string {
+	runes := []rune(name)
+
+	var (
+		words []string
+		start int
+	)
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == '_' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if !unicode.IsUpper(runes[i]) || i == start {
+			continue
+		}
+
+		prev := runes[i-1]
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return words
+}
@@ -0,0 +1,40 @@
+package reflect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Name":       "name",
+		"CreatedAt":  "created_at",
+		"ID":         "id",
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"Field1":     "field1",
+		"Address2ID": "address2_id",
+		"Snake_Case": "snake_case",
+	}
+	for name, expected := range tests {
+		assert.Equal(t, SnakeCase(name), expected, name)
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"Name":       "name",
+		"CreatedAt":  "createdAt",
+		"ID":         "id",
+		"UserID":     "userId",
+		"HTTPServer": "httpServer",
+	}
+	for name, expected := range tests {
+		assert.Equal(t, CamelCase(name), expected, name)
+	}
+}
+
+func TestLowerCase(t *testing.T) {
+	assert.Equal(t, LowerCase("CreatedAt"), "createdat")
+}
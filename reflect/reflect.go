@@ -1,6 +1,8 @@
 package reflect
 
 import (
+	"database/sql"
+	"database/sql/driver"
 	"reflect"
 	"runtime"
 	"sort"
@@ -24,18 +26,73 @@ func (r ReflectValue) Kind() reflect.Kind {
 type ReflectTag struct {
 	Name      string
 	OmitEmpty bool
+	// Prefix is prepended to the names of the fields of an embedded struct.
+	Prefix string
+	// JSON is true if the field is stored as JSON in the database.
+	JSON bool
+	// Type is the column type of the field used by schema generation,
+	// overriding the type derived from the field type.
+	Type string
+	// PrimaryKey is true if the field is part of the primary key.
+	PrimaryKey bool
+	// NotNull and Null override the nullability derived from the field type.
+	NotNull bool
+	Null    bool
+	// Unique is true if the values of the field must be unique.
+	Unique bool
+	// Index is true if the field is indexed. Fields with the same IndexName
+	// share an index.
+	Index     bool
+	IndexName string
+	// Version is true if the field holds the version of the row, which is
+	// used for optimistic locking.
+	Version bool
+	// SoftDelete is true if the field holds the time the row was soft
+	// deleted, which is NULL for rows that haven't been deleted.
+	SoftDelete bool
+	// HasMany is the column of the child rows that holds the primary key of
+	// the parent, for a slice field of child structs.
+	HasMany string
+	// BelongsTo is the column of the parent that holds the primary key of
+	// the related row, for a struct field.
+	BelongsTo string
+	// Enum are the values of an enum field, which is stored as one of the
+	// values. String fields hold the value itself, integer fields hold the
+	// index of the value.
+	Enum []string
+	// UUID is how a [16]byte field is stored, which is either "text" for the
+	// canonical string form, or "blob" for the 16 bytes.
+	UUID string
+	// Scan is the name of the concrete type that an interface field is
+	// scanned into, which is registered with the querier.
+	Scan string
 }
 
 type ReflectField struct {
-	Name  string
-	Tag   ReflectTag
+	Name string
+	Tag  ReflectTag
+	// Index is the index sequence of the field, which includes the indexes
+	// of any embedded structs it was promoted from.
+	Index []int
 	Value reflect.Value
+	// Scanner is true if a pointer to the field implements sql.Scanner.
+	Scanner bool
+	// Valuer is true if the field, or a pointer to the field, implements
+	// driver.Valuer.
+	Valuer bool
 }
 
 type ReflectStruct struct {
-	Name   string
+	Name string
+	// Table is the table of the struct, declared by the struct tag of a
+	// blank field: _ struct{} `sqlair:"table=people"`. It's empty if the
+	// struct doesn't declare a table.
+	Table  string
 	Fields map[string]ReflectField
-	Value  reflect.Value
+	// Relations are the fields that hold related structs rather than
+	// columns, keyed by the name of the Go field.
+	Relations map[string]ReflectField
+	Value     reflect.Value
 }
 
 func (r ReflectStruct) Kind() reflect.Kind {
@@ -52,9 +109,132 @@ func (r ReflectStruct) FieldNames() []string {
 	return names
 }
 
+// DeclaredFieldNames returns the field names in the order that the fields are
+// declared in. The fields of an embedded struct are placed where the embedded
+// struct is declared.
+func (r ReflectStruct) DeclaredFieldNames() []string {
+	names := r.FieldNames()
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := r.Fields[names[i]].Index, r.Fields[names[j]].Index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return names
+}
+
+// Bind returns a copy of the struct with the fields bound to the value, which
+// must be of the same type as the struct. Nil embedded struct pointers are
+// allocated, so that the promoted fields can be set.
+func (r ReflectStruct) Bind(value reflect.Value) ReflectStruct {
+	fields := make(map[string]ReflectField, len(r.Fields))
+	for name, field := range r.Fields {
+		field.Value = FieldByIndex(value, field.Index)
+		fields[name] = field
+	}
+	var relations map[string]ReflectField
+	if len(r.Relations) > 0 {
+		relations = make(map[string]ReflectField, len(r.Relations))
+		for name, field := range r.Relations {
+			field.Value = FieldByIndex(value, field.Index)
+			relations[name] = field
+		}
+	}
+	return ReflectStruct{
+		Name:      r.Name,
+		Table:     r.Table,
+		Fields:    fields,
+		Relations: relations,
+		Value:     value,
+	}
+}
+
+// unbind returns a copy of the struct without any values, leaving only the
+// metadata of the type.
+func (r ReflectStruct) unbind() ReflectStruct {
+	fields := make(map[string]ReflectField, len(r.Fields))
+	for name, field := range r.Fields {
+		field.Value = reflect.Value{}
+		fields[name] = field
+	}
+	var relations map[string]ReflectField
+	if len(r.Relations) > 0 {
+		relations = make(map[string]ReflectField, len(r.Relations))
+		for name, field := range r.Relations {
+			field.Value = reflect.Value{}
+			relations[name] = field
+		}
+	}
+	return ReflectStruct{
+		Name:      r.Name,
+		Table:     r.Table,
+		Fields:    fields,
+		Relations: relations,
+	}
+}
+
+// FieldByIndex returns the nested field of the value for the index sequence,
+// allocating any nil embedded struct pointers along the way. An invalid value
+// is returned if a nil pointer can't be allocated.
+func FieldByIndex(value reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				if !value.CanSet() {
+					return reflect.Value{}
+				}
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value
+}
+
+// DefaultTagKey is the struct tag key used to locate the column name of a
+// field.
+const DefaultTagKey = "db"
+
+// StructTagKey is the struct tag key of the options of a struct, which are
+// declared on a blank field, as Go doesn't have struct level tags:
+//
+//  type Person struct {
+//  	_    struct{} `sqlair:"table=people"`
+//  	Name string   `db:"name"`
+//  }
+//
+const StructTagKey = "sqlair"
+
+// Config defines how the fields of a struct are mapped to column names.
+type Config struct {
+	// TagKey is the struct tag key used to locate the column name of a field.
+	TagKey string
+	// NameMapper maps the field name to a column name for fields without a
+	// tag.
+	NameMapper NameMapper
+}
+
+// DefaultConfig returns the Config used when none is supplied.
+func DefaultConfig() Config {
+	return Config{
+		TagKey:     DefaultTagKey,
+		NameMapper: SnakeCase,
+	}
+}
+
 // Reflect parses a reflect.Value returning a ReflectInfo of fields and tags
-// for the reflect value.
+// for the reflect value, using the DefaultConfig.
 func Reflect(value reflect.Value) (ReflectInfo, error) {
+	return DefaultConfig().Reflect(value)
+}
+
+// Reflect parses a reflect.Value returning a ReflectInfo of fields and tags
+// for the reflect value.
+func (c Config) Reflect(value reflect.Value) (ReflectInfo, error) {
 	// Dereference the pointer if it is one.
 	value = reflect.Indirect(value)
 	if value.Kind() != reflect.Struct {
@@ -69,46 +249,334 @@ func Reflect(value reflect.Value) (ReflectInfo, error) {
 		Value:  value,
 	}
 
-	typ := value.Type()
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		rawTag := field.Tag.Get("db")
-		tag, err := parseTag(rawTag)
-		if err != nil {
-			return nil, err
-		}
+	// Walk the struct breadth first, so that the fields of embedded structs
+	// are only promoted if a shallower field doesn't already have the same
+	// name. This follows the same rules as Go field promotion.
+	depths := make(map[string]int)
+	visited := map[reflect.Type]bool{
+		value.Type(): true,
+	}
+	current := []embeddedStruct{{
+		value: value,
+	}}
+	for depth := 0; len(current) > 0; depth++ {
+		var next []embeddedStruct
+		for _, parent := range current {
+			typ := parent.value.Type()
+			for i := 0; i < typ.NumField(); i++ {
+				field := typ.Field(i)
 
-		name := tag.Name
-		if name == "" {
-			name = strings.ToLower(field.Name)
-		}
+				// Blank fields of the struct hold the options of the
+				// struct itself.
+				if field.Name == "_" {
+					if rawTag, ok := field.Tag.Lookup(StructTagKey); ok && depth == 0 {
+						table, err := parseStructTag(rawTag)
+						if err != nil {
+							return nil, err
+						}
+						refStruct.Table = table
+					}
+					continue
+				}
+
+				var tag ReflectTag
+				if rawTag, ok := field.Tag.Lookup(c.tagKey()); ok {
+					// Fields tagged with "-" are explicitly excluded from
+					// mapping.
+					if rawTag == "-" {
+						continue
+					}
+
+					var err error
+					if tag, err = ParseTag(rawTag); err != nil {
+						return nil, err
+					}
+				}
+
+				index := append(append([]int(nil), parent.index...), i)
+
+				// Anonymous structs without a name are flattened into the
+				// parent struct.
+				if embedded, ok := embeddedValue(field, parent.value.Field(i)); ok && tag.Name == "" && !tag.JSON {
+					if visited[embedded.Type()] {
+						continue
+					}
+					visited[embedded.Type()] = true
+
+					next = append(next, embeddedStruct{
+						value:  embedded,
+						index:  index,
+						prefix: parent.prefix + tag.Prefix,
+					})
+					continue
+				}
+
+				// Unexported fields can't be read or set, so skip over them.
+				if field.PkgPath != "" {
+					continue
+				}
+				if tag.Prefix != "" {
+					return nil, errors.Errorf("unexpected prefix on non-embedded field %q", field.Name)
+				}
+
+				// Relations hold the rows of another table, so they aren't
+				// mapped to a column.
+				if tag.HasMany != "" || tag.BelongsTo != "" {
+					if tag.HasMany != "" && !isStructSlice(field.Type) {
+						return nil, errors.Errorf("expected slice of structs for hasmany field %q, got %s", field.Name, field.Type)
+					}
+					if tag.BelongsTo != "" && !isStruct(field.Type) {
+						return nil, errors.Errorf("expected struct for belongsto field %q, got %s", field.Name, field.Type)
+					}
+					if refStruct.Relations == nil {
+						refStruct.Relations = make(map[string]ReflectField)
+					}
+					refStruct.Relations[field.Name] = ReflectField{
+						Name:  field.Name,
+						Tag:   tag,
+						Index: index,
+						Value: parent.value.Field(i),
+					}
+					continue
+				}
+
+				if len(tag.Enum) > 0 && !isEnumType(field.Type) {
+					return nil, errors.Errorf("expected string or integer for enum field %q, got %s", field.Name, field.Type)
+				}
+				if tag.UUID != "" && !IsUUID(field.Type) {
+					return nil, errors.Errorf("expected [16]byte for uuid field %q, got %s", field.Name, field.Type)
+				}
+				if tag.Scan != "" && field.Type.Kind() != reflect.Interface {
+					return nil, errors.Errorf("expected interface for scan field %q, got %s", field.Name, field.Type)
+				}
+
+				name := tag.Name
+				if name == "" {
+					name = c.nameMapper()(field.Name)
+				}
+				name = parent.prefix + name
 
-		refStruct.Fields[name] = ReflectField{
-			Name:  field.Name,
-			Tag:   tag,
-			Value: value.Field(i),
+				if existing, ok := depths[name]; ok {
+					if existing == depth {
+						return nil, errors.Errorf("ambiguous field %q found in embedded structs", name)
+					}
+					// A shallower field has already claimed the name.
+					continue
+				}
+				depths[name] = depth
+
+				refStruct.Fields[name] = ReflectField{
+					Name:    field.Name,
+					Tag:     tag,
+					Index:   index,
+					Value:   parent.value.Field(i),
+					Scanner: IsScanner(field.Type),
+					Valuer:  IsValuer(field.Type),
+				}
+			}
 		}
+		current = next
 	}
 
 	return refStruct, nil
 }
 
-func parseTag(tag string) (ReflectTag, error) {
+// isStructSlice returns true if the type is a slice of structs, or of
+// pointers to structs.
+func isStructSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct
+}
+
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// IsUUID returns true if the type is a [16]byte array, or a pointer to one,
+// which includes the UUID types of most UUID packages.
+func IsUUID(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// isEnumType returns true if the type can hold an enum, which is a string or
+// an integer, or a pointer to one.
+func isEnumType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+type embeddedStruct struct {
+	value  reflect.Value
+	index  []int
+	prefix string
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// IsScanner returns true if a pointer to the type implements sql.Scanner.
+func IsScanner(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(scannerType)
+}
+
+// IsValuer returns true if the type, or a pointer to the type, implements
+// driver.Valuer.
+func IsValuer(t reflect.Type) bool {
+	return t.Implements(valuerType) || reflect.PtrTo(t).Implements(valuerType)
+}
+
+// embeddedValue returns the struct value of an anonymous struct field. Nil
+// embedded pointers are allocated if they can be set, otherwise they're
+// ignored. Embedded types that implement sql.Scanner or driver.Valuer are
+// treated as a single column and aren't flattened.
+func embeddedValue(field reflect.StructField, value reflect.Value) (reflect.Value, bool) {
+	if !field.Anonymous || IsScanner(field.Type) || IsValuer(field.Type) {
+		return reflect.Value{}, false
+	}
+
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		// Unexported embedded pointers can't be allocated or read.
+		if field.PkgPath != "" || typ.Elem().Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		if value.IsNil() {
+			if !value.CanSet() {
+				return reflect.Value{}, false
+			}
+			value.Set(reflect.New(typ.Elem()))
+		}
+		return value.Elem(), true
+	}
+	if typ.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	return value, true
+}
+
+func (c Config) tagKey() string {
+	if c.TagKey == "" {
+		return DefaultTagKey
+	}
+	return c.TagKey
+}
+
+func (c Config) nameMapper() NameMapper {
+	if c.NameMapper == nil {
+		return SnakeCase
+	}
+	return c.NameMapper
+}
+
+// parseStructTag parses the value of the struct tag of a blank field,
+// returning the table of the struct.
+func parseStructTag(tag string) (string, error) {
+	var table string
+	for _, option := range strings.Split(tag, ",") {
+		switch {
+		case strings.HasPrefix(option, "table="):
+			table = strings.TrimPrefix(option, "table=")
+			if table == "" {
+				return "", errors.Errorf("unexpected empty table in struct tag %q", tag)
+			}
+		default:
+			return "", errors.Errorf("unexpected struct tag option %q", option)
+		}
+	}
+	return table, nil
+}
+
+// ParseTag parses the value of a struct tag into the column name and options.
+func ParseTag(tag string) (ReflectTag, error) {
 	if tag == "" {
 		return ReflectTag{}, errors.Errorf("unexpected empty tag")
 	}
 
-	var refTag ReflectTag
 	options := strings.Split(tag, ",")
-	switch len(options) {
-	case 2:
-		if strings.ToLower(options[1]) != "omitempty" {
-			return ReflectTag{}, errors.Errorf("unexpected tag value %q", options[1])
+	refTag := ReflectTag{
+		Name: options[0],
+	}
+	for _, option := range options[1:] {
+		switch {
+		case strings.ToLower(option) == "omitempty":
+			refTag.OmitEmpty = true
+		case strings.ToLower(option) == "json":
+			refTag.JSON = true
+		case strings.HasPrefix(option, "prefix="):
+			refTag.Prefix = strings.TrimPrefix(option, "prefix=")
+		case strings.HasPrefix(option, "type="):
+			refTag.Type = strings.TrimPrefix(option, "type=")
+		case strings.ToLower(option) == "pk":
+			refTag.PrimaryKey = true
+		case strings.ToLower(option) == "notnull":
+			refTag.NotNull = true
+		case strings.ToLower(option) == "null":
+			refTag.Null = true
+		case strings.ToLower(option) == "unique":
+			refTag.Unique = true
+		case strings.ToLower(option) == "index":
+			refTag.Index = true
+		case strings.ToLower(option) == "version":
+			refTag.Version = true
+		case strings.ToLower(option) == "softdelete":
+			refTag.SoftDelete = true
+		case strings.HasPrefix(option, "hasmany="):
+			refTag.HasMany = strings.TrimPrefix(option, "hasmany=")
+		case strings.HasPrefix(option, "belongsto="):
+			refTag.BelongsTo = strings.TrimPrefix(option, "belongsto=")
+		case strings.HasPrefix(option, "enum="):
+			refTag.Enum = strings.Split(strings.TrimPrefix(option, "enum="), "|")
+			for _, value := range refTag.Enum {
+				if value == "" {
+					return ReflectTag{}, errors.Errorf("unexpected empty enum value in %q", option)
+				}
+			}
+		case strings.ToLower(option) == "uuid":
+			refTag.UUID = "text"
+		case strings.HasPrefix(option, "uuid="):
+			refTag.UUID = strings.ToLower(strings.TrimPrefix(option, "uuid="))
+			if refTag.UUID != "text" && refTag.UUID != "blob" {
+				return ReflectTag{}, errors.Errorf("unexpected uuid tag value %q, expected text or blob", refTag.UUID)
+			}
+		case strings.HasPrefix(option, "index="):
+			refTag.Index = true
+			refTag.IndexName = strings.TrimPrefix(option, "index=")
+		case strings.HasPrefix(option, "scan="):
+			refTag.Scan = strings.TrimPrefix(option, "scan=")
+			if refTag.Scan == "" {
+				return ReflectTag{}, errors.Errorf("unexpected empty scan type in %q", option)
+			}
+		default:
+			return ReflectTag{}, errors.Errorf("unexpected tag value %q", option)
 		}
-		refTag.OmitEmpty = true
-		fallthrough
-	case 1:
-		refTag.Name = options[0]
+	}
+	if refTag.Null && refTag.NotNull {
+		return ReflectTag{}, errors.Errorf("unexpected null and notnull tag values")
+	}
+	if refTag.HasMany != "" && refTag.BelongsTo != "" {
+		return ReflectTag{}, errors.Errorf("unexpected hasmany and belongsto tag values")
 	}
 	return refTag, nil
 }
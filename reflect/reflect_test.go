@@ -1,6 +1,8 @@
 package reflect
 
 import (
+	"database/sql"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -21,3 +23,380 @@ func TestReflect(t *testing.T) {
 	assert.Len(t, structMap.Fields, 2)
 	assert.Equal(t, structMap.FieldNames(), []string{"id", "name"})
 }
+
+func TestReflectWithTagKey(t *testing.T) {
+	s := struct {
+		ID   int64  `db:"x" sql:"id"`
+		Name string `db:"y" sql:"name,omitempty"`
+	}{}
+	info, err := Config{TagKey: "sql"}.Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.FieldNames(), []string{"id", "name"})
+	assert.True(t, structMap.Fields["name"].Tag.OmitEmpty)
+}
+
+func TestReflectWithoutTags(t *testing.T) {
+	s := struct {
+		ID        int64
+		CreatedAt string
+		Name      string `db:"full_name"`
+		internal  string
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.FieldNames(), []string{"created_at", "full_name", "id"})
+}
+
+func TestReflectWithNameMapper(t *testing.T) {
+	s := struct {
+		ID        int64
+		CreatedAt string
+	}{}
+	info, err := Config{NameMapper: CamelCase}.Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.FieldNames(), []string{"createdAt", "id"})
+}
+
+func TestReflectWithEmptyTag(t *testing.T) {
+	s := struct {
+		ID int64 `db:""`
+	}{}
+	_, err := Reflect(reflect.ValueOf(&s))
+	assert.Equal(t, err.Error(), "unexpected empty tag")
+}
+
+func TestReflectWithExcludedField(t *testing.T) {
+	s := struct {
+		ID       int64  `db:"id"`
+		Computed string `db:"-"`
+		Dash     string `db:"-,omitempty"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.FieldNames(), []string{"-", "id"})
+}
+
+type Timestamps struct {
+	CreatedAt string `db:"created_at"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+type Audit struct {
+	By string `db:"by"`
+}
+
+type Node struct {
+	*Node
+	Name string `db:"name"`
+}
+
+func TestReflectWithEmbeddedStruct(t *testing.T) {
+	s := struct {
+		Timestamps
+		*Audit `db:",prefix=audit_"`
+		ID     int64 `db:"id"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.FieldNames(), []string{"audit_by", "created_at", "id", "updated_at"})
+	assert.Equal(t, structMap.Fields["created_at"].Index, []int{0, 0})
+	assert.Equal(t, structMap.Fields["audit_by"].Index, []int{1, 0})
+
+	// Embedded pointers are allocated, so that they can be scanned into.
+	assert.NotNil(t, s.Audit)
+	structMap.Fields["audit_by"].Value.SetString("fred")
+	assert.Equal(t, s.Audit.By, "fred")
+}
+
+func TestReflectWithEmbeddedStructShadowed(t *testing.T) {
+	s := struct {
+		Timestamps
+		CreatedAt int64 `db:"created_at"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.FieldNames(), []string{"created_at", "updated_at"})
+	assert.Equal(t, structMap.Fields["created_at"].Index, []int{1})
+}
+
+func TestReflectWithEmbeddedStructAmbiguous(t *testing.T) {
+	type Other struct {
+		CreatedAt string `db:"created_at"`
+	}
+	s := struct {
+		Timestamps
+		Other
+	}{}
+	_, err := Reflect(reflect.ValueOf(&s))
+	assert.Equal(t, err.Error(), `ambiguous field "created_at" found in embedded structs`)
+}
+
+func TestReflectWithEmbeddedStructCycle(t *testing.T) {
+	s := Node{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.FieldNames(), []string{"name"})
+}
+
+func TestReflectWithNamedEmbeddedStruct(t *testing.T) {
+	s := struct {
+		Timestamps `db:"timestamps"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.FieldNames(), []string{"timestamps"})
+}
+
+func TestReflectWithScannerAndValuer(t *testing.T) {
+	s := struct {
+		sql.NullString
+		Name  string         `db:"name"`
+		Alias sql.NullString `db:"alias"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.FieldNames(), []string{"alias", "name", "null_string"})
+	assert.True(t, structMap.Fields["alias"].Scanner)
+	assert.True(t, structMap.Fields["alias"].Valuer)
+	assert.False(t, structMap.Fields["name"].Scanner)
+	assert.False(t, structMap.Fields["name"].Valuer)
+}
+
+func TestReflectWithJSONTag(t *testing.T) {
+	s := struct {
+		ID      int64             `db:"id"`
+		Payload map[string]string `db:"payload,json,omitempty"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.Fields["payload"].Tag, ReflectTag{
+		Name:      "payload",
+		OmitEmpty: true,
+		JSON:      true,
+	})
+	assert.False(t, structMap.Fields["id"].Tag.JSON)
+}
+
+func TestReflectWithSchemaTags(t *testing.T) {
+	s := struct {
+		ID      int64   `db:"id,pk"`
+		Email   string  `db:"email,unique,notnull"`
+		City    *string `db:"city,index=idx_location"`
+		Balance float64 `db:"balance,type=NUMERIC,null,index"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok, true)
+
+	assert.Equal(t, structMap.Fields["id"].Tag, ReflectTag{
+		Name:       "id",
+		PrimaryKey: true,
+	})
+	assert.Equal(t, structMap.Fields["email"].Tag, ReflectTag{
+		Name:    "email",
+		Unique:  true,
+		NotNull: true,
+	})
+	assert.Equal(t, structMap.Fields["city"].Tag, ReflectTag{
+		Name:      "city",
+		Index:     true,
+		IndexName: "idx_location",
+	})
+	assert.Equal(t, structMap.Fields["balance"].Tag, ReflectTag{
+		Name:  "balance",
+		Type:  "NUMERIC",
+		Null:  true,
+		Index: true,
+	})
+}
+
+func TestReflectWithConflictingNullTags(t *testing.T) {
+	s := struct {
+		Name string `db:"name,null,notnull"`
+	}{}
+	_, err := Reflect(reflect.ValueOf(&s))
+	assert.Equal(t, err.Error(), "unexpected null and notnull tag values")
+}
+
+func TestReflectWithHasManyTag(t *testing.T) {
+	type Pet struct {
+		Name string `db:"name"`
+	}
+	s := struct {
+		ID   int64 `db:"id,pk"`
+		Pets []Pet `db:"pets,hasmany=person_id"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	refStruct := info.(ReflectStruct)
+	assert.Equal(t, refStruct.FieldNames(), []string{"id"})
+	assert.Equal(t, refStruct.Relations["Pets"].Tag.HasMany, "person_id")
+}
+
+func TestReflectWithHasManyTagNotSlice(t *testing.T) {
+	s := struct {
+		Pet string `db:"pet,hasmany=person_id"`
+	}{}
+	_, err := Reflect(reflect.ValueOf(&s))
+	assert.Equal(t, err.Error(), `expected slice of structs for hasmany field "Pet", got string`)
+}
+
+func TestReflectWithBelongsToTag(t *testing.T) {
+	type Address struct {
+		City string `db:"city"`
+	}
+	s := struct {
+		AddressID int64    `db:"address_id"`
+		Address   *Address `db:"address,belongsto=address_id"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	refStruct := info.(ReflectStruct)
+	assert.Equal(t, refStruct.FieldNames(), []string{"address_id"})
+	assert.Equal(t, refStruct.Relations["Address"].Tag.BelongsTo, "address_id")
+}
+
+func TestReflectWithUUIDTag(t *testing.T) {
+	s := struct {
+		ID     [16]byte  `db:"id,uuid=blob"`
+		Parent *[16]byte `db:"parent,uuid"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	refStruct := info.(ReflectStruct)
+	assert.Equal(t, refStruct.Fields["id"].Tag.UUID, "blob")
+	assert.Equal(t, refStruct.Fields["parent"].Tag.UUID, "text")
+
+	_, err = Reflect(reflect.ValueOf(&struct {
+		ID [16]byte `db:"id,uuid=binary"`
+	}{}))
+	assert.Equal(t, err.Error(), `unexpected uuid tag value "binary", expected text or blob`)
+
+	_, err = Reflect(reflect.ValueOf(&struct {
+		ID string `db:"id,uuid"`
+	}{}))
+	assert.Equal(t, err.Error(), `expected [16]byte for uuid field "ID", got string`)
+}
+
+func TestReflectWithEnumTag(t *testing.T) {
+	s := struct {
+		Status string `db:"status,enum=active|archived|deleted"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+	assert.Equal(t, info.(ReflectStruct).Fields["status"].Tag.Enum, []string{"active", "archived", "deleted"})
+
+	_, err = Reflect(reflect.ValueOf(&struct {
+		Status string `db:"status,enum=active||deleted"`
+	}{}))
+	assert.Equal(t, err.Error(), `unexpected empty enum value in "enum=active||deleted"`)
+
+	_, err = Reflect(reflect.ValueOf(&struct {
+		Status float64 `db:"status,enum=active|deleted"`
+	}{}))
+	assert.Equal(t, err.Error(), `expected string or integer for enum field "Status", got float64`)
+}
+
+func TestReflectWithScanTag(t *testing.T) {
+	s := struct {
+		Payload fmt.Stringer `db:"payload,scan=point"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+	assert.Equal(t, info.(ReflectStruct).Fields["payload"].Tag.Scan, "point")
+
+	_, err = Reflect(reflect.ValueOf(&struct {
+		Payload fmt.Stringer `db:"payload,scan="`
+	}{}))
+	assert.Equal(t, err.Error(), `unexpected empty scan type in "scan="`)
+
+	_, err = Reflect(reflect.ValueOf(&struct {
+		Payload string `db:"payload,scan=point"`
+	}{}))
+	assert.Equal(t, err.Error(), `expected interface for scan field "Payload", got string`)
+}
+
+func TestReflectWithStructTag(t *testing.T) {
+	s := struct {
+		_    struct{} `sqlair:"table=people"`
+		Name string   `db:"name"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+	assert.Equal(t, info.(ReflectStruct).Table, "people")
+	assert.Equal(t, info.(ReflectStruct).FieldNames(), []string{"name"})
+
+	_, err = Reflect(reflect.ValueOf(&struct {
+		_ struct{} `sqlair:"table="`
+	}{}))
+	assert.Equal(t, err.Error(), `unexpected empty table in struct tag "table="`)
+
+	_, err = Reflect(reflect.ValueOf(&struct {
+		_ struct{} `sqlair:"schema=main"`
+	}{}))
+	assert.Equal(t, err.Error(), `unexpected struct tag option "schema=main"`)
+}
+
+func TestReflectDeclaredFieldNames(t *testing.T) {
+	type Base struct {
+		ID      int64 `db:"id"`
+		Created int64 `db:"created"`
+	}
+	s := struct {
+		Name string `db:"name"`
+		Base
+		Age int `db:"age"`
+	}{}
+	info, err := Reflect(reflect.ValueOf(&s))
+	assert.Nil(t, err)
+
+	structMap, ok := info.(ReflectStruct)
+	assert.True(t, ok)
+	assert.Equal(t, structMap.DeclaredFieldNames(), []string{"name", "id", "created", "age"})
+	assert.Equal(t, structMap.FieldNames(), []string{"age", "created", "id", "name"})
+}
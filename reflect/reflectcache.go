@@ -5,39 +5,67 @@ import (
 	"sync"
 )
 
-// ReflectCache caches the types for faster look up times.
+// ReflectCache caches the types for faster look up times. Only the metadata
+// of a type is cached, the fields are bound to the value being reflected on
+// every look up, so the cache can be shared between goroutines.
 type ReflectCache struct {
-	mutex sync.RWMutex
-	cache map[reflect.Type]ReflectInfo
+	mutex  sync.RWMutex
+	cache  map[reflect.Type]ReflectStruct
+	config Config
 }
 
 // NewReflectCache creates a new ReflectCache that caches the types for faster
 // look up times.
 func NewReflectCache() *ReflectCache {
+	return NewReflectCacheWithConfig(DefaultConfig())
+}
+
+// NewReflectCacheWithConfig creates a new ReflectCache that uses the config to
+// map the fields of a type.
+func NewReflectCacheWithConfig(config Config) *ReflectCache {
 	return &ReflectCache{
-		cache: make(map[reflect.Type]ReflectInfo),
+		cache:  make(map[reflect.Type]ReflectStruct),
+		config: config,
 	}
 }
 
+// Config returns the config used to map the fields of a type.
+func (r *ReflectCache) Config() Config {
+	return r.config
+}
+
 // Reflect will return a Reflectstruct of a given type.
 func (r *ReflectCache) Reflect(value interface{}) (ReflectInfo, error) {
 	raw := reflect.ValueOf(value)
 	mustBe(raw, reflect.Ptr)
 
 	v := reflect.Indirect(raw)
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
+	if v.Kind() != reflect.Struct {
+		return ReflectValue{
+			Value: v,
+		}, nil
+	}
 
-	if rs, ok := r.cache[v.Type()]; ok {
-		return rs, nil
+	r.mutex.RLock()
+	rs, ok := r.cache[v.Type()]
+	r.mutex.RUnlock()
+	if ok {
+		return rs.Bind(v), nil
 	}
 
-	ri, err := Reflect(v)
+	// Reflect over a new value of the type, so that the cache never holds on
+	// to the value being reflected.
+	ri, err := r.config.Reflect(reflect.New(v.Type()))
 	if err != nil {
 		return ReflectStruct{}, err
 	}
-	r.cache[v.Type()] = ri
-	return ri, nil
+	rs = ri.(ReflectStruct).unbind()
+
+	r.mutex.Lock()
+	r.cache[v.Type()] = rs
+	r.mutex.Unlock()
+
+	return rs.Bind(v), nil
 }
 
 type kinder interface {
@@ -0,0 +1,60 @@
+package reflect
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cachedPerson struct {
+	*CachedTimestamps
+	Name string `db:"name"`
+}
+
+type CachedTimestamps struct {
+	CreatedAt string `db:"created_at"`
+}
+
+func TestReflectCacheBindsEveryValue(t *testing.T) {
+	cache := NewReflectCache()
+
+	var a, b cachedPerson
+	infoA, err := cache.Reflect(&a)
+	assert.Nil(t, err)
+	infoB, err := cache.Reflect(&b)
+	assert.Nil(t, err)
+
+	infoA.(ReflectStruct).Fields["name"].Value.SetString("fred")
+	infoB.(ReflectStruct).Fields["name"].Value.SetString("frank")
+	infoB.(ReflectStruct).Fields["created_at"].Value.SetString("monday")
+
+	assert.Equal(t, a.Name, "fred")
+	assert.Equal(t, a.CachedTimestamps, &CachedTimestamps{})
+	assert.Equal(t, b.Name, "frank")
+	assert.Equal(t, b.CachedTimestamps, &CachedTimestamps{CreatedAt: "monday"})
+}
+
+func TestReflectCacheStoresTypes(t *testing.T) {
+	cache := NewReflectCache()
+
+	var person cachedPerson
+	_, err := cache.Reflect(&person)
+	assert.Nil(t, err)
+
+	cached := cache.cache[reflect.TypeOf(person)]
+	assert.Equal(t, cached.Name, "cachedPerson")
+	assert.False(t, cached.Value.IsValid())
+	assert.Equal(t, cached.Fields["created_at"].Index, []int{0, 0})
+	assert.False(t, cached.Fields["created_at"].Value.IsValid())
+}
+
+func TestReflectCacheWithNonStruct(t *testing.T) {
+	cache := NewReflectCache()
+
+	var values []int
+	info, err := cache.Reflect(&values)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Kind(), reflect.Slice)
+	assert.Equal(t, len(cache.cache), 0)
+}
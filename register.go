@@ -0,0 +1,141 @@
+package sqlair
+
+import (
+	"reflect"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// Register reflects over the types of the values, populating the reflect cache
+// ahead of any query. Registering the types at startup moves the cost of
+// reflection out of the first query, and reports misconfigured tags straight
+// away:
+//
+//  if err := querier.Register(Person{}, &Location{}); err != nil {
+//  	log.Fatal(err)
+//  }
+//
+// The values can be structs, or pointers or slices of structs. Changing the tag
+// key or name mapper of the querier resets the reflect cache, so types should
+// be registered after the querier is configured. Types with methods generated
+// by sqlair-gen are checked against the reflected columns.
+func (q *Querier) Register(values ...interface{}) error {
+	for _, value := range values {
+		value, _ := unwrapAlias(value)
+		typ := structType(value)
+		if typ == nil {
+			return errors.Errorf("expected struct type to register, got nil")
+		}
+		if typ.Kind() != reflect.Struct {
+			return errors.Errorf("expected struct type to register, got %q", typ.Kind())
+		}
+
+		info, err := q.reflect.Reflect(reflect.New(typ).Interface())
+		if err != nil {
+			return errors.Wrapf(err, "register %q", typ.Name())
+		}
+		if refStruct, ok := info.(sreflect.ReflectStruct); ok {
+			if err := checkGenerated(typ, refStruct); err != nil {
+				return errors.Wrapf(err, "register %q", typ.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// registerStructs registers the values, returning the reflected struct of
+// each value. New values are reflected over, so that the registration never
+// holds on to the values passed in.
+func (q *Querier) registerStructs(values []interface{}) ([]sreflect.ReflectStruct, error) {
+	if err := q.Register(values...); err != nil {
+		return nil, err
+	}
+
+	structs := make([]sreflect.ReflectStruct, len(values))
+	for i, value := range values {
+		value, alias := unwrapAlias(value)
+		info, err := q.reflect.Reflect(reflect.New(structType(value)).Interface())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		refStruct, ok := info.(sreflect.ReflectStruct)
+		if !ok {
+			return nil, errors.Errorf("expected struct, got %q", info.Kind())
+		}
+		if alias != "" {
+			refStruct.Name = alias
+		}
+		structs[i] = refStruct
+	}
+	return structs, nil
+}
+
+// structType returns the struct type of a value, which can be a pointer or a
+// slice of the struct.
+func structType(value interface{}) reflect.Type {
+	typ := reflect.TypeOf(value)
+	for typ != nil && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice) {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// RegisterStatement compiles the statement for the values, storing the
+// compiled statement in the statement cache. Any query of ForOne with the same
+// statement then skips parsing and expanding the record expressions. The
+// values are the same as those given to ForOne:
+//
+//  err := querier.RegisterStatement(`SELECT {Person} FROM people WHERE name=:name;`, Person{})
+//
+func (q *Querier) RegisterStatement(stmt string, values ...interface{}) error {
+	compiledStmt, fields, err := q.compile(stmt, values)
+	if err != nil {
+		return err
+	}
+
+	// Only cache the statement if it differs from the original, in the same
+	// way as a query does.
+	if stmt != compiledStmt {
+		q.stmtCache.Set(stmt, cachedStmt{
+			stmt:   compiledStmt,
+			fields: fields,
+		})
+	}
+	return nil
+}
+
+// Compile returns the statement with the record expressions expanded for the
+// values, exactly as a query of ForOne would execute it, without executing
+// it. Named arguments are left in the statement. It's useful to review the
+// statements of an application, or to test that they don't change:
+//
+//  stmt, err := querier.Compile(`SELECT {Person} FROM people WHERE name=:name;`, Person{})
+//
+func (q *Querier) Compile(stmt string, values ...interface{}) (string, error) {
+	compiledStmt, _, err := q.compile(stmt, values)
+	return compiledStmt, err
+}
+
+// compile compiles the statement for the values, which are the same as those
+// given to ForOne.
+func (q *Querier) compile(stmt string, values []interface{}) (string, []recordBinding, error) {
+	values, _ = splitOptions(values)
+	structs, err := q.registerStructs(values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := Query{
+		mapper:      q.mapper,
+		order:       q.order,
+		aliasAll:    q.aliasAll,
+		columnAlias: q.columnAlias,
+		reflect:     q.reflect,
+	}
+	compiledStmt, fields, err := query.compileStatement(stmt, structs)
+	if err != nil {
+		return "", nil, statementError(err, stmt, "")
+	}
+	return compiledStmt, fields, nil
+}
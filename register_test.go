@@ -0,0 +1,101 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	type Person struct {
+		Name string `db:"name"`
+	}
+	type Location struct {
+		City string `db:"city"`
+	}
+
+	querier := NewQuerier()
+	err := querier.Register(Person{}, &Location{}, []Person{}, As("p", Person{}))
+	assert.Nil(t, err)
+
+	assert.Equal(t, querier.Register(1).Error(), `expected struct type to register, got "int"`)
+}
+
+func TestRegisterWithInvalidTag(t *testing.T) {
+	type Person struct {
+		Name string `db:"name,unknown"`
+	}
+
+	querier := NewQuerier()
+	err := querier.Register(&Person{})
+	assert.Equal(t, err.Error(), `register "Person": unexpected tag value "unknown"`)
+}
+
+func TestRegisterStatement(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO people(name, age) values ("fred", 21), ("frank", 42);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	stmt := `SELECT {people.* INTO Person} FROM people WHERE people.name=:name;`
+
+	querier := NewQuerier()
+	err = querier.RegisterStatement(stmt, Person{})
+	assert.Nil(t, err)
+
+	cached, ok := querier.stmtCache.Get(stmt)
+	assert.True(t, ok)
+	assert.Equal(t, cached.stmt, "SELECT people.age, people.name FROM people WHERE people.name=:name;")
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, stmt, map[string]interface{}{
+			"name": "frank",
+		})
+	})
+	assert.Equal(t, person, Person{Name: "frank", Age: 42})
+}
+
+func TestRegisterStatementErrors(t *testing.T) {
+	type Person struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+	err := querier.RegisterStatement(`SELECT {Location} FROM people;`, Person{})
+	assert.True(t, err != nil)
+	assert.Equal(t, len(querier.stmtCache.cache), 0)
+
+	err = querier.RegisterStatement(`SELECT {Person} FROM people;`, 1)
+	assert.Equal(t, err.Error(), `expected struct type to register, got "int"`)
+}
+
+func TestCompile(t *testing.T) {
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	querier := NewQuerier()
+	stmt, err := querier.Compile(`SELECT {people.* INTO Person} FROM people WHERE people.name=:name;`, Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, stmt, "SELECT people.age, people.name FROM people WHERE people.name=:name;")
+
+	// Compiling a statement never caches it.
+	assert.Equal(t, len(querier.stmtCache.cache), 0)
+}
@@ -0,0 +1,213 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// resultTarget is the destination of a result set, which is either a struct
+// or a slice of structs.
+type resultTarget struct {
+	entity sreflect.ReflectStruct
+	slice  *reflectSlice
+}
+
+// name returns the name of the entity of the destination.
+func (t resultTarget) name() string {
+	if t.slice != nil {
+		return t.slice.element.Name
+	}
+	return t.entity.Name
+}
+
+// ForResults creates a query for a statement that returns several result
+// sets, such as a batch of statements or a stored procedure. Every value is
+// the destination of a result set, in order, and is either a pointer to a
+// struct or a pointer to a slice of structs:
+//
+//  var (
+//  	person Person
+//  	pets   []Pet
+//  )
+//  query, err := querier.ForResults(&person, &pets)
+//  ...
+//  err = query.Query(tx, `SELECT {Person} FROM people WHERE id=:id; SELECT {Pet} FROM pets WHERE person_id=:id;`, sql.Named("id", 1))
+//
+// The result sets are read in a single round trip, advancing with
+// sql.Rows.NextResultSet between the destinations, so the driver must
+// support multiple result sets. The records of every result set are bound to
+// the destination of that result set.
+//
+// Query options (see QueryOption) can be passed along with the values.
+func (q *Querier) ForResults(values ...interface{}) (Query, error) {
+	values, options := splitOptions(values)
+	options.excludeDeleted = q.excludeDeleted
+	if len(values) == 0 {
+		return Query{}, errors.Errorf("expected at least one argument")
+	}
+
+	// The destinations are reflected one at a time, as they're allowed to be
+	// of different kinds.
+	var entities []sreflect.ReflectInfo
+	targets := make([]resultTarget, len(values))
+	for i, value := range values {
+		infos, err := q.reflectValues(value)
+		if err != nil {
+			return Query{}, errors.WithStack(err)
+		}
+		entity := infos[0]
+		entities = append(entities, entity)
+
+		switch entity.Kind() {
+		case reflect.Struct:
+			targets[i] = resultTarget{
+				entity: entity.(sreflect.ReflectStruct),
+			}
+
+		case reflect.Slice:
+			refValue := entity.(sreflect.ReflectValue)
+			element, err := q.reflect.Reflect(reflect.New(refValue.Value.Type().Elem()).Interface())
+			if err != nil {
+				return Query{}, errors.WithStack(err)
+			}
+			elementRefStruct, ok := element.(sreflect.ReflectStruct)
+			if !ok {
+				return Query{}, errors.Errorf("expected slice T to be struct")
+			}
+			if _, alias := unwrapAlias(value); alias != "" {
+				elementRefStruct.Name = alias
+			}
+			targets[i] = resultTarget{
+				slice: &reflectSlice{
+					slice:   refValue,
+					element: elementRefStruct,
+				},
+			}
+
+		default:
+			return Query{}, errors.Errorf("expected struct or slice for result set %d, got %q", i, entity.Kind())
+		}
+	}
+
+	query := Query{
+		entities:      entities,
+		hook:          q.hook,
+		statementHook: q.statementHook,
+		slowQuery:     q.slowQuery,
+		queryHook:     q.queryHook,
+		argCheck:      q.argCheck,
+		commenter:     q.commenter,
+		mapper:        q.mapper,
+		order:         q.order,
+		aliasAll:      q.aliasAll,
+		columnAlias:   q.columnAlias,
+		options:       options,
+		stmtCache:     q.stmtCache,
+		buffers:       q.buffers,
+		results:       q.results,
+		reflect:       q.reflect,
+		running:       new(int32),
+	}
+	query.executePlan = func(q Query, ctx context.Context, tx *sql.Tx, stmt string, args []interface{}) error {
+		return q.resultsScan(ctx, tx, stmt, args, targets)
+	}
+	return query, nil
+}
+
+func (q Query) resultsScan(ctx context.Context, tx *sql.Tx, stmt string, args []interface{}, targets []resultTarget) error {
+	elements := make([]sreflect.ReflectStruct, len(targets))
+	for i, target := range targets {
+		if target.slice != nil {
+			elements[i] = target.slice.element
+		} else {
+			elements[i] = target.entity
+		}
+	}
+
+	var (
+		compiledStmt string
+		fields       []recordBinding
+	)
+	if q.prepared != nil {
+		compiledStmt = q.prepared.stmt
+		fields = q.prepared.fields
+	} else {
+		var err error
+		compiledStmt, fields, err = q.compileStatement(stmt, elements)
+		if err != nil {
+			return statementError(err, stmt, "")
+		}
+	}
+
+	rows, columns, err := q.query(ctx, tx, compiledStmt, args, fields)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for i, target := range targets {
+		if i > 0 {
+			if !rows.NextResultSet() {
+				if err := rows.Err(); err != nil {
+					return err
+				}
+				return errors.Errorf("expected %d result sets, got %d", len(targets), i)
+			}
+			if columns, err = rows.ColumnTypes(); err != nil {
+				return err
+			}
+		}
+
+		// The columnar buffer can't be pooled by statement, as every result
+		// set has its own columns.
+		columnar := make([]interface{}, len(columns))
+		records := targetRecords(fields, target.name())
+		if target.slice != nil {
+			err = q.scanSlices(rows, columns, columnar, records, []reflectSlice{*target.slice})
+		} else {
+			err = q.scanResultStruct(rows, columns, columnar, records, target.entity)
+		}
+		if err != nil {
+			return statementError(err, stmt, compiledStmt)
+		}
+	}
+	return rows.Err()
+}
+
+// scanResultStruct scans the current result set into the struct.
+func (q Query) scanResultStruct(rows *queryRows, columns []*sql.ColumnType, columnar []interface{}, records []recordBinding, entity sreflect.ReflectStruct) error {
+	entities := []sreflect.ReflectStruct{entity}
+	if err := q.checkUnfolded(records, entities); err != nil {
+		return err
+	}
+	entities, err := q.bindEntities(records, entities)
+	if err != nil {
+		return err
+	}
+	if err := q.structMapping(columnar, columns, entities, records, nil); err != nil {
+		return err
+	}
+	for rows.Next() {
+		if err := rows.Scan(columnar...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// targetRecords returns the records that are bound to the entity, including
+// the records of its nested structs.
+func targetRecords(records []recordBinding, name string) []recordBinding {
+	var result []recordBinding
+	for _, record := range records {
+		if strings.SplitN(record.entityName(), ".", 2)[0] == name {
+			result = append(result, record)
+		}
+	}
+	return result
+}
@@ -0,0 +1,179 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// resultSetsConnector connects to a driver that returns the same result sets
+// for every query, as sqlite doesn't support multiple result sets.
+type resultSetsConnector struct {
+	columns [][]string
+	sets    [][][]driver.Value
+	stmts   []string
+}
+
+func (c *resultSetsConnector) Connect(context.Context) (driver.Conn, error) {
+	return resultSetsConn{connector: c}, nil
+}
+
+func (c *resultSetsConnector) Driver() driver.Driver {
+	return resultSetsDriver{}
+}
+
+type resultSetsDriver struct{}
+
+func (resultSetsDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("not supported")
+}
+
+type resultSetsConn struct {
+	connector *resultSetsConnector
+}
+
+func (resultSetsConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (resultSetsConn) Close() error {
+	return nil
+}
+
+func (resultSetsConn) Begin() (driver.Tx, error) {
+	return resultSetsTx{}, nil
+}
+
+func (resultSetsConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c resultSetsConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.connector.stmts = append(c.connector.stmts, query)
+	return &resultSetsRows{
+		columns: c.connector.columns,
+		sets:    c.connector.sets,
+	}, nil
+}
+
+type resultSetsTx struct{}
+
+func (resultSetsTx) Commit() error {
+	return nil
+}
+
+func (resultSetsTx) Rollback() error {
+	return nil
+}
+
+type resultSetsRows struct {
+	columns  [][]string
+	sets     [][][]driver.Value
+	set, row int
+}
+
+func (r *resultSetsRows) Columns() []string {
+	return r.columns[r.set]
+}
+
+func (r *resultSetsRows) Close() error {
+	return nil
+}
+
+func (r *resultSetsRows) Next(dest []driver.Value) error {
+	if r.row >= len(r.sets[r.set]) {
+		return io.EOF
+	}
+	copy(dest, r.sets[r.set][r.row])
+	r.row++
+	return nil
+}
+
+func (r *resultSetsRows) HasNextResultSet() bool {
+	return r.set < len(r.sets)-1
+}
+
+func (r *resultSetsRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.set++
+	r.row = 0
+	return nil
+}
+
+func TestQueryResults(t *testing.T) {
+	connector := &resultSetsConnector{
+		columns: [][]string{{"age", "name"}, {"name"}},
+		sets: [][][]driver.Value{
+			{{int64(21), "fred"}},
+			{{"rex"}, {"fido"}},
+		},
+	}
+	db := sql.OpenDB(connector)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	type Pet struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+
+	var (
+		person Person
+		pets   []Pet
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		query, err := querier.ForResults(&person, &pets)
+		assert.Nil(t, err)
+
+		return query.Query(tx, `SELECT {Person} FROM people WHERE id=:id; SELECT {Pet} FROM pets WHERE person_id=:id;`, sql.Named("id", 1))
+	})
+
+	assert.Equal(t, person, Person{Name: "fred", Age: 21})
+	assert.Equal(t, pets, []Pet{{Name: "rex"}, {Name: "fido"}})
+	assert.Equal(t, connector.stmts, []string{
+		"SELECT age, name FROM people WHERE id=:id; SELECT name FROM pets WHERE person_id=:id;",
+	})
+}
+
+func TestQueryResultsMissingResultSet(t *testing.T) {
+	connector := &resultSetsConnector{
+		columns: [][]string{{"name"}},
+		sets: [][][]driver.Value{
+			{{"fred"}},
+		},
+	}
+	db := sql.OpenDB(connector)
+
+	type Person struct {
+		Name string `db:"name"`
+	}
+	type Pet struct {
+		Name string `db:"name"`
+	}
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	var (
+		person Person
+		pets   []Pet
+	)
+	query, err := querier.ForResults(&person, &pets)
+	assert.Nil(t, err)
+
+	err = query.Query(tx, `SELECT {Person} FROM people; SELECT {Pet} FROM pets;`)
+	assert.Equal(t, err.Error(), "expected 2 result sets, got 1")
+}
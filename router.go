@@ -0,0 +1,143 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Router directs statements that only read from the database to read
+// replicas, and every other statement to the primary database. Statements
+// are classified from their leading keywords (see Classify), so a WITH ...
+// SELECT statement is read-only, whereas a WITH ... UPDATE statement isn't.
+// Locking reads (SELECT ... FOR UPDATE) go to the primary, as their locks
+// have to be taken there.
+//
+// Replicas that fail a health check (see CheckHealth) are skipped until they
+// pass again. If there are no healthy replicas, the primary is used for
+// every statement.
+type Router struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     uint32
+}
+
+type replica struct {
+	db      *sql.DB
+	healthy int32
+}
+
+// NewRouter creates a router for the primary database and the read replicas.
+// Every replica is assumed to be healthy until it's checked.
+func NewRouter(primary *sql.DB, replicas ...*sql.DB) *Router {
+	router := &Router{
+		primary:  primary,
+		replicas: make([]*replica, len(replicas)),
+	}
+	for i, db := range replicas {
+		router.replicas[i] = &replica{
+			db:      db,
+			healthy: 1,
+		}
+	}
+	return router
+}
+
+// Router assigns the router to the querier, which is used by DB to choose
+// the database of each statement. See NewDBWithRouter.
+func (q *Querier) Router(router *Router) {
+	q.router = router
+}
+
+// Primary returns the primary database.
+func (r *Router) Primary() *sql.DB {
+	return r.primary
+}
+
+// Reader returns the next healthy replica, in turn, or the primary if there
+// isn't one.
+func (r *Router) Reader() *sql.DB {
+	if len(r.replicas) == 0 {
+		return r.primary
+	}
+	start := atomic.AddUint32(&r.next, 1)
+	for i := range r.replicas {
+		replica := r.replicas[(int(start)+i)%len(r.replicas)]
+		if atomic.LoadInt32(&replica.healthy) == 1 {
+			return replica.db
+		}
+	}
+	return r.primary
+}
+
+// Route returns the database for the statement, which is a replica if the
+// statement is read-only and the primary otherwise.
+func (r *Router) Route(stmt string) *sql.DB {
+	if Classify(stmt).ReadOnly {
+		return r.Reader()
+	}
+	return r.primary
+}
+
+// has returns true if the database is the primary or one of the replicas.
+func (r *Router) has(db *sql.DB) bool {
+	if db == r.primary {
+		return true
+	}
+	for _, replica := range r.replicas {
+		if replica.db == db {
+			return true
+		}
+	}
+	return false
+}
+
+// Healthy returns the number of replicas that passed their last health check.
+func (r *Router) Healthy() int {
+	var healthy int
+	for _, replica := range r.replicas {
+		healthy += int(atomic.LoadInt32(&replica.healthy))
+	}
+	return healthy
+}
+
+// CheckHealth pings every replica, marking the replicas that fail to respond
+// as unhealthy and those that respond as healthy again.
+func (r *Router) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, rep := range r.replicas {
+		wg.Add(1)
+		go func(rep *replica) {
+			defer wg.Done()
+
+			var healthy int32
+			if err := rep.db.PingContext(ctx); err == nil {
+				healthy = 1
+			}
+			atomic.StoreInt32(&rep.healthy, healthy)
+		}(rep)
+	}
+	wg.Wait()
+}
+
+// MonitorHealth checks the health of the replicas at every interval, until
+// the context is done.
+//
+//  go router.MonitorHealth(ctx, 10*time.Second)
+//
+func (r *Router) MonitorHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.CheckHealth(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
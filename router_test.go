@@ -0,0 +1,96 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// replicaSchema is a table with a single person, which is named by the
+// argument of the schema, so that the database can be identified.
+const replicaSchema = `
+CREATE TABLE people(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO people(name, age) values (?, 1);
+`
+
+func TestRouterRoute(t *testing.T) {
+	primary := setupSchemaDB(t, replicaSchema, "primary")
+	replica := setupSchemaDB(t, replicaSchema, "replica")
+
+	router := NewRouter(primary, replica)
+	assert.True(t, router.Route(`SELECT * FROM people;`) == replica)
+	assert.True(t, router.Route(`WITH p AS (SELECT * FROM people) SELECT * FROM p;`) == replica)
+	assert.True(t, router.Route(`UPDATE people SET age=2;`) == primary)
+	assert.True(t, router.Route(`SELECT 1; DELETE FROM people;`) == primary)
+
+	// Locking reads take their locks on the primary.
+	assert.True(t, router.Route(`SELECT * FROM people WHERE name='fred' FOR UPDATE;`) == primary)
+	assert.True(t, router.Route(`SELECT * FROM people FOR SHARE SKIP LOCKED;`) == primary)
+
+	// Without replicas, every statement is routed to the primary.
+	router = NewRouter(primary)
+	assert.True(t, router.Route(`SELECT * FROM people;`) == primary)
+}
+
+func TestRouterReaderRoundRobin(t *testing.T) {
+	primary := setupSchemaDB(t, replicaSchema, "primary")
+	first := setupSchemaDB(t, replicaSchema, "first")
+	second := setupSchemaDB(t, replicaSchema, "second")
+
+	router := NewRouter(primary, first, second)
+	seen := map[*sql.DB]int{}
+	for i := 0; i < 4; i++ {
+		seen[router.Reader()]++
+	}
+	assert.Equal(t, seen, map[*sql.DB]int{first: 2, second: 2})
+}
+
+func TestRouterHealthCheck(t *testing.T) {
+	primary := setupSchemaDB(t, replicaSchema, "primary")
+	replica := setupSchemaDB(t, replicaSchema, "replica")
+
+	router := NewRouter(primary, replica)
+	router.CheckHealth(context.Background())
+	assert.Equal(t, router.Healthy(), 1)
+
+	replica.Close()
+	router.CheckHealth(context.Background())
+	assert.Equal(t, router.Healthy(), 0)
+	assert.True(t, router.Reader() == primary)
+}
+
+func TestDBWithRouter(t *testing.T) {
+	primary := setupSchemaDB(t, replicaSchema, "primary")
+	replica := setupSchemaDB(t, replicaSchema, "replica")
+
+	db := NewDBWithRouter(NewRouter(primary, replica))
+	ctx := context.Background()
+
+	var persons []argsPerson
+	err := db.GetAll(ctx, &persons, `SELECT {people.* INTO argsPerson} FROM people;`)
+	assert.Nil(t, err)
+	assert.Equal(t, persons, []argsPerson{{Name: "replica", Age: 1}})
+
+	_, err = db.Exec(ctx, `UPDATE people SET age=:age;`, map[string]interface{}{"age": 2})
+	assert.Nil(t, err)
+
+	// Reads within a transaction use the database of the transaction.
+	err = db.Tx(ctx, func(tx *TxQuerier) error {
+		persons = nil
+		return db.GetAll(tx.Context(), &persons, `SELECT {people.* INTO argsPerson} FROM people;`)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, persons, []argsPerson{{Name: "primary", Age: 2}})
+
+	err = db.TxWithOptions(ctx, &sql.TxOptions{ReadOnly: true}, func(tx *TxQuerier) error {
+		persons = nil
+		return db.GetAll(tx.Context(), &persons, `SELECT {people.* INTO argsPerson} FROM people;`)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, persons, []argsPerson{{Name: "replica", Age: 1}})
+}
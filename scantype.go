@@ -0,0 +1,221 @@
+package sqlair
+
+import (
+	"database/sql"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// zeroScanType returns a nullable scan destination for a given database type
+// name. Use nullableValue to retrieve the scanned value.
+//
+// Type names are normalised before being matched, so "VARCHAR(255)" and
+// "varchar" are treated the same. The SQLite affinity rules are used to match
+// any type names that aren't known, which also covers most of the common
+// Postgres and MySQL type names. Types that can't be matched (including
+// expressions without a declared type) are scanned as the driver's own
+// representation of the value.
+//
+// See https://www.sqlite.org/datatype3.html#determination_of_column_affinity
+func zeroScanType(t string) interface{} {
+	name := normalizeTypeName(t)
+	switch name {
+	case "BOOL", "BOOLEAN":
+		return new(nullBool)
+	case "DATE", "DATETIME", "TIMESTAMP", "TIMESTAMPTZ",
+		"TIMESTAMP WITH TIME ZONE", "TIMESTAMP WITHOUT TIME ZONE":
+		return new(sql.NullTime)
+	case "", "NUMERIC", "DECIMAL", "INTERVAL", "POINT":
+		return new(interface{})
+	}
+
+	switch {
+	case strings.Contains(name, "INT"):
+		return new(sql.NullInt64)
+	case strings.Contains(name, "CHAR"),
+		strings.Contains(name, "CLOB"),
+		strings.Contains(name, "TEXT"):
+		return new(sql.NullString)
+	case strings.Contains(name, "BLOB"),
+		strings.Contains(name, "BINARY"),
+		name == "BYTEA":
+		var a []byte
+		return &a
+	case strings.Contains(name, "REAL"),
+		strings.Contains(name, "FLOA"),
+		strings.Contains(name, "DOUB"):
+		return new(sql.NullFloat64)
+	default:
+		// SQLite would give the column a NUMERIC affinity, which can hold
+		// any value, so let the driver decide.
+		return new(interface{})
+	}
+}
+
+// normalizeTypeName upper cases the type name, removing any size or precision
+// arguments and collapsing any white space.
+func normalizeTypeName(t string) string {
+	if index := strings.IndexRune(t, '('); index >= 0 {
+		t = t[:index]
+	}
+	return strings.ToUpper(strings.Join(strings.Fields(t), " "))
+}
+
+// nullableValue returns the value scanned into a zeroScanType destination,
+// or nil if the value was NULL.
+func nullableValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *sql.NullString:
+		if t.Valid {
+			return t.String
+		}
+	case *sql.NullInt64:
+		if t.Valid {
+			return t.Int64
+		}
+	case *nullBool:
+		if t.Valid {
+			return t.Bool
+		}
+	case *sql.NullFloat64:
+		if t.Valid {
+			return t.Float64
+		}
+	case *sql.NullTime:
+		if t.Valid {
+			return t.Time
+		}
+	case *[]byte:
+		// The database/sql package copies the bytes of a BLOB into a
+		// *[]byte destination, so the value never refers to memory owned by
+		// the driver.
+		if *t != nil {
+			return *t
+		}
+	default:
+		return reflect.Indirect(reflect.ValueOf(v)).Interface()
+	}
+	return nil
+}
+
+// convertValue converts a value returned by nullableValue to the type, which
+// is the element type of a map destination. Numbers are converted between
+// numeric types as long as they don't overflow, and strings are parsed into
+// numbers and booleans, or formatted from them. NULL values are converted to
+// the zero value of the type.
+func convertValue(v interface{}, t reflect.Type) (reflect.Value, bool) {
+	if v == nil {
+		return reflect.Zero(t), true
+	}
+	value := reflect.ValueOf(v)
+	if value.Type().AssignableTo(t) {
+		return value, true
+	}
+
+	// Strings and byte slices are parsed as the number or boolean.
+	if s, ok := v.([]byte); ok {
+		v, value = string(s), reflect.ValueOf(string(s))
+	}
+	if s, ok := v.(string); ok && t.Kind() != reflect.String && t.Kind() != reflect.Slice {
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return reflect.Value{}, false
+			}
+			value = reflect.ValueOf(i)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return reflect.Value{}, false
+			}
+			value = reflect.ValueOf(u)
+		case reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return reflect.Value{}, false
+			}
+			value = reflect.ValueOf(f)
+		case reflect.Bool:
+			b, ok := parseBool(s)
+			if !ok {
+				return reflect.Value{}, false
+			}
+			return reflect.ValueOf(b).Convert(t), true
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		switch x := value.Interface().(type) {
+		case string:
+			return reflect.ValueOf(x).Convert(t), true
+		case int64:
+			return reflect.ValueOf(strconv.FormatInt(x, 10)).Convert(t), true
+		case float64:
+			return reflect.ValueOf(strconv.FormatFloat(x, 'g', -1, 64)).Convert(t), true
+		case bool:
+			return reflect.ValueOf(strconv.FormatBool(x)).Convert(t), true
+		case time.Time:
+			return reflect.ValueOf(x.Format(time.RFC3339Nano)).Convert(t), true
+		}
+	case reflect.Slice:
+		if s, ok := value.Interface().(string); ok && t.Elem().Kind() == reflect.Uint8 {
+			return reflect.ValueOf([]byte(s)).Convert(t), true
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch value.Kind() {
+		case reflect.Int64:
+			if reflect.Zero(t).OverflowInt(value.Int()) {
+				return reflect.Value{}, false
+			}
+			return value.Convert(t), true
+		case reflect.Uint64:
+			if value.Uint() > math.MaxInt64 || reflect.Zero(t).OverflowInt(int64(value.Uint())) {
+				return reflect.Value{}, false
+			}
+			return value.Convert(t), true
+		case reflect.Float64:
+			if f := value.Float(); f != math.Trunc(f) || reflect.Zero(t).OverflowInt(int64(f)) {
+				return reflect.Value{}, false
+			}
+			return value.Convert(t), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch value.Kind() {
+		case reflect.Int64:
+			if value.Int() < 0 || reflect.Zero(t).OverflowUint(uint64(value.Int())) {
+				return reflect.Value{}, false
+			}
+			return value.Convert(t), true
+		case reflect.Uint64:
+			if reflect.Zero(t).OverflowUint(value.Uint()) {
+				return reflect.Value{}, false
+			}
+			return value.Convert(t), true
+		case reflect.Float64:
+			if f := value.Float(); f < 0 || f != math.Trunc(f) || reflect.Zero(t).OverflowUint(uint64(f)) {
+				return reflect.Value{}, false
+			}
+			return value.Convert(t), true
+		}
+	case reflect.Float32, reflect.Float64:
+		switch value.Kind() {
+		case reflect.Int64, reflect.Uint64:
+			return value.Convert(t), true
+		case reflect.Float64:
+			if reflect.Zero(t).OverflowFloat(value.Float()) {
+				return reflect.Value{}, false
+			}
+			return value.Convert(t), true
+		}
+	case reflect.Bool:
+		if b, ok := parseBool(value.Interface()); ok {
+			return reflect.ValueOf(b).Convert(t), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,235 @@
+package sqlair
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZeroScanType(t *testing.T) {
+	var (
+		nullString  = new(sql.NullString)
+		nullInt64   = new(sql.NullInt64)
+		nullFloat64 = new(sql.NullFloat64)
+		boolean     = new(nullBool)
+		nullTime    = new(sql.NullTime)
+		bytes       = new([]byte)
+		iface       = new(interface{})
+	)
+	tests := map[string]interface{}{
+		"TEXT":                     nullString,
+		"varchar(255)":             nullString,
+		"CHARACTER VARYING ( 32 )": nullString,
+		"NVARCHAR":                 nullString,
+		"CLOB":                     nullString,
+		"INTEGER":                  nullInt64,
+		"int":                      nullInt64,
+		"BIGINT":                   nullInt64,
+		"UNSIGNED BIG INT":         nullInt64,
+		"INT8":                     nullInt64,
+		"BLOB":                     bytes,
+		"BYTEA":                    bytes,
+		"VARBINARY(16)":            bytes,
+		"REAL":                     nullFloat64,
+		"DOUBLE PRECISION":         nullFloat64,
+		"FLOAT8":                   nullFloat64,
+		"BOOL":                     boolean,
+		"boolean":                  boolean,
+		"DATETIME":                 nullTime,
+		"TIMESTAMPTZ":              nullTime,
+		"date":                     nullTime,
+		"NUMERIC(10,2)":            iface,
+		"DECIMAL":                  iface,
+		"UUID":                     iface,
+		"JSONB":                    iface,
+		"":                         iface,
+	}
+	for name, expected := range tests {
+		assert.IsType(t, expected, zeroScanType(name), name)
+	}
+}
+
+func TestQueryWithMapAndUnusualTypes(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name    VARCHAR(255),
+	price   NUMERIC(10,2),
+	amount  DECIMAL,
+	ratio   DOUBLE PRECISION,
+	id      UUID
+);
+INSERT INTO test(name, price, amount, ratio, id) values ("fred", 12.5, 3, 0.5, "a1b2");
+	`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	values := make(map[string]interface{})
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&values)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, "SELECT name, price, amount, ratio, id FROM test;")
+	})
+
+	assert.Equal(t, values, map[string]interface{}{
+		"name":   "fred",
+		"price":  12.5,
+		"amount": int64(3),
+		"ratio":  0.5,
+		"id":     "a1b2",
+	})
+}
+
+func TestQueryWithMapAndExpressions(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO test(name, age) values ("fred", 21), ("frank", NULL);
+	`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	values := make(map[string]interface{})
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&values)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `
+SELECT name, age + 1 AS next, NULL AS missing, 'x' || name AS label, age * 0.5 AS half, (SELECT COUNT(*) FROM test) AS total
+FROM test WHERE name=:name;`, map[string]interface{}{
+			"name": "frank",
+		})
+	})
+
+	assert.Equal(t, values, map[string]interface{}{
+		"name":    "frank",
+		"next":    nil,
+		"missing": nil,
+		"label":   "xfrank",
+		"half":    nil,
+		"total":   int64(2),
+	})
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&values)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT age + 1 AS next, age * 0.5 AS half FROM test WHERE name=:name;`, map[string]interface{}{
+			"name": "fred",
+		})
+	})
+
+	assert.Equal(t, values["next"], int64(22))
+	assert.Equal(t, values["half"], 10.5)
+}
+
+func TestQueryWithTypedMaps(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name  TEXT,
+	age   INTEGER,
+	score REAL,
+	count TEXT
+);
+INSERT INTO test(name, age, score, count) values ("fred", 21, 4, "7"), ("frank", NULL, 1.5, "x");
+	`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+
+	strs := make(map[string]string)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&strs)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, "SELECT name, age, score FROM test WHERE name=:name;", map[string]interface{}{
+			"name": "fred",
+		})
+	})
+	assert.Equal(t, strs, map[string]string{
+		"name":  "fred",
+		"age":   "21",
+		"score": "4",
+	})
+
+	ints := make(map[string]int64)
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&ints)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, "SELECT age, score, count FROM test WHERE name=:name;", map[string]interface{}{
+			"name": "fred",
+		})
+	})
+	assert.Equal(t, ints, map[string]int64{
+		"age":   21,
+		"score": 4,
+		"count": 7,
+	})
+
+	// NULL values are stored as the zero value.
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&ints)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, "SELECT age FROM test WHERE name=:name;", map[string]interface{}{
+			"name": "frank",
+		})
+	})
+	assert.Equal(t, ints["age"], int64(0))
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	getter, err := querier.ForOne(&ints)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, "SELECT score FROM test WHERE name=:name;", map[string]interface{}{
+		"name": "frank",
+	})
+	assert.True(t, errors.As(err, new(*ConversionError)))
+	assert.Equal(t, err.Error(), `unable to convert column "score" value 1.5 of type float64 to int64`)
+
+	err = getter.Query(tx, "SELECT count FROM test WHERE name=:name;", map[string]interface{}{
+		"name": "frank",
+	})
+	assert.Equal(t, err.Error(), `unable to convert column "count" value x of type string to int64`)
+}
+
+func TestConvertValue(t *testing.T) {
+	value, ok := convertValue(int64(300), reflect.TypeOf(int8(0)))
+	assert.False(t, ok)
+
+	value, ok = convertValue(int64(-1), reflect.TypeOf(uint(0)))
+	assert.False(t, ok)
+
+	value, ok = convertValue([]byte("true"), reflect.TypeOf(false))
+	assert.True(t, ok)
+	assert.Equal(t, value.Interface(), true)
+
+	value, ok = convertValue(int64(1), reflect.TypeOf(false))
+	assert.True(t, ok)
+	assert.Equal(t, value.Interface(), true)
+
+	value, ok = convertValue("abc", reflect.TypeOf([]byte(nil)))
+	assert.True(t, ok)
+	assert.Equal(t, value.Interface(), []byte("abc"))
+
+	value, ok = convertValue(nil, reflect.TypeOf(""))
+	assert.True(t, ok)
+	assert.Equal(t, value.Interface(), "")
+}
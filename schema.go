@@ -0,0 +1,307 @@
+package sqlair
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// Dialect is the SQL dialect of a database, which decides the column types of
+// generated schemas.
+type Dialect string
+
+const (
+	// SQLite is the dialect of SQLite databases.
+	SQLite Dialect = "sqlite"
+	// Postgres is the dialect of PostgreSQL databases.
+	Postgres Dialect = "postgres"
+	// MySQL is the dialect of MySQL databases.
+	MySQL Dialect = "mysql"
+)
+
+// TableNamer is implemented by types that provide the name of their table.
+// Types that don't implement it use the table of their struct tag (see
+// reflect.StructTagKey), otherwise the name of the type, mapped by the name
+// mapper of the querier.
+type TableNamer interface {
+	TableName() string
+}
+
+var (
+	bytesType      = reflect.TypeOf([]byte(nil))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	nullTypes      = map[reflect.Type]reflect.Type{
+		reflect.TypeOf(sql.NullString{}):  reflect.TypeOf(""),
+		reflect.TypeOf(sql.NullInt64{}):   reflect.TypeOf(int64(0)),
+		reflect.TypeOf(sql.NullInt32{}):   reflect.TypeOf(int32(0)),
+		reflect.TypeOf(sql.NullInt16{}):   reflect.TypeOf(int16(0)),
+		reflect.TypeOf(sql.NullByte{}):    reflect.TypeOf(byte(0)),
+		reflect.TypeOf(sql.NullFloat64{}): reflect.TypeOf(float64(0)),
+		reflect.TypeOf(sql.NullBool{}):    reflect.TypeOf(false),
+		reflect.TypeOf(sql.NullTime{}):    timeType,
+	}
+)
+
+// CreateTableSQL returns the CREATE TABLE statement for the struct, along
+// with a CREATE INDEX statement for every index, using the default reflect
+// configuration. See Querier.CreateTableSQL.
+func CreateTableSQL(value interface{}, dialect Dialect) (string, error) {
+	return NewQuerier().CreateTableSQL(value, dialect)
+}
+
+// CreateTableSQL returns the CREATE TABLE statement for the struct, along
+// with a CREATE INDEX statement for every index. The columns are in the order
+// that the fields are declared in, and the column types are derived from the
+// field types for the dialect.
+//
+// The schema can be refined with the following tag options:
+//
+//  type=NUMERIC    the column type, overriding the derived type
+//  pk              the column is part of the primary key
+//  notnull, null   overrides the nullability of the column
+//  unique          the values of the column must be unique
+//  index           the column is indexed
+//  index=name      the column is part of the named index
+//  uuid, uuid=blob the [16]byte column is stored as TEXT or as a BLOB
+//  enum=a|b|c      the column holds one of the values of the enum as TEXT
+//
+// Pointers, sql.Null types and []byte fields are nullable, all other fields
+// are NOT NULL by default.
+//
+//  type Person struct {
+//  	ID    int64  `db:"id,pk"`
+//  	Name  string `db:"name,index"`
+//  	Email string `db:"email,unique"`
+//  }
+//
+func (q *Querier) CreateTableSQL(value interface{}, dialect Dialect) (string, error) {
+	table, refStruct, err := q.reflectTable(value)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		columns     []string
+		primaryKeys []string
+		indexes     []string
+		indexed     = make(map[string][]string)
+	)
+	for _, name := range refStruct.DeclaredFieldNames() {
+		field := refStruct.Fields[name]
+
+		column, err := columnDefinition(dialect, name, field)
+		if err != nil {
+			return "", errors.Wrapf(err, "table %q", table)
+		}
+		columns = append(columns, column)
+
+		if field.Tag.PrimaryKey {
+			primaryKeys = append(primaryKeys, name)
+		}
+		if field.Tag.Index {
+			index := field.Tag.IndexName
+			if index == "" {
+				index = fmt.Sprintf("idx_%s_%s", table, name)
+			}
+			if _, ok := indexed[index]; !ok {
+				indexes = append(indexes, index)
+			}
+			indexed[index] = append(indexed[index], name)
+		}
+	}
+	if len(primaryKeys) > 0 {
+		columns = append(columns, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKeys, ", ")))
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "CREATE TABLE %s (\n\t%s\n);", table, strings.Join(columns, ",\n\t"))
+	for _, index := range indexes {
+		fmt.Fprintf(&builder, "\nCREATE INDEX %s ON %s (%s);", index, table, strings.Join(indexed[index], ", "))
+	}
+	return builder.String(), nil
+}
+
+// reflectTable returns the table name and the reflected struct of the value.
+func (q *Querier) reflectTable(value interface{}) (string, sreflect.ReflectStruct, error) {
+	typ := structType(value)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return "", sreflect.ReflectStruct{}, errors.Errorf("expected struct, got %T", value)
+	}
+
+	instance := reflect.New(typ)
+	info, err := q.reflect.Reflect(instance.Interface())
+	if err != nil {
+		return "", sreflect.ReflectStruct{}, errors.WithStack(err)
+	}
+	refStruct, ok := info.(sreflect.ReflectStruct)
+	if !ok {
+		return "", sreflect.ReflectStruct{}, errors.Errorf("expected struct, got %q", info.Kind())
+	}
+
+	return tableName(q.reflect.Config(), refStruct), refStruct, nil
+}
+
+// tableName returns the table of the struct, which is named by its TableName
+// method, then by its struct tag, and otherwise by the name of the type.
+func tableName(config sreflect.Config, refStruct sreflect.ReflectStruct) string {
+	typ := refStruct.Value.Type()
+	if namer, ok := reflect.New(typ).Interface().(TableNamer); ok {
+		return namer.TableName()
+	}
+	if refStruct.Table != "" {
+		return refStruct.Table
+	}
+	mapper := config.NameMapper
+	if mapper == nil {
+		mapper = sreflect.SnakeCase
+	}
+	return mapper(typ.Name())
+}
+
+// columnDefinition returns the definition of the column of the field within
+// a CREATE TABLE statement.
+func columnDefinition(dialect Dialect, name string, field sreflect.ReflectField) (string, error) {
+	typ, nullable := fieldType(field)
+	if field.Tag.PrimaryKey || field.Tag.NotNull {
+		nullable = false
+	} else if field.Tag.Null {
+		nullable = true
+	}
+
+	columnType := field.Tag.Type
+	if columnType == "" && isUUIDField(field, field.Value.Type()) {
+		columnType = dialect.uuidColumnType(field.Tag.UUID)
+	}
+	if columnType == "" {
+		var ok bool
+		if columnType, ok = dialect.columnType(typ, field.Tag.JSON); !ok {
+			return "", errors.Errorf("unknown column type for field %q of type %s, use the type tag option", field.Name, typ)
+		}
+	}
+
+	definition := name + " " + columnType
+	if !nullable {
+		definition += " NOT NULL"
+	}
+	if field.Tag.Unique {
+		definition += " UNIQUE"
+	}
+	return definition, nil
+}
+
+// fieldType returns the type of the value stored by the field, unwrapping
+// pointers and sql.Null types, and whether the type is nullable.
+func fieldType(field sreflect.ReflectField) (reflect.Type, bool) {
+	typ := field.Value.Type()
+
+	nullable := false
+	switch {
+	case typ.Kind() == reflect.Ptr:
+		nullable = true
+		typ = typ.Elem()
+	case typ == bytesType:
+		nullable = true
+	}
+	if base, ok := nullTypes[typ]; ok {
+		nullable = true
+		typ = base
+	}
+	// Enums are stored as strings, whatever the type of the field.
+	if len(field.Tag.Enum) > 0 {
+		typ = reflect.TypeOf("")
+	}
+	return typ, nullable
+}
+
+// uuidColumnType returns the column type of a UUID for the dialect, which is
+// stored as TEXT unless the storage is "blob".
+func (d Dialect) uuidColumnType(storage string) string {
+	if storage == "blob" {
+		switch d {
+		case Postgres:
+			return "BYTEA"
+		case MySQL:
+			return "BINARY(16)"
+		}
+		return "BLOB"
+	}
+	switch d {
+	case Postgres:
+		return "UUID"
+	case MySQL:
+		return "CHAR(36)"
+	}
+	return "TEXT"
+}
+
+// columnType returns the column type of the Go type for the dialect.
+func (d Dialect) columnType(typ reflect.Type, isJSON bool) (string, bool) {
+	if isJSON || typ == rawMessageType {
+		switch d {
+		case Postgres:
+			return "JSONB", true
+		case MySQL:
+			return "JSON", true
+		}
+		return "TEXT", true
+	}
+
+	switch typ {
+	case timeType:
+		switch d {
+		case Postgres:
+			return "TIMESTAMP WITH TIME ZONE", true
+		}
+		return "DATETIME", true
+	case bytesType:
+		switch d {
+		case Postgres:
+			return "BYTEA", true
+		}
+		return "BLOB", true
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		switch d {
+		case Postgres:
+			return "BOOLEAN", true
+		case MySQL:
+			return "TINYINT(1)", true
+		}
+		return "INTEGER", true
+	case reflect.Int8, reflect.Int16, reflect.Uint8, reflect.Uint16:
+		if d == SQLite {
+			return "INTEGER", true
+		}
+		return "SMALLINT", true
+	case reflect.Int32, reflect.Uint32:
+		return "INTEGER", true
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		if d == SQLite {
+			return "INTEGER", true
+		}
+		return "BIGINT", true
+	case reflect.Float32:
+		return "REAL", true
+	case reflect.Float64:
+		switch d {
+		case Postgres:
+			return "DOUBLE PRECISION", true
+		case MySQL:
+			return "DOUBLE", true
+		}
+		return "REAL", true
+	case reflect.String:
+		if d == MySQL {
+			return "VARCHAR(255)", true
+		}
+		return "TEXT", true
+	}
+	return "", false
+}
@@ -0,0 +1,119 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ExecScript executes a script of several statements, such as fixtures or
+// migrations. Many drivers only execute the first statement of a string
+// passed to Exec, so the script is split on the semicolons between the
+// statements, and every statement is executed in turn with Exec.
+//
+//  err := querier.ExecScript(tx, `
+//  CREATE TABLE people (id INTEGER, name TEXT);
+//  INSERT INTO people VALUES (1, 'fred; frank');
+//  `)
+//
+// Semicolons within quoted strings, comments and the BEGIN...END blocks of
+// triggers are ignored. Statements that are empty or only hold comments are
+// skipped.
+func (q *Querier) ExecScript(tx *sql.Tx, script string) error {
+	return q.ExecScriptContext(context.Background(), tx, script)
+}
+
+// ExecScriptContext executes a script of several statements. See
+// ExecScript.
+func (q *Querier) ExecScriptContext(ctx context.Context, tx *sql.Tx, script string) error {
+	for i, stmt := range splitStatements(script) {
+		if _, err := q.ExecContext(ctx, tx, stmt); err != nil {
+			return errors.Wrapf(err, "executing statement %d", i+1)
+		}
+	}
+	return nil
+}
+
+// splitStatements splits the script into its statements, without the
+// trailing semicolons. A BEGIN keyword within a statement opens a block,
+// which is closed by an END keyword, so that the statements of a trigger
+// body stay with the trigger. A BEGIN keyword that starts a statement begins
+// a transaction instead. CASE expressions are also closed by END, so they're
+// counted as blocks too.
+func splitStatements(script string) []string {
+	var (
+		stmts []string
+		start int
+		depth int
+		first = true
+		empty = true
+	)
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, c)
+			empty = false
+		case strings.HasPrefix(script[i:], "--"):
+			i = skipUntil(script, i, "\n")
+		case strings.HasPrefix(script[i:], "/*"):
+			i = skipUntil(script, i, "*/")
+		case c == '$':
+			i = skipDollarQuoted(script, i)
+			empty = false
+		case c == ';' && depth == 0:
+			if !empty {
+				stmts = append(stmts, strings.TrimSpace(script[start:i]))
+			}
+			i++
+			start, first, empty = i, true, true
+		case isKeywordChar(c):
+			begin := i
+			for i < len(script) && (isKeywordChar(script[i]) || (script[i] >= '0' && script[i] <= '9')) {
+				i++
+			}
+			empty = false
+			if begin > 0 && (script[begin-1] == '.' || script[begin-1] == ':' || script[begin-1] == '@') {
+				continue
+			}
+			switch strings.ToUpper(script[begin:i]) {
+			case "BEGIN":
+				if !first {
+					depth++
+				}
+			case "CASE":
+				depth++
+			case "END":
+				if depth > 0 {
+					depth--
+				}
+			}
+			first = false
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		default:
+			i++
+			empty = false
+		}
+	}
+	if !empty {
+		stmts = append(stmts, strings.TrimSpace(script[start:]))
+	}
+	return stmts
+}
+
+// skipDollarQuoted returns the index directly after a dollar quoted string
+// ($$...$$ or $tag$...$tag$) starting at the offset, or directly after the
+// dollar if it doesn't start one, such as a $1 placeholder.
+func skipDollarQuoted(stmt string, offset int) int {
+	end := offset + 1
+	for end < len(stmt) && isKeywordChar(stmt[end]) {
+		end++
+	}
+	if end >= len(stmt) || stmt[end] != '$' {
+		return offset + 1
+	}
+	return skipUntil(stmt, end+1, stmt[offset:end+1])
+}
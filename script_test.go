@@ -0,0 +1,82 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected []string
+	}{{
+		script:   `SELECT 1; SELECT 2;`,
+		expected: []string{"SELECT 1", "SELECT 2"},
+	}, {
+		script:   "INSERT INTO people VALUES ('fred; frank'); -- a; comment\n/* another; */ SELECT \"a;b\" FROM people",
+		expected: []string{"INSERT INTO people VALUES ('fred; frank')", "-- a; comment\n/* another; */ SELECT \"a;b\" FROM people"},
+	}, {
+		script:   "  ;\n-- nothing\n;",
+		expected: nil,
+	}, {
+		script: `CREATE TRIGGER audit AFTER INSERT ON people BEGIN INSERT INTO log VALUES (NEW.name); UPDATE counts SET n = CASE WHEN n IS NULL THEN 1 ELSE n + 1 END; END; SELECT 1;`,
+		expected: []string{
+			"CREATE TRIGGER audit AFTER INSERT ON people BEGIN INSERT INTO log VALUES (NEW.name); UPDATE counts SET n = CASE WHEN n IS NULL THEN 1 ELSE n + 1 END; END",
+			"SELECT 1",
+		},
+	}, {
+		script:   `BEGIN; DELETE FROM people; END;`,
+		expected: []string{"BEGIN", "DELETE FROM people", "END"},
+	}, {
+		script:   `CREATE FUNCTION one() RETURNS integer AS $body$ SELECT 1; $body$ LANGUAGE SQL; SELECT $1;`,
+		expected: []string{"CREATE FUNCTION one() RETURNS integer AS $body$ SELECT 1; $body$ LANGUAGE SQL", "SELECT $1"},
+	}}
+	for i, test := range tests {
+		t.Logf("test %d: %s", i, test.script)
+		assert.Equal(t, splitStatements(test.script), test.expected)
+	}
+}
+
+func TestExecScript(t *testing.T) {
+	db := setupDB(t)
+
+	var stmts []string
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		stmts = append(stmts, stmt)
+		return nil
+	})
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		return querier.ExecScript(tx, `
+CREATE TABLE people (name TEXT);
+CREATE TABLE log (name TEXT);
+CREATE TRIGGER audit AFTER INSERT ON people BEGIN
+	INSERT INTO log VALUES (NEW.name);
+END;
+INSERT INTO people VALUES ('fred; frank');
+`)
+	})
+	assert.Len(t, stmts, 4)
+
+	var name string
+	err := db.QueryRow("SELECT name FROM log").Scan(&name)
+	assert.Nil(t, err)
+	assert.Equal(t, name, "fred; frank")
+}
+
+func TestExecScriptError(t *testing.T) {
+	db := setupDB(t)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	err = querier.ExecScript(tx, `CREATE TABLE people (name TEXT); INSERT INTO missing VALUES (1);`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "executing statement 2")
+}
@@ -0,0 +1,85 @@
+package sqlair
+
+import (
+	"context"
+	"time"
+)
+
+// QueryInfo describes a statement that has been executed.
+type QueryInfo struct {
+	// Statement is the compiled statement that was sent to the database.
+	Statement string
+	// Duration is the time taken to execute the statement, including the time
+	// taken to read all the rows for queries.
+	Duration time.Duration
+	// Rows is the number of rows scanned by a query, or the number of rows
+	// affected by an exec. Slow query hooks aren't given the rows.
+	Rows int64
+	// Err is the error of the statement, if it failed. Slow query hooks
+	// aren't given the error.
+	Err error
+}
+
+// SlowQueryHook is called with the information of a statement that exceeded
+// the slow query threshold.
+type SlowQueryHook func(QueryInfo)
+
+// OnSlowQuery assigns the slow query hook to the querier. The hook is only
+// called once a statement has finished and its duration exceeds the threshold.
+func (q *Querier) OnSlowQuery(threshold time.Duration, hook SlowQueryHook) {
+	q.slowQuery = slowQueryHook{
+		threshold: threshold,
+		hook:      hook,
+	}
+}
+
+type slowQueryHook struct {
+	threshold time.Duration
+	hook      SlowQueryHook
+}
+
+// observe calls the hook if the time since start exceeds the threshold.
+func (s slowQueryHook) observe(stmt string, start time.Time) {
+	if s.hook == nil {
+		return
+	}
+	if elapsed := time.Since(start); elapsed > s.threshold {
+		s.hook(QueryInfo{
+			Statement: stmt,
+			Duration:  elapsed,
+		})
+	}
+}
+
+// QueryHook is called with the information of every statement once it has
+// finished, along with the context that the statement was executed with.
+type QueryHook func(context.Context, QueryInfo)
+
+// OnQuery assigns the query hook to the querier. Unlike a slow query hook, the
+// query hook is called for every statement, including those that fail, which
+// makes it the hook for structured logging (see the sqlairlog package):
+//
+//  querier.OnQuery(func(ctx context.Context, info sqlair.QueryInfo) {
+//  	log.Printf("%s took %s for %d rows", info.Statement, info.Duration, info.Rows)
+//  })
+//
+// Queries are finished once all their rows have been read. Queries that are
+// populated from the result cache don't execute a statement, so the hook
+// isn't called for them.
+func (q *Querier) OnQuery(hook QueryHook) {
+	q.queryHook = hook
+}
+
+// observe calls the query hook, if there is one, with the information of the
+// statement.
+func (h QueryHook) observe(ctx context.Context, stmt string, start time.Time, rows int64, err error) {
+	if h == nil {
+		return
+	}
+	h(ctx, QueryInfo{
+		Statement: stmt,
+		Duration:  time.Since(start),
+		Rows:      rows,
+		Err:       err,
+	})
+}
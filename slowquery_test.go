@@ -0,0 +1,129 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowQueryHookBelowThreshold(t *testing.T) {
+	var called bool
+	hook := slowQueryHook{
+		threshold: time.Hour,
+		hook: func(QueryInfo) {
+			called = true
+		},
+	}
+	hook.observe("SELECT 1;", time.Now())
+	assert.False(t, called)
+}
+
+func TestSlowQueryHookAboveThreshold(t *testing.T) {
+	var info QueryInfo
+	hook := slowQueryHook{
+		threshold: time.Millisecond,
+		hook: func(i QueryInfo) {
+			info = i
+		},
+	}
+	hook.observe("SELECT 1;", time.Now().Add(-time.Second))
+	assert.Equal(t, info.Statement, "SELECT 1;")
+	assert.True(t, info.Duration >= time.Second)
+}
+
+func TestOnSlowQuery(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name TEXT,
+	age  INTEGER
+);
+INSERT INTO test(name, age) values ("fred", 21), ("frank", 42);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	var infos []QueryInfo
+
+	querier := NewQuerier()
+	querier.OnSlowQuery(0, func(info QueryInfo) {
+		infos = append(infos, info)
+	})
+
+	var persons []Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := querier.Exec(tx, "UPDATE test SET age=age+1;"); err != nil {
+			return err
+		}
+
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person} FROM test;`)
+	})
+
+	assert.Len(t, persons, 2)
+	assert.Len(t, infos, 2)
+	assert.Equal(t, infos[0].Statement, "UPDATE test SET age=age+1;")
+	assert.Equal(t, infos[1].Statement, "SELECT test.age, test.name FROM test;")
+	for _, info := range infos {
+		assert.True(t, info.Duration > 0)
+	}
+}
+
+func TestOnQuery(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	type requestKey struct{}
+
+	var (
+		infos    []QueryInfo
+		requests []interface{}
+	)
+
+	querier := NewQuerier()
+	querier.OnQuery(func(ctx context.Context, info QueryInfo) {
+		infos = append(infos, info)
+		requests = append(requests, ctx.Value(requestKey{}))
+	})
+
+	ctx := context.WithValue(context.Background(), requestKey{}, "request-1")
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := querier.ExecContext(ctx, tx, "UPDATE people SET age=age+1 WHERE age<30;"); err != nil {
+			return err
+		}
+
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		if err := getter.QueryContext(ctx, tx, `SELECT {argsPerson} FROM people;`); err != nil {
+			return err
+		}
+
+		err = getter.Query(tx, `SELECT {argsPerson} FROM missing;`)
+		assert.NotNil(t, err)
+		return nil
+	})
+
+	assert.Len(t, persons, 3)
+	assert.Len(t, infos, 3)
+	assert.Equal(t, infos[0].Statement, "UPDATE people SET age=age+1 WHERE age<30;")
+	assert.Equal(t, infos[0].Rows, int64(2))
+	assert.Nil(t, infos[0].Err)
+	assert.Equal(t, infos[1].Statement, "SELECT age, name FROM people;")
+	assert.Equal(t, infos[1].Rows, int64(3))
+	assert.Nil(t, infos[1].Err)
+	assert.Equal(t, infos[2].Statement, "SELECT age, name FROM missing;")
+	assert.NotNil(t, infos[2].Err)
+	assert.Equal(t, requests, []interface{}{"request-1", "request-1", nil})
+}
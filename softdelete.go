@@ -0,0 +1,365 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// ExcludeDeleted sets whether queries exclude the rows that have been soft
+// deleted. A struct is soft deleted by setting the field tagged with the
+// softdelete option, which is NULL for rows that haven't been deleted:
+//
+//  type Person struct {
+//  	ID        int64      `db:"id,pk"`
+//  	Name      string     `db:"name"`
+//  	DeletedAt *time.Time `db:"deleted_at,softdelete"`
+//  }
+//
+// When enabled, a SELECT statement with a record expression of a soft
+// deleted struct gains a predicate in its WHERE clause, so that
+//
+//  SELECT {Person} FROM people WHERE name=:name;
+//
+// becomes
+//
+//  SELECT {Person} FROM people WHERE (name=:name) AND people.deleted_at IS NULL;
+//
+// The column is qualified with the table of the record expression if it has
+// one ({p.* INTO Person}), and otherwise with the alias of the table of the
+// struct in the FROM clause, so that it isn't ambiguous within joins. The
+// predicate is placed before any trailing comment of the statement. Queries
+// can include the deleted rows with the
+// IncludeDeleted option. Statements that are prepared with Prepare always
+// exclude the deleted rows.
+func (q *Querier) ExcludeDeleted(enabled bool) {
+	q.excludeDeleted = enabled
+}
+
+// IncludeDeleted returns an option that includes the rows that have been
+// soft deleted, when the querier excludes them. See Querier.ExcludeDeleted.
+func IncludeDeleted() QueryOption {
+	return func(o *queryOptions) {
+		o.includeDeleted = true
+	}
+}
+
+// softDeleteField returns the field of the struct that's tagged with the
+// softdelete option.
+func softDeleteField(refStruct sreflect.ReflectStruct) (string, sreflect.ReflectField, bool) {
+	for _, name := range refStruct.DeclaredFieldNames() {
+		if field := refStruct.Fields[name]; field.Tag.SoftDelete {
+			return name, field, true
+		}
+	}
+	return "", sreflect.ReflectField{}, false
+}
+
+// excludeDeletedStatement returns the statement with the predicates that
+// exclude the soft deleted rows of the entities of the query.
+func (q Query) excludeDeletedStatement(stmt string) string {
+	var entities []sreflect.ReflectStruct
+	for _, entity := range q.entities {
+		switch entity := entity.(type) {
+		case sreflect.ReflectStruct:
+			entities = append(entities, entity)
+		case sreflect.ReflectValue:
+			if entity.Value.Kind() != reflect.Slice {
+				continue
+			}
+			elem := entity.Value.Type().Elem()
+			for elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			if elem.Kind() != reflect.Struct {
+				continue
+			}
+			info, err := q.reflect.Reflect(reflect.New(elem).Interface())
+			if err != nil {
+				continue
+			}
+			entities = append(entities, info.(sreflect.ReflectStruct))
+		}
+	}
+	return excludeDeleted(q.reflect.Config(), stmt, entities)
+}
+
+// excludeDeleted adds a predicate to the WHERE clause of a SELECT statement
+// for every record expression of a soft deleted entity. Statements that
+// can't be parsed are returned untouched, so that the error is reported when
+// the statement is compiled.
+func excludeDeleted(config sreflect.Config, stmt string, entities []sreflect.ReflectStruct) string {
+	if !isSingleSelect(stmt) {
+		return stmt
+	}
+	offset := indexOfRecordArgs(stmt)
+	if offset < 0 {
+		return stmt
+	}
+	records, err := parseRecords(stmt, offset)
+	if err != nil {
+		return stmt
+	}
+	if entities, err = aliasEntities(records, entities); err != nil {
+		return stmt
+	}
+
+	var predicates []string
+	seen := make(map[string]bool)
+	for _, record := range records {
+		// Records of subqueries are left to the subqueries.
+		if record.depth > 0 || record.table {
+			continue
+		}
+		for _, entity := range entities {
+			if entity.Name != record.entityName() {
+				continue
+			}
+			column, _, ok := softDeleteField(entity)
+			if !ok {
+				break
+			}
+			if record.prefix != "" {
+				column = record.qualifier + "." + column
+			} else if alias := tableAlias(stmt, tableName(config, entity)); alias != "" {
+				column = alias + "." + column
+			}
+			if predicate := column + " IS NULL"; !seen[predicate] {
+				seen[predicate] = true
+				predicates = append(predicates, predicate)
+			}
+			break
+		}
+	}
+	if len(predicates) == 0 {
+		return stmt
+	}
+	return addSelectPredicate(stmt, strings.Join(predicates, " AND "))
+}
+
+// tableAlias returns the name that the table is referenced by in the FROM
+// clause of the statement, which is its alias if it has one, or an empty
+// string if the table isn't referenced.
+func tableAlias(stmt, table string) string {
+	for _, keyword := range clauseKeywords(stmt) {
+		if keyword.word != "FROM" && keyword.word != "JOIN" {
+			continue
+		}
+		// The tables of a FROM clause are separated by commas.
+		for i := keyword.end; ; {
+			i = skipSpace(stmt, i)
+			parts, _, next := scanName(stmt, i)
+			if len(parts) == 0 {
+				break
+			}
+			name := stmt[i:next]
+
+			i = skipSpace(stmt, next)
+			alias, end := nextWord(stmt, i)
+			if strings.EqualFold(alias, "AS") {
+				i = skipSpace(stmt, end)
+				alias, end = nextWord(stmt, i)
+			}
+			if alias != "" && !isFromKeyword(strings.ToUpper(alias)) {
+				i = skipSpace(stmt, end)
+			} else {
+				alias = ""
+			}
+			if strings.EqualFold(parts[len(parts)-1], table) {
+				if alias != "" {
+					return alias
+				}
+				return name
+			}
+			if keyword.word != "FROM" || i >= len(stmt) || stmt[i] != ',' {
+				break
+			}
+			i++
+		}
+	}
+	return ""
+}
+
+// nextWord returns the word at the offset, along with the offset directly
+// after it.
+func nextWord(stmt string, offset int) (string, int) {
+	end := offset
+	for end < len(stmt) && (isKeywordChar(stmt[end]) || isDigit(stmt[end])) {
+		end++
+	}
+	return stmt[offset:end], end
+}
+
+func skipSpace(stmt string, offset int) int {
+	for offset < len(stmt) && strings.IndexByte(" \t\r\n", stmt[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// isFromKeyword returns true if the keyword can follow a table of a FROM
+// clause, rather than being its alias.
+func isFromKeyword(word string) bool {
+	switch word {
+	case "ON", "USING", "WHERE", "JOIN", "LEFT", "RIGHT", "INNER", "OUTER", "CROSS", "FULL", "NATURAL",
+		"GROUP", "HAVING", "WINDOW", "ORDER", "LIMIT", "OFFSET", "FETCH", "FOR", "UNION", "INTERSECT", "EXCEPT":
+		return true
+	}
+	return false
+}
+
+// addSelectPredicate adds the predicate to the WHERE clause of the SELECT
+// statement, adding a WHERE clause if there isn't one. The predicate is placed
+// before any trailing comments and semicolons, so that a line comment doesn't
+// swallow it.
+func addSelectPredicate(stmt, predicate string) string {
+	end := contentEnd(stmt)
+
+	var from, where *keywordOffset
+	whereEnd := end
+	keywords := clauseKeywords(stmt[:end])
+	for i := range keywords {
+		keyword := &keywords[i]
+		switch keyword.word {
+		case "FROM":
+			if from == nil {
+				from = keyword
+			}
+		case "WHERE":
+			if from != nil && where == nil {
+				where = keyword
+			}
+		case "GROUP", "HAVING", "WINDOW", "ORDER", "LIMIT", "OFFSET", "FETCH", "FOR",
+			"UNION", "INTERSECT", "EXCEPT":
+			if from != nil {
+				whereEnd = min(whereEnd, keyword.start)
+			}
+		}
+		if whereEnd < end {
+			break
+		}
+	}
+	if from == nil {
+		return stmt
+	}
+
+	// Any comments at the end of the WHERE clause are kept after the
+	// predicate.
+	clauseEnd := contentEnd(stmt[:whereEnd])
+
+	var builder strings.Builder
+	if where != nil {
+		builder.WriteString(stmt[:where.end])
+		fmt.Fprintf(&builder, " (%s) AND %s", strings.TrimSpace(stmt[where.end:clauseEnd]), predicate)
+	} else {
+		builder.WriteString(stmt[:clauseEnd])
+		builder.WriteString(" WHERE " + predicate)
+	}
+	if whereEnd < end && strings.TrimSpace(stmt[clauseEnd:whereEnd]) == "" {
+		builder.WriteString(" " + stmt[whereEnd:])
+	} else {
+		builder.WriteString(trailingText(stmt[clauseEnd:]))
+	}
+	return builder.String()
+}
+
+// trailingText returns the text that follows a clause added to the end of a
+// statement, placing any comment on a line of its own.
+func trailingText(text string) string {
+	if comment := strings.TrimLeft(text, " \t"); strings.HasPrefix(comment, "--") || strings.HasPrefix(comment, "/*") {
+		return "\n" + comment
+	}
+	return text
+}
+
+// contentEnd returns the end of the statement without any trailing white
+// space, semicolons or comments.
+func contentEnd(stmt string) int {
+	var end int
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(stmt, i, c)
+			end = i
+		case strings.HasPrefix(stmt[i:], "--"):
+			i = skipUntil(stmt, i, "\n")
+		case strings.HasPrefix(stmt[i:], "/*"):
+			i = skipUntil(stmt, i, "*/")
+		case c == ';' || c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		default:
+			i++
+			end = i
+		}
+	}
+	return end
+}
+
+// Delete deletes the row of the struct from its table, identified by its
+// primary key. See Get for how the table and primary key are found.
+//
+//  err := querier.Delete(tx, &person)
+//
+// If the struct has a soft delete field (db:"deleted_at,softdelete") the row
+// is soft deleted instead: the statement is an UPDATE that sets the column to
+// the current time, and the field is set if the struct was passed by
+// pointer. See Querier.ExcludeDeleted.
+func (q *Querier) Delete(tx *sql.Tx, value interface{}) error {
+	return q.DeleteContext(context.Background(), tx, value)
+}
+
+// DeleteContext deletes the row of the struct from its table. See Delete.
+func (q *Querier) DeleteContext(ctx context.Context, tx *sql.Tx, value interface{}) error {
+	table, refStruct, err := q.reflectTable(value)
+	if err != nil {
+		return err
+	}
+
+	var where []string
+	for _, name := range primaryKeyColumns(refStruct) {
+		where = append(where, fmt.Sprintf("%s = :%s", name, name))
+	}
+	if len(where) == 0 {
+		return errors.Errorf("no primary key for table %q, expected fields with the pk tag option", table)
+	}
+
+	column, field, ok := softDeleteField(refStruct)
+	if !ok {
+		stmt := fmt.Sprintf("DELETE FROM %s WHERE %s;", table, strings.Join(where, " AND "))
+		_, err := q.ExecContext(ctx, tx, stmt, value)
+		return err
+	}
+
+	now := time.Now().UTC()
+	stmt := fmt.Sprintf("UPDATE %s SET %s = :%s WHERE %s;", table, column, column, strings.Join(where, " AND "))
+	if _, err := q.ExecContext(ctx, tx, stmt, value, sql.Named(column, now)); err != nil {
+		return err
+	}
+
+	if dest := reflect.ValueOf(value); dest.Kind() == reflect.Ptr && !dest.IsNil() {
+		setDeletedAt(sreflect.FieldByIndex(dest.Elem(), field.Index), now)
+	}
+	return nil
+}
+
+// setDeletedAt sets the soft delete field to the time.
+func setDeletedAt(field reflect.Value, now time.Time) {
+	if !field.IsValid() || !field.CanSet() {
+		return
+	}
+	switch field.Type() {
+	case timeType:
+		field.Set(reflect.ValueOf(now))
+	case reflect.PtrTo(timeType):
+		field.Set(reflect.ValueOf(&now))
+	case reflect.TypeOf(sql.NullTime{}):
+		field.Set(reflect.ValueOf(sql.NullTime{Time: now, Valid: true}))
+	}
+}
@@ -0,0 +1,164 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type softPerson struct {
+	ID        int64      `db:"id,pk"`
+	Name      string     `db:"name"`
+	DeletedAt *time.Time `db:"deleted_at,softdelete"`
+}
+
+func (softPerson) TableName() string {
+	return "people"
+}
+
+func TestExcludeDeletedStatement(t *testing.T) {
+	entity, err := NewQuerier().reflect.Reflect(&softPerson{})
+	assert.Nil(t, err)
+	entities := []sreflect.ReflectStruct{entity.(sreflect.ReflectStruct), entity.(sreflect.ReflectStruct)}
+
+	tests := []struct {
+		stmt     string
+		expected string
+	}{{
+		stmt:     `SELECT {softPerson} FROM people;`,
+		expected: `SELECT {softPerson} FROM people WHERE people.deleted_at IS NULL;`,
+	}, {
+		stmt:     `SELECT {softPerson} FROM people WHERE id=:id OR name=:name ORDER BY name LIMIT 10;`,
+		expected: `SELECT {softPerson} FROM people WHERE (id=:id OR name=:name) AND people.deleted_at IS NULL ORDER BY name LIMIT 10;`,
+	}, {
+		stmt:     "SELECT {softPerson} FROM people -- all people\n;",
+		expected: "SELECT {softPerson} FROM people WHERE people.deleted_at IS NULL\n-- all people\n;",
+	}, {
+		stmt:     "SELECT {softPerson} FROM people WHERE name=:name -- by name\nORDER BY name;",
+		expected: "SELECT {softPerson} FROM people WHERE (name=:name) AND people.deleted_at IS NULL\n-- by name\nORDER BY name;",
+	}, {
+		stmt:     `SELECT {softPerson}, {l.* INTO location} FROM location AS l JOIN people p ON p.location=l.id /* joined */;`,
+		expected: "SELECT {softPerson}, {l.* INTO location} FROM location AS l JOIN people p ON p.location=l.id WHERE p.deleted_at IS NULL\n/* joined */;",
+	}, {
+		stmt:     `SELECT {p.* INTO softPerson} FROM people AS p GROUP BY p.name`,
+		expected: `SELECT {p.* INTO softPerson} FROM people AS p WHERE p.deleted_at IS NULL GROUP BY p.name`,
+	}, {
+		stmt:     `SELECT {a.* INTO softPerson AS A}, {b.* INTO softPerson AS B} FROM people AS a JOIN people AS b ON a.id=b.id WHERE a.id IN (SELECT id FROM people WHERE name='x');`,
+		expected: `SELECT {a.* INTO softPerson AS A}, {b.* INTO softPerson AS B} FROM people AS a JOIN people AS b ON a.id=b.id WHERE (a.id IN (SELECT id FROM people WHERE name='x')) AND a.deleted_at IS NULL AND b.deleted_at IS NULL;`,
+	}, {
+		stmt:     `SELECT name FROM people;`,
+		expected: `SELECT name FROM people;`,
+	}, {
+		stmt:     `UPDATE people SET name=:name;`,
+		expected: `UPDATE people SET name=:name;`,
+	}}
+	for i, test := range tests {
+		t.Logf("test %d: %s", i, test.stmt)
+		assert.Equal(t, excludeDeleted(sreflect.DefaultConfig(), test.stmt, entities), test.expected)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, deleted_at DATETIME);
+INSERT INTO people (id, name) VALUES (1, "fred"), (2, "frank");
+`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+	querier.ExcludeDeleted(true)
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		person := softPerson{ID: 1, Name: "fred"}
+		err := querier.Delete(tx, &person)
+		assert.Nil(t, err)
+		assert.NotNil(t, person.DeletedAt)
+
+		var persons []softPerson
+		query, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+		err = query.Query(tx, `SELECT {softPerson} FROM people ORDER BY id;`)
+		assert.Nil(t, err)
+		assert.Equal(t, persons, []softPerson{{ID: 2, Name: "frank"}})
+
+		persons = nil
+		query, err = querier.ForMany(&persons, IncludeDeleted())
+		assert.Nil(t, err)
+		err = query.Query(tx, `SELECT {softPerson} FROM people ORDER BY id;`)
+		assert.Nil(t, err)
+		assert.Len(t, persons, 2)
+
+		var got softPerson
+		err = querier.Get(tx, &got, 1)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+
+		ok, err := querier.Exists(tx, &softPerson{}, 1)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+
+		stmt, err := querier.Prepare(`SELECT {softPerson} FROM people;`, softPerson{})
+		assert.Nil(t, err)
+		persons = nil
+		err = stmt.Query(tx, &persons)
+		assert.Nil(t, err)
+		assert.Len(t, persons, 1)
+
+		// Rows are hard deleted by structs without a soft delete field.
+		err = querier.Delete(tx, &pkPerson{ID: 2})
+		assert.Nil(t, err)
+
+		var count int
+		err = tx.QueryRow(`SELECT COUNT(*) FROM people;`).Scan(&count)
+		assert.Nil(t, err)
+		assert.Equal(t, count, 1)
+		return nil
+	})
+}
+
+func TestExcludeDeletedWithCommentsAndJoins(t *testing.T) {
+	type softPet struct {
+		ID        int64      `db:"id,pk"`
+		OwnerID   int64      `db:"owner_id"`
+		DeletedAt *time.Time `db:"deleted_at,softdelete"`
+	}
+
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, deleted_at DATETIME);
+CREATE TABLE soft_pet (id INTEGER PRIMARY KEY, owner_id INTEGER, deleted_at DATETIME);
+INSERT INTO people (id, name, deleted_at) VALUES (1, "fred", NULL), (2, "frank", NULL), (3, "jane", "2020-01-01 00:00:00");
+INSERT INTO soft_pet (id, owner_id) VALUES (1, 1), (2, 3);
+`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+	querier.ExcludeDeleted(true)
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		var persons []softPerson
+		query, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+		err = query.Query(tx, "SELECT {softPerson} FROM people -- all people\n;")
+		assert.Nil(t, err)
+		assert.Len(t, persons, 2)
+
+		// The deleted_at columns of the tables are told apart by the alias of
+		// the table.
+		persons = nil
+		var pets []softPet
+		query, err = querier.ForMany(&persons, &pets)
+		assert.Nil(t, err)
+		err = query.Query(tx, `SELECT {name INTO softPerson}, {pet.* INTO softPet} FROM people AS person JOIN soft_pet AS pet ON pet.owner_id=person.id;`)
+		assert.Nil(t, err)
+		assert.Equal(t, persons, []softPerson{{Name: "fred"}})
+		assert.Equal(t, pets, []softPet{{ID: 1, OwnerID: 1}})
+		return nil
+	})
+}
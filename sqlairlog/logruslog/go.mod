@@ -0,0 +1,15 @@
+module github.com/SimonRichardson/sqlair/sqlairlog/logruslog
+
+go 1.23
+
+require (
+	github.com/SimonRichardson/sqlair v0.1.0
+	github.com/pkg/errors v0.9.1
+	github.com/sirupsen/logrus v1.10.2
+	github.com/stretchr/testify v1.12.1
+)
+
+require (
+	go.yaml.in/yaml/v3 v3.0.5 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+)
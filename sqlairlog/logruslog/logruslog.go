@@ -0,0 +1,47 @@
+package logruslog
+
+import (
+	"context"
+
+	"github.com/SimonRichardson/sqlair"
+	"github.com/SimonRichardson/sqlair/sqlairlog"
+	"github.com/sirupsen/logrus"
+)
+
+// New returns a query hook that logs every statement to the logrus logger.
+// The context of the statement is added to the entry, so that logrus hooks
+// can read request values from it.
+//
+//  querier.OnQuery(logruslog.New(logrus.StandardLogger(), sqlairlog.WithErrorLevel(sqlairlog.LevelWarn)))
+//
+func New(logger *logrus.Logger, opts ...sqlairlog.Option) sqlair.QueryHook {
+	return sqlairlog.NewHook(logrusLogger{logger: logger}, opts...)
+}
+
+type logrusLogger struct {
+	logger *logrus.Logger
+}
+
+// Log implements sqlairlog.Logger.
+func (l logrusLogger) Log(ctx context.Context, level sqlairlog.Level, info sqlair.QueryInfo) {
+	logrusLevel := logrusLevels[level]
+	if !l.logger.IsLevelEnabled(logrusLevel) {
+		return
+	}
+	fields := logrus.Fields{
+		"statement": info.Statement,
+		"duration":  info.Duration,
+		"rows":      info.Rows,
+	}
+	if info.Err != nil {
+		fields[logrus.ErrorKey] = info.Err
+	}
+	l.logger.WithContext(ctx).WithFields(fields).Log(logrusLevel, sqlairlog.Message)
+}
+
+var logrusLevels = map[sqlairlog.Level]logrus.Level{
+	sqlairlog.LevelDebug: logrus.DebugLevel,
+	sqlairlog.LevelInfo:  logrus.InfoLevel,
+	sqlairlog.LevelWarn:  logrus.WarnLevel,
+	sqlairlog.LevelError: logrus.ErrorLevel,
+}
@@ -0,0 +1,60 @@
+package logruslog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/sqlair"
+	"github.com/SimonRichardson/sqlair/sqlairlog"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+type requestKey struct{}
+
+func TestNew(t *testing.T) {
+	logger, entries := test.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	hook := New(logger, sqlairlog.WithLevel(sqlairlog.LevelInfo))
+
+	ctx := context.WithValue(context.Background(), requestKey{}, "request-1")
+	hook(ctx, sqlair.QueryInfo{
+		Statement: "SELECT name FROM people;",
+		Duration:  time.Millisecond,
+		Rows:      3,
+	})
+	hook(ctx, sqlair.QueryInfo{
+		Statement: "DELETE FROM people;",
+		Err:       errors.New("boom"),
+	})
+
+	all := entries.AllEntries()
+	assert.Len(t, all, 2)
+
+	assert.Equal(t, all[0].Level, logrus.InfoLevel)
+	assert.Equal(t, all[0].Message, sqlairlog.Message)
+	assert.Equal(t, all[0].Data, logrus.Fields{
+		"statement": "SELECT name FROM people;",
+		"duration":  time.Millisecond,
+		"rows":      int64(3),
+	})
+	assert.Equal(t, all[0].Context.Value(requestKey{}), "request-1")
+
+	assert.Equal(t, all[1].Level, logrus.ErrorLevel)
+	assert.Equal(t, all[1].Data[logrus.ErrorKey].(error).Error(), "boom")
+}
+
+func TestNewDisabledLevel(t *testing.T) {
+	logger, entries := test.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	hook := New(logger)
+
+	// Successful statements are logged at debug by default.
+	hook(context.Background(), sqlair.QueryInfo{
+		Statement: "SELECT name FROM people;",
+	})
+	assert.Len(t, entries.AllEntries(), 0)
+}
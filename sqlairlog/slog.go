@@ -0,0 +1,46 @@
+//go:build go1.21
+
+package sqlairlog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SimonRichardson/sqlair"
+)
+
+// Slog returns a query hook that logs every statement to the slog logger.
+//
+//  querier.OnQuery(sqlairlog.Slog(slog.Default(), sqlairlog.WithLevel(sqlairlog.LevelInfo)))
+//
+func Slog(logger *slog.Logger, opts ...Option) sqlair.QueryHook {
+	return NewHook(slogLogger{logger: logger}, opts...)
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// Log implements Logger.
+func (l slogLogger) Log(ctx context.Context, level Level, info sqlair.QueryInfo) {
+	slogLevel := slogLevels[level]
+	if !l.logger.Enabled(ctx, slogLevel) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("statement", info.Statement),
+		slog.Duration("duration", info.Duration),
+		slog.Int64("rows", info.Rows),
+	}
+	if info.Err != nil {
+		attrs = append(attrs, slog.String("error", info.Err.Error()))
+	}
+	l.logger.LogAttrs(ctx, slogLevel, Message, attrs...)
+}
+
+var slogLevels = map[Level]slog.Level{
+	LevelDebug: slog.LevelDebug,
+	LevelInfo:  slog.LevelInfo,
+	LevelWarn:  slog.LevelWarn,
+	LevelError: slog.LevelError,
+}
@@ -0,0 +1,37 @@
+//go:build go1.21
+
+package sqlairlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/sqlair"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+	hook := Slog(logger, WithLevel(LevelInfo))
+
+	ctx := context.Background()
+	hook(ctx, sqlair.QueryInfo{Statement: "SELECT name FROM people;", Duration: time.Millisecond, Rows: 3})
+	hook(ctx, sqlair.QueryInfo{Statement: "DELETE FROM people;", Err: errors.New("boom")})
+
+	assert.Equal(t, buf.String(), `level=INFO msg="sqlair statement" statement="SELECT name FROM people;" duration=1ms rows=3
+level=ERROR msg="sqlair statement" statement="DELETE FROM people;" duration=0s rows=0 error=boom
+`)
+}
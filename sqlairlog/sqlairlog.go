@@ -0,0 +1,147 @@
+package sqlairlog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SimonRichardson/sqlair"
+)
+
+// Message is the message that every statement is logged with. The statement
+// itself, along with its duration, rows and error, are fields of the message.
+const Message = "sqlair statement"
+
+// Level is the level that a statement is logged at, which adapters map to
+// the levels of their logging package.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Logger logs the information of a finished statement at a level. The
+// adapters of the logging packages implement Logger, and NewHook turns a
+// Logger into a query hook:
+//
+//  querier.OnQuery(sqlairlog.NewHook(logger, sqlairlog.WithSlowQuery(time.Second, sqlairlog.LevelWarn)))
+//
+type Logger interface {
+	Log(ctx context.Context, level Level, info sqlair.QueryInfo)
+}
+
+// Option configures the levels and the sampling of a hook.
+type Option func(*options)
+
+type options struct {
+	level         Level
+	errorLevel    Level
+	slowLevel     Level
+	slowThreshold time.Duration
+	sampler       *sampler
+}
+
+// WithLevel returns an option that logs successful statements at the level.
+// Successful statements are logged at LevelDebug by default.
+func WithLevel(level Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithErrorLevel returns an option that logs failed statements at the level.
+// Failed statements are logged at LevelError by default.
+func WithErrorLevel(level Level) Option {
+	return func(o *options) {
+		o.errorLevel = level
+	}
+}
+
+// WithSlowQuery returns an option that logs successful statements that take
+// longer than the threshold at the level.
+func WithSlowQuery(threshold time.Duration, level Level) Option {
+	return func(o *options) {
+		o.slowThreshold = threshold
+		o.slowLevel = level
+	}
+}
+
+// WithSampling returns an option that samples high-volume statements. Within
+// every tick, the first statements of each kind are logged, after which only
+// every thereafter statement is logged. Statements are of the same kind if
+// their text is the same. Failed and slow statements are never sampled.
+//
+//  sqlairlog.WithSampling(time.Second, 10, 100)
+//
+func WithSampling(tick time.Duration, first, thereafter int) Option {
+	return func(o *options) {
+		o.sampler = newSampler(tick, first, thereafter, time.Now)
+	}
+}
+
+// NewHook returns a query hook that logs every statement to the logger, at
+// the levels of the options.
+func NewHook(logger Logger, opts ...Option) sqlair.QueryHook {
+	o := options{
+		level:      LevelDebug,
+		errorLevel: LevelError,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(ctx context.Context, info sqlair.QueryInfo) {
+		switch {
+		case info.Err != nil:
+			logger.Log(ctx, o.errorLevel, info)
+		case o.slowThreshold > 0 && info.Duration > o.slowThreshold:
+			logger.Log(ctx, o.slowLevel, info)
+		case o.sampler == nil || o.sampler.sample(info.Statement):
+			logger.Log(ctx, o.level, info)
+		}
+	}
+}
+
+// sampler counts the statements of each kind within a tick.
+type sampler struct {
+	mutex      sync.Mutex
+	tick       time.Duration
+	first      int
+	thereafter int
+	now        func() time.Time
+	reset      time.Time
+	counts     map[string]int
+}
+
+func newSampler(tick time.Duration, first, thereafter int, now func() time.Time) *sampler {
+	return &sampler{
+		tick:       tick,
+		first:      first,
+		thereafter: thereafter,
+		now:        now,
+		counts:     make(map[string]int),
+	}
+}
+
+// sample returns true if the statement should be logged.
+func (s *sampler) sample(stmt string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// The counts are reset every tick, so that the statements that are no
+	// longer executed don't hold on to their counts.
+	if now := s.now(); !now.Before(s.reset) {
+		s.counts = make(map[string]int)
+		s.reset = now.Add(s.tick)
+	}
+
+	s.counts[stmt]++
+	count := s.counts[stmt]
+	if count <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (count-s.first)%s.thereafter == 0
+}
@@ -0,0 +1,95 @@
+package sqlairlog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/sqlair"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type logged struct {
+	level     Level
+	statement string
+}
+
+type recordLogger struct {
+	logged []logged
+}
+
+func (l *recordLogger) Log(ctx context.Context, level Level, info sqlair.QueryInfo) {
+	l.logged = append(l.logged, logged{
+		level:     level,
+		statement: info.Statement,
+	})
+}
+
+func TestNewHookLevels(t *testing.T) {
+	logger := new(recordLogger)
+	hook := NewHook(logger,
+		WithLevel(LevelInfo),
+		WithErrorLevel(LevelWarn),
+		WithSlowQuery(time.Second, LevelError),
+	)
+
+	ctx := context.Background()
+	hook(ctx, sqlair.QueryInfo{Statement: "fast", Duration: time.Millisecond})
+	hook(ctx, sqlair.QueryInfo{Statement: "slow", Duration: time.Minute})
+	hook(ctx, sqlair.QueryInfo{Statement: "failed", Err: errors.New("boom")})
+
+	assert.Equal(t, logger.logged, []logged{
+		{level: LevelInfo, statement: "fast"},
+		{level: LevelError, statement: "slow"},
+		{level: LevelWarn, statement: "failed"},
+	})
+}
+
+func TestNewHookDefaultLevels(t *testing.T) {
+	logger := new(recordLogger)
+	hook := NewHook(logger)
+
+	ctx := context.Background()
+	hook(ctx, sqlair.QueryInfo{Statement: "fast", Duration: time.Minute})
+	hook(ctx, sqlair.QueryInfo{Statement: "failed", Err: errors.New("boom")})
+
+	assert.Equal(t, logger.logged, []logged{
+		{level: LevelDebug, statement: "fast"},
+		{level: LevelError, statement: "failed"},
+	})
+}
+
+func TestSampler(t *testing.T) {
+	now := time.Now()
+	s := newSampler(time.Second, 2, 3, func() time.Time { return now })
+
+	var sampled []bool
+	for i := 0; i < 8; i++ {
+		sampled = append(sampled, s.sample("SELECT 1;"))
+	}
+	assert.Equal(t, sampled, []bool{true, true, false, false, true, false, false, true})
+
+	// Statements are counted separately.
+	assert.True(t, s.sample("SELECT 2;"))
+
+	// The counts are reset every tick.
+	now = now.Add(time.Second)
+	assert.True(t, s.sample("SELECT 1;"))
+}
+
+func TestNewHookSampling(t *testing.T) {
+	logger := new(recordLogger)
+	hook := NewHook(logger, WithSampling(time.Hour, 1, 0))
+
+	ctx := context.Background()
+	hook(ctx, sqlair.QueryInfo{Statement: "sampled"})
+	hook(ctx, sqlair.QueryInfo{Statement: "sampled"})
+	// Failed statements are never sampled.
+	hook(ctx, sqlair.QueryInfo{Statement: "sampled", Err: errors.New("boom")})
+
+	assert.Equal(t, logger.logged, []logged{
+		{level: LevelDebug, statement: "sampled"},
+		{level: LevelError, statement: "sampled"},
+	})
+}
@@ -0,0 +1,17 @@
+module github.com/SimonRichardson/sqlair/sqlairlog/zaplog
+
+go 1.23
+
+require (
+	github.com/SimonRichardson/sqlair v0.1.0
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.8.1
+	go.uber.org/zap v1.28.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
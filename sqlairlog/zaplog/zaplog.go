@@ -0,0 +1,46 @@
+package zaplog
+
+import (
+	"context"
+
+	"github.com/SimonRichardson/sqlair"
+	"github.com/SimonRichardson/sqlair/sqlairlog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New returns a query hook that logs every statement to the zap logger.
+//
+//  querier.OnQuery(zaplog.New(logger, sqlairlog.WithSampling(time.Second, 10, 100)))
+//
+func New(logger *zap.Logger, opts ...sqlairlog.Option) sqlair.QueryHook {
+	return sqlairlog.NewHook(zapLogger{logger: logger}, opts...)
+}
+
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// Log implements sqlairlog.Logger.
+func (l zapLogger) Log(ctx context.Context, level sqlairlog.Level, info sqlair.QueryInfo) {
+	entry := l.logger.Check(zapLevels[level], sqlairlog.Message)
+	if entry == nil {
+		return
+	}
+	fields := []zap.Field{
+		zap.String("statement", info.Statement),
+		zap.Duration("duration", info.Duration),
+		zap.Int64("rows", info.Rows),
+	}
+	if info.Err != nil {
+		fields = append(fields, zap.Error(info.Err))
+	}
+	entry.Write(fields...)
+}
+
+var zapLevels = map[sqlairlog.Level]zapcore.Level{
+	sqlairlog.LevelDebug: zapcore.DebugLevel,
+	sqlairlog.LevelInfo:  zapcore.InfoLevel,
+	sqlairlog.LevelWarn:  zapcore.WarnLevel,
+	sqlairlog.LevelError: zapcore.ErrorLevel,
+}
@@ -0,0 +1,55 @@
+package zaplog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/sqlair"
+	"github.com/SimonRichardson/sqlair/sqlairlog"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNew(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	hook := New(zap.New(core), sqlairlog.WithLevel(sqlairlog.LevelInfo))
+
+	hook(context.Background(), sqlair.QueryInfo{
+		Statement: "SELECT name FROM people;",
+		Duration:  time.Millisecond,
+		Rows:      3,
+	})
+	hook(context.Background(), sqlair.QueryInfo{
+		Statement: "DELETE FROM people;",
+		Err:       errors.New("boom"),
+	})
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 2)
+
+	assert.Equal(t, entries[0].Level, zapcore.InfoLevel)
+	assert.Equal(t, entries[0].Message, sqlairlog.Message)
+	assert.Equal(t, entries[0].ContextMap(), map[string]interface{}{
+		"statement": "SELECT name FROM people;",
+		"duration":  time.Millisecond,
+		"rows":      int64(3),
+	})
+
+	assert.Equal(t, entries[1].Level, zapcore.ErrorLevel)
+	assert.Equal(t, entries[1].ContextMap()["error"], "boom")
+}
+
+func TestNewDisabledLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	hook := New(zap.New(core))
+
+	// Successful statements are logged at debug by default.
+	hook(context.Background(), sqlair.QueryInfo{
+		Statement: "SELECT name FROM people;",
+	})
+	assert.Equal(t, logs.Len(), 0)
+}
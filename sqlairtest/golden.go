@@ -0,0 +1,76 @@
+package sqlairtest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SimonRichardson/sqlair"
+)
+
+var update = flag.Bool("sqlairtest.update", false, "update the golden files of the compiled statements")
+
+// GoldenStatement is a statement along with the values that it's compiled
+// for, which are the same as those given to ForOne.
+type GoldenStatement struct {
+	// Name names the golden file of the statement, which is the name with
+	// a .sql extension.
+	Name      string
+	Statement string
+	Values    []interface{}
+}
+
+// CheckGolden compiles every statement with the querier, comparing the
+// compiled statement to its golden file within the directory. The test fails
+// if a compiled statement differs from its golden file, so that a change to
+// the struct tags or to the record expansion shows up as a diff of the golden
+// files when it's reviewed.
+//
+//  func TestStatements(t *testing.T) {
+//  	sqlairtest.CheckGolden(t, sqlair.NewQuerier(), "testdata", sqlairtest.GoldenStatement{
+//  		Name:      "person_by_name",
+//  		Statement: `SELECT {Person} FROM people WHERE name=:name;`,
+//  		Values:    []interface{}{Person{}},
+//  	})
+//  }
+//
+// The golden files are written, rather than compared, when the tests are run
+// with the -sqlairtest.update flag:
+//
+//  go test ./... -sqlairtest.update
+//
+func CheckGolden(t testing.TB, querier *sqlair.Querier, dir string, stmts ...GoldenStatement) {
+	t.Helper()
+
+	for _, stmt := range stmts {
+		compiled, err := querier.Compile(stmt.Statement, stmt.Values...)
+		if err != nil {
+			t.Errorf("compiling %q: %v", stmt.Name, err)
+			continue
+		}
+
+		path := filepath.Join(dir, stmt.Name+".sql")
+		if *update {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatalf("creating golden directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(compiled+"\n"), 0644); err != nil {
+				t.Fatalf("writing golden file: %v", err)
+			}
+			continue
+		}
+
+		golden, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			t.Errorf("missing golden file %q for %q, run the tests with -sqlairtest.update to create it", path, stmt.Name)
+			continue
+		} else if err != nil {
+			t.Fatalf("reading golden file: %v", err)
+		}
+		if expected := strings.TrimSuffix(string(golden), "\n"); compiled != expected {
+			t.Errorf("compiled statement %q changed, run the tests with -sqlairtest.update to accept it\n got: %s\nwant: %s", stmt.Name, compiled, expected)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package sqlairtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SimonRichardson/sqlair"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTB records the failures of a test, rather than failing it.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+var personByName = GoldenStatement{
+	Name:      "person_by_name",
+	Statement: `SELECT {Person} FROM people WHERE name=:name;`,
+	Values:    []interface{}{Person{}},
+}
+
+func TestCheckGolden(t *testing.T) {
+	CheckGolden(t, sqlair.NewQuerier(), "testdata", personByName)
+}
+
+func TestCheckGoldenChanged(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "person_by_name.sql"), []byte("SELECT name FROM people WHERE name=:name;\n"), 0644)
+	assert.Nil(t, err)
+
+	recorder := &recordingTB{TB: t}
+	CheckGolden(recorder, sqlair.NewQuerier(), dir, personByName)
+	assert.Equal(t, recorder.failures, []string{
+		"compiled statement \"person_by_name\" changed, run the tests with -sqlairtest.update to accept it\n got: SELECT age, name FROM people WHERE name=:name;\nwant: SELECT name FROM people WHERE name=:name;",
+	})
+}
+
+func TestCheckGoldenMissing(t *testing.T) {
+	dir := t.TempDir()
+
+	recorder := &recordingTB{TB: t}
+	CheckGolden(recorder, sqlair.NewQuerier(), dir, personByName)
+	assert.Len(t, recorder.failures, 1)
+	assert.Contains(t, recorder.failures[0], "missing golden file")
+}
+
+func TestCheckGoldenUpdate(t *testing.T) {
+	*update = true
+	defer func() { *update = false }()
+
+	dir := filepath.Join(t.TempDir(), "golden")
+	CheckGolden(t, sqlair.NewQuerier(), dir, personByName)
+
+	golden, err := os.ReadFile(filepath.Join(dir, "person_by_name.sql"))
+	assert.Nil(t, err)
+	assert.Equal(t, string(golden), "SELECT age, name FROM people WHERE name=:name;\n")
+}
@@ -0,0 +1,428 @@
+package sqlairtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Mock is an in-memory database for testing code that uses a sqlair Querier,
+// without a real database. The Querier is used as it is, against the
+// database returned by DB, so the statements are compiled exactly as they
+// would be. Every compiled statement is recorded along with its bound
+// arguments, and answered with the results of the first expectation that
+// matches it.
+//
+//  mock := sqlairtest.New()
+//  mock.ExpectQuery(`SELECT .* FROM people`).
+//  	WithArgs(sql.Named("name", "fred")).
+//  	WillReturnRows([]string{"name", "age"}, []interface{}{"fred", 21})
+//
+//  db := mock.DB()
+//  ...
+//  if err := mock.ExpectationsWereMet(); err != nil {
+//  	t.Fatal(err)
+//  }
+//
+// Statements that don't match an expectation fail with an error.
+type Mock struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+	unexpected   []string
+	db           *sql.DB
+}
+
+// New creates a new Mock without any expectations.
+func New() *Mock {
+	m := &Mock{}
+	m.db = sql.OpenDB(connector{mock: m})
+	return m
+}
+
+// DB returns the database of the mock, which is shared by every call.
+func (m *Mock) DB() *sql.DB {
+	return m.db
+}
+
+// ExpectQuery adds an expectation of a query that returns rows, where the
+// pattern is a regular expression that matches the compiled statement.
+func (m *Mock) ExpectQuery(pattern string) *Expectation {
+	return m.expect(queryKind, pattern)
+}
+
+// ExpectExec adds an expectation of a statement that doesn't return rows,
+// where the pattern is a regular expression that matches the compiled
+// statement.
+func (m *Mock) ExpectExec(pattern string) *Expectation {
+	return m.expect(execKind, pattern)
+}
+
+func (m *Mock) expect(kind callKind, pattern string) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &Expectation{
+		kind:    kind,
+		pattern: regexp.MustCompile(pattern),
+		times:   1,
+	}
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// Calls returns every statement that has been executed, in order, including
+// those that didn't match an expectation.
+func (m *Mock) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Call(nil), m.calls...)
+}
+
+// Statements returns the compiled statements that have been executed, in
+// order.
+func (m *Mock) Statements() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stmts := make([]string, len(m.calls))
+	for i, call := range m.calls {
+		stmts[i] = call.Statement
+	}
+	return stmts
+}
+
+// ExpectationsWereMet returns an error if any expectation hasn't been
+// matched as many times as it was expected, or if any statement didn't match
+// an expectation.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var problems []string
+	for _, stmt := range m.unexpected {
+		problems = append(problems, fmt.Sprintf("unexpected statement %q", stmt))
+	}
+	for _, e := range m.expectations {
+		if e.times > 0 && e.calls < e.times {
+			problems = append(problems, fmt.Sprintf("expected %s matching %q %d times, got %d", e.kind, e.pattern, e.times, e.calls))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// match records the call, returning the first expectation that matches it.
+func (m *Mock) match(kind callKind, stmt string, args []driver.NamedValue) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	call := Call{
+		Statement: stmt,
+		Args:      make([]Arg, len(args)),
+	}
+	for i, arg := range args {
+		call.Args[i] = Arg{
+			Name:    arg.Name,
+			Ordinal: arg.Ordinal,
+			Value:   arg.Value,
+		}
+	}
+	m.calls = append(m.calls, call)
+
+	for _, e := range m.expectations {
+		if e.kind != kind || (e.times > 0 && e.calls >= e.times) {
+			continue
+		}
+		if !e.pattern.MatchString(stmt) || !e.matchArgs(call.Args) {
+			continue
+		}
+		e.calls++
+		return e, e.err
+	}
+	m.unexpected = append(m.unexpected, stmt)
+	return nil, errors.Errorf("unexpected %s %q", kind, stmt)
+}
+
+// Call is a statement that has been executed, along with its arguments.
+type Call struct {
+	Statement string
+	Args      []Arg
+}
+
+// Arg is an argument bound to a statement. Named arguments have a name,
+// while positional arguments only have their ordinal position, starting at
+// one.
+type Arg struct {
+	Name    string
+	Ordinal int
+	Value   interface{}
+}
+
+type callKind string
+
+const (
+	queryKind callKind = "query"
+	execKind  callKind = "exec"
+)
+
+// Expectation is a statement that the mock expects, along with the results
+// that it returns.
+type Expectation struct {
+	kind    callKind
+	pattern *regexp.Regexp
+	args    []interface{}
+	times   int
+	calls   int
+
+	columns []string
+	rows    [][]driver.Value
+	result  driver.Result
+	err     error
+}
+
+// WithArgs sets the arguments that the statement is expected to be bound
+// with, in order. A sql.NamedArg matches the argument with the same name,
+// and other values match the argument at the same position.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = args
+	return e
+}
+
+// Times sets the number of times that the expectation is matched, which is
+// once by default. Zero matches any number of times, including never.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// WillReturnRows sets the columns and the rows returned by a query.
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]interface{}) *Expectation {
+	e.columns = columns
+	e.rows = make([][]driver.Value, len(rows))
+	for i, row := range rows {
+		e.rows[i] = make([]driver.Value, len(row))
+		for j, value := range row {
+			e.rows[i][j] = driverValue(value)
+		}
+	}
+	return e
+}
+
+// WillReturnResult sets the result of a statement that doesn't return rows.
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.result = result{
+		lastInsertID: lastInsertID,
+		rowsAffected: rowsAffected,
+	}
+	return e
+}
+
+// WillReturnError sets the error returned by the statement.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) matchArgs(args []Arg) bool {
+	if e.args == nil {
+		return true
+	}
+	if len(e.args) != len(args) {
+		return false
+	}
+	for i, expected := range e.args {
+		if named, ok := expected.(sql.NamedArg); ok {
+			var found bool
+			for _, arg := range args {
+				if arg.Name == named.Name {
+					found = reflect.DeepEqual(arg.Value, driverValue(named.Value))
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(args[i].Value, driverValue(expected)) {
+			return false
+		}
+	}
+	return true
+}
+
+// driverValue converts the value in the same way as the arguments of a
+// statement, so that an int matches an int64 argument.
+func driverValue(value interface{}) driver.Value {
+	if converted, err := driver.DefaultParameterConverter.ConvertValue(value); err == nil {
+		return converted
+	}
+	return value
+}
+
+type result struct {
+	lastInsertID, rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type connector struct {
+	mock *Mock
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return conn{mock: c.mock}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return mockDriver{mock: c.mock}
+}
+
+type mockDriver struct {
+	mock *Mock
+}
+
+func (d mockDriver) Open(string) (driver.Conn, error) {
+	return conn{mock: d.mock}, nil
+}
+
+type conn struct {
+	mock *Mock
+}
+
+func (c conn) Prepare(query string) (driver.Stmt, error) {
+	return stmt{conn: c, query: query}, nil
+}
+
+func (c conn) Close() error {
+	return nil
+}
+
+func (c conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+func (c conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return tx{}, nil
+}
+
+// CheckNamedValue converts the arguments like the default converter, but
+// accepts the values that it can't convert, so that they're recorded as they
+// are.
+func (c conn) CheckNamedValue(arg *driver.NamedValue) error {
+	arg.Value = driverValue(arg.Value)
+	return nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.mock.match(queryKind, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{
+		columns: e.columns,
+		rows:    e.rows,
+	}, nil
+}
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.mock.match(execKind, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.result == nil {
+		return driver.ResultNoRows, nil
+	}
+	return e.result, nil
+}
+
+type stmt struct {
+	conn  conn
+	query string
+}
+
+func (s stmt) Close() error {
+	return nil
+}
+
+func (s stmt) NumInput() int {
+	return -1
+}
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{
+			Ordinal: i + 1,
+			Value:   arg,
+		}
+	}
+	return named
+}
+
+type tx struct{}
+
+func (tx) Commit() error {
+	return nil
+}
+
+func (tx) Rollback() error {
+	return nil
+}
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+	index   int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.index >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.index])
+	r.index++
+	return nil
+}
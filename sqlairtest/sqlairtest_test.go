@@ -0,0 +1,109 @@
+package sqlairtest
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/SimonRichardson/sqlair"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type Person struct {
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func runTx(t testing.TB, db *sql.DB, fn func(*sql.Tx) error) {
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		assert.Nil(t, err)
+	}
+
+	err = tx.Commit()
+	assert.Nil(t, err)
+}
+
+func TestMockQuery(t *testing.T) {
+	mock := New()
+	mock.ExpectQuery(`SELECT age, name FROM people WHERE name=:name;`).
+		WithArgs(sql.Named("name", "fred")).
+		WillReturnRows([]string{"age", "name"}, []interface{}{21, "fred"}, []interface{}{42, "fred"})
+
+	querier := sqlair.NewQuerier()
+
+	var persons []Person
+	runTx(t, mock.DB(), func(tx *sql.Tx) error {
+		query, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return query.Query(tx, `SELECT {Person} FROM people WHERE name=:name;`, map[string]interface{}{
+			"name": "fred",
+		})
+	})
+
+	assert.Equal(t, persons, []Person{{Name: "fred", Age: 21}, {Name: "fred", Age: 42}})
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, mock.Calls(), []Call{{
+		Statement: "SELECT age, name FROM people WHERE name=:name;",
+		Args:      []Arg{{Name: "name", Ordinal: 1, Value: "fred"}},
+	}})
+}
+
+func TestMockExec(t *testing.T) {
+	mock := New()
+	mock.ExpectExec(`^INSERT INTO people`).
+		WithArgs("fred", 21).
+		WillReturnResult(1, 1)
+
+	querier := sqlair.NewQuerier()
+
+	var affected int64
+	runTx(t, mock.DB(), func(tx *sql.Tx) error {
+		result, err := querier.Exec(tx, `INSERT INTO people (name, age) VALUES (?, ?);`, "fred", 21)
+		if err != nil {
+			return err
+		}
+		affected, err = result.RowsAffected()
+		return err
+	})
+
+	assert.Equal(t, affected, int64(1))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMockError(t *testing.T) {
+	mock := New()
+	mock.ExpectExec(`DELETE FROM people`).WillReturnError(errors.New("boom"))
+
+	querier := sqlair.NewQuerier()
+
+	tx, err := mock.DB().Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `DELETE FROM people;`)
+	assert.Equal(t, errors.Cause(err).Error(), "boom")
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMockExpectationsWereNotMet(t *testing.T) {
+	mock := New()
+	mock.ExpectQuery(`SELECT`)
+
+	querier := sqlair.NewQuerier()
+
+	tx, err := mock.DB().Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = querier.Exec(tx, `DELETE FROM people;`)
+	assert.Equal(t, err.Error(), `unexpected exec "DELETE FROM people;"`)
+
+	err = mock.ExpectationsWereMet()
+	assert.Equal(t, err.Error(), `unexpected statement "DELETE FROM people;"; expected query matching "SELECT" 1 times, got 0`)
+	assert.Equal(t, mock.Statements(), []string{"DELETE FROM people;"})
+}
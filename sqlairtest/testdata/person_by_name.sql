@@ -0,0 +1 @@
+SELECT age, name FROM people WHERE name=:name;
@@ -0,0 +1,227 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Statement is a statement prepared by Querier.Prepare. The record expressions
+// of the statement are parsed and expanded once, when the statement is
+// prepared, rather than being looked up in the statement cache on every
+// query.
+//
+// A Statement is immutable and safe for concurrent use. Changing the
+// configuration of the querier after the statement is prepared doesn't
+// affect the statement.
+type Statement struct {
+	querier  Querier
+	stmt     string
+	compiled cachedStmt
+	types    []reflect.Type
+	aliases  []string
+}
+
+// Prepare parses and expands the record expressions of the statement for the
+// types, returning a Statement that can be queried many times. Any errors in
+// the statement, such as a record expression for a type that isn't given or a
+// malformed named argument, are returned straight away rather than on the
+// first query.
+//
+//  stmt, err := querier.Prepare(`SELECT {Person} FROM people WHERE name=:name;`, Person{})
+//
+// The types can be structs, or pointers or slices of structs, along with
+// aliased types (see As).
+func (q *Querier) Prepare(stmt string, types ...interface{}) (*Statement, error) {
+	structs, err := q.registerStructs(types)
+	if err != nil {
+		return nil, err
+	}
+
+	source := stmt
+	if q.excludeDeleted {
+		source = excludeDeleted(q.reflect.Config(), stmt, structs)
+	}
+
+	names, err := parseNames(source, 0)
+	if err != nil {
+		return nil, rewrittenStatementError(err, stmt, source, "")
+	}
+	rewritten, err := rewriteNames(source, names)
+	if err != nil {
+		return nil, rewrittenStatementError(err, stmt, source, "")
+	}
+
+	query := Query{
+		mapper:      q.mapper,
+		order:       q.order,
+		aliasAll:    q.aliasAll,
+		columnAlias: q.columnAlias,
+		reflect:     q.reflect,
+	}
+	compiledStmt, fields, err := query.compileStatement(rewritten, structs)
+	if err != nil {
+		return nil, rewrittenStatementError(err, stmt, rewritten, "")
+	}
+
+	statement := &Statement{
+		querier: *q,
+		stmt:    stmt,
+		compiled: cachedStmt{
+			stmt:   compiledStmt,
+			fields: fields,
+		},
+		types:   make([]reflect.Type, len(types)),
+		aliases: make([]string, len(types)),
+	}
+	for i, value := range types {
+		value, alias := unwrapAlias(value)
+		statement.types[i] = structType(value)
+		statement.aliases[i] = alias
+	}
+	return statement, nil
+}
+
+// Classification classifies the compiled statement, so that the tables of its
+// record expressions ({Person.table}) are included. See Classify.
+func (s *Statement) Classification() Classification {
+	return Classify(s.compiled.stmt)
+}
+
+// Query executes the statement, populating the destinations from the rows.
+// The first values are the destinations, one for each type the statement was
+// prepared with, and the remaining values are the arguments of the statement.
+//
+//  var person Person
+//  err := stmt.Query(tx, &person, map[string]interface{}{"name": "fred"})
+//
+// The destinations are either pointers to the types, which populate a single
+// row in the same way as ForOne, or pointers to slices of the types, which
+// populate every row in the same way as ForMany.
+func (s *Statement) Query(tx *sql.Tx, values ...interface{}) error {
+	return s.QueryContext(context.Background(), tx, values...)
+}
+
+// QueryContext executes the statement, using the context for cancellation and
+// for any statement comments. See Query for the destinations and arguments.
+func (s *Statement) QueryContext(ctx context.Context, tx *sql.Tx, values ...interface{}) error {
+	// Query options are passed on to the query along with the destinations.
+	var options []interface{}
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		if _, ok := value.(QueryOption); ok {
+			options = append(options, value)
+			continue
+		}
+		args = append(args, value)
+	}
+	if len(args) < len(s.types) {
+		return errors.Errorf("expected %d destinations, got %d", len(s.types), len(args))
+	}
+
+	if ident, ok := findIdent(args[len(s.types):]); ok {
+		return errors.Errorf("unexpected identifier %q for prepared statement", string(ident))
+	}
+
+	destinations, many, err := s.destinations(args[:len(s.types)])
+	if err != nil {
+		return err
+	}
+	destinations = append(destinations, options...)
+
+	var query Query
+	if many {
+		query, err = s.querier.ForMany(destinations...)
+	} else {
+		query, err = s.querier.ForOne(destinations...)
+	}
+	if err != nil {
+		return err
+	}
+	query.prepared = &s.compiled
+
+	return query.QueryContext(ctx, tx, s.stmt, args[len(s.types):]...)
+}
+
+// destinations checks the destinations match the types of the statement,
+// returning the destinations bound to the aliases of the types. The
+// destinations must either all be slices or none of them.
+func (s *Statement) destinations(values []interface{}) ([]interface{}, bool, error) {
+	var many bool
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		value, alias := unwrapAlias(value)
+		typ := reflect.TypeOf(value)
+		if typ == nil || typ.Kind() != reflect.Ptr {
+			return nil, false, errors.Errorf("expected destination %d to be a pointer, got %T", i, value)
+		}
+
+		elem := typ.Elem()
+		slice := elem.Kind() == reflect.Slice
+		if slice {
+			elem = elem.Elem()
+		}
+		if i == 0 {
+			many = slice
+		} else if slice != many {
+			return nil, false, errors.Errorf("expected destinations to all be slices or all be structs")
+		}
+		if elem != s.types[i] {
+			return nil, false, errors.Errorf("expected destination %d to be of type %s, got %T", i, s.types[i], value)
+		}
+
+		if alias == "" {
+			alias = s.aliases[i]
+		}
+		result[i] = value
+		if alias != "" {
+			result[i] = As(alias, value)
+		}
+	}
+	return result, many, nil
+}
+
+// Exec executes the statement, which doesn't return rows, with the arguments.
+func (s *Statement) Exec(tx *sql.Tx, args ...interface{}) (sql.Result, error) {
+	return s.ExecContext(context.Background(), tx, args...)
+}
+
+// ExecContext executes the statement, which doesn't return rows, using the
+// context for cancellation and for any statement comments. The record
+// expressions of the statement were expanded for the types it was prepared
+// with, so the arguments only provide the values of the named arguments, and
+// can be maps as well as structs.
+func (s *Statement) ExecContext(ctx context.Context, tx *sql.Tx, args ...interface{}) (sql.Result, error) {
+	if ident, ok := findIdent(args); ok {
+		return nil, errors.Errorf("unexpected identifier %q for prepared statement", string(ident))
+	}
+
+	q := &s.querier
+	source, compiled := s.stmt, s.compiled.stmt
+	locked, lock, err := q.optimisticLockStatement(source, args)
+	if err != nil {
+		return nil, err
+	}
+	if lock != nil {
+		// The version check is added to the compiled statement, and bound
+		// from the locked statement along with the other arguments.
+		var ok bool
+		if compiled, ok = versionedUpdate(compiled, lock.column); !ok {
+			locked, lock = source, nil
+		}
+	}
+
+	var arguments []string
+	if q.statementHook != nil {
+		if arguments, err = argumentNames(locked); err != nil {
+			return nil, rewrittenStatementError(err, source, locked, "")
+		}
+	}
+	_, namedArgs, err := constructNamedArguments(q.reflect.Config(), q.mapper, q.argCheck, locked, args)
+	if err != nil {
+		return nil, errors.Wrap(rewrittenStatementError(err, source, locked, ""), "constructing named arguments")
+	}
+	return q.exec(ctx, tx, compiled, namedArgs, s.compiled.fields, arguments, lock)
+}
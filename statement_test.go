@@ -0,0 +1,167 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareQueryOne(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var processedStmt string
+
+	querier := NewQuerier()
+	querier.Hook(func(stmt string) error {
+		processedStmt = stmt
+		return nil
+	})
+
+	stmt, err := querier.Prepare(`SELECT {argsPerson} FROM people WHERE name=:name;`, argsPerson{})
+	assert.Nil(t, err)
+
+	for _, name := range []string{"fred", "frank"} {
+		var person argsPerson
+		runTx(t, db, func(tx *sql.Tx) error {
+			return stmt.Query(tx, &person, map[string]interface{}{
+				"name": name,
+			})
+		})
+		assert.Equal(t, person.Name, name)
+	}
+	assert.Equal(t, processedStmt, "SELECT age, name FROM people WHERE name=:name;")
+
+	// The prepared statement is used, rather than the statement cache.
+	_, ok := querier.stmtCache.Get(`SELECT {argsPerson} FROM people WHERE name=:name;`)
+	assert.False(t, ok)
+}
+
+func TestPrepareQueryMany(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	stmt, err := querier.Prepare(`SELECT {people.* INTO argsPerson} FROM people WHERE age>? ORDER BY people.name;`, []argsPerson{})
+	assert.Nil(t, err)
+
+	var persons []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		return stmt.Query(tx, &persons, 22, Capacity(4))
+	})
+
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "jane", Age: 23},
+	})
+	assert.Equal(t, cap(persons), 4)
+}
+
+func TestPrepareQueryAliased(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	stmt, err := querier.Prepare(`SELECT {a.* INTO argsPerson AS first}, {b.* INTO argsPerson AS second} FROM people AS a, people AS b WHERE a.name=:first AND b.name=:second;`, As("first", argsPerson{}), As("second", argsPerson{}))
+	assert.Nil(t, err)
+
+	var first, second argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		return stmt.Query(tx, &first, &second, map[string]interface{}{
+			"first":  "fred",
+			"second": "jane",
+		})
+	})
+
+	assert.Equal(t, first, argsPerson{Name: "fred", Age: 21})
+	assert.Equal(t, second, argsPerson{Name: "jane", Age: 23})
+}
+
+func TestPrepareExec(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	stmt, err := querier.Prepare(`UPDATE people SET age=:age WHERE name=:name;`)
+	assert.Nil(t, err)
+
+	var person argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := stmt.Exec(tx, argsPerson{Name: "fred", Age: 22}); err != nil {
+			return err
+		}
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {argsPerson} FROM people WHERE name="fred";`)
+	})
+
+	assert.Equal(t, person, argsPerson{Name: "fred", Age: 22})
+}
+
+func TestPrepareExecWithRecordAndMapArguments(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema+`CREATE TABLE archive(age INTEGER, name TEXT);`)
+
+	querier := NewQuerier()
+
+	stmt, err := querier.Prepare(`INSERT INTO archive SELECT {argsPerson} FROM people WHERE age>:age;`, argsPerson{})
+	assert.Nil(t, err)
+
+	var archived []argsPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		result, err := stmt.Exec(tx, map[string]interface{}{"age": 22})
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		assert.Nil(t, err)
+		assert.Equal(t, affected, int64(2))
+
+		getter, err := querier.ForMany(&archived)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {argsPerson} FROM archive ORDER BY name;`)
+	})
+
+	assert.Equal(t, archived, []argsPerson{{Name: "frank", Age: 42}, {Name: "jane", Age: 23}})
+}
+
+func TestPrepareErrors(t *testing.T) {
+	querier := NewQuerier()
+
+	_, err := querier.Prepare(`SELECT {Location} FROM people;`, argsPerson{})
+	assert.NotNil(t, err)
+
+	_, err = querier.Prepare(`SELECT {argsPerson} FROM people WHERE name=:;`, argsPerson{})
+	assert.Equal(t, errors.Cause(err).Error(), `unexpected named argument found in statement "SELECT {argsPerson} FROM people WHERE name=:;"`)
+
+	_, err = querier.Prepare(`SELECT {argsPerson} FROM people;`, 1)
+	assert.Equal(t, err.Error(), `expected struct type to register, got "int"`)
+}
+
+func TestPrepareQueryDestinationErrors(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	type Location struct {
+		City string `db:"city"`
+	}
+
+	querier := NewQuerier()
+
+	stmt, err := querier.Prepare(`SELECT {argsPerson} FROM people;`, argsPerson{})
+	assert.Nil(t, err)
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	err = stmt.Query(tx)
+	assert.Equal(t, err.Error(), `expected 1 destinations, got 0`)
+
+	err = stmt.Query(tx, argsPerson{})
+	assert.Equal(t, err.Error(), `expected destination 0 to be a pointer, got sqlair.argsPerson`)
+
+	err = stmt.Query(tx, &Location{})
+	assert.Equal(t, err.Error(), `expected destination 0 to be of type sqlair.argsPerson, got *sqlair.Location`)
+}
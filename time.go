@@ -0,0 +1,142 @@
+package sqlair
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TimeFormat defines how time.Time values are stored in the database.
+//
+// SQLite has no dedicated storage class for times, so they're stored as TEXT,
+// INTEGER (unix seconds) or REAL values. Scanning a time.Time field accepts
+// all of these.
+type TimeFormat struct {
+	// Layout is the layout used to format times when binding them as named
+	// arguments, and to parse times stored as TEXT. If the layout is empty,
+	// times are passed to the driver untouched and TEXT values are parsed
+	// using the common SQLite layouts.
+	Layout string
+	// Location is the location that times are converted to before they're
+	// stored, and the location that times without a zone are parsed in. If
+	// the location is nil, UTC is used.
+	Location *time.Location
+}
+
+// TimeFormat sets how time.Time fields and arguments are stored in the
+// database.
+func (q *Querier) TimeFormat(format TimeFormat) {
+	q.mapper.timeFormat = format
+}
+
+// timeLayouts are the layouts that are attempted when parsing a time stored
+// as TEXT, if no layout is specified.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// isTimeType returns true if the type is a time.Time or *time.Time.
+func isTimeType(t reflect.Type) bool {
+	return t == timeType || (t.Kind() == reflect.Ptr && t.Elem() == timeType)
+}
+
+func (f TimeFormat) location() *time.Location {
+	if f.Location == nil {
+		return time.UTC
+	}
+	return f.Location
+}
+
+// format returns the value that should be bound for a named argument. Only
+// time values are formatted, all other values are returned untouched.
+func (f TimeFormat) format(v interface{}) interface{} {
+	var t time.Time
+	switch tv := v.(type) {
+	case time.Time:
+		t = tv
+	case *time.Time:
+		if tv == nil {
+			return v
+		}
+		t = *tv
+	default:
+		return v
+	}
+
+	if f.Layout != "" {
+		return t.In(f.location()).Format(f.Layout)
+	}
+	if f.Location != nil {
+		return t.In(f.Location)
+	}
+	return v
+}
+
+// parse parses a time from the value scanned from the database.
+func (f TimeFormat) parse(src interface{}) (time.Time, error) {
+	loc := f.location()
+	switch t := src.(type) {
+	case time.Time:
+		return t.In(loc), nil
+	case int64:
+		return time.Unix(t, 0).In(loc), nil
+	case float64:
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)).In(loc), nil
+	case []byte:
+		return f.parse(string(t))
+	case string:
+		if f.Layout != "" {
+			return time.ParseInLocation(f.Layout, t, loc)
+		}
+		s := strings.TrimSuffix(t, "Z")
+		for _, layout := range timeLayouts {
+			if parsed, err := time.ParseInLocation(layout, s, loc); err == nil {
+				return parsed, nil
+			}
+		}
+		return time.Time{}, errors.Errorf("unable to parse time %q", t)
+	default:
+		return time.Time{}, errors.Errorf("unexpected time type %T", src)
+	}
+}
+
+// timeScanner scans TEXT, INTEGER, REAL and time values into a time.Time or
+// *time.Time destination.
+type timeScanner struct {
+	dest   reflect.Value
+	format TimeFormat
+}
+
+// Scan implements sql.Scanner.
+func (s timeScanner) Scan(src interface{}) error {
+	if src == nil {
+		s.dest.Set(reflect.Zero(s.dest.Type()))
+		return nil
+	}
+
+	t, err := s.format.parse(src)
+	if err != nil {
+		return err
+	}
+
+	if s.dest.Kind() == reflect.Ptr {
+		s.dest.Set(reflect.ValueOf(&t))
+		return nil
+	}
+	s.dest.Set(reflect.ValueOf(t))
+	return nil
+}
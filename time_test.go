@@ -0,0 +1,149 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeFormatParse(t *testing.T) {
+	expected := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	for _, src := range []interface{}{
+		"2021-03-04 05:06:07",
+		"2021-03-04T05:06:07Z",
+		[]byte("2021-03-04 05:06:07+00:00"),
+		int64(1614834367),
+		float64(1614834367),
+		expected,
+	} {
+		parsed, err := TimeFormat{}.parse(src)
+		assert.Nil(t, err)
+		assert.True(t, parsed.Equal(expected), "%v", src)
+	}
+
+	_, err := TimeFormat{}.parse("tuesday")
+	assert.Equal(t, err.Error(), `unable to parse time "tuesday"`)
+}
+
+func TestTimeFormatParseWithLayout(t *testing.T) {
+	loc := time.FixedZone("test", 3600)
+	format := TimeFormat{
+		Layout:   "02/01/2006 15:04",
+		Location: loc,
+	}
+	parsed, err := format.parse("04/03/2021 05:06")
+	assert.Nil(t, err)
+	assert.True(t, parsed.Equal(time.Date(2021, 3, 4, 5, 6, 0, 0, loc)))
+}
+
+func TestTimeFormatFormat(t *testing.T) {
+	tm := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	assert.Equal(t, TimeFormat{}.format(tm), tm)
+	assert.Equal(t, TimeFormat{}.format("fred"), "fred")
+	assert.Equal(t, TimeFormat{Layout: time.RFC3339}.format(tm), "2021-03-04T05:06:07Z")
+	assert.Equal(t, TimeFormat{Layout: time.RFC3339}.format(&tm), "2021-03-04T05:06:07Z")
+}
+
+func TestQueryWithTimeFields(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name       TEXT,
+	created_at TEXT,
+	updated_at INTEGER,
+	deleted_at DATETIME
+);
+INSERT INTO test(name, created_at, updated_at, deleted_at) values ("fred", "2021-03-04 05:06:07", 1614834367, NULL);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name      string     `db:"name"`
+		CreatedAt time.Time  `db:"created_at"`
+		UpdatedAt *time.Time `db:"updated_at"`
+		DeletedAt *time.Time `db:"deleted_at"`
+	}
+
+	querier := NewQuerier()
+
+	var person Person
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {test.* INTO Person} FROM test;`)
+	})
+
+	expected := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	assert.Equal(t, person.Name, "fred")
+	assert.True(t, person.CreatedAt.Equal(expected))
+	assert.True(t, person.UpdatedAt.Equal(expected))
+	assert.Nil(t, person.DeletedAt)
+}
+
+func TestQueryWithTimeFormat(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE test(
+	name       TEXT,
+	created_at DATETIME
+);
+	`)
+	assert.Nil(t, err)
+
+	type Person struct {
+		Name      string    `db:"name"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	loc := time.FixedZone("test", 3600)
+
+	querier := NewQuerier()
+	querier.TimeFormat(TimeFormat{
+		Layout:   time.RFC3339,
+		Location: loc,
+	})
+
+	tm := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	var (
+		raw    string
+		person Person
+		values = make(map[string]interface{})
+	)
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := querier.Exec(tx, "INSERT INTO test(name, created_at) VALUES (:name, :created_at);", Person{Name: "fred", CreatedAt: tm}); err != nil {
+			return err
+		}
+
+		if err := tx.QueryRow("SELECT CAST(created_at AS TEXT) FROM test;").Scan(&raw); err != nil {
+			return err
+		}
+
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		if err := getter.Query(tx, `SELECT {test.* INTO Person} FROM test WHERE created_at=:created_at;`, map[string]interface{}{
+			"created_at": tm,
+		}); err != nil {
+			return err
+		}
+
+		getter, err = querier.ForOne(&values)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT created_at FROM test;`)
+	})
+
+	assert.Equal(t, raw, "2021-03-04T06:06:07+01:00")
+	assert.Equal(t, person.Name, "fred")
+	assert.True(t, person.CreatedAt.Equal(tm))
+	assert.Equal(t, person.CreatedAt.Location(), loc)
+	assert.True(t, values["created_at"].(time.Time).Equal(tm))
+}
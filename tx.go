@@ -0,0 +1,315 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy configures how Querier.Tx retries a transaction that failed
+// because the database was busy.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times the transaction is run,
+	// including the first attempt.
+	Attempts int
+	// Backoff returns the delay before the given retry, starting at one for
+	// the first retry.
+	Backoff func(retry int) time.Duration
+	// Retryable returns true if the transaction can be retried after the
+	// error. By default busy and locked errors from SQLite, along with
+	// serialization failures from other databases, are retried.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy is the retry policy used by Querier.Tx unless one is set
+// with Querier.TxRetry.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:  5,
+	Backoff:   ExponentialBackoff(10*time.Millisecond, time.Second),
+	Retryable: IsRetryable,
+}
+
+// ExponentialBackoff returns a backoff that doubles the delay of every retry,
+// starting at the initial delay and never exceeding the maximum delay.
+func ExponentialBackoff(initial, max time.Duration) func(int) time.Duration {
+	return func(retry int) time.Duration {
+		delay := initial
+		for i := 1; i < retry && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+// IsRetryable returns true if the error is from a database that was busy or
+// locked, or from a transaction that couldn't be serialized, in which case
+// running the transaction again can succeed.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// Drivers that report the SQLSTATE of the error, where 40001 is a
+	// serialization failure and 40P01 is a deadlock.
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		switch state.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range []string{
+		"database is locked",
+		"database table is locked",
+		"sqlite_busy",
+		"could not serialize access",
+	} {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// TxRetry sets the retry policy of Querier.Tx. Any fields of the policy that
+// aren't set are taken from DefaultRetryPolicy.
+func (q *Querier) TxRetry(policy RetryPolicy) {
+	q.retry = policy
+}
+
+// Tx runs the function in a transaction, committing the transaction if the
+// function returns nil and rolling it back otherwise. The error from the
+// function is returned untouched.
+//
+// If the transaction fails because the database is busy, the whole
+// transaction is run again according to the retry policy (see TxRetry). The
+// function must therefore be safe to call more than once.
+//
+//  err := querier.Tx(ctx, db, func(tx *sqlair.TxQuerier) error {
+//  	_, err := tx.Exec(`UPDATE people SET age=:age WHERE name=:name;`, person)
+//  	return err
+//  })
+//
+// If the context is that of a transaction on the same database (see
+// TxQuerier.Context), the function is run in a savepoint of that transaction
+// instead. The savepoint is released if the function returns nil, otherwise
+// only the changes made since the savepoint are rolled back. This allows
+// units of work to be composed, without knowing if they're already in a
+// transaction. Nested transactions are never retried by themselves, as the
+// whole outer transaction has to be run again.
+func (q *Querier) Tx(ctx context.Context, db *sql.DB, fn func(*TxQuerier) error) error {
+	return q.TxWithOptions(ctx, db, nil, fn)
+}
+
+// TxWithOptions runs the function in a transaction in the same way as Tx,
+// beginning the transaction with the options. The options are ignored by
+// nested transactions, apart from read-only, which makes the savepoint
+// read-only.
+//
+// Only statements that don't modify the database can be executed by a
+// read-only transaction. Any other statement, such as an UPDATE, returns a
+// ReadOnlyError without reaching the database, regardless of whether the
+// driver enforces read-only transactions.
+//
+//  err := querier.TxWithOptions(ctx, db, &sql.TxOptions{ReadOnly: true}, func(tx *sqlair.TxQuerier) error {
+//  	return tx.Query(getter, `SELECT {Person} FROM people;`)
+//  })
+//
+func (q *Querier) TxWithOptions(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*TxQuerier) error) error {
+	readOnly := opts != nil && opts.ReadOnly
+	if parent, ok := ctx.Value(txContextKey{}).(*TxQuerier); ok && parent.db == db {
+		return parent.savepoint(q, readOnly, fn)
+	}
+
+	policy := q.retryPolicy()
+	for attempt := 1; ; attempt++ {
+		err := q.runTx(ctx, db, opts, fn)
+		if err == nil || attempt >= policy.Attempts || !policy.Retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryPolicy returns the retry policy of the querier, with the defaults
+// filled in.
+func (q *Querier) retryPolicy() RetryPolicy {
+	policy := q.retry
+	if policy.Attempts <= 0 {
+		policy.Attempts = DefaultRetryPolicy.Attempts
+	}
+	if policy.Backoff == nil {
+		policy.Backoff = DefaultRetryPolicy.Backoff
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = DefaultRetryPolicy.Retryable
+	}
+	return policy
+}
+
+// runTx runs a single attempt of the transaction. The transaction is rolled
+// back if the function panics, before the panic is propagated.
+func (q *Querier) runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*TxQuerier) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(newTxQuerier(ctx, q, db, tx, 0, opts != nil && opts.ReadOnly)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return errors.WithStack(tx.Commit())
+}
+
+type txContextKey struct{}
+
+// TxQuerier executes statements within a transaction that's run by
+// Querier.Tx, using the context of the transaction.
+type TxQuerier struct {
+	querier *Querier
+	ctx     context.Context
+	db      *sql.DB
+	tx      *sql.Tx
+	// depth is the number of savepoints the transaction is nested in.
+	depth    int
+	readOnly bool
+}
+
+func newTxQuerier(ctx context.Context, querier *Querier, db *sql.DB, tx *sql.Tx, depth int, readOnly bool) *TxQuerier {
+	t := &TxQuerier{
+		querier:  querier,
+		db:       db,
+		tx:       tx,
+		depth:    depth,
+		readOnly: readOnly,
+	}
+	t.ctx = context.WithValue(ctx, txContextKey{}, t)
+	return t
+}
+
+// Savepoint runs the function in a savepoint of the transaction. See
+// Querier.Tx for nested transactions.
+func (t *TxQuerier) Savepoint(fn func(*TxQuerier) error) error {
+	return t.savepoint(t.querier, false, fn)
+}
+
+// savepoint runs the function in a new savepoint, releasing the savepoint if
+// the function returns nil and rolling back to it otherwise.
+func (t *TxQuerier) savepoint(querier *Querier, readOnly bool, fn func(*TxQuerier) error) (err error) {
+	name := fmt.Sprintf("sqlair_savepoint_%d", t.depth+1)
+	if _, err := t.tx.ExecContext(t.ctx, "SAVEPOINT "+name); err != nil {
+		return errors.Wrap(err, "savepoint")
+	}
+
+	rollback := func() {
+		_, _ = t.tx.ExecContext(t.ctx, "ROLLBACK TO "+name)
+		_, _ = t.tx.ExecContext(t.ctx, "RELEASE "+name)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(newTxQuerier(t.ctx, querier, t.db, t.tx, t.depth+1, t.readOnly || readOnly)); err != nil {
+		rollback()
+		return err
+	}
+	_, err = t.tx.ExecContext(t.ctx, "RELEASE "+name)
+	return errors.Wrap(err, "release savepoint")
+}
+
+// Tx returns the underlying transaction.
+func (t *TxQuerier) Tx() *sql.Tx {
+	return t.tx
+}
+
+// Context returns the context of the transaction. Passing the context to
+// Querier.Tx runs a nested transaction in a savepoint.
+func (t *TxQuerier) Context() context.Context {
+	return t.ctx
+}
+
+// Exec executes a statement that doesn't return rows. See Querier.Exec.
+func (t *TxQuerier) Exec(stmt string, args ...interface{}) (sql.Result, error) {
+	if err := t.checkReadOnly(stmt); err != nil {
+		return nil, err
+	}
+	return t.querier.ExecContext(t.ctx, t.tx, stmt, args...)
+}
+
+// Query executes a statement that returns rows, populating the values of the
+// query created by ForOne or ForMany.
+//
+//  getter, err := querier.ForOne(&person)
+//  ...
+//  err = tx.Query(getter, `SELECT {Person} FROM people WHERE name=:name;`, filter)
+//
+func (t *TxQuerier) Query(query Query, stmt string, args ...interface{}) error {
+	if err := t.checkReadOnly(stmt); err != nil {
+		return err
+	}
+	return query.QueryContext(t.ctx, t.tx, stmt, args...)
+}
+
+// QueryStatement executes a prepared statement that returns rows. See
+// Statement.Query for the destinations and arguments.
+func (t *TxQuerier) QueryStatement(stmt *Statement, values ...interface{}) error {
+	if err := t.checkReadOnly(stmt.stmt); err != nil {
+		return err
+	}
+	return stmt.QueryContext(t.ctx, t.tx, values...)
+}
+
+// ExecStatement executes a prepared statement that doesn't return rows.
+func (t *TxQuerier) ExecStatement(stmt *Statement, args ...interface{}) (sql.Result, error) {
+	if err := t.checkReadOnly(stmt.stmt); err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(t.ctx, t.tx, args...)
+}
+
+// ReadOnly returns true if the transaction, or any of the transactions it's
+// nested in, is read-only.
+func (t *TxQuerier) ReadOnly() bool {
+	return t.readOnly
+}
+
+// checkReadOnly returns a ReadOnlyError if the transaction is read-only and
+// the statement modifies the database.
+func (t *TxQuerier) checkReadOnly(stmt string) error {
+	if !t.readOnly {
+		return nil
+	}
+	if c := Classify(stmt); !c.ReadOnly {
+		return errors.WithStack(&ReadOnlyError{
+			Keyword: c.Keyword,
+		})
+	}
+	return nil
+}
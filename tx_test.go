@@ -0,0 +1,297 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxCommits(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	err := querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		_, err := tx.Exec(`UPDATE people SET age=:age WHERE name=:name;`, argsPerson{Name: "fred", Age: 22})
+		return err
+	})
+	assert.Nil(t, err)
+
+	var person argsPerson
+	err = querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		getter, err := querier.ForOne(&person)
+		assert.Nil(t, err)
+
+		return tx.Query(getter, `SELECT {argsPerson} FROM people WHERE name=:name;`, map[string]interface{}{
+			"name": "fred",
+		})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, person, argsPerson{Name: "fred", Age: 22})
+}
+
+func TestTxRollsBack(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	boom := errors.New("boom")
+	err := querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		if _, err := tx.Exec(`DELETE FROM people;`); err != nil {
+			return err
+		}
+		return boom
+	})
+	assert.Equal(t, err, boom)
+
+	assert.Panics(t, func() {
+		_ = querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+			if _, err := tx.Exec(`DELETE FROM people;`); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	})
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM people;`).Scan(&count)
+	assert.Nil(t, err)
+	assert.Equal(t, count, 3)
+}
+
+func TestTxRetries(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	var retries []int
+
+	querier := NewQuerier()
+	querier.TxRetry(RetryPolicy{
+		Attempts: 3,
+		Backoff: func(retry int) time.Duration {
+			retries = append(retries, retry)
+			return 0
+		},
+	})
+
+	var attempts int
+	err := querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("database is locked")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, attempts, 3)
+	assert.Equal(t, retries, []int{1, 2})
+
+	// The last error is returned once the attempts are exhausted.
+	attempts = 0
+	err = querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		attempts++
+		return errors.Errorf("database is locked %d", attempts)
+	})
+	assert.Equal(t, err.Error(), "database is locked 3")
+
+	// Errors that can't be retried are returned straight away.
+	attempts = 0
+	err = querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		attempts++
+		return errors.New("boom")
+	})
+	assert.Equal(t, err.Error(), "boom")
+	assert.Equal(t, attempts, 1)
+}
+
+func TestTxRetriesBusyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.db") + "?_busy_timeout=0"
+
+	locker, err := sql.Open("sqlite3", path)
+	assert.Nil(t, err)
+	defer locker.Close()
+
+	_, err = locker.Exec(`CREATE TABLE people(name TEXT);`)
+	assert.Nil(t, err)
+
+	// Hold the write lock until the first retry.
+	lock, err := locker.Begin()
+	assert.Nil(t, err)
+	_, err = lock.Exec(`INSERT INTO people(name) VALUES ("fred");`)
+	assert.Nil(t, err)
+
+	db, err := sql.Open("sqlite3", path)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	querier := NewQuerier()
+	querier.TxRetry(RetryPolicy{
+		Backoff: func(retry int) time.Duration {
+			if retry == 1 {
+				assert.Nil(t, lock.Commit())
+			}
+			return 0
+		},
+	})
+
+	var attempts int
+	err = querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		attempts++
+		_, err := tx.Exec(`INSERT INTO people(name) VALUES (:name);`, map[string]interface{}{
+			"name": "frank",
+		})
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, attempts, 2)
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM people;`).Scan(&count)
+	assert.Nil(t, err)
+	assert.Equal(t, count, 2)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(errors.New("database is locked")))
+	assert.True(t, IsRetryable(errors.Wrap(errors.New("database table is locked: people"), "exec")))
+	assert.True(t, IsRetryable(errors.New("ERROR: could not serialize access due to concurrent update")))
+	assert.False(t, IsRetryable(errors.New("no such table: people")))
+	assert.False(t, IsRetryable(nil))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	assert.Equal(t, backoff(1), 10*time.Millisecond)
+	assert.Equal(t, backoff(2), 20*time.Millisecond)
+	assert.Equal(t, backoff(3), 40*time.Millisecond)
+	assert.Equal(t, backoff(4), 50*time.Millisecond)
+	assert.Equal(t, backoff(100), 50*time.Millisecond)
+}
+
+func TestTxNestedSavepoints(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	deletePerson := func(ctx context.Context, name string, fail bool) error {
+		return querier.Tx(ctx, db, func(tx *TxQuerier) error {
+			if _, err := tx.Exec(`DELETE FROM people WHERE name=:name;`, map[string]interface{}{
+				"name": name,
+			}); err != nil {
+				return err
+			}
+			if fail {
+				return errors.New("boom")
+			}
+			return nil
+		})
+	}
+
+	var persons []argsPerson
+	err := querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		assert.Nil(t, deletePerson(tx.Context(), "fred", false))
+		assert.Equal(t, deletePerson(tx.Context(), "frank", true).Error(), "boom")
+
+		// Savepoints can be nested within savepoints.
+		err := tx.Savepoint(func(tx *TxQuerier) error {
+			assert.Nil(t, deletePerson(tx.Context(), "jane", false))
+			return errors.New("boom")
+		})
+		assert.Equal(t, err.Error(), "boom")
+
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+		return tx.Query(getter, `SELECT {people.* INTO argsPerson} FROM people ORDER BY people.name;`)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "jane", Age: 23},
+	})
+}
+
+func TestTxNestedPanic(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	err := querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		assert.Panics(t, func() {
+			_ = tx.Savepoint(func(tx *TxQuerier) error {
+				if _, err := tx.Exec(`DELETE FROM people;`); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		})
+		return nil
+	})
+	assert.Nil(t, err)
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM people;`).Scan(&count)
+	assert.Nil(t, err)
+	assert.Equal(t, count, 3)
+}
+
+func TestTxReadOnly(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	var persons []argsPerson
+	err := querier.TxWithOptions(context.Background(), db, &sql.TxOptions{ReadOnly: true}, func(tx *TxQuerier) error {
+		assert.True(t, tx.ReadOnly())
+
+		_, err := tx.Exec(`DELETE FROM people;`)
+		assert.True(t, errors.As(err, new(*ReadOnlyError)))
+		assert.Equal(t, err.Error(), "DELETE statement not allowed in read-only transaction")
+
+		// Nested transactions are read-only as well.
+		err = querier.Tx(tx.Context(), db, func(tx *TxQuerier) error {
+			_, err := tx.Exec(`UPDATE people SET age=1;`)
+			return err
+		})
+		assert.True(t, errors.As(err, new(*ReadOnlyError)))
+
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+		return tx.Query(getter, `SELECT {people.* INTO argsPerson} FROM people WHERE people.age>:age ORDER BY people.name;`, map[string]interface{}{
+			"age": 22,
+		})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, persons, []argsPerson{
+		{Name: "frank", Age: 42},
+		{Name: "jane", Age: 23},
+	})
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM people;`).Scan(&count)
+	assert.Nil(t, err)
+	assert.Equal(t, count, 3)
+}
+
+func TestTxReadOnlySavepoint(t *testing.T) {
+	db := setupSchemaDB(t, peopleSchema)
+
+	querier := NewQuerier()
+
+	err := querier.Tx(context.Background(), db, func(tx *TxQuerier) error {
+		assert.False(t, tx.ReadOnly())
+
+		err := querier.TxWithOptions(tx.Context(), db, &sql.TxOptions{ReadOnly: true}, func(tx *TxQuerier) error {
+			_, err := tx.Exec(`DELETE FROM people;`)
+			return err
+		})
+		assert.True(t, errors.As(err, new(*ReadOnlyError)))
+
+		_, err = tx.Exec(`DELETE FROM people WHERE name="fred";`)
+		return err
+	})
+	assert.Nil(t, err)
+}
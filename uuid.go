@@ -0,0 +1,129 @@
+package sqlair
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// isUUIDField returns true if the field of the type is stored as a UUID. A
+// [16]byte field is a UUID if it's tagged with the uuid tag option, or if it
+// doesn't implement sql.Scanner and driver.Valuer itself. UUIDs are stored as
+// TEXT in the canonical form by default, or as a 16 byte BLOB with the
+// uuid=blob tag option:
+//
+//  type Person struct {
+//  	ID       uuid.UUID `db:"id,uuid=blob"`
+//  	ParentID [16]byte  `db:"parent_id"`
+//  }
+//
+func isUUIDField(field sreflect.ReflectField, t reflect.Type) bool {
+	if !sreflect.IsUUID(t) {
+		return false
+	}
+	return field.Tag.UUID != "" || !(field.Scanner || field.Valuer)
+}
+
+// uuidArgument returns the value of a UUID for use as a named argument, which
+// is either the canonical string, or the bytes if stored as a BLOB.
+func uuidArgument(value reflect.Value, storage string) interface{} {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	var id [16]byte
+	reflect.Copy(reflect.ValueOf(&id).Elem(), value)
+	if storage == "blob" {
+		return id[:]
+	}
+	return formatUUID(id)
+}
+
+// formatUUID returns the canonical form of the UUID, such as
+// "6ba7b810-9dad-11d1-80b4-00c04fd430c8".
+func formatUUID(id [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+	return string(buf[:])
+}
+
+// parseUUID parses the canonical form of a UUID, along with the forms with
+// braces, a "urn:uuid:" prefix or without any hyphens.
+func parseUUID(s string) ([16]byte, error) {
+	var id [16]byte
+
+	text := strings.TrimPrefix(strings.ToLower(s), "urn:uuid:")
+	if strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}") {
+		text = text[1 : len(text)-1]
+	}
+	if len(text) == 36 {
+		if text[8] != '-' || text[13] != '-' || text[18] != '-' || text[23] != '-' {
+			return id, errors.Errorf("invalid UUID %q", s)
+		}
+		text = text[0:8] + text[9:13] + text[14:18] + text[19:23] + text[24:]
+	}
+	if len(text) != 32 {
+		return id, errors.Errorf("invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(id[:], []byte(text)); err != nil {
+		return id, errors.Errorf("invalid UUID %q", s)
+	}
+	return id, nil
+}
+
+// uuidScanner scans a TEXT or BLOB column into a [16]byte or *[16]byte
+// destination. BLOBs of 16 bytes are the bytes of the UUID, any other values
+// are parsed from the string form.
+type uuidScanner struct {
+	dest reflect.Value
+}
+
+// Scan implements sql.Scanner.
+func (s uuidScanner) Scan(src interface{}) error {
+	if src == nil {
+		s.dest.Set(reflect.Zero(s.dest.Type()))
+		return nil
+	}
+
+	var id [16]byte
+	switch src := src.(type) {
+	case []byte:
+		if len(src) == 16 {
+			copy(id[:], src)
+			break
+		}
+		var err error
+		if id, err = parseUUID(string(src)); err != nil {
+			return err
+		}
+	case string:
+		var err error
+		if id, err = parseUUID(src); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("unable to scan %T into %s", src, s.dest.Type())
+	}
+
+	dest := s.dest
+	if dest.Kind() == reflect.Ptr {
+		dest.Set(reflect.New(dest.Type().Elem()))
+		dest = dest.Elem()
+	}
+	reflect.Copy(dest, reflect.ValueOf(id))
+	return nil
+}
@@ -0,0 +1,123 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUUID [16]byte
+
+type uuidPerson struct {
+	ID       testUUID  `db:"id,pk,uuid=blob"`
+	ParentID *[16]byte `db:"parent_id"`
+	Name     string    `db:"name"`
+}
+
+func (uuidPerson) TableName() string {
+	return "people"
+}
+
+func TestQueryWithUUIDs(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`CREATE TABLE people (id BLOB PRIMARY KEY, parent_id TEXT, name TEXT);`)
+	assert.Nil(t, err)
+
+	id := testUUID{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	parent := [16]byte{15: 1}
+
+	querier := NewQuerier()
+	runTx(t, db, func(tx *sql.Tx) error {
+		for _, person := range []uuidPerson{{
+			ID:   testUUID{15: 1},
+			Name: "fred",
+		}, {
+			ID:       id,
+			ParentID: &parent,
+			Name:     "frank",
+		}} {
+			if _, err := querier.Exec(tx, `INSERT INTO people (id, parent_id, name) VALUES (:id, :parent_id, :name);`, person); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// The blob UUIDs are stored as 16 bytes, the others as the canonical
+	// text.
+	var (
+		typ, text string
+		length    int
+	)
+	err = db.QueryRow(`SELECT typeof(id), length(id), parent_id FROM people WHERE name="frank";`).Scan(&typ, &length, &text)
+	assert.Nil(t, err)
+	assert.Equal(t, typ, "blob")
+	assert.Equal(t, length, 16)
+	assert.Equal(t, text, "00000000-0000-0000-0000-000000000001")
+
+	var persons []uuidPerson
+	runTx(t, db, func(tx *sql.Tx) error {
+		getter, err := querier.ForMany(&persons)
+		assert.Nil(t, err)
+
+		return getter.Query(tx, `SELECT {uuidPerson} FROM people WHERE parent_id IS NULL OR parent_id=:parent ORDER BY name;`, map[string]interface{}{
+			"parent": parent,
+		})
+	})
+	assert.Equal(t, persons, []uuidPerson{{
+		ID:       id,
+		ParentID: &parent,
+		Name:     "frank",
+	}, {
+		ID:   testUUID{15: 1},
+		Name: "fred",
+	}})
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE people SET parent_id='x' WHERE name="fred";`)
+	assert.Nil(t, err)
+
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+	err = getter.Query(tx, `SELECT {uuidPerson} FROM people WHERE name="fred";`)
+	assert.Equal(t, err.Error(), `sql: Scan error on column index 2, name "parent_id": invalid UUID "x"
+	statement: SELECT {uuidPerson} FROM people WHERE name="fred";
+	compiled: SELECT id, name, parent_id FROM people WHERE name="fred";`)
+}
+
+func TestParseUUID(t *testing.T) {
+	expected := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	for _, s := range []string{
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"6BA7B810-9DAD-11D1-80B4-00C04FD430C8",
+		"{6ba7b810-9dad-11d1-80b4-00c04fd430c8}",
+		"urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"6ba7b8109dad11d180b400c04fd430c8",
+	} {
+		id, err := parseUUID(s)
+		assert.Nil(t, err)
+		assert.Equal(t, id, expected)
+	}
+	assert.Equal(t, formatUUID(expected), "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	_, err := parseUUID("6ba7b810x9dad-11d1-80b4-00c04fd430c8")
+	assert.Equal(t, err.Error(), `invalid UUID "6ba7b810x9dad-11d1-80b4-00c04fd430c8"`)
+	_, err = parseUUID("6ba7b810")
+	assert.Equal(t, err.Error(), `invalid UUID "6ba7b810"`)
+}
+
+func TestCreateTableSQLWithUUIDs(t *testing.T) {
+	stmt, err := CreateTableSQL(uuidPerson{}, Postgres)
+	assert.Nil(t, err)
+	assert.Equal(t, stmt, `CREATE TABLE people (
+	id BYTEA NOT NULL,
+	parent_id UUID,
+	name TEXT NOT NULL,
+	PRIMARY KEY (id)
+);`)
+}
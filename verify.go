@@ -0,0 +1,205 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// VerifySchema compares the columns of the table in the database with the
+// fields of the struct, returning a SchemaDriftError if there are fields
+// without columns, columns without fields, or columns with a type that can't
+// hold the field. It's intended to be run at startup, to catch migrations
+// that have drifted from the Go types.
+//
+//  if err := querier.VerifySchema(db, &Person{}, "people"); err != nil {
+//  	log.Fatal(err)
+//  }
+//
+// The columns are read from PRAGMA table_info for SQLite and from
+// information_schema for other databases.
+func (q *Querier) VerifySchema(db *sql.DB, value interface{}, table string) error {
+	return q.VerifySchemaContext(context.Background(), db, value, table)
+}
+
+// VerifySchemaContext compares the columns of the table in the database with
+// the fields of the struct. See VerifySchema.
+func (q *Querier) VerifySchemaContext(ctx context.Context, db *sql.DB, value interface{}, table string) error {
+	_, refStruct, err := q.reflectTable(value)
+	if err != nil {
+		return err
+	}
+
+	columns, err := tableColumns(ctx, db, table)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return errors.Errorf("table %q not found", table)
+	}
+
+	drift := &SchemaDriftError{
+		Table: table,
+	}
+	fields := make(map[string]struct{})
+	for _, name := range refStruct.DeclaredFieldNames() {
+		fields[strings.ToLower(name)] = struct{}{}
+
+		columnType, ok := columns[strings.ToLower(name)]
+		if !ok {
+			drift.MissingColumns = append(drift.MissingColumns, name)
+			continue
+		}
+
+		field := refStruct.Fields[name]
+		typ, _ := fieldType(field)
+		if !compatibleColumnType(typ, field.Tag.Type, field.Tag.JSON, columnType) {
+			drift.Mismatches = append(drift.Mismatches, TypeMismatch{
+				Column:     name,
+				FieldType:  field.Value.Type().String(),
+				ColumnType: columnType,
+			})
+		}
+	}
+	for name := range columns {
+		if _, ok := fields[name]; !ok {
+			drift.UnknownColumns = append(drift.UnknownColumns, name)
+		}
+	}
+	sort.Strings(drift.UnknownColumns)
+
+	if len(drift.MissingColumns) == 0 && len(drift.UnknownColumns) == 0 && len(drift.Mismatches) == 0 {
+		return nil
+	}
+	return errors.WithStack(drift)
+}
+
+// tableColumns returns the declared types of the columns of the table, keyed
+// by column name.
+func tableColumns(ctx context.Context, db *sql.DB, table string) (map[string]string, error) {
+	var stmt string
+	switch driverDialect(db) {
+	case SQLite:
+		stmt = `SELECT name, type FROM pragma_table_info(?);`
+	case MySQL:
+		stmt = `SELECT column_name, column_type FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?;`
+	default:
+		stmt = `SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1;`
+	}
+
+	rows, err := db.QueryContext(ctx, stmt, table)
+	if err != nil {
+		return nil, errors.Wrapf(err, "columns of table %q", table)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, columnType string
+		if err := rows.Scan(&name, &columnType); err != nil {
+			return nil, errors.Wrapf(err, "columns of table %q", table)
+		}
+		columns[strings.ToLower(name)] = columnType
+	}
+	return columns, errors.WithStack(rows.Err())
+}
+
+// driverDialect returns the dialect of the database, from the name of the
+// type of its driver.
+func driverDialect(db *sql.DB) Dialect {
+	name := strings.ToLower(fmt.Sprintf("%T", db.Driver()))
+	switch {
+	case strings.Contains(name, "sqlite"):
+		return SQLite
+	case strings.Contains(name, "mysql"):
+		return MySQL
+	}
+	return Postgres
+}
+
+// columnClass is the broad class of values that a column type holds, in the
+// same spirit as the type affinity of SQLite.
+type columnClass int
+
+const (
+	unknownClass columnClass = iota
+	integerClass
+	numericClass
+	boolClass
+	textClass
+	blobClass
+	timeClass
+	jsonClass
+)
+
+// classifyColumnType returns the class of the declared type of a column.
+func classifyColumnType(columnType string) columnClass {
+	columnType = strings.ToUpper(columnType)
+	switch {
+	case strings.Contains(columnType, "JSON"):
+		return jsonClass
+	case strings.Contains(columnType, "BOOL"), columnType == "TINYINT(1)":
+		return boolClass
+	case strings.Contains(columnType, "INT"), strings.Contains(columnType, "SERIAL"):
+		return integerClass
+	case strings.Contains(columnType, "CHAR"), strings.Contains(columnType, "CLOB"), strings.Contains(columnType, "TEXT"):
+		return textClass
+	case strings.Contains(columnType, "BLOB"), strings.Contains(columnType, "BYTEA"), strings.Contains(columnType, "BINARY"):
+		return blobClass
+	case strings.Contains(columnType, "DATE"), strings.Contains(columnType, "TIME"):
+		return timeClass
+	case strings.Contains(columnType, "REAL"), strings.Contains(columnType, "FLOA"), strings.Contains(columnType, "DOUB"),
+		strings.Contains(columnType, "NUMERIC"), strings.Contains(columnType, "DECIMAL"):
+		return numericClass
+	}
+	return unknownClass
+}
+
+// compatibleColumnType returns true if a column of the declared type can hold
+// the values of the field type. Columns without a declared type, and fields
+// of types that aren't known, such as custom scanners, are always
+// compatible.
+func compatibleColumnType(typ reflect.Type, tagType string, isJSON bool, columnType string) bool {
+	class := classifyColumnType(columnType)
+	if class == unknownClass {
+		return true
+	}
+	if tagType != "" {
+		return classifyColumnType(tagType) == class
+	}
+
+	var accepted []columnClass
+	switch {
+	case isJSON || typ == rawMessageType:
+		accepted = []columnClass{jsonClass, textClass, blobClass}
+	case typ == timeType:
+		accepted = []columnClass{timeClass, textClass, integerClass}
+	case typ == bytesType:
+		accepted = []columnClass{blobClass, textClass, jsonClass}
+	default:
+		switch typ.Kind() {
+		case reflect.Bool:
+			accepted = []columnClass{boolClass, integerClass}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			accepted = []columnClass{integerClass, numericClass, boolClass}
+		case reflect.Float32, reflect.Float64:
+			accepted = []columnClass{numericClass, integerClass}
+		case reflect.String:
+			accepted = []columnClass{textClass, jsonClass, timeClass}
+		default:
+			return true
+		}
+	}
+	for _, c := range accepted {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
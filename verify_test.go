@@ -0,0 +1,74 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type verifyPerson struct {
+	ID       int64          `db:"id"`
+	Name     string         `db:"name"`
+	Nickname sql.NullString `db:"nickname"`
+	Balance  float64        `db:"balance"`
+	Tags     []string       `db:"tags,json"`
+	Created  time.Time      `db:"created"`
+	Active   bool           `db:"active"`
+}
+
+func TestVerifySchema(t *testing.T) {
+	db := setupDB(t)
+
+	stmt, err := CreateTableSQL(verifyPerson{}, SQLite)
+	assert.Nil(t, err)
+	_, err = db.Exec(stmt)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+	err = querier.VerifySchema(db, &verifyPerson{}, "verify_person")
+	assert.Nil(t, err)
+}
+
+func TestVerifySchemaWithDrift(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`CREATE TABLE people (
+	id TEXT,
+	name VARCHAR(32),
+	nickname TEXT,
+	balance NUMERIC(10, 2),
+	tags JSON,
+	created TIMESTAMP,
+	age INTEGER,
+	email TEXT
+);`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+	err = querier.VerifySchema(db, &verifyPerson{}, "people")
+
+	var drift *SchemaDriftError
+	assert.True(t, errors.As(err, &drift))
+	assert.Equal(t, drift, &SchemaDriftError{
+		Table:          "people",
+		MissingColumns: []string{"active"},
+		UnknownColumns: []string{"age", "email"},
+		Mismatches: []TypeMismatch{{
+			Column:     "id",
+			FieldType:  "int64",
+			ColumnType: "TEXT",
+		}},
+	})
+	assert.Equal(t, err.Error(), `table "people" drifted from struct: fields without columns: active; columns without fields: age, email; type mismatches: id (int64 as TEXT)`)
+}
+
+func TestVerifySchemaWithMissingTable(t *testing.T) {
+	db := setupDB(t)
+
+	querier := NewQuerier()
+	err := querier.VerifySchema(db, &verifyPerson{}, "people")
+	assert.Equal(t, err.Error(), `table "people" not found`)
+}
@@ -0,0 +1,281 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// optimisticLock is the version of a struct that's passed to an UPDATE
+// statement, which is checked and incremented by the statement.
+type optimisticLock struct {
+	entity  string
+	table   string
+	column  string
+	version interface{}
+	// field is the version field of the struct, which can only be set if
+	// the struct was passed by pointer.
+	field reflect.Value
+}
+
+// optimisticLockStatement rewrites an UPDATE statement of the table of the
+// first struct argument that has a field tagged with the version option, so
+// that the row is only updated if its version hasn't changed, and the version
+// is incremented by the update:
+//
+//  UPDATE people SET name=:name WHERE id=:id;
+//  UPDATE people SET name=:name, version = version + 1 WHERE (id=:id) AND version = :version;
+//
+// Statements that update another table, and statements that already refer to
+// the version argument, are left alone, as the latter manage the version
+// themselves.
+func (q *Querier) optimisticLockStatement(stmt string, args []interface{}) (string, *optimisticLock, error) {
+	lock, err := q.versionedArgument(args)
+	if err != nil || lock == nil {
+		return stmt, nil, err
+	}
+
+	keywords, _, _ := classifyStatement(stmt)
+	if len(keywords) != 1 || keywords[0] != "UPDATE" || !updatesTable(stmt, lock.table) || hasNamedArgument(stmt, lock.column) {
+		return stmt, nil, nil
+	}
+
+	rewritten, ok := versionedUpdate(stmt, lock.column)
+	if !ok {
+		return stmt, nil, nil
+	}
+	return rewritten, lock, nil
+}
+
+// updatesTable returns true if the table is the one that the UPDATE statement
+// updates. A qualified table (main.people) matches the table without its
+// schema.
+func updatesTable(stmt, table string) bool {
+	for _, keyword := range clauseKeywords(stmt) {
+		if keyword.word != "UPDATE" {
+			continue
+		}
+		// The table can follow a conflict clause (UPDATE OR IGNORE).
+		i := skipSpace(stmt, keyword.end)
+		for {
+			word, end := nextWord(stmt, i)
+			if !isTableModifier(strings.ToUpper(word)) {
+				break
+			}
+			i = skipSpace(stmt, end)
+		}
+		parts, _, _ := scanName(stmt, i)
+		return len(parts) > 0 && strings.EqualFold(parts[len(parts)-1], table)
+	}
+	return false
+}
+
+// versionedArgument returns the lock of the first struct argument with a
+// version field.
+func (q *Querier) versionedArgument(args []interface{}) (*optimisticLock, error) {
+	for _, arg := range args {
+		if _, ok := arg.(sql.NamedArg); ok {
+			continue
+		}
+
+		// Structs passed by value are copied, so that they can be
+		// reflected, but only the version of a struct passed by pointer is
+		// incremented once the statement succeeds.
+		value := reflect.ValueOf(arg)
+		switch {
+		case value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Struct:
+		case value.Kind() == reflect.Struct:
+			copied := reflect.New(value.Type())
+			copied.Elem().Set(value)
+			value = copied
+		default:
+			continue
+		}
+
+		info, err := q.reflect.Reflect(value.Interface())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		refStruct, ok := info.(sreflect.ReflectStruct)
+		if !ok {
+			continue
+		}
+		column, field, ok := versionField(refStruct)
+		if !ok {
+			continue
+		}
+
+		lock := &optimisticLock{
+			entity:  refStruct.Name,
+			table:   tableName(q.reflect.Config(), refStruct),
+			column:  column,
+			version: field.Value.Interface(),
+		}
+		if reflect.ValueOf(arg).Kind() == reflect.Ptr {
+			lock.field = field.Value
+		}
+		return lock, nil
+	}
+	return nil, nil
+}
+
+// versionField returns the field of the struct that's tagged with the
+// version option.
+func versionField(refStruct sreflect.ReflectStruct) (string, sreflect.ReflectField, bool) {
+	for _, name := range refStruct.DeclaredFieldNames() {
+		if field := refStruct.Fields[name]; field.Tag.Version {
+			return name, field, true
+		}
+	}
+	return "", sreflect.ReflectField{}, false
+}
+
+// versionedUpdate appends the increment of the version column to the SET
+// clause of the UPDATE statement, and the check of the version to its WHERE
+// clause. Both are placed before any comments at the end of their clauses, so
+// that a line comment doesn't swallow them.
+func versionedUpdate(stmt, column string) (string, bool) {
+	end := contentEnd(stmt)
+
+	var set, where *keywordOffset
+	setEnd, whereEnd := end, end
+	keywords := clauseKeywords(stmt[:end])
+	for i := range keywords {
+		keyword := &keywords[i]
+		switch keyword.word {
+		case "SET":
+			if set == nil {
+				set = keyword
+			}
+		case "WHERE":
+			if set != nil && where == nil {
+				where = keyword
+				setEnd = min(setEnd, keyword.start)
+			}
+		case "FROM":
+			if set != nil && where == nil {
+				setEnd = min(setEnd, keyword.start)
+			}
+		case "RETURNING", "ORDER", "LIMIT":
+			if set != nil {
+				setEnd = min(setEnd, keyword.start)
+				whereEnd = min(whereEnd, keyword.start)
+			}
+		}
+	}
+	if set == nil {
+		return stmt, false
+	}
+
+	check := fmt.Sprintf("%s = :%s", column, column)
+	setContent := contentEnd(stmt[:setEnd])
+	whereContent := contentEnd(stmt[:whereEnd])
+
+	var builder strings.Builder
+	builder.WriteString(stmt[:setContent])
+	fmt.Fprintf(&builder, ", %s = %s + 1", column, column)
+	if where != nil {
+		builder.WriteString(stmt[setContent:where.end])
+		fmt.Fprintf(&builder, " (%s) AND %s", strings.TrimSpace(stmt[where.end:whereContent]), check)
+	} else {
+		builder.WriteString(stmt[setContent:whereContent])
+		builder.WriteString(" WHERE " + check)
+	}
+	builder.WriteString(trailingText(stmt[whereContent:]))
+	return builder.String(), true
+}
+
+// hasNamedArgument returns true if the statement refers to the named
+// argument, with any of the prefixes of named arguments.
+func hasNamedArgument(stmt, name string) bool {
+	names, err := parseNames(stmt, 0)
+	if err != nil {
+		// The statement is left to report the error when it's compiled.
+		return true
+	}
+	for _, binding := range names {
+		if binding.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns a StaleObjectError if the statement didn't update any rows,
+// otherwise the version of the struct is incremented.
+func (l *optimisticLock) check(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "rows affected")
+	}
+	if affected == 0 {
+		return errors.WithStack(&StaleObjectError{
+			Entity:  l.entity,
+			Version: l.version,
+		})
+	}
+
+	if !l.field.IsValid() || !l.field.CanSet() {
+		return nil
+	}
+	switch l.field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		l.field.SetInt(l.field.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		l.field.SetUint(l.field.Uint() + 1)
+	}
+	return nil
+}
+
+// Update updates the row of the struct in its table, setting every column
+// apart from the primary key, which identifies the row. See Get for how the
+// table and primary key are found.
+//
+//  err := querier.Update(tx, &person)
+//
+// If the struct has a version field (db:"version,version") the update is
+// optimistically locked: the row is only updated if its version is that of
+// the struct, otherwise ErrStaleObject is returned. The version of the
+// struct is incremented by a successful update. UPDATE statements of the
+// table of the struct that are passed to Exec along with the struct are
+// locked in the same way.
+func (q *Querier) Update(tx *sql.Tx, value interface{}) error {
+	return q.UpdateContext(context.Background(), tx, value)
+}
+
+// UpdateContext updates the row of the struct in its table. See Update.
+func (q *Querier) UpdateContext(ctx context.Context, tx *sql.Tx, value interface{}) error {
+	table, refStruct, err := q.reflectTable(value)
+	if err != nil {
+		return err
+	}
+
+	var (
+		sets  []string
+		where []string
+	)
+	for _, name := range refStruct.DeclaredFieldNames() {
+		field := refStruct.Fields[name]
+		switch {
+		case field.Tag.PrimaryKey:
+			where = append(where, fmt.Sprintf("%s = :%s", name, name))
+		case !field.Tag.Version:
+			sets = append(sets, fmt.Sprintf("%s = :%s", name, name))
+		}
+	}
+	if len(where) == 0 {
+		return errors.Errorf("no primary key for table %q, expected fields with the pk tag option", table)
+	}
+	if len(sets) == 0 {
+		return errors.Errorf("no columns to update for table %q", table)
+	}
+
+	stmt := fmt.Sprintf("UPDATE %s SET %s WHERE %s;", table, strings.Join(sets, ", "), strings.Join(where, " AND "))
+	_, err = q.ExecContext(ctx, tx, stmt, value)
+	return err
+}
@@ -0,0 +1,134 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type versionPerson struct {
+	ID      int64  `db:"id,pk"`
+	Name    string `db:"name"`
+	Version int    `db:"version,version"`
+}
+
+func (versionPerson) TableName() string {
+	return "people"
+}
+
+func TestVersionedUpdate(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected string
+	}{{
+		stmt:     `UPDATE people SET name=:name WHERE id=:id;`,
+		expected: `UPDATE people SET name=:name, version = version + 1 WHERE (id=:id) AND version = :version;`,
+	}, {
+		stmt:     `UPDATE people SET name=:name WHERE id=:id OR name='where'`,
+		expected: `UPDATE people SET name=:name, version = version + 1 WHERE (id=:id OR name='where') AND version = :version`,
+	}, {
+		stmt:     `UPDATE people SET name=:name;`,
+		expected: `UPDATE people SET name=:name, version = version + 1 WHERE version = :version;`,
+	}, {
+		stmt:     `UPDATE people SET name=(SELECT name FROM other WHERE id=1) WHERE id=:id RETURNING id;`,
+		expected: `UPDATE people SET name=(SELECT name FROM other WHERE id=1), version = version + 1 WHERE (id=:id) AND version = :version RETURNING id;`,
+	}, {
+		stmt:     `UPDATE people SET name=o.name FROM other AS o WHERE people.id=o.id;`,
+		expected: `UPDATE people SET name=o.name, version = version + 1 FROM other AS o WHERE (people.id=o.id) AND version = :version;`,
+	}, {
+		stmt:     "UPDATE people SET name=:name WHERE id=:id -- rename\n;",
+		expected: "UPDATE people SET name=:name, version = version + 1 WHERE (id=:id) AND version = :version\n-- rename\n;",
+	}, {
+		stmt:     "UPDATE people SET name=:name -- rename\nWHERE id=:id /* by id */ RETURNING id;",
+		expected: "UPDATE people SET name=:name, version = version + 1 -- rename\nWHERE (id=:id) AND version = :version\n/* by id */ RETURNING id;",
+	}, {
+		stmt:     "UPDATE people SET name=:name -- everyone",
+		expected: "UPDATE people SET name=:name, version = version + 1 WHERE version = :version\n-- everyone",
+	}}
+	for i, test := range tests {
+		t.Logf("test %d: %s", i, test.stmt)
+		got, ok := versionedUpdate(test.stmt, "version")
+		assert.True(t, ok)
+		assert.Equal(t, got, test.expected)
+	}
+}
+
+func TestHasNamedArgument(t *testing.T) {
+	assert.True(t, hasNamedArgument(`UPDATE people SET version=:version;`, "version"))
+	assert.True(t, hasNamedArgument(`UPDATE people SET version=@version;`, "version"))
+	assert.True(t, hasNamedArgument(`UPDATE people SET version=$version;`, "version"))
+	assert.False(t, hasNamedArgument(`UPDATE people SET version=:versions;`, "version"))
+	assert.False(t, hasNamedArgument(`UPDATE people SET name=':version';`, "version"))
+}
+
+func TestUpdatesTable(t *testing.T) {
+	assert.True(t, updatesTable(`UPDATE people SET name=:name;`, "people"))
+	assert.True(t, updatesTable(`UPDATE OR IGNORE main."People" SET name=:name;`, "people"))
+	assert.True(t, updatesTable(`WITH a AS (SELECT id FROM audit) UPDATE people SET name=:name WHERE id IN a;`, "people"))
+	assert.False(t, updatesTable(`UPDATE audit SET note='seen' WHERE person_id=:id;`, "people"))
+}
+
+func TestExecWithOptimisticLock(t *testing.T) {
+	db := setupDB(t)
+
+	_, err := db.Exec(`
+CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, version INTEGER NOT NULL);
+CREATE TABLE audit (person_id INTEGER, note TEXT);
+INSERT INTO people VALUES (1, "fred", 1);
+INSERT INTO audit VALUES (1, "");
+`)
+	assert.Nil(t, err)
+
+	querier := NewQuerier()
+	runTx(t, db, func(tx *sql.Tx) error {
+		var person versionPerson
+		err := querier.Get(tx, &person, 1)
+		assert.Nil(t, err)
+		stale := person
+
+		person.Name = "frank"
+		err = querier.Update(tx, &person)
+		assert.Nil(t, err)
+		assert.Equal(t, person.Version, 2)
+
+		stale.Name = "bob"
+		_, err = querier.Exec(tx, `UPDATE people SET name=:name WHERE id=:id;`, stale)
+		assert.True(t, errors.Is(err, ErrStaleObject))
+		assert.Equal(t, err.Error(), "stale versionPerson: version 1 has been modified")
+
+		_, err = querier.Exec(tx, `UPDATE people SET name=:name WHERE id=:id;`, &person)
+		assert.Nil(t, err)
+		assert.Equal(t, person.Version, 3)
+
+		// Prepared statements are locked in the same way.
+		prepared, err := querier.Prepare(`UPDATE people SET name=:name WHERE id=:id;`)
+		assert.Nil(t, err)
+		_, err = prepared.Exec(tx, stale)
+		assert.True(t, errors.Is(err, ErrStaleObject))
+		_, err = prepared.Exec(tx, &person)
+		assert.Nil(t, err)
+		assert.Equal(t, person.Version, 4)
+
+		// Statements of other tables are left alone.
+		_, err = querier.Exec(tx, `UPDATE audit SET note='seen' WHERE person_id=:id;`, &person)
+		assert.Nil(t, err)
+		assert.Equal(t, person.Version, 4)
+
+		// Statements with a trailing comment are locked.
+		_, err = querier.Exec(tx, "UPDATE people SET name=:name WHERE id=:id -- rename\n", &person)
+		assert.Nil(t, err)
+		assert.Equal(t, person.Version, 5)
+
+		// Statements that manage the version themselves are left alone.
+		_, err = querier.Exec(tx, `UPDATE people SET version=:version WHERE id=:id;`, versionPerson{ID: 1, Version: 10})
+		assert.Nil(t, err)
+
+		var got versionPerson
+		err = querier.Get(tx, &got, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, got, versionPerson{ID: 1, Name: "frank", Version: 10})
+		return nil
+	})
+}
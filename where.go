@@ -0,0 +1,144 @@
+package sqlair
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+
+	sreflect "github.com/SimonRichardson/sqlair/reflect"
+	"github.com/pkg/errors"
+)
+
+// expandWhereClauses replaces the where clauses of the statement
+// ({where Filters}) with the conditions of the filter structs of the
+// arguments. Every field of a filter is a condition on the column of the same
+// name (name = :name), other than nil pointer fields, which are left out:
+//
+//  type Filters struct {
+//  	Name *string `db:"name"`
+//  	Age  *int    `db:"age"`
+//  }
+//
+//  SELECT {Person} FROM people {where Filters} ORDER BY name;
+//
+// The conditions are joined with AND, after the WHERE keyword. A where clause
+// without any conditions is removed, so every row is matched. The filters are
+// replaced by the values of the conditions, which are bound as named
+// arguments.
+func expandWhereClauses(cache *sreflect.ReflectCache, mapper fieldMapper, stmt string, args []interface{}) (string, []interface{}, error) {
+	offset := indexOfRecordArgs(stmt)
+	if offset < 0 {
+		return stmt, args, nil
+	}
+	records, err := parseRecords(stmt, offset)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		builder strings.Builder
+		last    int
+		used    = make(map[int]bool)
+		named   []interface{}
+	)
+	for _, record := range records {
+		if !record.where {
+			continue
+		}
+		index, err := findFilter(args, record.name)
+		if err != nil {
+			return "", nil, &offsetError{
+				err:    err,
+				offset: record.start,
+			}
+		}
+		used[index] = true
+
+		value, _ := unwrapAlias(args[index])
+		conditions, values, err := filterConditions(cache, mapper, value)
+		if err != nil {
+			return "", nil, &offsetError{
+				err:    err,
+				offset: record.start,
+			}
+		}
+		builder.WriteString(stmt[last:record.start])
+		if len(conditions) > 0 {
+			builder.WriteString("WHERE " + strings.Join(conditions, " AND "))
+		}
+		last = record.end
+		named = append(named, values...)
+	}
+	if len(used) == 0 {
+		return stmt, args, nil
+	}
+	builder.WriteString(stmt[last:])
+
+	// The values of the conditions take the place of the filters, so that
+	// the filters aren't used as the source of other named arguments.
+	result := make([]interface{}, 0, len(args)+len(named))
+	for i, arg := range args {
+		if !used[i] {
+			result = append(result, arg)
+		}
+	}
+	return builder.String(), append(result, named...), nil
+}
+
+// findFilter returns the index of the filter argument for the where clause,
+// which is matched by the alias of the argument or by the name of its type.
+func findFilter(args []interface{}, name string) (int, error) {
+	for i, arg := range args {
+		value, alias := unwrapAlias(arg)
+		typ := structType(value)
+		if typ == nil || typ.Kind() != reflect.Struct || isTimeType(typ) {
+			continue
+		}
+		if alias == name || (alias == "" && typ.Name() == name) {
+			return i, nil
+		}
+	}
+	return -1, &UnknownEntityError{
+		Name: name,
+	}
+}
+
+// filterConditions returns the conditions of the fields of the filter, in the
+// order they're declared in, along with their values.
+func filterConditions(cache *sreflect.ReflectCache, mapper fieldMapper, filter interface{}) ([]string, []interface{}, error) {
+	value := reflect.ValueOf(filter)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil, nil
+		}
+		value = value.Elem()
+	}
+
+	info, err := cache.Reflect(reflect.New(value.Type()).Interface())
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	refStruct, ok := info.(sreflect.ReflectStruct)
+	if !ok {
+		return nil, nil, errors.Errorf("expected struct, got %q", info.Kind())
+	}
+	refStruct = refStruct.Bind(value)
+
+	var (
+		conditions []string
+		values     []interface{}
+	)
+	for _, name := range refStruct.DeclaredFieldNames() {
+		field := refStruct.Fields[name]
+		if field.Value.Kind() == reflect.Ptr && field.Value.IsNil() {
+			continue
+		}
+		arg, err := mapper.argument(field)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "field %q", name)
+		}
+		conditions = append(conditions, name+" = :"+name)
+		values = append(values, sql.Named(name, arg))
+	}
+	return conditions, values, nil
+}
@@ -0,0 +1,113 @@
+package sqlair
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type wherePerson struct {
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+	City string `db:"city"`
+}
+
+type whereFilters struct {
+	Name *string `db:"name"`
+	Age  *int    `db:"age"`
+	City *string `db:"city"`
+}
+
+// whereSchema is a table of people with the city that they live in.
+const whereSchema = `
+CREATE TABLE people (name TEXT, age INTEGER, city TEXT);
+INSERT INTO people (name, age, city) VALUES ("fred", 21, "london"), ("frank", 42, "london"), ("jane", 21, "paris");
+`
+
+func TestQueryWithWhereClauses(t *testing.T) {
+	db := setupSchemaDB(t, whereSchema)
+
+	querier := NewQuerier()
+
+	query := func(stmt string, args ...interface{}) []string {
+		var persons []wherePerson
+		runTx(t, db, func(tx *sql.Tx) error {
+			getter, err := querier.ForMany(&persons)
+			assert.Nil(t, err)
+
+			return getter.Query(tx, stmt, args...)
+		})
+		names := make([]string, len(persons))
+		for i, person := range persons {
+			names[i] = person.Name
+		}
+		return names
+	}
+
+	age, city := 21, "london"
+
+	// Only the fields that are set are conditions.
+	names := query(`SELECT {wherePerson} FROM people {where whereFilters} ORDER BY name;`, whereFilters{Age: &age})
+	assert.Equal(t, names, []string{"fred", "jane"})
+
+	names = query(`SELECT {wherePerson} FROM people {where whereFilters} ORDER BY name;`, &whereFilters{Age: &age, City: &city})
+	assert.Equal(t, names, []string{"fred"})
+
+	// Without any conditions every row is matched.
+	names = query(`SELECT {wherePerson} FROM people {where whereFilters} ORDER BY name;`, whereFilters{})
+	assert.Equal(t, names, []string{"frank", "fred", "jane"})
+
+	// The filters can be used along with other named arguments.
+	names = query(`SELECT {wherePerson} FROM people {where whereFilters} ORDER BY name LIMIT :limit;`, map[string]interface{}{
+		"limit": 1,
+	}, whereFilters{City: &city})
+	assert.Equal(t, names, []string{"frank"})
+
+	// Aliased filters are matched by their alias.
+	names = query(`SELECT {wherePerson} FROM people {where filters} ORDER BY name;`, As("filters", whereFilters{City: &city}))
+	assert.Equal(t, names, []string{"frank", "fred"})
+}
+
+func TestExecWithWhereClauses(t *testing.T) {
+	db := setupSchemaDB(t, whereSchema)
+
+	querier := NewQuerier()
+
+	name := "fred"
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := querier.Exec(tx, `UPDATE people SET age=:new_age {where whereFilters};`, whereFilters{Name: &name}, sql.Named("new_age", 22))
+		return err
+	})
+
+	var age int
+	err := db.QueryRow(`SELECT age FROM people WHERE name="fred";`).Scan(&age)
+	assert.Nil(t, err)
+	assert.Equal(t, age, 22)
+}
+
+func TestQueryWithWhereClauseErrors(t *testing.T) {
+	db := setupSchemaDB(t, whereSchema)
+
+	querier := NewQuerier()
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	var persons []wherePerson
+	getter, err := querier.ForMany(&persons)
+	assert.Nil(t, err)
+
+	err = getter.Query(tx, `SELECT {wherePerson} FROM people {where whereFilters};`)
+	assert.True(t, errors.As(err, new(*UnknownEntityError)))
+	assert.Equal(t, err.Error(), `no entity found with the name "whereFilters" at 1:34
+	SELECT {wherePerson} FROM people {where whereFilters};
+	                                 ^`)
+
+	_, err = querier.Prepare(`SELECT {wherePerson} FROM people {where whereFilters};`, wherePerson{})
+	assert.Equal(t, err.Error(), `unexpected where clause "{where whereFilters}" without a filter argument at 1:34
+	SELECT {wherePerson} FROM people {where whereFilters};
+	                                 ^`)
+}